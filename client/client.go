@@ -0,0 +1,276 @@
+// Package client talks to a running Talaria over its REST and WebSocket
+// APIs. It imports neither the server nor the monitor package, so it builds
+// on any platform without cgo.
+//
+//	c := client.New("http://mac-mini.local:8080")
+//	if err := c.Login(ctx, "", password); err != nil { ... }
+//	m, err := c.Metrics(ctx)
+//	fmt.Println(m.CPU.UsagePercent, m.Values()["thermal.cpu_temp"])
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const csrfCookie = "talaria_csrf"
+
+// Client is safe for concurrent use.
+type Client struct {
+	BaseURL string // e.g. "http://mac-mini.local:8080"
+	// Token is sent as a Bearer token. Only token endpoints accept it:
+	// /api/v1/check (history.check_tokens), /api/v1/history/export
+	// (history.replica_tokens) and /api/v1/push (push_gateway.tokens).
+	// Everything else needs Login.
+	Token string
+	HTTP  *http.Client
+
+	mu       sync.Mutex
+	user     string
+	password string
+}
+
+// Error is a non-2xx response.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("talaria: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func New(baseURL string) *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		HTTP:    &http.Client{Jar: jar, Timeout: 30 * time.Second},
+	}
+}
+
+// Login starts a session. The credentials are kept so an expired session is
+// renewed transparently. user may be empty with the built-in password login.
+func (c *Client) Login(ctx context.Context, user, password string) error {
+	c.mu.Lock()
+	c.user, c.password = user, password
+	c.mu.Unlock()
+	return c.login(ctx)
+}
+
+func (c *Client) login(ctx context.Context) error {
+	c.mu.Lock()
+	body, _ := json.Marshal(map[string]string{"username": c.user, "password": c.password})
+	c.mu.Unlock()
+	return c.send(ctx, http.MethodPost, "/api/v1/login", body, nil)
+}
+
+func (c *Client) loggedIn() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.password != ""
+}
+
+// do sends a request and decodes a JSON answer into out (if non-nil). A 401
+// with stored credentials logs in again and retries once.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	err := c.send(ctx, method, path, body, out)
+	if e, ok := err.(*Error); ok && e.StatusCode == http.StatusUnauthorized && c.loggedIn() {
+		if err := c.login(ctx); err != nil {
+			return err
+		}
+		err = c.send(ctx, method, path, body, out)
+	}
+	return err
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out any) error {
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, rd)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if method != http.MethodGet {
+		req.Header.Set("X-CSRF-Token", c.cookie(csrfCookie))
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(msg, &e) == nil && e.Error != "" {
+			msg = []byte(e.Error)
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) cookie(name string) string {
+	if c.HTTP.Jar == nil {
+		return ""
+	}
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return ""
+	}
+	for _, ck := range c.HTTP.Jar.Cookies(u) {
+		if ck.Name == name {
+			return ck.Value
+		}
+	}
+	return ""
+}
+
+// Metrics returns the current payload, as served by /api/v1/metrics.
+func (c *Client) Metrics(ctx context.Context) (*Metrics, error) {
+	var m Metrics
+	if err := c.do(ctx, http.MethodGet, "/api/v1/metrics", nil, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// HistoryQuery selects a series from /api/v1/history. Zero From means 24h ago
+// and zero To means now. Step 0 lets the server choose.
+type HistoryQuery struct {
+	Metric string // a key such as "cpu.usage_percent", or a prefix like "cpu"
+	From   time.Time
+	To     time.Time
+	Step   time.Duration
+	Agg    string // "avg" (default), "min" or "max"
+	Host   string // a federated host on a central instance
+}
+
+type Point struct {
+	T     time.Time
+	Value float64
+}
+
+type Series struct {
+	Metric string
+	Points []Point
+}
+
+// Gap is a period with no samples because the machine slept, Talaria was not
+// running, or collection was idle.
+type Gap struct {
+	From   time.Time
+	To     time.Time
+	Reason string // "sleep", "restart" or "idle"
+}
+
+type History struct {
+	Step   time.Duration
+	Series []Series
+	Gaps   []Gap
+}
+
+func (c *Client) History(ctx context.Context, q HistoryQuery) (*History, error) {
+	v := url.Values{"metric": {q.Metric}}
+	if !q.From.IsZero() {
+		v.Set("from", strconv.FormatInt(q.From.UnixMilli(), 10))
+	}
+	if !q.To.IsZero() {
+		v.Set("to", strconv.FormatInt(q.To.UnixMilli(), 10))
+	}
+	if q.Step > 0 {
+		v.Set("step", strconv.FormatInt(q.Step.Milliseconds(), 10))
+	}
+	if q.Agg != "" {
+		v.Set("agg", q.Agg)
+	}
+	if q.Host != "" {
+		v.Set("host", q.Host)
+	}
+	var raw struct {
+		Step   int64 `json:"step"`
+		Series []struct {
+			Metric string       `json:"metric"`
+			Points [][2]float64 `json:"points"`
+		} `json:"series"`
+		Gaps []struct {
+			From   int64  `json:"from"`
+			To     int64  `json:"to"`
+			Reason string `json:"reason"`
+		} `json:"gaps"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/history?"+v.Encode(), nil, &raw); err != nil {
+		return nil, err
+	}
+	h := &History{Step: time.Duration(raw.Step) * time.Millisecond}
+	for _, s := range raw.Series {
+		series := Series{Metric: s.Metric, Points: make([]Point, len(s.Points))}
+		for i, p := range s.Points {
+			series.Points[i] = Point{T: time.UnixMilli(int64(p[0])), Value: p[1]}
+		}
+		h.Series = append(h.Series, series)
+	}
+	for _, g := range raw.Gaps {
+		h.Gaps = append(h.Gaps, Gap{From: time.UnixMilli(g.From), To: time.UnixMilli(g.To), Reason: g.Reason})
+	}
+	return h, nil
+}
+
+// Gauge is a custom value for the push gateway.
+type Gauge struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// Push submits custom gauges to /api/v1/push. It needs Token set to one of
+// push_gateway.tokens.
+func (c *Client) Push(ctx context.Context, gauges ...Gauge) error {
+	body, err := json.Marshal(gauges)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/api/v1/push", body, nil)
+}
+
+// SchemaField describes one payload field; see /api/v1/schema.
+type SchemaField struct {
+	Path        string `json:"path"`
+	Type        string `json:"type"`
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description,omitempty"`
+	Interval    string `json:"interval"`
+	Deprecated  string `json:"deprecated,omitempty"` // the replacement to move to
+}
+
+// Schema returns the server's payload schema version and field list.
+func (c *Client) Schema(ctx context.Context) (string, []SchemaField, error) {
+	var out struct {
+		Version string        `json:"version"`
+		Fields  []SchemaField `json:"fields"`
+	}
+	err := c.do(ctx, http.MethodGet, "/api/v1/schema", nil, &out)
+	return out.Version, out.Fields, err
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// SchemaMajor is the payload schema major version these types are written
+// against. Fields are only added within a major version.
+const SchemaMajor = 1
+
+// Metrics is one collection. The commonly used sections are typed; Raw keeps
+// the whole payload and Values flattens it, so fields without a Go type here
+// are still reachable under the keys history and alert rules use.
+type Metrics struct {
+	SchemaVersion string             `json:"schema_version"`
+	Timestamp     int64              `json:"timestamp"` // unix ms
+	Seq           uint64             `json:"seq"`
+	CPU           CPU                `json:"cpu"`
+	Memory        Memory             `json:"memory"`
+	Disks         []Disk             `json:"disks"`
+	Network       Network            `json:"network"`
+	Battery       Battery            `json:"battery"`
+	System        System             `json:"system"`
+	Thermal       Thermal            `json:"thermal"`
+	GPU           GPU                `json:"gpu"`
+	Health        Health             `json:"health"`
+	Custom        map[string]float64 `json:"custom"`
+
+	Raw json.RawMessage `json:"-"`
+}
+
+type CPU struct {
+	UsagePercent float64   `json:"usage_percent"`
+	CoreCount    int       `json:"core_count"`
+	PerCore      []float64 `json:"per_core"`
+	Model        string    `json:"model"`
+}
+
+type Memory struct {
+	TotalMB       uint64  `json:"total_mb"`
+	UsedMB        uint64  `json:"used_mb"`
+	FreeMB        uint64  `json:"free_mb"`
+	SwapUsedMB    uint64  `json:"swap_used_mb"`
+	UsedPercent   float64 `json:"used_percent"`
+	PressureLevel string  `json:"pressure_level"`
+}
+
+type Disk struct {
+	Filesystem  string  `json:"filesystem"`
+	MountPoint  string  `json:"mount_point"`
+	TotalGB     float64 `json:"total_gb"`
+	UsedGB      float64 `json:"used_gb"`
+	FreeGB      float64 `json:"free_gb"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+type Network struct {
+	BytesIn        uint64  `json:"bytes_in"`
+	BytesOut       uint64  `json:"bytes_out"`
+	BytesInRate    float64 `json:"bytes_in_rate"`  // bytes/s
+	BytesOutRate   float64 `json:"bytes_out_rate"` // bytes/s
+	LocalIP        string  `json:"local_ip"`
+	PublicIP       string  `json:"public_ip"`
+	WiFiSSID       string  `json:"wifi_ssid"`
+	ConnectionType string  `json:"connection_type"`
+}
+
+type Battery struct {
+	HasBattery    bool    `json:"has_battery"`
+	Percent       int     `json:"percent"`
+	Charging      bool    `json:"charging"`
+	PowerSource   string  `json:"power_source"`
+	TimeLeft      string  `json:"time_left"`
+	CycleCount    int     `json:"cycle_count"`
+	HealthPercent float64 `json:"health_percent"`
+}
+
+type System struct {
+	HostID    string `json:"host_id"`
+	Hostname  string `json:"hostname"`
+	OSVersion string `json:"os_version"`
+	Uptime    string `json:"uptime"`
+	BootTime  int64  `json:"boot_time"` // unix s
+	LoadAvg   string `json:"load_avg"`
+	Arch      string `json:"arch"`
+}
+
+type Thermal struct {
+	ThermalState string `json:"thermal_state"`
+	CPUTemp      int    `json:"cpu_temp"` // celsius, 0 if unavailable
+}
+
+type GPU struct {
+	Utilization int    `json:"utilization"`
+	Model       string `json:"model"`
+	CoreCount   int    `json:"core_count"`
+}
+
+type Health struct {
+	HealthScore        int  `json:"health_score"`
+	SIPEnabled         bool `json:"sip_enabled"`
+	FileVaultEnabled   bool `json:"filevault_enabled"`
+	FirewallEnabled    bool `json:"firewall_enabled"`
+	TimeMachineAgeMins int  `json:"tm_age_mins"`
+	KernelErrorsLast5m int  `json:"kernel_errors_last_5m"`
+}
+
+func (m *Metrics) UnmarshalJSON(data []byte) error {
+	type plain Metrics
+	if err := json.Unmarshal(data, (*plain)(m)); err != nil {
+		return err
+	}
+	m.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// Compatible reports whether the payload's schema major version matches
+// SchemaMajor. Servers older than schema versioning send none and count as
+// compatible.
+func (m *Metrics) Compatible() bool {
+	if m.SchemaVersion == "" {
+		return true
+	}
+	major, _, _ := strings.Cut(m.SchemaVersion, ".")
+	n, err := strconv.Atoi(major)
+	return err == nil && n == SchemaMajor
+}
+
+// Values maps every numeric leaf of the payload to its dotted key, e.g.
+// "cpu.usage_percent" or "disks.0.used_percent". Booleans become 0/1 and the
+// process list is skipped, matching the keys used by history and alerts.
+func (m *Metrics) Values() map[string]float64 {
+	out := make(map[string]float64)
+	var tree map[string]any
+	if json.Unmarshal(m.Raw, &tree) != nil {
+		return out
+	}
+	delete(tree, "processes")
+	flatten(out, "", tree)
+	return out
+}
+
+func flatten(out map[string]float64, prefix string, v any) {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			flatten(out, join(k), child)
+		}
+	case []any:
+		for i, child := range v {
+			flatten(out, join(strconv.Itoa(i)), child)
+		}
+	case float64:
+		out[prefix] = v
+	case bool:
+		if v {
+			out[prefix] = 1
+		} else {
+			out[prefix] = 0
+		}
+	}
+}
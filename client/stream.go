@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	streamReadTimeout = 2 * time.Minute // the server pings every 54s
+	maxStreamBackoff  = 30 * time.Second
+)
+
+// Stream calls fn with every payload the server broadcasts on /ws until ctx
+// is done, reconnecting with backoff (and logging in again if the session
+// expired) whenever the connection drops. fn runs on the reading goroutine;
+// a slow fn delays later payloads rather than dropping them. Stream needs a
+// session from Login.
+func (c *Client) Stream(ctx context.Context, fn func(*Metrics)) error {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := c.stream(ctx, fn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if e, ok := err.(*Error); ok && e.StatusCode == http.StatusUnauthorized {
+			if !c.loggedIn() {
+				return err
+			}
+			if lerr := c.login(ctx); lerr != nil {
+				if e, ok := lerr.(*Error); ok && e.StatusCode == http.StatusUnauthorized {
+					return lerr
+				}
+			}
+		}
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxStreamBackoff)
+	}
+}
+
+func (c *Client) stream(ctx context.Context, fn func(*Metrics)) error {
+	u, err := url.Parse(c.BaseURL + "/ws")
+	if err != nil {
+		return err
+	}
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+
+	header := http.Header{}
+	if c.HTTP.Jar != nil {
+		base, _ := url.Parse(c.BaseURL)
+		for _, ck := range c.HTTP.Jar.Cookies(base) {
+			header.Add("Cookie", ck.Name+"="+ck.Value)
+		}
+	}
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil && resp.StatusCode/100 != 1 {
+			return &Error{StatusCode: resp.StatusCode, Message: err.Error()}
+		}
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		// Other messages (sparkline seeds, query replies) carry a type.
+		var probe struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(data, &probe) != nil || probe.Type != "" {
+			continue
+		}
+		var m Metrics
+		if err := json.Unmarshal(data, &m); err != nil {
+			continue
+		}
+		fn(&m)
+	}
+}
@@ -6,14 +6,16 @@ require github.com/gorilla/websocket v1.5.3
 
 require github.com/creack/pty v1.1.24
 
-require golang.org/x/crypto v0.48.0
+require (
+	github.com/fatih/color v1.18.0
+	golang.org/x/crypto v0.48.0
+	golang.org/x/term v0.40.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/fatih/color v1.18.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/term v0.40.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
@@ -1,25 +1,43 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"github.com/fatih/color"
+	"golang.org/x/term"
 
 	"talaria/server"
 )
 
+const version = "1.0.0"
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "alerts":
+			os.Exit(server.AlertsCommand(os.Args[2:]))
+		case "check":
+			os.Exit(server.CheckCommand(os.Args[2:]))
+		case "report-bug":
+			os.Exit(server.BugReportCommand(os.Args[2:], version, openBrowser))
+		}
+	}
+
 	var (
 		noBrowser    = flag.Bool("no-browser", false, "Don't auto-open browser")
 		configPath   = flag.String("config", "config.yml", "Path to config file")
@@ -43,6 +61,9 @@ func main() {
 		
 		color.New(color.FgHiWhite, color.Bold).Println("  USAGE")
 		fmt.Println("    talaria [flags]")
+		fmt.Println("    talaria alerts test [-config <path>] [-history <file> | -url <url> -token <token>]")
+		fmt.Println("    talaria check -metric <key> [-warn <n>] [-crit <n>] [-below] [-local | -url <url> -token <token>]")
+		fmt.Println("    talaria report-bug [-config <path>] [-open]")
 		fmt.Println()
 
 		color.New(color.FgHiWhite, color.Bold).Println("  FLAGS")
@@ -61,6 +82,15 @@ func main() {
 		appleDim.Println("    Run headless (for servers) with a custom config file:")
 		appleCode.Println("    $ ./talaria -no-browser -config /etc/talaria/config.yml\n")
 
+		appleDim.Println("    Check alert thresholds against recorded history:")
+		appleCode.Println("    $ ./talaria alerts test -history export.ndjson\n")
+
+		appleDim.Println("    Run as a Nagios/Icinga check against the local instance:")
+		appleCode.Println("    $ ./talaria check -metric disk.used_percent -warn 80 -crit 90\n")
+
+		appleDim.Println("    Collect details for a bug report and open a GitHub issue:")
+		appleCode.Println("    $ ./talaria report-bug -open\n")
+
 		appleDim.Println("    Safely generate a bcrypt hash to paste into config.yml:")
 		appleCode.Println("    $ ./talaria -hash-password \"my_secret_password\"\n")
 	}
@@ -69,6 +99,9 @@ func main() {
 
 	if *silentFlag || *sFlag {
 		if os.Getenv("TALARIA_BACKGROUND") != "1" {
+			if info, running := server.RunningInstance(*configPath); running {
+				reportRunningInstance(info, *noBrowser)
+			}
 			cmd := exec.Command(os.Args[0], os.Args[1:]...)
 			cmd.Env = append(os.Environ(), "TALARIA_BACKGROUND=1")
 			if err := cmd.Start(); err != nil {
@@ -85,7 +118,7 @@ func main() {
 
 	if *versionFlag || *vFlag {
 		color.New(color.FgHiCyan, color.Bold).Println("\n  Talaria System Monitor")
-		color.New(color.FgHiWhite).Println("  Version:  1.0.0")
+		color.New(color.FgHiWhite).Println("  Version:  " + version)
 		color.New(color.FgHiBlack).Printf("  OS/Arch:  %s/%s\n", runtime.GOOS, runtime.GOARCH)
 		color.New(color.FgHiBlack).Printf("  Compiler: %s\n\n", runtime.Compiler)
 		os.Exit(0)
@@ -107,6 +140,28 @@ func main() {
 		color.New(color.FgRed, color.Bold).Printf("\n  [FATAL] Failed to load config from %s: %v\n", *configPath, err)
 		os.Exit(1)
 	}
+	if err := server.SetupLogging(); err != nil {
+		color.New(color.FgRed, color.Bold).Printf("\n  [FATAL] %v\n", err)
+		os.Exit(1)
+	}
+
+	addrs, err := server.ListenAddrs()
+	if err != nil {
+		color.New(color.FgRed, color.Bold).Printf("\n  [FATAL] %v\n", err)
+		os.Exit(1)
+	}
+	url := server.LocalURL()
+	tlsConfig, err := server.TLSConfig()
+	if err != nil {
+		color.New(color.FgRed, color.Bold).Printf("\n  [FATAL] %v\n", err)
+		os.Exit(1)
+	}
+
+	if info, err := server.AcquireInstanceLock(*configPath, url); err == server.ErrInstanceRunning {
+		reportRunningInstance(info, *noBrowser)
+	} else if err != nil {
+		slog.Warn("Failed to create instance lock", "err", err)
+	}
 
 	if server.GlobalConfig.Auth.PasswordHash == "" {
 		pwd := server.GenerateRandomPassword()
@@ -119,28 +174,35 @@ func main() {
 
 	server.SetPasswordHash(server.GlobalConfig.Auth.PasswordHash)
 
-	addr := fmt.Sprintf("%s:%d", server.GlobalConfig.Server.Host, server.GlobalConfig.Server.Port)
-	url := fmt.Sprintf("http://localhost:%d", server.GlobalConfig.Server.Port)
-
 	hub := server.NewHub()
 	go hub.Run()
+	server.StartServices(hub)
 
 	router := server.NewRouter(hub)
 
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: router,
+		Addr:      addrs[0],
+		Handler:   router,
+		TLSConfig: tlsConfig,
 
-		ReadHeaderTimeout: 5 * time.Second,
 		ConnState: func(c net.Conn, state http.ConnState) {
 			if state == http.StateNew {
-
+				if tc, ok := c.(*tls.Conn); ok {
+					c = tc.NetConn()
+				}
 				if tc, ok := c.(*net.TCPConn); ok {
 					tc.SetLinger(0)
 				}
 			}
 		},
 	}
+	server.ConfigureHTTPServer(srv)
+
+	listeners, err := server.Listeners()
+	if err != nil {
+		slog.Error("Server error", "err", err)
+		os.Exit(1)
+	}
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -150,10 +212,27 @@ func main() {
 		color.New(color.FgHiCyan, color.Bold).Println("  Talaria System Monitor")
 		fmt.Println()
 		
-		fmt.Print("  ")
-		color.New(color.FgHiBlack).Print("→")
-		fmt.Print(" Running at ")
-		color.New(color.FgHiBlue, color.Underline).Println(url)
+		if server.SocketsActivated() {
+			for _, ln := range listeners {
+				fmt.Print("  ")
+				color.New(color.FgHiBlack).Print("→")
+				fmt.Print(" Listening on ")
+				color.New(color.FgHiBlue).Println(ln.Addr().String())
+			}
+		} else {
+			if !server.GlobalConfig.Server.SocketOnly {
+				fmt.Print("  ")
+				color.New(color.FgHiBlack).Print("→")
+				fmt.Print(" Running at ")
+				color.New(color.FgHiBlue, color.Underline).Println(url)
+			}
+			if sock := server.GlobalConfig.Server.Socket; sock != "" {
+				fmt.Print("  ")
+				color.New(color.FgHiBlack).Print("→")
+				fmt.Print(" Listening on ")
+				color.New(color.FgHiBlue).Println(sock)
+			}
+		}
 		
 		fmt.Print("  ")
 		color.New(color.FgHiBlack).Print("→")
@@ -162,46 +241,101 @@ func main() {
 		fmt.Println(" to stop")
 		fmt.Println()
 
-		ln, err := server.NewListener(addr)
-		if err != nil {
-			color.New(color.FgRed, color.Bold).Printf("  [FATAL] Server error: %v\n", err)
-			os.Exit(1)
-		}
+		slog.Info("Talaria started", "version", version, "listen", addrs, "socket", server.GlobalConfig.Server.Socket)
 
-		server.NotifyTelegramStart()
+		server.NotifyStartup()
 
-		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
-			color.New(color.FgRed, color.Bold).Printf("  [FATAL] Server error: %v\n", err)
-			os.Exit(1)
+		for _, ln := range listeners {
+			go func(ln net.Listener) {
+				serve := srv.Serve
+				if _, unix := ln.(*net.UnixListener); tlsConfig != nil && !unix {
+					serve = func(ln net.Listener) error { return srv.ServeTLS(ln, "", "") }
+				}
+				if err := serve(ln); err != nil && err != http.ErrServerClosed {
+					slog.Error("Server error", "err", err)
+					os.Exit(1)
+				}
+			}(ln)
 		}
 	}()
 
-	if !*noBrowser {
+	if !*noBrowser && !server.GlobalConfig.Server.SocketOnly && !server.SocketsActivated() && os.Getenv("TALARIA_RESTARTED") != "1" {
 		go func() {
 			time.Sleep(300 * time.Millisecond)
 			openBrowser(url)
 		}()
 	}
 
-	<-stop
+	restart := false
+	select {
+	case <-stop:
+	case <-server.RestartRequested():
+		restart = true
+	}
 	fmt.Println()
 	fmt.Print("  ")
 	color.New(color.FgHiBlack).Print("→")
 	color.New(color.FgHiWhite).Println(" Shutting down...")
+	slog.Info("Shutting down", "restart", restart)
 
 	hub.Stop()
+	server.ResumeSuspendedProcesses()
+	server.RunShutdownHooks()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), server.ShutdownGrace())
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		color.New(color.FgRed, color.Bold).Printf("  [FATAL] Server forced to shutdown: %v\n", err)
+		slog.Error("Server forced to shut down", "err", err)
 		os.Exit(1)
 	}
 
 	fmt.Print("  ")
 	color.New(color.FgHiBlack).Print("→")
 	color.New(color.FgHiCyan, color.Bold).Println(" Bye!")
+
+	if restart {
+		exe, err := os.Executable()
+		if err == nil {
+			err = syscall.Exec(exe, os.Args, append(server.RestartEnv(), "TALARIA_RESTARTED=1"))
+		}
+		slog.Error("Failed to restart", "err", err)
+		os.Exit(1)
+	}
+}
+
+// reportRunningInstance shows where the existing instance lives, offers to
+// open its dashboard, and exits.
+func reportRunningInstance(info *server.InstanceInfo, noBrowser bool) {
+	fmt.Println()
+	color.New(color.FgHiYellow, color.Bold).Println("  [INFO] Talaria is already running with this config")
+	if info == nil {
+		os.Exit(1)
+	}
+
+	responding := server.InstanceResponding(info)
+	color.New(color.FgHiBlack).Printf("            PID:     %d\n", info.PID)
+	color.New(color.FgHiBlack).Printf("            Started: %s\n", time.Unix(info.Started, 0).Format("2006-01-02 15:04:05"))
+	color.New(color.FgHiBlack).Printf("            URL:     %s\n", info.URL)
+	if info.PublicURL != "" {
+		color.New(color.FgHiBlack).Printf("            Public:  %s\n", info.PublicURL)
+	}
+	if responding {
+		color.New(color.FgGreen).Println("            Status:  responding")
+	} else {
+		color.New(color.FgRed).Println("            Status:  not responding")
+	}
+	fmt.Println()
+
+	if responding && !noBrowser && term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Print("  Open its dashboard in the browser? [Y/n] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer == "" || answer == "y" || answer == "yes" {
+			openBrowser(info.URL)
+			os.Exit(0)
+		}
+	}
+	os.Exit(1)
 }
 
 func openBrowser(url string) {
@@ -9,16 +9,16 @@ import (
 )
 
 type BatteryMetrics struct {
-	Percent        int     `json:"percent"`
-	Charging       bool    `json:"charging"`
-	PowerSource    string  `json:"power_source"`
-	TimeLeft       string  `json:"time_left"`
-	HasBattery     bool    `json:"has_battery"`
-	CycleCount     int     `json:"cycle_count"`
-	DesignCapacity int     `json:"design_capacity_mah"` // mAh
-	MaxCapacity    int     `json:"max_capacity_mah"`    // mAh (current actual)
-	HealthPercent  float64 `json:"health_percent"`      // max/design * 100
-	Temperature    float64 `json:"temperature"`         // Celsius
+	Percent        int     `json:"percent" unit:"percent" desc:"Charge level"`
+	Charging       bool    `json:"charging" desc:"Battery is charging"`
+	PowerSource    string  `json:"power_source" desc:"AC Power or Battery Power"`
+	TimeLeft       string  `json:"time_left" desc:"Estimated time to empty or full"`
+	HasBattery     bool    `json:"has_battery" desc:"Machine has a battery"`
+	CycleCount     int     `json:"cycle_count" unit:"count" desc:"Charge cycles"`
+	DesignCapacity int     `json:"design_capacity_mah" unit:"mAh" desc:"Capacity when new"`                      // mAh
+	MaxCapacity    int     `json:"max_capacity_mah" unit:"mAh" desc:"Current full-charge capacity"`              // mAh (current actual)
+	HealthPercent  float64 `json:"health_percent" unit:"percent" desc:"Full-charge capacity relative to design"` // max/design * 100
+	Temperature    float64 `json:"temperature" unit:"celsius" desc:"Battery temperature"`                        // Celsius
 }
 
 var batteryCache = NewCachedValue[BatteryMetrics](3 * time.Second)
@@ -28,6 +28,7 @@ func GetBattery() BatteryMetrics {
 }
 
 func fetchBattery() BatteryMetrics {
+	defer markCollected("battery")
 	m := BatteryMetrics{}
 
 	type pmsetResult struct {
@@ -42,3 +42,9 @@ func (c *CachedValue[T]) Get(fetch func() T) T {
 	c.mu.Unlock()
 	return result
 }
+
+func (c *CachedValue[T]) Invalidate() {
+	c.mu.Lock()
+	c.last = time.Time{}
+	c.mu.Unlock()
+}
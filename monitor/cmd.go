@@ -2,32 +2,48 @@ package monitor
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os/exec"
+	"sync/atomic"
+	"time"
 )
 
+var commandTracing atomic.Bool
+
+func SetCommandTracing(on bool) {
+	commandTracing.Store(on)
+}
+
+func traceCmd(name string, args []string, start time.Time) {
+	if commandTracing.Load() {
+		slog.Info("Subprocess trace", "cmd", name, "args", args, "took", time.Since(start).Round(time.Microsecond))
+	}
+}
+
+func logCmdError(name string, args []string, err error) {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		slog.Warn("Subprocess failed", "cmd", name, "args", args, "err", err, "stderr", string(exitErr.Stderr))
+	} else {
+		slog.Warn("Subprocess failed", "cmd", name, "args", args, "err", err)
+	}
+}
+
 func RunCmd(ctx context.Context, name string, args ...string) ([]byte, error) {
+	defer traceCmd(name, args, time.Now())
 	cmd := exec.CommandContext(ctx, name, args...)
 	out, err := cmd.Output()
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			log.Printf("Subprocess error [%s %v]: %v, stderr: %s", name, args, err, string(exitErr.Stderr))
-		} else {
-			log.Printf("Subprocess error [%s %v]: %v", name, args, err)
-		}
+		logCmdError(name, args, err)
 	}
 	return out, err
 }
 
 func RunCmdPlain(name string, args ...string) ([]byte, error) {
+	defer traceCmd(name, args, time.Now())
 	cmd := exec.Command(name, args...)
 	out, err := cmd.Output()
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			log.Printf("Subprocess error [%s %v]: %v, stderr: %s", name, args, err, string(exitErr.Stderr))
-		} else {
-			log.Printf("Subprocess error [%s %v]: %v", name, args, err)
-		}
+		logCmdError(name, args, err)
 	}
 	return out, err
 }
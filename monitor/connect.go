@@ -11,22 +11,23 @@ import (
 )
 
 type ConnectivityMetrics struct {
-	ActiveConnections int               `json:"active_connections"` // ESTABLISHED
-	ListeningPorts    int               `json:"listening_ports"`    // LISTEN
-	VPNActive         bool              `json:"vpn_active"`
-	VPNInterface      string            `json:"vpn_interface"`
-	BluetoothDevices  []BluetoothDevice `json:"bluetooth_devices"`
+	ActiveConnections int               `json:"active_connections" unit:"count" desc:"Established TCP connections"` // ESTABLISHED
+	ListeningPorts    int               `json:"listening_ports" unit:"count" desc:"Listening TCP ports"`            // LISTEN
+	VPNActive         bool              `json:"vpn_active" desc:"A VPN tunnel is up"`
+	VPNInterface      string            `json:"vpn_interface" desc:"VPN interface name"`
+	BluetoothDevices  []BluetoothDevice `json:"bluetooth_devices" desc:"Paired Bluetooth devices"`
 }
 
 type BluetoothDevice struct {
-	Name      string `json:"name"`
-	Battery   string `json:"battery"` // "85%" or ""
-	Connected bool   `json:"connected"`
+	Name      string `json:"name" desc:"Device name"`
+	Battery   string `json:"battery" desc:"Battery level, e.g. 85%"` // "85%" or ""
+	Connected bool   `json:"connected" desc:"Device is connected"`
 }
 
 var (
 	cachedBluetooth   []BluetoothDevice
 	lastBluetoothTime time.Time
+	bluetoothInterval = 30 * time.Second
 	connMutex         sync.Mutex
 
 	connectCache = NewCachedValue[ConnectivityMetrics](2 * time.Second)
@@ -39,6 +40,7 @@ func GetConnectivity() ConnectivityMetrics {
 }
 
 func fetchConnectivity() ConnectivityMetrics {
+	defer markCollected("connectivity")
 	m := ConnectivityMetrics{}
 
 	conns, err := net.Connections("tcp")
@@ -85,7 +87,7 @@ func fetchConnectivity() ConnectivityMetrics {
 
 	connMutex.Lock()
 	now := time.Now()
-	if now.Sub(lastBluetoothTime) > 30*time.Second {
+	if now.Sub(lastBluetoothTime) > bluetoothInterval && !Throttled() {
 		go updateBluetooth()
 		lastBluetoothTime = now
 	}
@@ -95,6 +97,14 @@ func fetchConnectivity() ConnectivityMetrics {
 	return m
 }
 
+// SetBluetoothInterval changes how often system_profiler is asked for
+// Bluetooth devices (30s by default).
+func SetBluetoothInterval(d time.Duration) {
+	connMutex.Lock()
+	bluetoothInterval = d
+	connMutex.Unlock()
+}
+
 func updateBluetooth() {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -169,6 +179,7 @@ func updateBluetooth() {
 
 	connMutex.Lock()
 	cachedBluetooth = devices
+	markCollected("connectivity.bluetooth_devices")
 	connMutex.Unlock()
 }
 
@@ -178,12 +189,18 @@ type ConnectionDetails struct {
 }
 
 type ConnectionInfo struct {
-	Process  string `json:"process"`
-	PID      int    `json:"pid"`
-	Protocol string `json:"protocol"` // TCP
-	Local    string `json:"local"`
-	Remote   string `json:"remote"`
-	State    string `json:"state"`
+	Process     string `json:"process"`
+	PID         int    `json:"pid"`
+	Protocol    string `json:"protocol"` // TCP
+	Local       string `json:"local"`
+	Remote      string `json:"remote"`
+	RemoteIP    string `json:"remote_ip,omitempty"`
+	State       string `json:"state"`
+	ThreatMatch string `json:"threat_match,omitempty"` // source list that flagged RemoteIP
+
+	// Filled in by /api/connections on request.
+	RemoteHost    string `json:"remote_host,omitempty"`    // reverse DNS of RemoteIP
+	RemoteCountry string `json:"remote_country,omitempty"` // country code from whois
 }
 
 func GetConnectionDetails() ConnectionDetails {
@@ -229,6 +246,7 @@ func fetchConnectionDetails() ConnectionDetails {
 			d.Listening = append(d.Listening, info)
 		} else {
 			info.Remote = fmt.Sprintf("%s:%d", c.Raddr.IP, c.Raddr.Port)
+			info.RemoteIP = c.Raddr.IP
 			d.Active = append(d.Active, info)
 		}
 	}
@@ -16,10 +16,10 @@ import (
 )
 
 type CPUMetrics struct {
-	UsagePercent float64   `json:"usage_percent"`
-	CoreCount    int       `json:"core_count"`
-	PerCore      []float64 `json:"per_core"`
-	Model        string    `json:"model"`
+	UsagePercent float64   `json:"usage_percent" unit:"percent" desc:"Total CPU utilisation across all cores"`
+	CoreCount    int       `json:"core_count" unit:"count" desc:"Logical CPU cores"`
+	PerCore      []float64 `json:"per_core" unit:"percent" desc:"Utilisation of each logical core"`
+	Model        string    `json:"model" desc:"CPU brand string"`
 }
 
 var (
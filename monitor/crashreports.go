@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PanicReport is a kernel panic log left in DiagnosticReports after reboot.
+type PanicReport struct {
+	Path    string   `json:"path"`
+	ModTime int64    `json:"mod_time"` // unix seconds
+	Lines   []string `json:"lines"`    // head of the panic string
+}
+
+var panicReportDirs = []string{
+	"/Library/Logs/DiagnosticReports",
+	"/Library/Logs/DiagnosticReports/Retired",
+}
+
+const panicReportLines = 8
+
+// ListPanicReports returns the *.panic files on disk, oldest first, with the
+// first lines of each panic string.
+func ListPanicReports() []PanicReport {
+	var reports []PanicReport
+	for _, dir := range panicReportDirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.panic"))
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			reports = append(reports, PanicReport{Path: path, ModTime: info.ModTime().Unix(), Lines: readPanicLines(path)})
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ModTime < reports[j].ModTime })
+	return reports
+}
+
+// readPanicLines handles both the JSON format (a metadata line followed by a
+// body whose panicString carries the text) and older plain-text reports.
+func readPanicLines(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	text := string(data)
+	if i := bytes.IndexByte(data, '\n'); i > 0 && data[0] == '{' {
+		var body struct {
+			PanicString      string `json:"panicString"`
+			MacOSPanicString string `json:"macOSPanicString"`
+		}
+		if json.Unmarshal(data[i+1:], &body) == nil {
+			text = body.PanicString
+			if text == "" {
+				text = body.MacOSPanicString
+			}
+		}
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() && len(lines) < panicReportLines {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+var rePanicLine = regexp.MustCompile(`(?i)\bpanic\b`)
+
+// KernelPanicLines returns the recent kernel error lines that mention a panic.
+func KernelPanicLines() []string {
+	healthMutex.Lock()
+	defer healthMutex.Unlock()
+	var out []string
+	for _, line := range cachedKernelLogs {
+		if rePanicLine.MatchString(line) {
+			out = append(out, line)
+		}
+	}
+	return out
+}
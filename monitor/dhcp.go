@@ -0,0 +1,199 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type DHCPLease struct {
+	Interface    string   `json:"interface" desc:"Interface name"`
+	IP           string   `json:"ip" desc:"Leased address"`
+	Server       string   `json:"server" desc:"DHCP server"`
+	Router       string   `json:"router" desc:"Router option"`
+	DNS          []string `json:"dns" desc:"DNS servers"`
+	Domain       string   `json:"domain" desc:"Domain name option"`
+	LeaseSeconds int      `json:"lease_seconds" unit:"seconds" desc:"Lease duration"`
+	LeaseStart   int64    `json:"lease_start" unit:"unix seconds" desc:"Lease start, 0 if unknown"`    // unix seconds, 0 if unknown
+	LeaseExpires int64    `json:"lease_expires" unit:"unix seconds" desc:"Lease expiry, 0 if unknown"` // unix seconds, 0 if unknown
+}
+
+type GatewayInfo struct {
+	IP         string  `json:"ip" desc:"Default gateway address"`
+	Interface  string  `json:"interface" desc:"Interface of the default route"`
+	Reachable  bool    `json:"reachable" desc:"Gateway answered ping"`
+	LatencyMs  float64 `json:"latency_ms" unit:"ms" desc:"Average ping round trip"`
+	PacketLoss float64 `json:"packet_loss" unit:"percent" desc:"Ping packet loss"` // percent
+	CheckedAt  int64   `json:"checked_at" unit:"unix seconds" desc:"Time of the last check"`
+}
+
+var (
+	cachedLeases    []DHCPLease
+	cachedGateway   GatewayInfo
+	lastRouterCheck time.Time
+	routerPending   bool
+	routerMutex     sync.Mutex
+
+	reRouteGateway   = regexp.MustCompile(`(?m)^\s*gateway:\s*(\S+)`)
+	reRouteInterface = regexp.MustCompile(`(?m)^\s*interface:\s*(\S+)`)
+	rePingRTT        = regexp.MustCompile(`= [\d.]+/([\d.]+)/`)
+	rePingLoss       = regexp.MustCompile(`([\d.]+)% packet loss`)
+	reLeaseStart     = regexp.MustCompile(`<key>LeaseStartDate</key>\s*<date>([^<]+)</date>`)
+)
+
+func getRouterInfo() ([]DHCPLease, GatewayInfo) {
+	routerMutex.Lock()
+	defer routerMutex.Unlock()
+
+	if time.Since(lastRouterCheck) > 30*time.Second && !routerPending {
+		routerPending = true
+		go updateRouterInfo()
+	}
+	return cachedLeases, cachedGateway
+}
+
+func updateRouterInfo() {
+	defer func() {
+		routerMutex.Lock()
+		lastRouterCheck = time.Now()
+		routerPending = false
+		routerMutex.Unlock()
+	}()
+
+	var leases []DHCPLease
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			if iface.Flags&net.FlagUp == 0 || !strings.HasPrefix(iface.Name, "en") {
+				continue
+			}
+			if lease, ok := readDHCPLease(iface.Name); ok {
+				leases = append(leases, lease)
+			}
+		}
+	}
+
+	gw := checkGateway()
+
+	routerMutex.Lock()
+	cachedLeases = leases
+	cachedGateway = gw
+	routerMutex.Unlock()
+}
+
+func readDHCPLease(iface string) (DHCPLease, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// ipconfig exits non-zero for interfaces without a lease (static IP, unplugged)
+	out, err := RunCmd(ctx, "ipconfig", "getpacket", iface)
+	if err != nil || len(out) == 0 {
+		return DHCPLease{}, false
+	}
+
+	lease := DHCPLease{Interface: iface}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			if k, v, ok := strings.Cut(line, "="); ok && strings.TrimSpace(k) == "yiaddr" {
+				lease.IP = strings.TrimSpace(v)
+			}
+			continue
+		}
+		val = strings.TrimSpace(val)
+		switch {
+		case strings.HasPrefix(key, "server_identifier"):
+			lease.Server = val
+		case strings.HasPrefix(key, "router"):
+			if list := parseIPList(val); len(list) > 0 {
+				lease.Router = list[0]
+			}
+		case strings.HasPrefix(key, "domain_name_server"):
+			lease.DNS = parseIPList(val)
+		case strings.HasPrefix(key, "domain_name "):
+			lease.Domain = val
+		case strings.HasPrefix(key, "lease_time"):
+			if n, err := strconv.ParseInt(strings.TrimPrefix(val, "0x"), 16, 64); err == nil {
+				lease.LeaseSeconds = int(n)
+			}
+		}
+	}
+
+	if start := dhcpLeaseStart(iface); !start.IsZero() {
+		lease.LeaseStart = start.Unix()
+		if lease.LeaseSeconds > 0 {
+			lease.LeaseExpires = start.Add(time.Duration(lease.LeaseSeconds) * time.Second).Unix()
+		}
+	}
+	return lease, true
+}
+
+func parseIPList(val string) []string {
+	val = strings.Trim(val, "{}")
+	var ips []string
+	for _, p := range strings.Split(val, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			ips = append(ips, p)
+		}
+	}
+	return ips
+}
+
+// The lease start is only recorded in configd's lease cache, one plist per interface.
+func dhcpLeaseStart(iface string) time.Time {
+	matches, _ := filepath.Glob("/var/db/dhcpclient/leases/" + iface + "*")
+	if len(matches) == 0 {
+		return time.Time{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, err := RunCmd(ctx, "plutil", "-convert", "xml1", "-o", "-", matches[0])
+	if err != nil {
+		return time.Time{}
+	}
+	if m := reLeaseStart.FindSubmatch(out); m != nil {
+		if t, err := time.Parse(time.RFC3339, string(m[1])); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func checkGateway() GatewayInfo {
+	gw := GatewayInfo{CheckedAt: time.Now().Unix()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, err := RunCmd(ctx, "route", "-n", "get", "default")
+	if err != nil {
+		return gw
+	}
+	if m := reRouteGateway.FindSubmatch(out); m != nil {
+		gw.IP = string(m[1])
+	}
+	if m := reRouteInterface.FindSubmatch(out); m != nil {
+		gw.Interface = string(m[1])
+	}
+	if gw.IP == "" {
+		return gw
+	}
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 6*time.Second)
+	defer cancel2()
+	// ping exits 2 when nothing answers; the summary is still printed
+	pingOut, _ := RunCmd(ctx2, "ping", "-n", "-q", "-c", "3", "-t", "5", gw.IP)
+	if m := rePingLoss.FindSubmatch(pingOut); m != nil {
+		gw.PacketLoss, _ = strconv.ParseFloat(string(m[1]), 64)
+	}
+	if m := rePingRTT.FindSubmatch(pingOut); m != nil {
+		gw.LatencyMs, _ = strconv.ParseFloat(string(m[1]), 64)
+		gw.Reachable = true
+	}
+	return gw
+}
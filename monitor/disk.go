@@ -10,26 +10,26 @@ import (
 )
 
 type DiskInfo struct {
-	Filesystem string  `json:"filesystem"`
-	MountPoint string  `json:"mount_point"`
-	TotalGB    float64 `json:"total_gb"`
-	UsedGB     float64 `json:"used_gb"`
-	FreeGB     float64 `json:"free_gb"`
-	UsedPct    float64 `json:"used_percent"`
+	Filesystem string  `json:"filesystem" desc:"Filesystem type"`
+	MountPoint string  `json:"mount_point" desc:"Mount point"`
+	TotalGB    float64 `json:"total_gb" unit:"GB" desc:"Volume capacity"`
+	UsedGB     float64 `json:"used_gb" unit:"GB" desc:"Space used"`
+	FreeGB     float64 `json:"free_gb" unit:"GB" desc:"Space available"`
+	UsedPct    float64 `json:"used_percent" unit:"percent" desc:"Space used as a share of capacity"`
 }
 
 type StorageCategory struct {
-	Name string  `json:"name"`
-	Size float64 `json:"size_gb"`
-	Icon string  `json:"icon"`
+	Name string  `json:"name" desc:"Category name"`
+	Size float64 `json:"size_gb" unit:"GB" desc:"Space used by the category"`
+	Icon string  `json:"icon" desc:"Icon identifier for the dashboard"`
 }
 
 type StorageBreakdown struct {
-	TotalGB     float64           `json:"total_gb"`
-	UsedGB      float64           `json:"used_gb"`
-	FreeGB      float64           `json:"free_gb"`
-	PurgeableGB float64           `json:"purgeable_gb"` // APFS purgeable (local TM snapshots)
-	Categories  []StorageCategory `json:"categories"`
+	TotalGB     float64           `json:"total_gb" unit:"GB" desc:"Boot volume capacity"`
+	UsedGB      float64           `json:"used_gb" unit:"GB" desc:"Space used on the boot volume"`
+	FreeGB      float64           `json:"free_gb" unit:"GB" desc:"Space available on the boot volume"`
+	PurgeableGB float64           `json:"purgeable_gb" unit:"GB" desc:"APFS purgeable space (local Time Machine snapshots)"` // APFS purgeable (local TM snapshots)
+	Categories  []StorageCategory `json:"categories" desc:"Space used per category"`
 }
 
 var (
@@ -115,6 +115,7 @@ func GetDisks() []DiskInfo {
 	diskMutex.Lock()
 	cachedDisks = disks
 	lastDiskTime = time.Now()
+	markCollected("disks")
 	diskMutex.Unlock()
 
 	return disks
@@ -350,6 +351,7 @@ func updateBreakdown() {
 	breakdownMutex.Lock()
 	cachedBreakdown = breakdown
 	lastBreakdownUpdate = time.Now()
+	markCollected("storage_breakdown")
 	breakdownPending = false
 	breakdownMutex.Unlock()
 }
@@ -8,12 +8,12 @@ import (
 )
 
 type DiskIOMetrics struct {
-	ReadMBps  float64 `json:"read_mbps"`  // Read throughput MB/s
-	WriteMBps float64 `json:"write_mbps"` // Write throughput MB/s
-	TotalMBps float64 `json:"total_mbps"` // Combined throughput
-	ReadMB    float64 `json:"read_mb"`    // Cumulative read since boot
-	WriteMB   float64 `json:"write_mb"`   // Cumulative write since boot
-	TotalMB   float64 `json:"total_mb"`   // Cumulative total since boot
+	ReadMBps  float64 `json:"read_mbps" unit:"MB/s" desc:"Read throughput"`               // Read throughput MB/s
+	WriteMBps float64 `json:"write_mbps" unit:"MB/s" desc:"Write throughput"`             // Write throughput MB/s
+	TotalMBps float64 `json:"total_mbps" unit:"MB/s" desc:"Combined throughput"`          // Combined throughput
+	ReadMB    float64 `json:"read_mb" unit:"MB" desc:"Data read since boot"`              // Cumulative read since boot
+	WriteMB   float64 `json:"write_mb" unit:"MB" desc:"Data written since boot"`          // Cumulative write since boot
+	TotalMB   float64 `json:"total_mb" unit:"MB" desc:"Data read and written since boot"` // Cumulative total since boot
 }
 
 var (
@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type FocusStatus struct {
+	Active bool   `json:"active" desc:"A Focus mode is on"`
+	Mode   string `json:"mode" desc:"Name of the active Focus mode"` // "Do Not Disturb", "Work", ... or "" when off
+}
+
+var focusCache = NewCachedValue[FocusStatus](5 * time.Second)
+
+func GetFocus() FocusStatus {
+	return focusCache.Get(fetchFocus)
+}
+
+func InvalidateFocus() {
+	focusCache.Invalidate()
+}
+
+func fetchFocus() FocusStatus {
+	defer markCollected("system.focus")
+	m := FocusStatus{}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return m
+	}
+	dbDir := filepath.Join(home, "Library", "DoNotDisturb", "DB")
+
+	var assertions struct {
+		Data []struct {
+			StoreAssertionRecords []struct {
+				AssertionDetails struct {
+					ModeIdentifier string `json:"assertionDetailsModeIdentifier"`
+				} `json:"assertionDetails"`
+			} `json:"storeAssertionRecords"`
+		} `json:"data"`
+	}
+	raw, err := os.ReadFile(filepath.Join(dbDir, "Assertions.json"))
+	if err != nil || json.Unmarshal(raw, &assertions) != nil {
+		return fetchFocusLegacy()
+	}
+
+	modeID := ""
+	for _, d := range assertions.Data {
+		for _, rec := range d.StoreAssertionRecords {
+			if id := rec.AssertionDetails.ModeIdentifier; id != "" {
+				modeID = id
+				break
+			}
+		}
+	}
+	if modeID == "" {
+		return m
+	}
+
+	m.Active = true
+	m.Mode = focusModeName(dbDir, modeID)
+	return m
+}
+
+func focusModeName(dbDir, modeID string) string {
+	var configs struct {
+		Data []struct {
+			ModeConfigurations map[string]struct {
+				Mode struct {
+					Name string `json:"name"`
+				} `json:"mode"`
+			} `json:"modeConfigurations"`
+		} `json:"data"`
+	}
+	raw, err := os.ReadFile(filepath.Join(dbDir, "ModeConfigurations.json"))
+	if err == nil && json.Unmarshal(raw, &configs) == nil {
+		for _, d := range configs.Data {
+			if c, ok := d.ModeConfigurations[modeID]; ok && c.Mode.Name != "" {
+				return c.Mode.Name
+			}
+		}
+	}
+
+	if modeID == "com.apple.donotdisturb.mode.default" {
+		return "Do Not Disturb"
+	}
+	return strings.TrimPrefix(modeID, "com.apple.focus.")
+}
+
+// Pre-Monterey systems keep DND as a plain NotificationCenter preference.
+func fetchFocusLegacy() FocusStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	out, err := RunCmd(ctx, "defaults", "-currentHost", "read", "com.apple.notificationcenterui", "doNotDisturb")
+	if err != nil {
+		return FocusStatus{}
+	}
+	if strings.TrimSpace(string(out)) == "1" {
+		return FocusStatus{Active: true, Mode: "Do Not Disturb"}
+	}
+	return FocusStatus{}
+}
@@ -0,0 +1,32 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	collectedAt   = make(map[string]time.Time)
+	collectedAtMu sync.Mutex
+)
+
+// markCollected records that the data behind name was just refreshed. Names
+// are section keys ("battery") or "section.field" for values that are cached
+// separately from the rest of their section.
+func markCollected(name string) {
+	collectedAtMu.Lock()
+	collectedAt[name] = time.Now()
+	collectedAtMu.Unlock()
+}
+
+// CollectedAt returns when each cached section or field was last refreshed,
+// in unix milliseconds.
+func CollectedAt() map[string]int64 {
+	collectedAtMu.Lock()
+	defer collectedAtMu.Unlock()
+	out := make(map[string]int64, len(collectedAt))
+	for name, t := range collectedAt {
+		out[name] = t.UnixMilli()
+	}
+	return out
+}
@@ -9,13 +9,13 @@ import (
 )
 
 type GPUMetrics struct {
-	Utilization  int    `json:"utilization"`   // Device Utilization %
-	RendererUtil int    `json:"renderer_util"` // Renderer Utilization %
-	TilerUtil    int    `json:"tiler_util"`    // Tiler Utilization %
-	VRAMUsedMB   uint64 `json:"vram_used_mb"`  // In use system memory
-	VRAMAllocMB  uint64 `json:"vram_alloc_mb"` // Alloc system memory
-	Model        string `json:"model"`         // e.g. "Apple M1"
-	CoreCount    int    `json:"core_count"`    // gpu-core-count
+	Utilization  int    `json:"utilization" unit:"percent" desc:"Device utilisation"`     // Device Utilization %
+	RendererUtil int    `json:"renderer_util" unit:"percent" desc:"Renderer utilisation"` // Renderer Utilization %
+	TilerUtil    int    `json:"tiler_util" unit:"percent" desc:"Tiler utilisation"`       // Tiler Utilization %
+	VRAMUsedMB   uint64 `json:"vram_used_mb" unit:"MiB" desc:"GPU memory in use"`         // In use system memory
+	VRAMAllocMB  uint64 `json:"vram_alloc_mb" unit:"MiB" desc:"GPU memory allocated"`     // Alloc system memory
+	Model        string `json:"model" desc:"GPU model"`                                   // e.g. "Apple M1"
+	CoreCount    int    `json:"core_count" unit:"count" desc:"GPU cores"`                 // gpu-core-count
 }
 
 var (
@@ -35,6 +35,7 @@ func GetGPU() GPUMetrics {
 }
 
 func fetchGPU() GPUMetrics {
+	defer markCollected("gpu")
 	m := GPUMetrics{}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type HardwareInfo struct {
+	ModelName    string          `json:"model_name"`
+	ModelID      string          `json:"model_id"`
+	ModelYear    int             `json:"model_year"` // 0 if unknown
+	AgeYears     int             `json:"age_years"`  // approximate, from ModelYear
+	Chip         string          `json:"chip"`
+	Cores        string          `json:"cores"` // "proc 8:4:4" style from system_profiler
+	Serial       string          `json:"serial"`
+	MemoryTotal  string          `json:"memory_total"`
+	MemoryModule []MemoryModule  `json:"memory_modules"`
+	Storage      []StorageDevice `json:"storage"`
+	Ready        bool            `json:"ready"` // false until the startup scan finishes
+}
+
+type MemoryModule struct {
+	Slot         string `json:"slot"`
+	Size         string `json:"size"`
+	Type         string `json:"type"`
+	Speed        string `json:"speed"`
+	Manufacturer string `json:"manufacturer"`
+}
+
+type StorageDevice struct {
+	Model    string `json:"model"`
+	Medium   string `json:"medium"`   // "ssd", "rotational"
+	Protocol string `json:"protocol"` // "Apple Fabric", "PCI-Express", "USB"
+	Internal bool   `json:"internal"`
+}
+
+var (
+	hardwareInfo   HardwareInfo
+	hardwareInfoMu sync.RWMutex
+
+	reModelYear = regexp.MustCompile(`\b((?:19|20)\d{2})\b`)
+)
+
+func init() {
+	go loadHardwareInfo()
+}
+
+func GetHardwareInfo() HardwareInfo {
+	hardwareInfoMu.RLock()
+	defer hardwareInfoMu.RUnlock()
+	return hardwareInfo
+}
+
+func loadHardwareInfo() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	info := HardwareInfo{Ready: true}
+	out, err := RunCmd(ctx, "system_profiler", "-json", "SPHardwareDataType", "SPMemoryDataType", "SPStorageDataType")
+	if err == nil {
+		var data struct {
+			Hardware []struct {
+				MachineName  string `json:"machine_name"`
+				MachineModel string `json:"machine_model"`
+				ModelNumber  string `json:"model_number"`
+				ChipType     string `json:"chip_type"`
+				CPUType      string `json:"cpu_type"`
+				Processors   string `json:"number_processors"`
+				Serial       string `json:"serial_number"`
+				Memory       string `json:"physical_memory"`
+			} `json:"SPHardwareDataType"`
+			Memory  []memoryItem `json:"SPMemoryDataType"`
+			Storage []struct {
+				Physical struct {
+					DeviceName string `json:"device_name"`
+					Medium     string `json:"medium_type"`
+					Protocol   string `json:"protocol"`
+					Internal   string `json:"is_internal_disk"`
+				} `json:"physical_drive"`
+			} `json:"SPStorageDataType"`
+		}
+		if json.Unmarshal(out, &data) == nil {
+			if len(data.Hardware) > 0 {
+				hw := data.Hardware[0]
+				info.ModelID = hw.MachineModel
+				info.Chip = hw.ChipType
+				if info.Chip == "" {
+					info.Chip = hw.CPUType
+				}
+				info.Cores = hw.Processors
+				info.Serial = hw.Serial
+				info.MemoryTotal = hw.Memory
+				info.ModelName = hw.MachineName
+			}
+
+			for _, item := range data.Memory {
+				info.MemoryModule = append(info.MemoryModule, item.modules()...)
+			}
+
+			seen := make(map[string]bool)
+			for _, v := range data.Storage {
+				p := v.Physical
+				if p.DeviceName == "" || seen[p.DeviceName] {
+					continue
+				}
+				seen[p.DeviceName] = true
+				info.Storage = append(info.Storage, StorageDevice{
+					Model:    p.DeviceName,
+					Medium:   p.Medium,
+					Protocol: p.Protocol,
+					Internal: p.Internal == "yes",
+				})
+			}
+		}
+	}
+
+	// The marketing name carries the year ("MacBook Pro (14-inch, 2021)").
+	if id := GetHostIdentity(); id.ModelName != "" {
+		info.ModelName = id.ModelName
+	}
+	if m := reModelYear.FindStringSubmatch(info.ModelName); m != nil {
+		info.ModelYear, _ = strconv.Atoi(m[1])
+		if age := time.Now().Year() - info.ModelYear; age >= 0 {
+			info.AgeYears = age
+		}
+	}
+
+	hardwareInfoMu.Lock()
+	hardwareInfo = info
+	hardwareInfoMu.Unlock()
+}
+
+// Apple Silicon reports unified memory as a single item; Intel Macs nest one
+// item per DIMM slot.
+type memoryItem struct {
+	Name         string       `json:"_name"`
+	Size         string       `json:"dimm_size"`
+	Unified      string       `json:"SPMemoryDataType"`
+	Type         string       `json:"dimm_type"`
+	Speed        string       `json:"dimm_speed"`
+	Manufacturer string       `json:"dimm_manufacturer"`
+	Items        []memoryItem `json:"_items"`
+}
+
+func (m memoryItem) modules() []MemoryModule {
+	var mods []MemoryModule
+	size := m.Size
+	if size == "" {
+		size = m.Unified
+	}
+	if m.Type != "" && size != "" && size != "empty" {
+		mods = append(mods, MemoryModule{
+			Slot:         m.Name,
+			Size:         size,
+			Type:         m.Type,
+			Speed:        m.Speed,
+			Manufacturer: m.Manufacturer,
+		})
+	}
+	for _, child := range m.Items {
+		mods = append(mods, child.modules()...)
+	}
+	return mods
+}
@@ -3,7 +3,7 @@ package monitor
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"regexp"
 	"strconv"
@@ -13,23 +13,25 @@ import (
 )
 
 type HealthMetrics struct {
-	SIPEnabled       bool `json:"sip_enabled"`
-	FileVaultEnabled bool `json:"filevault_enabled"`
-	FirewallEnabled  bool `json:"firewall_enabled"`
+	SIPEnabled       bool `json:"sip_enabled" desc:"System Integrity Protection is on"`
+	FileVaultEnabled bool `json:"filevault_enabled" desc:"FileVault disk encryption is on"`
+	FirewallEnabled  bool `json:"firewall_enabled" desc:"Application firewall is on" interval:"5s"`
 
-	TimeMachineLastBackup string  `json:"tm_last_backup"`
-	TimeMachineStatus     string  `json:"tm_status"`    // "Running", "Idle", "Error", "Unknown"
-	TimeMachinePercent    float64 `json:"tm_percent"`   // backup progress 0-100 if running, -1 if not
-	TimeMachineAgeMins    int     `json:"tm_age_mins"`  // minutes since last backup, -1 if never
-	TimeMachineAgeLabel   string  `json:"tm_age_label"` // human-readable age: "2h 15m", "3d", etc.
+	TimeMachineLastBackup string  `json:"tm_last_backup" desc:"Last Time Machine backup" interval:"15s"`
+	TimeMachineStatus     string  `json:"tm_status" desc:"Running, Idle, Error or Unknown" interval:"15s"`                          // "Running", "Idle", "Error", "Unknown"
+	TimeMachinePercent    float64 `json:"tm_percent" unit:"percent" desc:"Backup progress, -1 when idle" interval:"15s"`            // backup progress 0-100 if running, -1 if not
+	TimeMachineAgeMins    int     `json:"tm_age_mins" unit:"minutes" desc:"Time since the last backup, -1 if never" interval:"15s"` // minutes since last backup, -1 if never
+	TimeMachineAgeLabel   string  `json:"tm_age_label" desc:"Time since the last backup, human-readable" interval:"15s"`            // human-readable age: "2h 15m", "3d", etc.
 
-	KernelErrorsLast5m int      `json:"kernel_errors_last_5m"`
-	KernelLogs         []string `json:"kernel_logs"` // The actual log lines for transparency
+	KernelErrorsLast5m int      `json:"kernel_errors_last_5m" unit:"count" desc:"Kernel errors logged in the last 5 minutes" interval:"60s"`
+	KernelLogs         []string `json:"kernel_logs" desc:"Recent kernel error lines" interval:"60s"` // The actual log lines for transparency
 
-	ErrorHistory []int `json:"error_history"` // Now tracks Kernel Errors only
+	ErrorHistory []int `json:"error_history" unit:"count" desc:"Kernel error counts, oldest first" interval:"60s"` // Now tracks Kernel Errors only
 
-	HealthScore int    `json:"health_score"` // 0-100 overall health
-	ErrorTrend  string `json:"error_trend"`  // "rising", "stable", "falling"
+	LeakSuspects []LeakSuspect `json:"leak_suspects" desc:"Processes whose memory has grown steadily for hours"`
+
+	HealthScore int    `json:"health_score" unit:"score" desc:"Overall health 0-100"` // 0-100 overall health
+	ErrorTrend  string `json:"error_trend" desc:"rising, stable or falling"`          // "rising", "stable", "falling"
 }
 
 const errorHistorySize = 30
@@ -129,6 +131,7 @@ func GetHealth() HealthMetrics {
 	}
 
 	checkSecurity(&m)
+	m.LeakSuspects = GetLeakSuspects()
 
 	healthMutex.Lock()
 	now := time.Now()
@@ -154,6 +157,7 @@ func GetHealth() HealthMetrics {
 		cachedTMAgeMins = m.TimeMachineAgeMins
 		cachedTMAgeLabel = m.TimeMachineAgeLabel
 		lastTMCheckTime = now
+		markCollected("health.time_machine")
 		if parsed {
 			_ = backupTime // stored for potential future use
 		}
@@ -161,7 +165,7 @@ func GetHealth() HealthMetrics {
 	}
 
 	healthMutex.Lock()
-	if now.Sub(lastErrorCheck) > 60*time.Second && !kernelErrorsPending {
+	if now.Sub(lastErrorCheck) > 60*time.Second && !kernelErrorsPending && !Throttled() {
 
 		kernelErrorsPending = true
 		go updateKernelErrors()
@@ -281,7 +285,7 @@ func checkTimeMachine(m *HealthMetrics) (backupTime time.Time, parsed bool) {
 func updateKernelErrors() {
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("Panic in updateKernelErrors: %v", r)
+			slog.Error("Panic", "where", "updateKernelErrors", "value", r)
 			healthMutex.Lock()
 			kernelErrorsPending = false
 			healthMutex.Unlock()
@@ -330,6 +334,7 @@ func updateKernelErrors() {
 	}
 	lastErrorCheck = time.Now()
 	kernelErrorsPending = false
+	markCollected("health.kernel_errors")
 	healthMutex.Unlock()
 }
 
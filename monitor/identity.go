@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type HostIdentity struct {
+	HostID    string `json:"host_id" desc:"Stable host ID derived from the hardware UUID"` // hashed IOPlatformUUID, stable across renames
+	ModelID   string `json:"model_id" desc:"Model identifier, e.g. Mac14,2"`               // "Mac14,2"
+	ModelName string `json:"model_name" desc:"Marketing model name"`                       // "MacBook Air (M2, 2022)"
+}
+
+var (
+	hostIdentity   HostIdentity
+	hostIdentityMu sync.RWMutex
+
+	rePlatformUUID = regexp.MustCompile(`"IOPlatformUUID"\s*=\s*"([^"]+)"`)
+	reCPUName      = regexp.MustCompile(`=\s*"([^"]+)"`)
+)
+
+func init() {
+	go loadHostIdentity()
+}
+
+func GetHostIdentity() HostIdentity {
+	hostIdentityMu.RLock()
+	defer hostIdentityMu.RUnlock()
+	return hostIdentity
+}
+
+func loadHostIdentity() {
+	id := HostIdentity{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if out, err := RunCmd(ctx, "ioreg", "-rd1", "-c", "IOPlatformExpertDevice"); err == nil {
+		if m := rePlatformUUID.FindSubmatch(out); m != nil {
+			sum := sha256.Sum256(append([]byte("talaria-host:"), m[1]...))
+			id.HostID = hex.EncodeToString(sum[:16])
+		}
+	}
+
+	if out, err := RunCmdPlain("sysctl", "-n", "hw.model"); err == nil {
+		id.ModelID = strings.TrimSpace(string(out))
+	}
+
+	// System Information caches the marketing name ("MacBook Air (M2, 2022)") once it has been opened.
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel2()
+	if out, err := RunCmd(ctx2, "defaults", "read", "com.apple.SystemProfiler", "CPU Names"); err == nil {
+		if m := reCPUName.FindSubmatch(out); m != nil {
+			id.ModelName = string(m[1])
+		}
+	}
+	if id.ModelName == "" {
+		id.ModelName = modelNameFromProfiler()
+	}
+	if id.HostID == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			sum := sha256.Sum256([]byte("talaria-host:" + hostname))
+			id.HostID = hex.EncodeToString(sum[:16])
+		}
+	}
+
+	hostIdentityMu.Lock()
+	hostIdentity = id
+	hostIdentityMu.Unlock()
+}
+
+func modelNameFromProfiler() string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := RunCmd(ctx, "system_profiler", "SPHardwareDataType", "-json")
+	if err != nil {
+		return ""
+	}
+	var data struct {
+		Hardware []struct {
+			MachineName string `json:"machine_name"`
+		} `json:"SPHardwareDataType"`
+	}
+	if json.Unmarshal(out, &data) != nil || len(data.Hardware) == 0 {
+		return ""
+	}
+	return data.Hardware[0].MachineName
+}
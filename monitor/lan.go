@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LANNeighbor is one resolved entry in the ARP cache.
+type LANNeighbor struct {
+	IP        string `json:"ip"`
+	MAC       string `json:"mac"`
+	Interface string `json:"interface"`
+}
+
+// "? (192.168.1.20) at 0:1c:42:a:b:c on en0 ifscope [ethernet]"
+var reARPEntry = regexp.MustCompile(`\(([\d.]+)\) at ([0-9a-fA-F:]+) on (\S+)`)
+
+// GetLANNeighbors reads the ARP cache. Discovery is passive: a device shows up
+// once it has exchanged traffic with this Mac or broadcast an ARP request.
+func GetLANNeighbors() []LANNeighbor {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	out, err := RunCmd(ctx, "arp", "-an")
+	if err != nil {
+		return nil
+	}
+
+	var neighbors []LANNeighbor
+	for _, line := range strings.Split(string(out), "\n") {
+		m := reARPEntry.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		mac, ok := normalizeMAC(m[2])
+		if !ok {
+			continue
+		}
+		neighbors = append(neighbors, LANNeighbor{IP: m[1], MAC: mac, Interface: m[3]})
+	}
+	return neighbors
+}
+
+// normalizeMAC zero-pads arp's "0:1c:42:a:b:c" form and rejects broadcast and
+// multicast addresses, which are not devices.
+func normalizeMAC(s string) (string, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 6 {
+		return "", false
+	}
+	for i, p := range parts {
+		b, err := strconv.ParseUint(p, 16, 8)
+		if err != nil {
+			return "", false
+		}
+		if i == 0 && b&1 == 1 {
+			return "", false
+		}
+		parts[i] = strconv.FormatUint(b|0x100, 16)[1:]
+	}
+	return strings.Join(parts, ":"), true
+}
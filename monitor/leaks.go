@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	rssSampleEvery   = 10 * time.Minute
+	rssMaxSamples    = 37 // six hours of steps
+	leakMinSamples   = 13 // two hours of steps
+	leakMinGrowthMB  = 100
+	leakMinGrowthPct = 25
+)
+
+// LeakSuspect is a process whose resident memory has grown steadily for hours.
+type LeakSuspect struct {
+	PID         int     `json:"pid" desc:"Process ID"`
+	Name        string  `json:"name" desc:"Executable name"`
+	StartMB     float64 `json:"start_mb" unit:"MiB" desc:"Resident memory at the start of the window"`
+	CurrentMB   float64 `json:"current_mb" unit:"MiB" desc:"Resident memory now"`
+	MBPerHour   float64 `json:"mb_per_hour" unit:"MiB/h" desc:"Average growth rate"`
+	WindowHours float64 `json:"window_hours" unit:"hours" desc:"How long the growth has been observed"`
+}
+
+type rssSample struct {
+	at time.Time
+	mb float64
+}
+
+var cachedLeakSuspects []LeakSuspect // guarded by procMutex
+
+// trackRSS keeps one RSS sample per rssSampleEvery on the cached process.
+// Called with procMutex held.
+func (cp *cachedProc) trackRSS(now time.Time, mb float64) {
+	if n := len(cp.rss); n > 0 && now.Sub(cp.rss[n-1].at) < rssSampleEvery {
+		return
+	}
+	cp.rss = append(cp.rss, rssSample{now, mb})
+	if len(cp.rss) > rssMaxSamples {
+		cp.rss = append(cp.rss[:0:0], cp.rss[len(cp.rss)-rssMaxSamples:]...)
+	}
+}
+
+// leakSuspect reports whether the samples show sustained growth: no step may
+// drop by more than 2%, most steps must rise, and the total rise must be
+// large both absolutely and relative to the starting size.
+func (cp *cachedProc) leakSuspect(pid int32) (LeakSuspect, bool) {
+	if len(cp.rss) < leakMinSamples {
+		return LeakSuspect{}, false
+	}
+	rises := 0
+	for i := 1; i < len(cp.rss); i++ {
+		prev, cur := cp.rss[i-1].mb, cp.rss[i].mb
+		if cur < prev*0.98 {
+			return LeakSuspect{}, false
+		}
+		if cur > prev {
+			rises++
+		}
+	}
+	first, last := cp.rss[0], cp.rss[len(cp.rss)-1]
+	growth := last.mb - first.mb
+	if rises*3 < (len(cp.rss)-1)*2 || growth < leakMinGrowthMB || growth < first.mb*leakMinGrowthPct/100 {
+		return LeakSuspect{}, false
+	}
+	hours := last.at.Sub(first.at).Hours()
+	return LeakSuspect{
+		PID:         int(pid),
+		Name:        cp.name,
+		StartMB:     first.mb,
+		CurrentMB:   last.mb,
+		MBPerHour:   growth / hours,
+		WindowHours: hours,
+	}, true
+}
+
+// updateLeakSuspects records RSS for every live process and recomputes the
+// suspect list. Called with procMutex held.
+func updateLeakSuspects(infos []ProcessInfo, now time.Time) {
+	var suspects []LeakSuspect
+	for _, p := range infos {
+		cp := procCache[int32(p.PID)]
+		if cp == nil {
+			continue
+		}
+		cp.trackRSS(now, p.MemMB)
+		if s, ok := cp.leakSuspect(int32(p.PID)); ok {
+			suspects = append(suspects, s)
+		}
+	}
+	sort.Slice(suspects, func(i, j int) bool { return suspects[i].MBPerHour > suspects[j].MBPerHour })
+	cachedLeakSuspects = suspects
+}
+
+// GetLeakSuspects returns the processes flagged by the last process scan,
+// fastest-growing first.
+func GetLeakSuspects() []LeakSuspect {
+	procMutex.Lock()
+	defer procMutex.Unlock()
+	return cachedLeakSuspects
+}
@@ -13,18 +13,18 @@ import (
 )
 
 type MemoryMetrics struct {
-	TotalMB       uint64  `json:"total_mb"`
-	UsedMB        uint64  `json:"used_mb"`
-	FreeMB        uint64  `json:"free_mb"`
-	WiredMB       uint64  `json:"wired_mb"`
-	ActiveMB      uint64  `json:"active_mb"`
-	InactiveMB    uint64  `json:"inactive_mb"`
-	CompressedMB  uint64  `json:"compressed_mb"`
-	PurgeableMB   uint64  `json:"purgeable_mb"`
-	SwapTotalMB   uint64  `json:"swap_total_mb"`
-	SwapUsedMB    uint64  `json:"swap_used_mb"`
-	UsedPercent   float64 `json:"used_percent"`
-	PressureLevel string  `json:"pressure_level"` // "Normal", "Warn", "Critical"
+	TotalMB       uint64  `json:"total_mb" unit:"MiB" desc:"Physical memory"`
+	UsedMB        uint64  `json:"used_mb" unit:"MiB" desc:"Memory in use (app + wired + compressed)"`
+	FreeMB        uint64  `json:"free_mb" unit:"MiB" desc:"Memory available without swapping"`
+	WiredMB       uint64  `json:"wired_mb" unit:"MiB" desc:"Wired memory that cannot be paged out"`
+	ActiveMB      uint64  `json:"active_mb" unit:"MiB" desc:"Recently used pages"`
+	InactiveMB    uint64  `json:"inactive_mb" unit:"MiB" desc:"Pages not recently used"`
+	CompressedMB  uint64  `json:"compressed_mb" unit:"MiB" desc:"Memory held by the compressor"`
+	PurgeableMB   uint64  `json:"purgeable_mb" unit:"MiB" desc:"Purgeable memory"`
+	SwapTotalMB   uint64  `json:"swap_total_mb" unit:"MiB" desc:"Swap file size"`
+	SwapUsedMB    uint64  `json:"swap_used_mb" unit:"MiB" desc:"Swap in use"`
+	UsedPercent   float64 `json:"used_percent" unit:"percent" desc:"Used memory as a share of total"`
+	PressureLevel string  `json:"pressure_level" desc:"Kernel memory pressure: Normal, Warn or Critical"` // "Normal", "Warn", "Critical"
 }
 
 func vmStatsFromMach() (active, inactive, wired, free, compressed, purgeable uint64, ok bool) {
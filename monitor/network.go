@@ -13,21 +13,23 @@ import (
 )
 
 type NetworkMetrics struct {
-	BytesIn        uint64             `json:"bytes_in"`
-	BytesOut       uint64             `json:"bytes_out"`
-	BytesInRate    float64            `json:"bytes_in_rate"`
-	BytesOutRate   float64            `json:"bytes_out_rate"`
-	Interfaces     []NetworkInterface `json:"interfaces"`
-	LocalIP        string             `json:"local_ip"`
-	PublicIP       string             `json:"public_ip"`
-	WiFiSSID       string             `json:"wifi_ssid"`
-	ConnectionType string             `json:"connection_type"` // "Wi-Fi", "Ethernet", "Unknown"
+	BytesIn        uint64             `json:"bytes_in" unit:"bytes" desc:"Bytes received since boot, all interfaces"`
+	BytesOut       uint64             `json:"bytes_out" unit:"bytes" desc:"Bytes sent since boot, all interfaces"`
+	BytesInRate    float64            `json:"bytes_in_rate" unit:"bytes/s" desc:"Receive rate"`
+	BytesOutRate   float64            `json:"bytes_out_rate" unit:"bytes/s" desc:"Send rate"`
+	Interfaces     []NetworkInterface `json:"interfaces" desc:"Per-interface counters"`
+	LocalIP        string             `json:"local_ip" desc:"Primary LAN address"`
+	PublicIP       string             `json:"public_ip" desc:"Public address as seen from the internet" interval:"60s"`
+	WiFiSSID       string             `json:"wifi_ssid" desc:"Connected Wi-Fi network" interval:"5s"`
+	ConnectionType string             `json:"connection_type" desc:"Wi-Fi, Ethernet or Unknown"` // "Wi-Fi", "Ethernet", "Unknown"
+	DHCP           []DHCPLease        `json:"dhcp" desc:"DHCP leases of active interfaces" interval:"30s"`
+	Gateway        GatewayInfo        `json:"gateway" desc:"Default gateway reachability" interval:"30s"`
 }
 
 type NetworkInterface struct {
-	Name     string `json:"name"`
-	BytesIn  uint64 `json:"bytes_in"`
-	BytesOut uint64 `json:"bytes_out"`
+	Name     string `json:"name" desc:"Interface name"`
+	BytesIn  uint64 `json:"bytes_in" unit:"bytes" desc:"Bytes received since boot"`
+	BytesOut uint64 `json:"bytes_out" unit:"bytes" desc:"Bytes sent since boot"`
 }
 
 var (
@@ -37,6 +39,7 @@ var (
 
 	cachedPublicIP   string
 	lastPublicIPTime time.Time
+	publicIPChanged  func(ip string)
 
 	cachedSSID   string
 	lastSSIDTime time.Time
@@ -80,6 +83,7 @@ func GetNetwork() NetworkMetrics {
 	}
 
 	m.LocalIP, m.ConnectionType = getLocalIP()
+	m.DHCP, m.Gateway = getRouterInfo()
 
 	now := time.Now()
 	netMutex.Lock()
@@ -141,6 +145,7 @@ func GetNetwork() NetworkMetrics {
 			netMutex.Lock()
 			cachedSSID = newSSID
 			lastSSIDTime = now
+			markCollected("network.ssid")
 			netMutex.Unlock()
 			m.WiFiSSID = newSSID
 		} else {
@@ -227,8 +232,27 @@ func updatePublicIP() {
 	ip := strings.TrimSpace(string(body))
 	if len(ip) > 0 {
 		netMutex.Lock()
+		old := cachedPublicIP
 		cachedPublicIP = ip
+		markCollected("network.public_ip")
 		publicIPRefreshPending = false // success: back to normal 60s cycle
+		fn := publicIPChanged
 		netMutex.Unlock()
+		if fn != nil && ip != old {
+			fn(ip)
+		}
+	}
+}
+
+// OnPublicIPChange registers fn to be called with the new address whenever a
+// refresh returns a different public IP. If an address is already known, fn
+// is called with it right away.
+func OnPublicIPChange(fn func(ip string)) {
+	netMutex.Lock()
+	publicIPChanged = fn
+	current := cachedPublicIP
+	netMutex.Unlock()
+	if current != "" {
+		fn(current)
 	}
 }
@@ -0,0 +1,54 @@
+package monitor
+
+/*
+#cgo CFLAGS: -fobjc-arc
+#cgo LDFLAGS: -framework OpenDirectory -framework Foundation
+#include <objc/runtime.h>
+#include <objc/message.h>
+#include <stdbool.h>
+#include <stdlib.h>
+
+// kODNodeTypeAuthentication: the search node the login window uses.
+#define OD_NODE_TYPE_AUTHENTICATION 0x2201
+
+static id ns_string(const char* s) {
+    return ((id (*)(id, SEL, const char*))objc_msgSend)(
+        (id)objc_getClass("NSString"), sel_registerName("stringWithUTF8String:"), s);
+}
+
+static bool od_verify_password(const char* user, const char* pass) {
+    Class nodeCls = objc_getClass("ODNode");
+    Class sessionCls = objc_getClass("ODSession");
+    if (!nodeCls || !sessionCls) return false;
+
+    id session = ((id (*)(id, SEL))objc_msgSend)(
+        (id)sessionCls, sel_registerName("defaultSession"));
+    if (!session) return false;
+
+    id node = ((id (*)(id, SEL, id, unsigned int, id*))objc_msgSend)(
+        (id)nodeCls, sel_registerName("nodeWithSession:type:error:"),
+        session, OD_NODE_TYPE_AUTHENTICATION, NULL);
+    if (!node) return false;
+
+    id record = ((id (*)(id, SEL, id, id, id, id*))objc_msgSend)(
+        node, sel_registerName("recordWithRecordType:name:attributes:error:"),
+        ns_string("dsRecTypeStandard:Users"), ns_string(user), nil, NULL);
+    if (!record) return false;
+
+    return ((bool (*)(id, SEL, id, id*))objc_msgSend)(
+        record, sel_registerName("verifyPassword:error:"), ns_string(pass), NULL);
+}
+*/
+import "C"
+
+import "unsafe"
+
+// VerifyLocalPassword checks a macOS account password through OpenDirectory,
+// so it never appears on a command line.
+func VerifyLocalPassword(user, password string) bool {
+	cu := C.CString(user)
+	cp := C.CString(password)
+	defer C.free(unsafe.Pointer(cu))
+	defer C.free(unsafe.Pointer(cp))
+	return bool(C.od_verify_password(cu, cp))
+}
@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/host"
+)
+
+const (
+	PowerBoot     = "boot"
+	PowerShutdown = "shutdown"
+	PowerSleep    = "sleep"
+	PowerWake     = "wake"
+)
+
+type PowerEvent struct {
+	Time int64  `json:"time"` // unix seconds
+	Kind string `json:"kind"`
+}
+
+var (
+	powerEventsCache = NewCachedValue[[]PowerEvent](10 * time.Minute)
+
+	rePmsetLine = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} [+-]\d{4})\s+(\S+)`)
+	reLastLine  = regexp.MustCompile(`^(reboot|shutdown)\s.*?(\w{3} \w{3} [ \d]\d \d{2}:\d{2})`)
+)
+
+// GetPowerEvents merges boots and shutdowns from wtmp (`last`) with sleep/wake
+// transitions from the power management log, oldest first. Coverage is
+// limited by how far back macOS keeps each log.
+func GetPowerEvents() []PowerEvent {
+	return powerEventsCache.Get(func() []PowerEvent {
+		events := append(lastEvents(), pmsetEvents()...)
+		if bt, err := host.BootTime(); err == nil {
+			events = append(events, PowerEvent{Time: int64(bt), Kind: PowerBoot})
+		}
+
+		sort.Slice(events, func(i, j int) bool { return events[i].Time < events[j].Time })
+		// wtmp and kern.boottime can both report the current boot.
+		out := events[:0]
+		for _, e := range events {
+			if n := len(out); n > 0 && out[n-1].Kind == e.Kind && e.Time-out[n-1].Time < 120 {
+				continue
+			}
+			out = append(out, e)
+		}
+		return out
+	})
+}
+
+func pmsetEvents() []PowerEvent {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := RunCmd(ctx, "pmset", "-g", "log")
+	if err != nil {
+		return nil
+	}
+
+	var events []PowerEvent
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		m := rePmsetLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		var kind string
+		switch m[2] {
+		case "Sleep":
+			kind = PowerSleep
+		case "Wake": // DarkWake is a maintenance wake; the display stays asleep
+			kind = PowerWake
+		default:
+			continue
+		}
+		if t, err := time.Parse("2006-01-02 15:04:05 -0700", m[1]); err == nil {
+			events = append(events, PowerEvent{Time: t.Unix(), Kind: kind})
+		}
+	}
+	return events
+}
+
+// lastEvents parses `last reboot shutdown`, which omits the year: entries are
+// newest first, so a timestamp jumping forward means we crossed a new year.
+func lastEvents() []PowerEvent {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := RunCmd(ctx, "last", "reboot", "shutdown")
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	year := now.Year()
+	newer := now.Add(24 * time.Hour)
+	var events []PowerEvent
+	for _, line := range strings.Split(string(out), "\n") {
+		m := reLastLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		t, err := time.ParseInLocation("2006 Mon Jan _2 15:04", strconv.Itoa(year)+" "+m[2], time.Local)
+		if err != nil {
+			continue
+		}
+		if t.After(newer) {
+			year--
+			t = t.AddDate(-1, 0, 0)
+		}
+		newer = t
+		kind := PowerBoot
+		if m[1] == "shutdown" {
+			kind = PowerShutdown
+		}
+		events = append(events, PowerEvent{Time: t.Unix(), Kind: kind})
+	}
+	return events
+}
@@ -0,0 +1,77 @@
+package monitor
+
+/*
+#cgo LDFLAGS: -framework IOKit -framework CoreFoundation
+#include <IOKit/pwr_mgt/IOPMLib.h>
+#include <IOKit/IOMessage.h>
+
+extern void goSystemPowerEvent(int);
+
+static io_connect_t root_port;
+
+static void power_callback(void* refCon, io_service_t service, natural_t messageType, void* messageArgument) {
+    switch (messageType) {
+    case kIOMessageCanSystemSleep:
+        IOAllowPowerChange(root_port, (long)messageArgument);
+        break;
+    case kIOMessageSystemWillSleep:
+        // Sleep is held until the Go side returns (macOS caps this at ~30s).
+        goSystemPowerEvent(1);
+        IOAllowPowerChange(root_port, (long)messageArgument);
+        break;
+    case kIOMessageSystemHasPoweredOn:
+        goSystemPowerEvent(2);
+        break;
+    }
+}
+
+static int run_power_loop() {
+    IONotificationPortRef port;
+    io_object_t notifier;
+    root_port = IORegisterForSystemPower(NULL, &port, power_callback, &notifier);
+    if (!root_port) return 0;
+    CFRunLoopAddSource(CFRunLoopGetCurrent(), IONotificationPortGetRunLoopSource(port), kCFRunLoopDefaultMode);
+    CFRunLoopRun();
+    return 1;
+}
+*/
+import "C"
+
+import (
+	"log/slog"
+	"runtime"
+	"sync"
+)
+
+var (
+	powerWatchFn   func(event string)
+	powerWatchOnce sync.Once
+)
+
+// WatchSystemPower calls fn with PowerSleep before the system sleeps (sleep
+// waits for fn to return) and PowerWake after it wakes. Only the first call
+// registers a handler.
+func WatchSystemPower(fn func(event string)) {
+	powerWatchOnce.Do(func() {
+		powerWatchFn = fn
+		go func() {
+			runtime.LockOSThread()
+			if C.run_power_loop() == 0 {
+				slog.Warn("IORegisterForSystemPower failed; sleep hooks disabled")
+			}
+		}()
+	})
+}
+
+//export goSystemPowerEvent
+func goSystemPowerEvent(kind C.int) {
+	if powerWatchFn == nil {
+		return
+	}
+	switch kind {
+	case 1:
+		powerWatchFn(PowerSleep)
+	case 2:
+		powerWatchFn(PowerWake)
+	}
+}
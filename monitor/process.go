@@ -4,24 +4,30 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/shirou/gopsutil/v4/process"
 )
 
 type ProcessInfo struct {
-	PID    int     `json:"pid"`
-	Name   string  `json:"name"`
-	CPU    float64 `json:"cpu"`
-	MemMB  float64 `json:"mem_mb"`
-	MemPct float64 `json:"mem_percent"`
-	User   string  `json:"user"`
+	PID     int     `json:"pid" desc:"Process ID"`
+	Name    string  `json:"name" desc:"Executable name"`
+	CPU     float64 `json:"cpu" unit:"percent" desc:"CPU usage (100 = one core)"`
+	MemMB   float64 `json:"mem_mb" unit:"MiB" desc:"Resident memory"`
+	MemPct  float64 `json:"mem_percent" unit:"percent" desc:"Resident memory as a share of physical memory"`
+	User    string  `json:"user" desc:"Owning user"`
+	Command string  `json:"command" desc:"Command line, redacted"`
 }
 
+const maxCommandLen = 1024
+
 type cachedProc struct {
-	proc *process.Process
-	name string
-	user string
+	proc    *process.Process
+	name    string
+	user    string
+	command string
+	rss     []rssSample // for leak detection
 }
 
 var (
@@ -31,6 +37,14 @@ var (
 	cachedProcs []ProcessInfo // last successful result
 )
 
+// AllProcesses returns every process from the last GetProcesses scan, not
+// just the top 25.
+func AllProcesses() []ProcessInfo {
+	procMutex.Lock()
+	defer procMutex.Unlock()
+	return append([]ProcessInfo(nil), cachedProcs...)
+}
+
 func GetProcesses() []ProcessInfo {
 
 	if !procExecMu.TryLock() {
@@ -85,6 +99,8 @@ func GetProcesses() []ProcessInfo {
 		}
 	}
 	cachedProcs = pInfos // store for concurrent-return path
+	updateLeakSuspects(pInfos, time.Now())
+	markCollected("processes")
 	procMutex.Unlock()
 
 	sort.Slice(pInfos, func(i, j int) bool {
@@ -149,14 +165,20 @@ func processOnePID(pid int32, cacheSnapshot map[int32]*cachedProc, totalMem uint
 
 		user, _ := newP.Username()
 
+		command, _ := newP.Cmdline()
+		if len(command) > maxCommandLen {
+			command = command[:maxCommandLen]
+		}
+
 		if idx := strings.LastIndex(name, "/"); idx >= 0 {
 			name = name[idx+1:]
 		}
 
 		cp = &cachedProc{
-			proc: newP,
-			name: name,
-			user: user,
+			proc:    newP,
+			name:    name,
+			user:    user,
+			command: command,
 		}
 		isNew = true
 	}
@@ -175,12 +197,13 @@ func processOnePID(pid int32, cacheSnapshot map[int32]*cachedProc, totalMem uint
 
 	return result{
 		info: ProcessInfo{
-			PID:    int(pid),
-			Name:   cp.name,
-			CPU:    sanitizeFloat(cpu),
-			MemMB:  sanitizeFloat(float64(memInfo.RSS) / float64(MB)),
-			MemPct: sanitizeFloat(memPct),
-			User:   cp.user,
+			PID:     int(pid),
+			Name:    cp.name,
+			CPU:     sanitizeFloat(cpu),
+			MemMB:   sanitizeFloat(float64(memInfo.RSS) / float64(MB)),
+			MemPct:  sanitizeFloat(memPct),
+			User:    cp.user,
+			Command: cp.command,
 		},
 		pid:   pid,
 		cp:    cp,
@@ -8,16 +8,17 @@ import (
 )
 
 type SecurityMetrics struct {
-	ScreenLocked bool          `json:"screen_locked"`
-	SSHActive    bool          `json:"ssh_active"`
-	UserSessions []SessionInfo `json:"user_sessions"`
-	WakeHistory  []string      `json:"wake_history"` // Last 5 wake/sleep events
+	ScreenLocked bool          `json:"screen_locked" desc:"Screen is locked"`
+	SSHActive    bool          `json:"ssh_active" desc:"An SSH session is open"`
+	UserSessions []SessionInfo `json:"user_sessions" desc:"Logged-in sessions" interval:"5s"`
+	WakeHistory  []string      `json:"wake_history" desc:"Last sleep/wake events" interval:"60s"` // Last 5 wake/sleep events
 }
 
 type SessionInfo struct {
-	User     string `json:"user"`
-	Terminal string `json:"terminal"`
-	Host     string `json:"host"`
+	User     string `json:"user" desc:"User name"`
+	Terminal string `json:"terminal" desc:"TTY"`
+	Host     string `json:"host" desc:"Remote host, empty for local sessions"`
+	Login    string `json:"login" desc:"Login time as reported by who, e.g. \"Oct 15 10:02\""`
 }
 
 var (
@@ -60,6 +61,7 @@ func GetSecurity() SecurityMetrics {
 					}
 
 					if len(parts) >= 5 {
+						s.Login = strings.Join(parts[2:5], " ")
 						lastField := parts[len(parts)-1]
 						if strings.HasPrefix(lastField, "(") && strings.HasSuffix(lastField, ")") {
 							s.Host = strings.Trim(lastField, "()")
@@ -78,11 +80,12 @@ func GetSecurity() SecurityMetrics {
 		cachedUserSessions = m.UserSessions
 		cachedSSHActive = m.SSHActive
 		lastSessionTime = now
+		markCollected("security.user_sessions")
 		secMutex.Unlock()
 	}
 
 	secMutex.Lock()
-	if now.Sub(lastWakeHistoryTime) > 60*time.Second {
+	if now.Sub(lastWakeHistoryTime) > 60*time.Second && !Throttled() {
 		go updateWakeHistory()
 		lastWakeHistoryTime = now
 	}
@@ -142,5 +145,6 @@ func updateWakeHistory() {
 
 	secMutex.Lock()
 	cachedWakeHistory = events
+	markCollected("security.wake_history")
 	secMutex.Unlock()
 }
@@ -9,14 +9,17 @@ import (
 )
 
 type SystemMetrics struct {
-	Hostname    string `json:"hostname"`
-	OSVersion   string `json:"os_version"`
-	KernelVer   string `json:"kernel_version"`
-	Uptime      string `json:"uptime"`
-	LoadAvg     string `json:"load_avg"`
-	CurrentTime string `json:"current_time"`
-	CurrentDate string `json:"current_date"`
-	Arch        string `json:"arch"`
+	HostIdentity
+	Hostname    string      `json:"hostname" desc:"Host name"`
+	OSVersion   string      `json:"os_version" desc:"macOS version and build"`
+	KernelVer   string      `json:"kernel_version" desc:"Darwin kernel version"`
+	Uptime      string      `json:"uptime" desc:"Time since boot, human-readable"`
+	BootTime    int64       `json:"boot_time" unit:"unix s" desc:"When this boot began; identifies the boot session"`
+	LoadAvg     string      `json:"load_avg" desc:"1, 5 and 15 minute load averages"`
+	CurrentTime string      `json:"current_time" desc:"Local time HH:MM:SS"`
+	CurrentDate string      `json:"current_date" desc:"Local date"`
+	Arch        string      `json:"arch" desc:"CPU architecture"`
+	Focus       FocusStatus `json:"focus" desc:"Focus / Do Not Disturb state" interval:"5s"`
 }
 
 var (
@@ -46,7 +49,9 @@ func GetSystem() SystemMetrics {
 		KernelVer:   cachedKernelVer,
 		Arch:        cachedArch,
 		Hostname:    cachedHostname,
+		Focus:       GetFocus(),
 	}
+	m.HostIdentity = GetHostIdentity()
 
 	uptimeSeconds, err := host.Uptime()
 	if err == nil {
@@ -62,6 +67,10 @@ func GetSystem() SystemMetrics {
 		}
 	}
 
+	if bt, err := host.BootTime(); err == nil {
+		m.BootTime = int64(bt)
+	}
+
 	loadAvg, err := load.Avg()
 	if err == nil {
 		m.LoadAvg = fmt.Sprintf("%.2f %.2f %.2f", loadAvg.Load1, loadAvg.Load5, loadAvg.Load15)
@@ -21,8 +21,8 @@ static long get_thermal_state() {
 import "C"
 
 type ThermalMetrics struct {
-	ThermalState string `json:"thermal_state"` // "Nominal", "Fair", "Serious", "Critical"
-	CPUTemp      int    `json:"cpu_temp"`      // Degree Celsius (if available)
+	ThermalState string `json:"thermal_state" desc:"Nominal, Fair, Serious or Critical"`          // "Nominal", "Fair", "Serious", "Critical"
+	CPUTemp      int    `json:"cpu_temp" unit:"celsius" desc:"CPU temperature, 0 if unavailable"` // Degree Celsius (if available)
 }
 
 var thermalStates = [4]string{"Nominal", "Fair", "Serious", "Critical"}
@@ -0,0 +1,15 @@
+package monitor
+
+import "sync/atomic"
+
+var throttled atomic.Bool
+
+// SetThrottled pauses the expensive background refreshes (system_profiler,
+// log show, pmset log); callers keep getting the last cached values.
+func SetThrottled(on bool) {
+	throttled.Store(on)
+}
+
+func Throttled() bool {
+	return throttled.Load()
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Query parameters whose values never reach the access log.
+var accessLogRedactedParams = []string{"code", "state", "token", "password"}
+
+type accessEntry struct {
+	Time      string  `json:"time"`
+	IP        string  `json:"ip"`
+	User      string  `json:"user,omitempty"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Query     string  `json:"query,omitempty"`
+	Proto     string  `json:"proto"`
+	Status    int     `json:"status"`
+	Bytes     int64   `json:"bytes"`
+	LatencyMs float64 `json:"latency_ms"`
+	Referer   string  `json:"referer,omitempty"`
+	UserAgent string  `json:"user_agent,omitempty"`
+}
+
+// accessRecorder captures the status and size of a response. Flush, Hijack
+// and Unwrap pass through so SSE and WebSocket upgrades keep working.
+type accessRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (a *accessRecorder) WriteHeader(code int) {
+	if a.status == 0 {
+		a.status = code
+	}
+	a.ResponseWriter.WriteHeader(code)
+}
+
+func (a *accessRecorder) Write(b []byte) (int, error) {
+	if a.status == 0 {
+		a.status = http.StatusOK
+	}
+	n, err := a.ResponseWriter.Write(b)
+	a.bytes += int64(n)
+	return n, err
+}
+
+func (a *accessRecorder) Flush() {
+	http.NewResponseController(a.ResponseWriter).Flush()
+}
+
+func (a *accessRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(a.ResponseWriter).Hijack()
+	if err == nil && a.status == 0 {
+		a.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (a *accessRecorder) Unwrap() http.ResponseWriter {
+	return a.ResponseWriter
+}
+
+// AccessLogMiddleware records every request when logging.access.enabled is
+// set, in combined log format with the latency in seconds appended, or as
+// JSON lines. The user is the session's, taken before the handler runs so
+// a logout is still attributed.
+func AccessLogMiddleware(next http.Handler) http.Handler {
+	cfg := GlobalConfig.Logging.Access
+	if !cfg.Enabled {
+		return next
+	}
+	path := cfg.File
+	if path == "" {
+		path = dataPath("access.log")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		slog.Error("Access log disabled", "file", path, "err", err)
+		return next
+	}
+	asJSON := false
+	switch strings.ToLower(cfg.Format) {
+	case "", "combined":
+	case "json":
+		asJSON = true
+	default:
+		slog.Warn("logging.access.format is not combined or json; using combined", "format", cfg.Format)
+	}
+	out := log.New(f, "", 0)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		user := ""
+		if s := getSessionFromRequest(r); s != nil {
+			user = s.user
+		}
+		rec := &accessRecorder{ResponseWriter: w}
+		defer func() {
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			e := accessEntry{
+				Time:      start.Format(time.RFC3339),
+				IP:        getRealIP(r),
+				User:      user,
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Query:     redactQuery(r.URL.RawQuery),
+				Proto:     r.Proto,
+				Status:    rec.status,
+				Bytes:     rec.bytes,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+				Referer:   r.Referer(),
+				UserAgent: r.UserAgent(),
+			}
+			if asJSON {
+				line, _ := json.Marshal(e)
+				out.Print(string(line))
+			} else {
+				out.Print(combinedLogLine(e, start))
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+func combinedLogLine(e accessEntry, start time.Time) string {
+	user, target, size := "-", e.Path, "-"
+	if e.User != "" {
+		user = e.User
+	}
+	if e.Query != "" {
+		target += "?" + e.Query
+	}
+	if e.Bytes > 0 {
+		size = fmt.Sprint(e.Bytes)
+	}
+	return fmt.Sprintf("%s - %s [%s] %q %d %s %q %q %.3f",
+		e.IP, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method+" "+target+" "+e.Proto, e.Status, size, orDash(e.Referer), orDash(e.UserAgent),
+		e.LatencyMs/1000)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func redactQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return "[unparseable]"
+	}
+	changed := false
+	for _, p := range accessLogRedactedParams {
+		if _, ok := q[p]; ok {
+			q.Set(p, redactedMarker)
+			changed = true
+		}
+	}
+	if !changed {
+		return raw
+	}
+	return q.Encode()
+}
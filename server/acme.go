@@ -0,0 +1,334 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	acmeRenewBefore   = 30 * 24 * time.Hour
+	acmeCheckInterval = 12 * time.Hour
+	acmeRetryInterval = time.Hour
+	acmeOrderTimeout  = 5 * time.Minute
+	// How long a dns-01 record is polled for before the CA is asked anyway.
+	acmeDNSPropagation = 2 * time.Minute
+)
+
+// acmeManager keeps a certificate for tls.acme.domain issued and renewed.
+// Until the first one arrives the self-signed certificate is served, so the
+// dashboard is reachable while the CA is still validating.
+type acmeManager struct {
+	domain    string
+	email     string
+	challenge string
+	dnsHook   []string
+	directory string
+	dir       string
+	fallback  func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+
+	tokensMu sync.Mutex
+	tokens   map[string]string // http-01 token → key authorization
+}
+
+func newACMEManager(fallback func(*tls.ClientHelloInfo) (*tls.Certificate, error)) (*acmeManager, error) {
+	cfg := GlobalConfig.TLS.ACME
+	m := &acmeManager{
+		domain:    strings.ToLower(strings.TrimSuffix(cfg.Domain, ".")),
+		email:     cfg.Email,
+		challenge: cfg.Challenge,
+		dnsHook:   cfg.DNSHook,
+		directory: cfg.Directory,
+		dir:       dataPath("acme"),
+		fallback:  fallback,
+		tokens:    make(map[string]string),
+	}
+	if m.challenge == "" {
+		m.challenge = "http-01"
+	}
+	switch m.challenge {
+	case "http-01":
+	case "dns-01":
+		if len(m.dnsHook) == 0 {
+			return nil, errors.New("tls.acme.challenge dns-01 needs tls.acme.dns_hook")
+		}
+	default:
+		return nil, fmt.Errorf("tls.acme.challenge %q is not http-01 or dns-01", m.challenge)
+	}
+	if m.directory == "" {
+		m.directory = acme.LetsEncryptURL
+	}
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return nil, err
+	}
+	if cert, err := tls.LoadX509KeyPair(m.certPath(), m.keyPath()); err == nil {
+		m.cert = &cert
+	}
+	return m, nil
+}
+
+func (m *acmeManager) certPath() string { return filepath.Join(m.dir, m.domain+".crt") }
+func (m *acmeManager) keyPath() string  { return filepath.Join(m.dir, m.domain+".key") }
+
+func (m *acmeManager) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	cert := m.cert
+	m.mu.RUnlock()
+	if cert != nil && (hello.ServerName == "" || strings.EqualFold(hello.ServerName, m.domain)) {
+		return cert, nil
+	}
+	return m.fallback(hello)
+}
+
+// run renews the certificate in the background for as long as Talaria runs.
+func (m *acmeManager) run() {
+	if m.challenge == "http-01" {
+		go m.serveHTTP01()
+	}
+	for {
+		wait := acmeCheckInterval
+		if m.needsRenewal() {
+			ctx, cancel := context.WithTimeout(context.Background(), acmeOrderTimeout)
+			err := m.obtain(ctx)
+			cancel()
+			if err != nil {
+				slog.Error("ACME certificate request failed", "domain", m.domain, "err", err)
+				wait = acmeRetryInterval
+			}
+		}
+		time.Sleep(wait)
+	}
+}
+
+func (m *acmeManager) needsRenewal() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cert == nil || m.cert.Leaf == nil || time.Until(m.cert.Leaf.NotAfter) < acmeRenewBefore
+}
+
+func (m *acmeManager) accountKey() (crypto.Signer, error) {
+	path := filepath.Join(m.dir, "account.key")
+	if data, err := os.ReadFile(path); err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s is not PEM", path)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return key, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+}
+
+func (m *acmeManager) obtain(ctx context.Context) error {
+	accountKey, err := m.accountKey()
+	if err != nil {
+		return fmt.Errorf("account key: %w", err)
+	}
+	client := &acme.Client{Key: accountKey, DirectoryURL: m.directory, UserAgent: "talaria"}
+	acct := &acme.Account{}
+	if m.email != "" {
+		acct.Contact = []string{"mailto:" + m.email}
+	}
+	if _, err := client.Register(ctx, acct, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return fmt.Errorf("register: %w", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(m.domain))
+	if err != nil {
+		return fmt.Errorf("order: %w", err)
+	}
+	for _, u := range order.AuthzURLs {
+		if err := m.authorize(ctx, client, u); err != nil {
+			return err
+		}
+	}
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return fmt.Errorf("order: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{m.domain}}, key)
+	if err != nil {
+		return err
+	}
+	der, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("finalize: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return err
+	}
+
+	var chain []byte
+	for _, c := range der {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c})...)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.keyPath(), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.certPath(), chain, 0644); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.cert = &tls.Certificate{Certificate: der, PrivateKey: key, Leaf: leaf}
+	m.mu.Unlock()
+	slog.Info("ACME certificate issued", "domain", m.domain, "expires", leaf.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+func (m *acmeManager) authorize(ctx context.Context, client *acme.Client, url string) error {
+	z, err := client.GetAuthorization(ctx, url)
+	if err != nil {
+		return fmt.Errorf("authorization: %w", err)
+	}
+	if z.Status == acme.StatusValid {
+		return nil
+	}
+	i := slices.IndexFunc(z.Challenges, func(c *acme.Challenge) bool { return c.Type == m.challenge })
+	if i < 0 {
+		return fmt.Errorf("CA offers no %s challenge for %s", m.challenge, z.Identifier.Value)
+	}
+	chal := z.Challenges[i]
+
+	switch m.challenge {
+	case "http-01":
+		resp, err := client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return err
+		}
+		m.tokensMu.Lock()
+		m.tokens[chal.Token] = resp
+		m.tokensMu.Unlock()
+		defer func() {
+			m.tokensMu.Lock()
+			delete(m.tokens, chal.Token)
+			m.tokensMu.Unlock()
+		}()
+	case "dns-01":
+		value, err := client.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return err
+		}
+		record := "_acme-challenge." + z.Identifier.Value
+		if err := m.runDNSHook(ctx, "present", record, value); err != nil {
+			return err
+		}
+		defer m.runDNSHook(context.Background(), "cleanup", record, value)
+		waitForTXT(ctx, record, value)
+	}
+
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("accept %s: %w", m.challenge, err)
+	}
+	if _, err := client.WaitAuthorization(ctx, z.URI); err != nil {
+		return fmt.Errorf("%s validation: %w", m.challenge, err)
+	}
+	return nil
+}
+
+// runDNSHook is the dns-01 provider plugin: dns_hook is run with "present"
+// or "cleanup", the record name and its TXT value appended, and must have
+// published (or removed) the record when it exits.
+func (m *acmeManager) runDNSHook(ctx context.Context, action, record, value string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	args := append(slices.Clone(m.dnsHook[1:]), action, record, value)
+	out, err := exec.CommandContext(ctx, m.dnsHook[0], args...).CombinedOutput()
+	if err != nil {
+		slog.Warn("ACME DNS hook failed", "action", action, "record", record, "err", err, "output", truncate(string(out), 500))
+		return fmt.Errorf("dns_hook %s: %w", action, err)
+	}
+	return nil
+}
+
+// waitForTXT gives the record time to reach the resolvers the CA uses; a
+// record that never shows up locally is tried anyway.
+func waitForTXT(ctx context.Context, record, value string) {
+	ctx, cancel := context.WithTimeout(ctx, acmeDNSPropagation)
+	defer cancel()
+	for {
+		if txts, err := net.DefaultResolver.LookupTXT(ctx, record); err == nil && slices.Contains(txts, value) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// serveHTTP01 answers http-01 challenges on tls.acme.http_addr.
+func (m *acmeManager) serveHTTP01() {
+	addr := GlobalConfig.TLS.ACME.HTTPAddr
+	if addr == "" {
+		addr = ":80"
+	}
+	srv := &http.Server{Addr: addr, Handler: m.http01Handler(), ReadHeaderTimeout: 5 * time.Second}
+	if err := srv.ListenAndServe(); err != nil {
+		slog.Error("ACME http-01 listener failed; certificates cannot be issued", "addr", addr, "err", err)
+	}
+}
+
+// http01Handler serves pending challenge tokens and sends every other
+// request to HTTPS.
+func (m *acmeManager) http01Handler() http.Handler {
+	host := m.domain
+	if port := listenPort(); port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := strings.CutPrefix(r.URL.Path, "/.well-known/acme-challenge/"); ok {
+			m.tokensMu.Lock()
+			resp, found := m.tokens[token]
+			m.tokensMu.Unlock()
+			if !found {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(resp))
+			return
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// systemd passes activated sockets from fd 3 on.
+const sdListenFdsStart = 3
+
+var (
+	// activatedFDs are the sockets as handed over, kept open (close-on-exec)
+	// so a restart in place can pass them on; the listeners use duplicates.
+	activatedFDs []int
+	sdFDNames    string
+)
+
+// activatedListeners returns sockets handed over by the service manager,
+// systemd (LISTEN_FDS) or launchd (the plist's Sockets entry named by
+// server.launchd_socket). They replace the configured addresses: the
+// manager owns binding, and Talaria can be started on first connection.
+func activatedListeners() ([]net.Listener, error) {
+	lns, err := systemdListeners()
+	if err != nil || len(lns) > 0 {
+		return lns, err
+	}
+	name := GlobalConfig.Server.LaunchdSocket
+	if name == "" {
+		name = "Listeners"
+	}
+	return launchdListeners(name)
+}
+
+func systemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	// Hooks and other children must not think they were activated too;
+	// RestartEnv puts these back for a restart in place.
+	sdFDNames = os.Getenv("LISTEN_FDNAMES")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	var lns []net.Listener
+	for fd := sdListenFdsStart; fd < sdListenFdsStart+n; fd++ {
+		syscall.CloseOnExec(fd)
+		ln, err := fileListener(fd, "systemd")
+		if err != nil {
+			for _, l := range lns {
+				l.Close()
+			}
+			return nil, err
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// fileListener wraps an activated socket. fd itself stays open for
+// RestartEnv.
+func fileListener(fd int, source string) (net.Listener, error) {
+	dup, err := syscall.Dup(fd)
+	if err != nil {
+		return nil, err
+	}
+	syscall.CloseOnExec(dup)
+	f := os.NewFile(uintptr(dup), source)
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, err
+	}
+	activatedFDs = append(activatedFDs, fd)
+	slog.Info("Using activated socket", "source", source, "addr", ln.Addr().String())
+	return ln, nil
+}
+
+// SocketsActivated reports whether the listeners came from a service
+// manager rather than the configured addresses.
+func SocketsActivated() bool {
+	return len(activatedFDs) > 0
+}
+
+// RestartEnv returns the environment for restarting Talaria in place with
+// syscall.Exec. Activated sockets are moved to fd 3 on without
+// close-on-exec and announced with LISTEN_PID/LISTEN_FDS, as systemd would,
+// since the service manager will not hand them over a second time. Call it
+// just before the exec: whatever else held those fds is replaced.
+func RestartEnv() []string {
+	env := os.Environ()
+	n := len(activatedFDs)
+	if n == 0 {
+		return env
+	}
+	// Copy above the target range first so no socket is overwritten
+	// before it has been moved.
+	tmp := make([]int, n)
+	for i, fd := range activatedFDs {
+		r, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_DUPFD, uintptr(sdListenFdsStart+n))
+		if errno != 0 {
+			slog.Warn("Activated sockets not passed to the restarted process", "err", errno)
+			return env
+		}
+		tmp[i] = int(r)
+	}
+	var err error
+	for i, fd := range tmp {
+		if err == nil {
+			err = syscall.Dup2(fd, sdListenFdsStart+i)
+		}
+		syscall.Close(fd)
+	}
+	if err != nil {
+		slog.Warn("Activated sockets not passed to the restarted process", "err", err)
+		return env
+	}
+	env = append(env, "LISTEN_PID="+strconv.Itoa(os.Getpid()), "LISTEN_FDS="+strconv.Itoa(n))
+	if sdFDNames != "" {
+		env = append(env, "LISTEN_FDNAMES="+sdFDNames)
+	}
+	return env
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	alertOK      = "ok"
+	alertPending = "pending" // breaching, waiting out the "for" duration
+	alertFiring  = "firing"
+)
+
+type AlertRule struct {
+	Name      string   `yaml:"name" json:"name"`
+	Metric    string   `yaml:"metric" json:"metric"` // flattened key, e.g. "cpu.usage_percent"
+	Op        string   `yaml:"op" json:"op"`         // ">", ">=", "<", "<="
+	Threshold float64  `yaml:"threshold" json:"threshold"`
+	Clear     *float64 `yaml:"clear" json:"clear,omitempty"` // resolve threshold; defaults to Threshold
+	For       string   `yaml:"for" json:"for,omitempty"`     // e.g. "5m"; empty fires immediately
+	Severity  string   `yaml:"severity" json:"severity"`
+	Repeat    string   `yaml:"repeat" json:"repeat,omitempty"` // re-notify interval while firing; overrides alerts.repeat_interval
+}
+
+type alertState struct {
+	Rule     AlertRule `json:"rule"`
+	State    string    `json:"state"`
+	Value    *float64  `json:"value"` // nil when the metric was missing
+	Since    int64     `json:"since"` // unix seconds of the last state change
+	FiredAt  int64     `json:"fired_at,omitempty"`
+	LastEval int64     `json:"last_eval"`
+	Notified int64     `json:"notified_at,omitempty"` // last firing notification
+	Repeats  int       `json:"repeats,omitempty"`     // re-notifications in this firing episode
+
+	forDur time.Duration
+	repeat time.Duration // 0 notifies once per episode
+	clear  float64
+}
+
+var (
+	alertStates []*alertState
+	alertsMu    sync.Mutex
+)
+
+func (r AlertRule) compile() (*alertState, error) {
+	if r.Name == "" || r.Metric == "" {
+		return nil, fmt.Errorf("name and metric are required")
+	}
+	switch r.Op {
+	case ">", ">=", "<", "<=":
+	default:
+		return nil, fmt.Errorf("unknown op %q", r.Op)
+	}
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityWarning
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return nil, fmt.Errorf("unknown severity %q", r.Severity)
+	}
+
+	s := &alertState{Rule: r, State: alertOK, Since: time.Now().Unix(), clear: r.Threshold}
+	if r.Clear != nil {
+		s.clear = *r.Clear
+		// A clear level on the wrong side of the threshold would never resolve.
+		if (r.Op[0] == '>' && s.clear > r.Threshold) || (r.Op[0] == '<' && s.clear < r.Threshold) {
+			return nil, fmt.Errorf("clear %v is on the wrong side of threshold %v", s.clear, r.Threshold)
+		}
+	}
+	if r.For != "" {
+		d, err := time.ParseDuration(r.For)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid for %q", r.For)
+		}
+		s.forDur = d
+	}
+	repeat := GlobalConfig.Alerts.RepeatInterval
+	if r.Repeat != "" {
+		repeat = r.Repeat
+	}
+	if repeat != "" && repeat != "0" {
+		d, err := time.ParseDuration(repeat)
+		if err != nil || d < time.Minute {
+			return nil, fmt.Errorf("invalid repeat %q (minimum 1m)", repeat)
+		}
+		s.repeat = d
+	}
+	return s, nil
+}
+
+func compareOp(op string, v, limit float64) bool {
+	switch op {
+	case ">":
+		return v > limit
+	case ">=":
+		return v >= limit
+	case "<":
+		return v < limit
+	case "<=":
+		return v <= limit
+	}
+	return false
+}
+
+// step advances the rule's state machine. A pending rule drops back to ok as
+// soon as the threshold is no longer breached; a firing rule only resolves once
+// the value crosses the clear level.
+func (s *alertState) step(v float64, now time.Time) (changed bool) {
+	r := s.Rule
+	switch s.State {
+	case alertOK:
+		if compareOp(r.Op, v, r.Threshold) {
+			s.State, s.Since = alertPending, now.Unix()
+			changed = true
+		}
+	case alertPending:
+		if !compareOp(r.Op, v, r.Threshold) {
+			s.State, s.Since = alertOK, now.Unix()
+			return true
+		}
+	case alertFiring:
+		if !compareOp(r.Op, v, s.clear) {
+			s.State, s.Since = alertOK, now.Unix()
+			return true
+		}
+		return false
+	}
+	if s.State == alertPending && now.Sub(time.Unix(s.Since, 0)) >= s.forDur {
+		s.State, s.Since, s.FiredAt = alertFiring, now.Unix(), now.Unix()
+		changed = true
+	}
+	return changed
+}
+
+func startAlerts() {
+	cfg := GlobalConfig.Alerts
+	if !cfg.Enabled {
+		return
+	}
+
+	rules := cfg.Rules
+	if cfg.Builtin {
+		rules = append(builtinRules(), rules...)
+	}
+	if cfg.Battery.Enabled {
+		rules = append(batteryRules(), rules...)
+	}
+
+	var states []*alertState
+	for _, rule := range rules {
+		s, err := rule.compile()
+		if err != nil {
+			slog.Warn("Skipping alert rule", "rule", rule.Name, "err", err)
+			continue
+		}
+		states = append(states, s)
+	}
+	if cfg.Sessions.Enabled {
+		startSessionWatch()
+	}
+	if cfg.Builtin {
+		startKernelPanicWatch()
+	}
+	if len(states) == 0 && !cfg.Builtin && !cfg.MemoryLeaks && !cfg.Battery.Enabled {
+		return
+	}
+	alertsMu.Lock()
+	alertStates = states
+	alertsMu.Unlock()
+
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			m := backgroundMetrics()
+			if m == nil {
+				continue
+			}
+			values := flattenMetrics(m)
+			if cfg.Builtin {
+				addBuiltinValues(m, values)
+				if m != nil && !cfg.Sessions.Enabled {
+					checkSessions(m.Security.UserSessions)
+				}
+			}
+			if cfg.MemoryLeaks {
+				checkLeakSuspects(m)
+			}
+			if cfg.Battery.Enabled {
+				addBatteryValues(m, values)
+				checkBatteryMilestones(m)
+			}
+			evaluateAlerts(values, time.Now())
+		}
+	}()
+}
+
+func evaluateAlerts(values map[string]float64, now time.Time) {
+	alertsMu.Lock()
+	var fired, resolved []alertState
+	for _, s := range alertStates {
+		s.LastEval = now.Unix()
+		v, ok := values[s.Rule.Metric]
+		if !ok {
+			s.Value = nil
+			continue
+		}
+		s.Value = &v
+		prev := s.State
+		changed := s.step(v, now)
+		switch {
+		case changed && s.State == alertFiring:
+			s.Notified, s.Repeats = now.Unix(), 0
+			fired = append(fired, *s)
+		case changed && prev == alertFiring:
+			// Only episodes that were announced get a "resolved" message.
+			if s.Notified != 0 {
+				resolved = append(resolved, *s)
+			}
+			s.Notified, s.Repeats = 0, 0
+		case s.State == alertFiring && s.repeat > 0 && now.Sub(time.Unix(s.Notified, 0)) >= s.repeat:
+			s.Notified = now.Unix()
+			s.Repeats++
+			fired = append(fired, *s)
+		}
+	}
+	alertsMu.Unlock()
+
+	for _, s := range fired {
+		raiseAlertEvent(s, true)
+	}
+	for _, s := range resolved {
+		raiseAlertEvent(s, false)
+	}
+}
+
+func raiseAlertEvent(s alertState, firing bool) {
+	r := s.Rule
+	value := strconv.FormatFloat(*s.Value, 'f', -1, 64)
+	e := Event{
+		Kind: "alert",
+		Fields: map[string]string{
+			"rule":   r.Name,
+			"metric": r.Metric,
+			"value":  value,
+		},
+	}
+	if firing {
+		e.Severity = r.Severity
+		e.Title = r.Name + " firing"
+		if s.Repeats > 0 {
+			e.Title = r.Name + " still firing"
+			e.Fields["repeat"] = strconv.Itoa(s.Repeats)
+			e.Fields["firing_since"] = time.Unix(s.FiredAt, 0).Format(time.RFC3339)
+		}
+		e.Message = fmt.Sprintf("%s is %s (%s %v", r.Metric, value, r.Op, r.Threshold)
+		if s.forDur > 0 {
+			e.Message += " for " + s.forDur.String()
+		}
+		e.Message += ")"
+		e.Fields["state"] = alertFiring
+	} else {
+		e.Severity = SeverityInfo
+		e.Title = r.Name + " resolved"
+		e.Message = fmt.Sprintf("%s is back to %s", r.Metric, value)
+		e.Fields["state"] = "resolved"
+	}
+	RaiseEvent(e)
+}
+
+func handleAlerts(w http.ResponseWriter, r *http.Request) {
+	alertsMu.Lock()
+	list := make([]alertState, len(alertStates))
+	for i, s := range alertStates {
+		list[i] = *s
+	}
+	alertsMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": GlobalConfig.Alerts.Enabled,
+		"rules":   list,
+	})
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// simEpisode is one period a rule would have spent firing.
+type simEpisode struct {
+	FiredAt    int64   `json:"fired_at"`              // unix ms
+	ResolvedAt int64   `json:"resolved_at,omitempty"` // 0 if still firing at the end of history
+	Peak       float64 `json:"peak"`
+}
+
+type simResult struct {
+	Rule     AlertRule    `json:"rule"`
+	Error    string       `json:"error,omitempty"`
+	Samples  int          `json:"samples"` // samples that carried the metric
+	Episodes []simEpisode `json:"episodes"`
+}
+
+// simulateAlerts replays samples (oldest first) through rules using the same
+// state machine as the live engine. Repeat notifications are not modelled.
+func simulateAlerts(rules []AlertRule, samples []historySample) []simResult {
+	results := make([]simResult, 0, len(rules))
+	for _, rule := range rules {
+		res := simResult{Rule: rule, Episodes: []simEpisode{}}
+		s, err := rule.compile()
+		if err != nil {
+			res.Error = err.Error()
+			results = append(results, res)
+			continue
+		}
+		var cur *simEpisode
+		for _, sample := range samples {
+			v, ok := sample.V[rule.Metric]
+			if !ok {
+				continue
+			}
+			res.Samples++
+			at := time.UnixMilli(sample.T)
+			if res.Samples == 1 {
+				s.Since = at.Unix()
+			}
+			prev := s.State
+			if s.step(v, at) {
+				switch {
+				case s.State == alertFiring:
+					cur = &simEpisode{FiredAt: sample.T, Peak: v}
+				case prev == alertFiring && cur != nil:
+					cur.ResolvedAt = sample.T
+					res.Episodes = append(res.Episodes, *cur)
+					cur = nil
+				}
+			}
+			if cur != nil && worse(rule.Op, v, cur.Peak) {
+				cur.Peak = v
+			}
+		}
+		if cur != nil {
+			res.Episodes = append(res.Episodes, *cur)
+		}
+		if res.Samples == 0 {
+			res.Error = "metric not found in history (see history.metrics)"
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// worse reports whether v is further past the threshold than peak.
+func worse(op string, v, peak float64) bool {
+	if op[0] == '<' {
+		return v < peak
+	}
+	return v > peak
+}
+
+// handleAlertsTest replays the in-memory history through the configured
+// rules (GET) or through candidate rules posted as {"rules": [...]}.
+func handleAlertsTest(w http.ResponseWriter, r *http.Request) {
+	rules := GlobalConfig.Alerts.Rules
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var body struct {
+			Rules []AlertRule `json:"rules"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		rules = body.Rules
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	samples, _ := historySince(0, 0)
+	res := map[string]interface{}{
+		"samples": len(samples),
+		"results": simulateAlerts(rules, samples),
+	}
+	if len(samples) > 0 {
+		res["from"] = samples[0].T
+		res["to"] = samples[len(samples)-1].T
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// AlertsCommand implements "talaria alerts test": it replays history from an
+// NDJSON export (-history) or from the running instance's export endpoint
+// through the rules in the config and prints when each would have fired.
+func AlertsCommand(args []string) int {
+	if len(args) == 0 || args[0] != "test" {
+		fmt.Fprintln(os.Stderr, "usage: talaria alerts test [-config config.yml] [-history export.ndjson | -url http://host:port -token TOKEN]")
+		return 2
+	}
+	fs := flag.NewFlagSet("alerts test", flag.ContinueOnError)
+	cfgPath := fs.String("config", "config.yml", "Path to config file")
+	historyFile := fs.String("history", "", "NDJSON history export to replay")
+	baseURL := fs.String("url", "", "Instance to fetch history from (default: this config's server)")
+	token := fs.String("token", "", "Replica token for the history export (default: first history.replica_tokens entry)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if _, err := os.Stat(*cfgPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read config: %v\n", err)
+		return 1
+	}
+	if err := LoadConfig(*cfgPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+	if len(GlobalConfig.Alerts.Rules) == 0 {
+		fmt.Fprintln(os.Stderr, "No alert rules configured")
+		return 1
+	}
+
+	var samples []historySample
+	var err error
+	if *historyFile != "" {
+		samples, err = readHistoryFile(*historyFile)
+	} else {
+		samples, err = fetchHistory(*baseURL, *token)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load history: %v\n", err)
+		return 1
+	}
+	if len(samples) == 0 {
+		fmt.Fprintln(os.Stderr, "History is empty")
+		return 1
+	}
+
+	const ts = "2006-01-02 15:04:05"
+	fmt.Printf("Replaying %d samples from %s to %s\n\n", len(samples),
+		time.UnixMilli(samples[0].T).Format(ts), time.UnixMilli(samples[len(samples)-1].T).Format(ts))
+	for _, res := range simulateAlerts(GlobalConfig.Alerts.Rules, samples) {
+		r := res.Rule
+		desc := fmt.Sprintf("%s %s %v", r.Metric, r.Op, r.Threshold)
+		if r.For != "" {
+			desc += " for " + r.For
+		}
+		if res.Error != "" {
+			fmt.Printf("%s (%s): %s\n\n", r.Name, desc, res.Error)
+			continue
+		}
+		fmt.Printf("%s (%s): %d episode(s)\n", r.Name, desc, len(res.Episodes))
+		for _, ep := range res.Episodes {
+			fired := time.UnixMilli(ep.FiredAt)
+			if ep.ResolvedAt == 0 {
+				fmt.Printf("  %s → still firing  (peak %v)\n", fired.Format(ts), ep.Peak)
+				continue
+			}
+			resolved := time.UnixMilli(ep.ResolvedAt)
+			fmt.Printf("  %s → %s  (%s, peak %v)\n", fired.Format(ts), resolved.Format(ts), resolved.Sub(fired).Round(time.Second), ep.Peak)
+		}
+		fmt.Println()
+	}
+	return 0
+}
+
+func readHistoryFile(path string) ([]historySample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeHistory(f)
+}
+
+func fetchHistory(baseURL, token string) ([]historySample, error) {
+	if baseURL == "" {
+		baseURL = LocalURL()
+	}
+	if token == "" && len(GlobalConfig.History.ReplicaTokens) > 0 {
+		token = GlobalConfig.History.ReplicaTokens[0]
+	}
+	if token == "" {
+		return nil, errors.New("no replica token; pass -token or -history")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/api/history/export?cursor=0", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", baseURL, resp.Status)
+	}
+	return decodeHistory(resp.Body)
+}
+
+func decodeHistory(r io.Reader) ([]historySample, error) {
+	var samples []historySample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var s historySample
+		if err := json.Unmarshal(line, &s); err != nil {
+			return nil, err
+		}
+		samples = append(samples, s)
+	}
+	return samples, scanner.Err()
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAnomalySigma      = 4.0
+	defaultAnomalyMinSamples = 60
+	anomalySustain           = 3 // consecutive unusual samples before raising
+)
+
+var defaultAnomalyMetrics = []string{
+	"cpu.usage_percent",
+	"network.bytes_in_rate",
+	"network.bytes_out_rate",
+	"disk_io.read_mbps",
+	"disk_io.write_mbps",
+}
+
+// runningStats is Welford's online mean and variance.
+type runningStats struct {
+	N    int
+	Mean float64
+	m2   float64
+}
+
+func (s *runningStats) add(v float64) {
+	s.N++
+	d := v - s.Mean
+	s.Mean += d / float64(s.N)
+	s.m2 += d * (v - s.Mean)
+}
+
+func (s *runningStats) stddev() float64 {
+	if s.N < 2 {
+		return 0
+	}
+	return math.Sqrt(s.m2 / float64(s.N-1))
+}
+
+// anomalyBaseline holds one metric's hour-of-day profile.
+type anomalyBaseline struct {
+	hours  [24]runningStats
+	streak int
+	active bool
+}
+
+var (
+	anomalyBaselines = make(map[string]*anomalyBaseline)
+	anomalyMu        sync.Mutex
+)
+
+func anomalyMetrics() []string {
+	if keys := GlobalConfig.Anomaly.Metrics; len(keys) > 0 {
+		return keys
+	}
+	return defaultAnomalyMetrics
+}
+
+// startAnomalyDetector follows history: existing samples seed the baselines,
+// and each new one is scored against its hour-of-day before being learned.
+func startAnomalyDetector() {
+	cfg := GlobalConfig.Anomaly
+	if !cfg.Enabled {
+		return
+	}
+	if GlobalConfig.History.Disabled {
+		slog.Warn("Anomaly detection needs history; set history.disabled to false")
+		return
+	}
+	keys := historyKeys()
+	for _, metric := range anomalyMetrics() {
+		if !slices.Contains(keys, metric) {
+			slog.Warn("Anomaly metric is not recorded in history.metrics", "metric", metric)
+		}
+	}
+
+	go func() {
+		var cursor int64
+		samples, next := historySince(0, 0)
+		for _, s := range samples {
+			scoreSample(s, false)
+			cursor = s.T
+		}
+		for {
+			<-next
+			samples, next = historySince(cursor, 0)
+			for _, s := range samples {
+				scoreSample(s, true)
+				cursor = s.T
+			}
+		}
+	}()
+}
+
+// scoreSample learns s and, when detect is set, raises an event for any
+// metric that has stayed far above its usual level for this hour. Unusual
+// values are left out of the baseline so a long spike does not become normal.
+func scoreSample(s historySample, detect bool) {
+	cfg := GlobalConfig.Anomaly
+	sigma := cfg.Sigma
+	if sigma <= 0 {
+		sigma = defaultAnomalySigma
+	}
+	minSamples := cfg.MinSamples
+	if minSamples <= 0 {
+		minSamples = defaultAnomalyMinSamples
+	}
+	at := time.UnixMilli(s.T)
+
+	var raise []Event
+	anomalyMu.Lock()
+	for _, metric := range anomalyMetrics() {
+		v, ok := s.V[metric]
+		if !ok {
+			continue
+		}
+		b := anomalyBaselines[metric]
+		if b == nil {
+			b = &anomalyBaseline{}
+			anomalyBaselines[metric] = b
+		}
+		st := &b.hours[at.Hour()]
+
+		unusual := false
+		if st.N >= minSamples {
+			// Floor the spread so an almost flat baseline (an idle NIC)
+			// does not turn every small blip into an anomaly.
+			sd := math.Max(st.stddev(), math.Max(math.Abs(st.Mean)*0.1, 1e-6))
+			unusual = (v-st.Mean)/sd > sigma
+		}
+		if !unusual {
+			st.add(v)
+			b.streak, b.active = 0, false
+			continue
+		}
+		b.streak++
+		if detect && !b.active && b.streak >= anomalySustain {
+			b.active = true
+			raise = append(raise, anomalyEvent(metric, v, *st, at))
+		}
+	}
+	anomalyMu.Unlock()
+
+	for _, e := range raise {
+		RaiseEvent(e)
+	}
+}
+
+func anomalyEvent(metric string, v float64, st runningStats, at time.Time) Event {
+	sd := st.stddev()
+	return Event{
+		Kind:     "anomaly",
+		Severity: SeverityWarning,
+		Title:    "Unusual " + metric,
+		Message: fmt.Sprintf("%s is %.2f, usually %.2f ± %.2f around %02d:00",
+			metric, v, st.Mean, sd, at.Hour()),
+		Fields: map[string]string{
+			"metric": metric,
+			"value":  fmt.Sprintf("%g", v),
+			"mean":   fmt.Sprintf("%g", st.Mean),
+			"stddev": fmt.Sprintf("%g", sd),
+		},
+	}
+}
+
+// handleAnomaly reports each metric's baseline for the current hour.
+func handleAnomaly(w http.ResponseWriter, r *http.Request) {
+	type baseline struct {
+		Metric  string  `json:"metric"`
+		Samples int     `json:"samples"`
+		Mean    float64 `json:"mean"`
+		StdDev  float64 `json:"stddev"`
+		Active  bool    `json:"active"`
+	}
+	hour := time.Now().Hour()
+	out := []baseline{}
+	anomalyMu.Lock()
+	for _, metric := range anomalyMetrics() {
+		b := anomalyBaselines[metric]
+		if b == nil {
+			continue
+		}
+		st := b.hours[hour]
+		out = append(out, baseline{Metric: metric, Samples: st.N, Mean: st.Mean, StdDev: st.stddev(), Active: b.active})
+	}
+	anomalyMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":   GlobalConfig.Anomaly.Enabled,
+		"hour":      hour,
+		"baselines": out,
+	})
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	apiVersion  = "1"
+	apiV1Prefix = "/api/v1"
+	legacySince = "2026-10-16" // unversioned /api paths became aliases of /api/v1
+)
+
+// apiDeprecation announces that a path is going away. Responses on it carry
+// Deprecation (RFC 9745), Sunset (RFC 8594) and a successor-version Link, and
+// GET /api/v1 lists every entry, so scripts can notice before anything
+// breaks. A v1 path stays served at least until its sunset; incompatible
+// payloads go to /api/v2 rather than changing v1 in place.
+type apiDeprecation struct {
+	Path      string `json:"path"`
+	Since     string `json:"since"`            // YYYY-MM-DD
+	Sunset    string `json:"sunset,omitempty"` // YYYY-MM-DD; empty until removal is scheduled
+	Successor string `json:"successor,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// apiDeprecations lists deprecated /api/v1 paths. Deprecated payload fields
+// are marked with a deprecated tag instead and show up in /api/schema.
+var apiDeprecations = []apiDeprecation{}
+
+// apiVersions serves /api/v1/... from the handlers registered at /api/...,
+// and answers the old unversioned paths too, flagged as deprecated in favour
+// of their /api/v1 equivalents.
+func apiVersions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case path == apiV1Prefix || path == apiV1Prefix+"/":
+			handleAPIIndex(w, r)
+			return
+		case strings.HasPrefix(path, apiV1Prefix+"/"):
+			w.Header().Set("X-Talaria-API-Version", apiVersion)
+			if d := findDeprecation(path); d != nil {
+				setDeprecationHeaders(w, *d)
+			}
+			r2 := new(http.Request)
+			*r2 = *r
+			u := *r.URL
+			u.Path = "/api" + strings.TrimPrefix(path, apiV1Prefix)
+			u.RawPath = ""
+			r2.URL = &u
+			next.ServeHTTP(w, r2)
+			return
+		case strings.HasPrefix(path, "/api/") && path != "/api/docs":
+			setDeprecationHeaders(w, apiDeprecation{Since: legacySince, Successor: apiV1Prefix + strings.TrimPrefix(path, "/api")})
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func findDeprecation(path string) *apiDeprecation {
+	for i := range apiDeprecations {
+		if apiDeprecations[i].Path == path {
+			return &apiDeprecations[i]
+		}
+	}
+	return nil
+}
+
+func setDeprecationHeaders(w http.ResponseWriter, d apiDeprecation) {
+	h := w.Header()
+	if t, err := time.Parse(time.DateOnly, d.Since); err == nil {
+		h.Set("Deprecation", "@"+strconv.FormatInt(t.Unix(), 10))
+	}
+	if t, err := time.Parse(time.DateOnly, d.Sunset); err == nil {
+		h.Set("Sunset", t.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		h.Add("Link", "<"+d.Successor+`>; rel="successor-version"`)
+	}
+}
+
+// handleAPIIndex describes the API version and its deprecations. It needs
+// no session so clients can probe for /api/v1 before logging in.
+func handleAPIIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"version":        apiVersion,
+		"schema_version": metricsSchemaVersion,
+		"openapi":        apiV1Prefix + "/openapi.json",
+		"deprecations":   apiDeprecations,
+	})
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 200
+	maxAuditLimit     = 5000
+)
+
+type auditEntry struct {
+	Time     int64             `json:"time"`
+	Action   string            `json:"action"`
+	SourceIP string            `json:"source_ip"`
+	User     string            `json:"user,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+	Result   string            `json:"result"`
+}
+
+var auditMu sync.Mutex
+
+func auditLog(r *http.Request, action string, params map[string]string, result string) {
+	e := auditEntry{
+		Time:   time.Now().Unix(),
+		Action: action,
+		Params: params,
+		Result: result,
+	}
+	if r != nil {
+		e.SourceIP = getRealIP(r)
+		if s := getSessionFromRequest(r); s != nil {
+			e.User = s.user
+		}
+	}
+
+	slog.Info("Audit", "action", action, "ip", e.SourceIP, "user", e.User, "result", result)
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	f, err := os.OpenFile(dataPath("audit.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		slog.Error("Audit log write failed", "err", err)
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
+
+// auditResult is "ok", or the failure for a privileged action that did not
+// go through.
+func auditResult(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return "failed: " + err.Error()
+}
+
+// handleAudit serves /api/audit, newest first. ?since= (unix seconds),
+// ?action= (prefix, so "process." matches every process action), ?user=
+// and ?limit= narrow the result.
+func handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	var since int64
+	if v := q.Get("since"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+		since = n
+	}
+	limit := defaultAuditLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxAuditLimit)
+	}
+	action, user := q.Get("action"), q.Get("user")
+
+	entries, err := readAuditLog(func(e auditEntry) bool {
+		return e.Time >= since && strings.HasPrefix(e.Action, action) && (user == "" || e.User == user)
+	}, limit)
+	if err != nil {
+		slog.Error("Failed to read audit log", "err", err)
+		http.Error(w, "Failed to read audit log", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// readAuditLog returns the newest limit entries that match, newest first.
+// Lines that do not parse, such as one cut short by a crash, are skipped.
+func readAuditLog(match func(auditEntry) bool, limit int) ([]auditEntry, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	entries := []auditEntry{}
+	f, err := os.Open(dataPath("audit.log"))
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		var e auditEntry
+		if json.Unmarshal(sc.Bytes(), &e) != nil || !match(e) {
+			continue
+		}
+		entries = append(entries, e)
+		// Keep memory bounded on a long log; only the tail is returned.
+		if len(entries) >= 2*limit {
+			entries = append(entries[:0], entries[len(entries)-limit:]...)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
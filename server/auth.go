@@ -2,15 +2,17 @@ package server
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 	"sync"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 var passwordHash []byte
@@ -34,7 +36,11 @@ const (
 type session struct {
 	token   string
 	csrf    string
+	user    string
+	role    string
 	created time.Time
+	// viaCert marks a per-request session from a client certificate.
+	viaCert bool
 }
 
 var (
@@ -48,10 +54,12 @@ func generateToken(n int) string {
 	return hex.EncodeToString(b)
 }
 
-func createSession() *session {
+func createSession(user, role string) *session {
 	s := &session{
 		token:   generateToken(32),
 		csrf:    generateToken(16),
+		user:    user,
+		role:    role,
 		created: time.Now(),
 	}
 	sessionsMu.Lock()
@@ -133,19 +141,79 @@ func clearAttempts(ip string) {
 	attemptsMu.Unlock()
 }
 
-func getRealIP(r *http.Request) string {
-	if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
+var (
+	trustedProxies     []netip.Prefix
+	trustedProxiesOnce sync.Once
+)
+
+func compileTrustedProxies() {
+	for _, p := range GlobalConfig.Server.TrustedProxies {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(p)
+			if aerr != nil {
+				slog.Warn("Ignoring invalid server.trusted_proxies entry", "entry", p, "err", err)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		trustedProxies = append(trustedProxies, prefix.Masked())
+	}
+}
 
-		ips := strings.Split(ip, ",")
-		return strings.TrimSpace(ips[0])
+func isTrustedProxy(ip string) bool {
+	trustedProxiesOnce.Do(compileTrustedProxies)
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
 	}
-	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+	return false
+}
+
+// getRealIP is the client address used for lockouts, rate limits and the
+// audit log. X-Forwarded-For and X-Real-IP are only believed from
+// server.trusted_proxies; the client is then the nearest forwarded address
+// that is not a proxy itself. Unix socket peers have none.
+func getRealIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil || !isTrustedProxy(ip) {
 		return ip
 	}
-	ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+	if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+		hops := strings.Split(strings.Join(fwd, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			if hop := strings.TrimSpace(hops[i]); hop != "" && !isTrustedProxy(hop) {
+				return hop
+			}
+		}
+	}
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); real != "" {
+		return real
+	}
 	return ip
 }
 
+// bearerTokenIn reports whether the request carries one of tokens as its
+// bearer token. Each token list grants access to its own endpoints only.
+func bearerTokenIn(r *http.Request, tokens []string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	for _, t := range tokens {
+		if t != "" && subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
 func handleLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -167,44 +235,50 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
+		Username string `json:"username"`
 		Password string `json:"password"`
 	}
-	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 256)).Decode(&req); err != nil {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 512)).Decode(&req); err != nil {
 		http.Error(w, "Bad request", http.StatusBadRequest)
 		return
 	}
 
-	if len(req.Password) == 0 || len(req.Password) > 72 {
-		rem := recordFailedAttempt(ip)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":     "Invalid password",
-			"remaining": rem,
-		})
-		return
-	}
-
-	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(req.Password)); err != nil {
+	id, role, err := authenticate(r.Context(), req.Username, req.Password)
+	if err != nil {
 		rem := recordFailedAttempt(ip)
+		msg := "Invalid password"
+		if errors.Is(err, errNoRole) {
+			msg = "Account is not allowed to use Talaria"
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":     "Invalid password",
+			"error":     msg,
 			"remaining": rem,
 		})
 		return
 	}
 
 	clearAttempts(ip)
-	sess := createSession()
+	sess := createSession(id.User, role)
+	setSessionCookies(w, r, sess)
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":   true,
+		"user": sess.user,
+		"role": sess.role,
+	})
+}
+
+func setSessionCookies(w http.ResponseWriter, r *http.Request, sess *session) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookie,
 		Value:    sess.token,
 		Path:     "/",
 		MaxAge:   int(sessionMaxAge.Seconds()),
 		HttpOnly: true,
+		Secure:   r.TLS != nil,
 		SameSite: http.SameSiteStrictMode,
 	})
 
@@ -213,13 +287,9 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 		Value:    sess.csrf,
 		Path:     "/",
 		MaxAge:   int(sessionMaxAge.Seconds()),
+		Secure:   r.TLS != nil,
 		SameSite: http.SameSiteStrictMode,
 	})
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"ok": true,
-	})
 }
 
 func handleLogout(w http.ResponseWriter, r *http.Request) {
@@ -248,25 +318,26 @@ func handleAuthCheck(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]interface{}{"authenticated": false})
+		json.NewEncoder(w).Encode(map[string]interface{}{"authenticated": false, "sso": GlobalConfig.Auth.OIDC.Enabled})
 		return
 	}
 	if s := getSession(c.Value); s != nil {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{"authenticated": true})
+		json.NewEncoder(w).Encode(map[string]interface{}{"authenticated": true, "user": s.user, "role": s.role})
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
-	json.NewEncoder(w).Encode(map[string]interface{}{"authenticated": false})
+	json.NewEncoder(w).Encode(map[string]interface{}{"authenticated": false, "sso": GlobalConfig.Auth.OIDC.Enabled})
 }
 
 func getSessionFromRequest(r *http.Request) *session {
-	c, err := r.Cookie(sessionCookie)
-	if err != nil {
-		return nil
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		if s := getSession(c.Value); s != nil {
+			return s
+		}
 	}
-	return getSession(c.Value)
+	return clientCertSession(r)
 }
 
 func isAuthenticated(r *http.Request) bool {
@@ -276,13 +347,11 @@ func isAuthenticated(r *http.Request) bool {
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("X-Frame-Options", "DENY")
-		w.Header().Set("Referrer-Policy", "same-origin")
+		setSecurityHeaders(w, r)
 
 		path := r.URL.Path
 
-		if isStaticAsset(path) {
+		if isStaticAsset(path) && !isAPIPath(path) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -304,8 +373,11 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+			// A browser presents its certificate on its own, so cert
+			// sessions still need the header; any value will do, since a
+			// cross-site page cannot set it without a CORS preflight.
 			clientCSRF := r.Header.Get("X-CSRF-Token")
-			if clientCSRF == "" || clientCSRF != session.csrf {
+			if clientCSRF == "" || !session.viaCert && clientCSRF != session.csrf {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusForbidden)
 				json.NewEncoder(w).Encode(map[string]interface{}{
@@ -315,10 +387,25 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			}
 		}
 
+		if session.role != roleAdmin && requiresAdmin(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": "Admin role required",
+			})
+			return
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
 
+// isAPIPath covers the routes that must never be served as static assets,
+// whatever their names end in.
+func isAPIPath(path string) bool {
+	return strings.HasPrefix(path, "/api/") || path == "/ws" || strings.HasPrefix(path, "/ws/")
+}
+
 func isStaticAsset(path string) bool {
 	for _, ext := range []string{".css", ".js", ".woff", ".woff2", ".ttf", ".ico", ".png", ".jpg", ".svg"} {
 		if len(path) > len(ext) && path[len(path)-len(ext):] == ext {
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"talaria/monitor"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	roleAdmin  = "admin"
+	roleViewer = "viewer" // read-only dashboard access
+)
+
+var (
+	errInvalidCredentials = errors.New("invalid credentials")
+	errNoRole             = errors.New("user has no Talaria role")
+
+	usernameRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+	// Endpoints a viewer may not reach even with GET.
+	adminOnlyPaths = []string{"/ws/terminal", "/ws/diag", "/api/diag/", "/api/screenshot", "/api/admin/", "/api/debug/", "/api/audit"}
+
+	// Endpoints a viewer may POST to; they only touch the caller's own state.
+	viewerWritePaths = []string{"/api/push/", "/api/graphql"}
+)
+
+// identity is what a backend knows about an authenticated user.
+type identity struct {
+	User   string
+	Groups []string
+	Role   string // set by backends that decide it themselves; skips auth.roles
+}
+
+// AuthBackend verifies login credentials; configured with auth.backend.
+type AuthBackend interface {
+	Name() string
+	Authenticate(ctx context.Context, user, password string) (identity, error)
+}
+
+func newAuthBackend() AuthBackend {
+	cfg := GlobalConfig.Auth
+	switch cfg.Backend {
+	case "local":
+		return localBackend{}
+	case "ldap":
+		return &ldapBackend{
+			url:                cfg.LDAP.URL,
+			userDN:             cfg.LDAP.UserDN,
+			groupBaseDN:        cfg.LDAP.GroupBaseDN,
+			groupAttr:          cfg.LDAP.GroupAttribute,
+			insecureSkipVerify: cfg.LDAP.InsecureSkipVerify,
+		}
+	case "", "static":
+	default:
+		slog.Warn("Unknown auth.backend, falling back to static password", "backend", cfg.Backend)
+	}
+	return staticBackend{}
+}
+
+// staticBackend is the single shared passphrase from auth.password_hash.
+type staticBackend struct{}
+
+func (staticBackend) Name() string { return "static" }
+
+func (staticBackend) Authenticate(_ context.Context, _, password string) (identity, error) {
+	if len(password) > 72 {
+		return identity{}, errInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(password)); err != nil {
+		return identity{}, errInvalidCredentials
+	}
+	// The owner's passphrase is always admin, whatever auth.roles maps the
+	// directory groups to.
+	return identity{User: "admin", Role: roleAdmin}, nil
+}
+
+// localBackend authenticates macOS accounts through OpenDirectory and uses
+// their local group memberships for role mapping.
+type localBackend struct{}
+
+func (localBackend) Name() string { return "local" }
+
+func (localBackend) Authenticate(ctx context.Context, user, password string) (identity, error) {
+	if !monitor.VerifyLocalPassword(user, password) {
+		return identity{}, errInvalidCredentials
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	out, err := monitor.RunCmd(ctx, "id", "-Gn", user)
+	if err != nil {
+		return identity{}, fmt.Errorf("group lookup for %s: %w", user, err)
+	}
+	return identity{User: user, Groups: strings.Fields(string(out))}, nil
+}
+
+// roleFor maps directory groups to a Talaria role via auth.roles; admin wins
+// over viewer. Without a mapping, the "admin" group is admin.
+func roleFor(id identity) (string, error) {
+	if id.Role != "" {
+		return id.Role, nil
+	}
+	mapping := GlobalConfig.Auth.Roles
+	if len(mapping) == 0 {
+		mapping = map[string]string{"admin": roleAdmin}
+	}
+
+	role := ""
+	for _, g := range id.Groups {
+		switch mapping[g] {
+		case roleAdmin:
+			return roleAdmin, nil
+		case roleViewer:
+			role = roleViewer
+		}
+	}
+	if role == "" {
+		role = GlobalConfig.Auth.DefaultRole
+	}
+	if role != roleAdmin && role != roleViewer {
+		return "", errNoRole
+	}
+	return role, nil
+}
+
+func authenticate(ctx context.Context, user, password string) (identity, string, error) {
+	if GlobalConfig.Auth.Backend != "" && GlobalConfig.Auth.Backend != "static" && !usernameRegex.MatchString(user) {
+		return identity{}, "", errInvalidCredentials
+	}
+	if len(password) == 0 {
+		return identity{}, "", errInvalidCredentials
+	}
+
+	backend := newAuthBackend()
+	id, err := backend.Authenticate(ctx, user, password)
+	if err != nil {
+		if !errors.Is(err, errInvalidCredentials) {
+			slog.Error("Auth backend failed", "backend", backend.Name(), "err", err)
+		}
+		return identity{}, "", err
+	}
+	role, err := roleFor(id)
+	if err != nil {
+		return identity{}, "", err
+	}
+	return id, role, nil
+}
+
+func requiresAdmin(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		for _, p := range viewerWritePaths {
+			if strings.HasPrefix(r.URL.Path, p) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, p := range adminOnlyPaths {
+		if r.URL.Path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(r.URL.Path, p)) {
+			return true
+		}
+	}
+	return false
+}
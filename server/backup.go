@@ -0,0 +1,359 @@
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	backupManifest   = "talaria-backup.json"
+	backupConfig     = "config.yml"
+	backupHistoryDir = "data/history"
+	maxRestoreSize   = 4 << 30
+)
+
+// Keys dropped from config.yml when a backup excludes secrets. Narrower than
+// the bug report redaction: hosts, URLs and user names are kept so the
+// restored config still works once the secrets are filled back in. Only
+// leaves are dropped, never whole sections.
+var reSecretConfigKey = regexp.MustCompile(`(?i)pass|token|secret|_key$|private|hash|salt`)
+
+type backupInfo struct {
+	Version         int    `json:"version"`
+	Created         int64  `json:"created"`
+	Host            string `json:"host"`
+	SecretsExcluded bool   `json:"secrets_excluded"`
+}
+
+// handleBackup streams a .tar.gz of config.yml and the history database.
+// ?exclude_secrets=1 strips passwords, tokens and keys from the config.
+func handleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	excludeSecrets := r.URL.Query().Get("exclude_secrets") == "1"
+	cfg, err := os.ReadFile(configPath)
+	if err == nil && excludeSecrets {
+		cfg, err = stripSecrets(cfg)
+	}
+	if err != nil {
+		http.Error(w, "Failed to read config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	host, _ := os.Hostname()
+	info := backupInfo{Version: 1, Created: time.Now().Unix(), Host: host, SecretsExcluded: excludeSecrets}
+	name := fmt.Sprintf("talaria-backup-%s-%s.tar.gz", host, time.Now().Format("20060102-1504"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest, _ := json.MarshalIndent(info, "", "  ")
+	err = errors.Join(
+		writeTarFile(tw, backupManifest, manifest),
+		writeTarFile(tw, backupConfig, cfg),
+		writeTarDir(tw, dataPath("history"), backupHistoryDir),
+		tw.Close(),
+		gz.Close(),
+	)
+	result := "ok"
+	if err != nil {
+		// Headers are gone; the client sees a truncated archive.
+		slog.Error("Backup failed", "err", err)
+		result = "error"
+	}
+	auditLog(r, "backup", map[string]string{"exclude_secrets": fmt.Sprint(excludeSecrets)}, result)
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// writeTarDir adds every regular file under dir with names below prefix.
+// Segments still being appended to are copied up to their current size.
+func writeTarDir(tw *tar.Writer, dir, prefix string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		hdr := &tar.Header{Name: prefix + "/" + filepath.ToSlash(rel), Mode: 0600, Size: fi.Size(), ModTime: fi.ModTime()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = io.CopyN(tw, f, fi.Size())
+		return err
+	})
+}
+
+func stripSecrets(cfg []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(cfg, &doc); err != nil {
+		return nil, err
+	}
+	stripSecretNodes(&doc)
+	return yaml.Marshal(&doc)
+}
+
+func stripSecretNodes(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		kept := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if isSecretLeaf(n.Content[i], n.Content[i+1]) {
+				continue
+			}
+			stripSecretNodes(n.Content[i+1])
+			kept = append(kept, n.Content[i], n.Content[i+1])
+		}
+		n.Content = kept
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, c := range n.Content {
+			stripSecretNodes(c)
+		}
+	}
+}
+
+func isSecretLeaf(key, value *yaml.Node) bool {
+	if !reSecretConfigKey.MatchString(key.Value) {
+		return false
+	}
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return true
+	case yaml.SequenceNode:
+		return !slices.ContainsFunc(value.Content, func(c *yaml.Node) bool { return c.Kind != yaml.ScalarNode })
+	}
+	return false
+}
+
+// mergeSecrets puts the secrets a backup left out back from the running
+// config, so restoring one does not lock the admin out or drop notifier
+// tokens. List items are matched by position, and only when both lists are
+// the same length.
+func mergeSecrets(restored, current []byte) ([]byte, error) {
+	var dst, src yaml.Node
+	if err := yaml.Unmarshal(restored, &dst); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(current, &src); err != nil {
+		return nil, err
+	}
+	mergeSecretNodes(&dst, &src)
+	return yaml.Marshal(&dst)
+}
+
+func mergeSecretNodes(dst, src *yaml.Node) {
+	if dst.Kind != src.Kind {
+		return
+	}
+	switch src.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+			j := 0
+			for j < len(dst.Content) && dst.Content[j].Value != key.Value {
+				j += 2
+			}
+			switch {
+			case j+1 < len(dst.Content):
+				mergeSecretNodes(dst.Content[j+1], value)
+			case isSecretLeaf(key, value):
+				// Stripping may have left "{}" behind.
+				dst.Style &^= yaml.FlowStyle
+				dst.Content = append(dst.Content, key, value)
+			}
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		if len(dst.Content) != len(src.Content) {
+			return
+		}
+		for i := range src.Content {
+			mergeSecretNodes(dst.Content[i], src.Content[i])
+		}
+	}
+}
+
+// handleRestore accepts a backup archive as the request body. The archive is
+// unpacked into a staging directory and checked before anything is replaced;
+// the previous config and history are kept as config.yml.before-restore and
+// data/history.before-restore. The server then restarts to load them. A
+// backup made without secrets keeps the current passwords, tokens and keys.
+func handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	stage := dataPath("restore-staging")
+	os.RemoveAll(stage)
+	defer os.RemoveAll(stage)
+
+	info, err := unpackBackup(http.MaxBytesReader(w, r.Body, maxRestoreSize), stage)
+	if err != nil {
+		auditLog(r, "restore", nil, "error")
+		http.Error(w, "Invalid backup: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := applyRestore(stage, info.SecretsExcluded); err != nil {
+		slog.Error("Restore failed", "err", err)
+		auditLog(r, "restore", nil, "error")
+		http.Error(w, "Restore failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	auditLog(r, "restore", map[string]string{"from_host": info.Host, "created": time.Unix(info.Created, 0).Format(time.RFC3339)}, "ok")
+
+	select {
+	case restartCh <- struct{}{}:
+	default:
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":               true,
+		"secrets_excluded": info.SecretsExcluded,
+		"message":          "Restored; restarting to load the new config and history",
+	})
+}
+
+func unpackBackup(body io.Reader, stage string) (backupInfo, error) {
+	var info backupInfo
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return info, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return info, err
+		}
+		name := path.Clean(hdr.Name)
+		switch {
+		case hdr.Typeflag == tar.TypeDir:
+			continue
+		case hdr.Typeflag != tar.TypeReg:
+			return info, fmt.Errorf("unexpected entry %q", hdr.Name)
+		case name != backupManifest && name != backupConfig && !strings.HasPrefix(name, backupHistoryDir+"/"):
+			return info, fmt.Errorf("unexpected file %q", hdr.Name)
+		case strings.Contains(name, ".."):
+			return info, fmt.Errorf("unsafe path %q", hdr.Name)
+		}
+		dst := filepath.Join(stage, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+			return info, err
+		}
+		f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			return info, err
+		}
+		_, err = io.Copy(f, tr)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return info, err
+		}
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(stage, backupManifest))
+	if err != nil {
+		return info, errors.New("missing " + backupManifest)
+	}
+	if err := json.Unmarshal(manifest, &info); err != nil || info.Version != 1 {
+		return info, errors.New("unsupported backup version")
+	}
+	cfg, err := os.ReadFile(filepath.Join(stage, backupConfig))
+	if err != nil {
+		return info, errors.New("missing " + backupConfig)
+	}
+	if err := yaml.Unmarshal(cfg, &Config{}); err != nil {
+		return info, fmt.Errorf("config.yml: %v", err)
+	}
+	return info, nil
+}
+
+func applyRestore(stage string, secretsExcluded bool) error {
+	cfg, err := os.ReadFile(filepath.Join(stage, backupConfig))
+	if err != nil {
+		return err
+	}
+	old, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if old != nil && secretsExcluded {
+		if cfg, err = mergeSecrets(cfg, old); err != nil {
+			return fmt.Errorf("merging secrets: %w", err)
+		}
+	}
+
+	history := dataPath("history")
+	previous := history + ".before-restore"
+	if err := os.RemoveAll(previous); err != nil {
+		return err
+	}
+	if err := os.Rename(history, previous); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	staged := filepath.Join(stage, filepath.FromSlash(backupHistoryDir))
+	if err := os.MkdirAll(staged, 0700); err != nil {
+		return err
+	}
+	if err := os.Rename(staged, history); err != nil {
+		os.Rename(previous, history)
+		return err
+	}
+
+	if old != nil {
+		if err := os.WriteFile(configPath+".before-restore", old, 0600); err != nil {
+			return err
+		}
+	}
+	tmp := configPath + ".tmp"
+	if err := os.WriteFile(tmp, cfg, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, configPath)
+}
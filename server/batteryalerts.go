@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+)
+
+const metricBatteryChargingTemp = "builtin.battery_charging_temp"
+
+var (
+	defaultBatteryMinHealth   = 80.0
+	defaultBatteryCycleLevels = []int{500, 800, 1000}
+	defaultBatteryMaxTemp     = 45.0
+)
+
+// batteryAlertState remembers which one-off battery milestones were announced,
+// so they are not repeated on every restart.
+type batteryAlertState struct {
+	HealthAlerted bool `json:"health_alerted"`
+	CycleLevel    int  `json:"cycle_level"` // highest service level announced
+}
+
+var (
+	batteryAlerts   *batteryAlertState // nil until loaded
+	batteryAlertsMu sync.Mutex
+)
+
+// batteryRules covers the transient condition; health and cycle milestones
+// are tracked by checkBatteryMilestones.
+func batteryRules() []AlertRule {
+	limit := GlobalConfig.Alerts.Battery.MaxChargingTemp
+	if limit <= 0 {
+		limit = defaultBatteryMaxTemp
+	}
+	tempClear := limit - 3
+	return []AlertRule{
+		{Name: "Battery hot while charging", Metric: metricBatteryChargingTemp, Op: ">", Threshold: limit, Clear: &tempClear, For: "2m", Severity: SeverityCritical},
+	}
+}
+
+// Reports 0 while discharging so a firing rule resolves when unplugged.
+func addBatteryValues(m *AllMetrics, values map[string]float64) {
+	if m == nil || !m.Battery.HasBattery || m.Battery.Temperature == 0 {
+		return
+	}
+	if m.Battery.Charging {
+		values[metricBatteryChargingTemp] = m.Battery.Temperature
+	} else {
+		values[metricBatteryChargingTemp] = 0
+	}
+}
+
+func checkBatteryMilestones(m *AllMetrics) {
+	if m == nil || !m.Battery.HasBattery {
+		return
+	}
+	cfg := GlobalConfig.Alerts.Battery
+	minHealth := cfg.MinHealth
+	if minHealth <= 0 {
+		minHealth = defaultBatteryMinHealth
+	}
+	levels := cfg.CycleLevels
+	if len(levels) == 0 {
+		levels = defaultBatteryCycleLevels
+	}
+	levels = append([]int(nil), levels...)
+	sort.Ints(levels)
+	b := m.Battery
+
+	batteryAlertsMu.Lock()
+	if batteryAlerts == nil {
+		batteryAlerts = loadBatteryAlerts()
+	}
+	st := batteryAlerts
+	var events []Event
+	changed := false
+
+	if b.HealthPercent > 0 {
+		switch {
+		case b.HealthPercent < minHealth && !st.HealthAlerted:
+			st.HealthAlerted, changed = true, true
+			events = append(events, batteryEvent("Battery health", SeverityWarning, alertFiring, "Battery health degraded",
+				fmt.Sprintf("Full-charge capacity is %.0f%% of design (%d of %d mAh), below %.0f%%", b.HealthPercent, b.MaxCapacity, b.DesignCapacity, minHealth)))
+		case b.HealthPercent >= minHealth && st.HealthAlerted:
+			// Calibration drift or a replaced battery.
+			st.HealthAlerted, changed = false, true
+			events = append(events, batteryEvent("Battery health", SeverityInfo, "resolved", "Battery health recovered",
+				fmt.Sprintf("Full-charge capacity is back to %.0f%% of design", b.HealthPercent)))
+		}
+	}
+
+	reached := 0
+	for _, level := range levels {
+		if b.CycleCount >= level {
+			reached = level
+		}
+	}
+	switch {
+	case reached > st.CycleLevel:
+		st.CycleLevel, changed = reached, true
+		severity := SeverityWarning
+		if reached == levels[len(levels)-1] {
+			severity = SeverityCritical
+		}
+		events = append(events, batteryEvent(fmt.Sprintf("Battery cycles %d", reached), severity, alertFiring, "Battery cycle count",
+			fmt.Sprintf("Battery has %d charge cycles, past the %d-cycle service level", b.CycleCount, reached)))
+	case reached < st.CycleLevel:
+		// New battery: re-arm.
+		st.CycleLevel, changed = reached, true
+	}
+	if changed {
+		saveBatteryAlerts(st)
+	}
+	batteryAlertsMu.Unlock()
+
+	for _, e := range events {
+		RaiseEvent(e)
+	}
+}
+
+func batteryEvent(rule, severity, state, title, message string) Event {
+	return Event{
+		Kind:     "alert",
+		Severity: severity,
+		Title:    title,
+		Message:  message,
+		Fields:   map[string]string{"rule": rule, "state": state},
+	}
+}
+
+func loadBatteryAlerts() *batteryAlertState {
+	st := &batteryAlertState{}
+	data, err := os.ReadFile(dataPath("battery_alerts.json"))
+	if err != nil {
+		return st
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		slog.Warn("Ignoring corrupt battery alert state", "err", err)
+		return &batteryAlertState{}
+	}
+	return st
+}
+
+func saveBatteryAlerts(st *batteryAlertState) {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(dataPath("battery_alerts.json"), data, 0600); err != nil {
+		slog.Error("Failed to save battery alert state", "err", err)
+	}
+}
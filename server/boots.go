@@ -0,0 +1,194 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	maxBootSessions = 200
+	bootSaveEvery   = time.Minute
+	// A panic report is written during the boot after the panic; one this
+	// soon after a boot began belongs to the boot before it.
+	panicReportDelay = 15 * time.Minute
+)
+
+// bootSession accumulates what history saw during one boot. Boot (unix s)
+// is the ID, and is also the "boot" tag on raw history samples.
+type bootSession struct {
+	Boot      int64   `json:"boot"`
+	OSVersion string  `json:"os_version"`
+	First     int64   `json:"first"` // unix ms of the first and last samples
+	Last      int64   `json:"last"`
+	Samples   int     `json:"samples"`
+	CPUSum    float64 `json:"cpu_sum"`
+	LoadSum   float64 `json:"load_sum"`
+}
+
+// bootSummary is one row of /api/boots.
+type bootSummary struct {
+	Boot          int64   `json:"boot"`
+	OSVersion     string  `json:"os_version"`
+	Current       bool    `json:"current"`
+	UptimeSeconds int64   `json:"uptime_seconds"` // up to the last sample seen
+	Samples       int     `json:"samples"`
+	AvgCPU        float64 `json:"avg_cpu_percent"`
+	AvgLoad       float64 `json:"avg_load_1m"`
+	KernelPanics  int     `json:"kernel_panics"`
+	Unclean       bool    `json:"unclean_shutdown"` // next boot came without a recorded shutdown
+	TalariaPanics int     `json:"talaria_panics"`
+}
+
+var (
+	bootSessions  []*bootSession
+	bootsLoaded   bool
+	bootsSavedAt  time.Time
+	bootSessionMu sync.Mutex
+)
+
+func loadBootSessions() {
+	if bootsLoaded {
+		return
+	}
+	bootsLoaded = true
+	data, err := os.ReadFile(dataPath("boots.json"))
+	if err == nil {
+		json.Unmarshal(data, &bootSessions)
+	}
+}
+
+func saveBootSessions() {
+	data, err := json.Marshal(bootSessions)
+	if err == nil {
+		err = os.WriteFile(dataPath("boots.json"), data, 0600)
+	}
+	if err != nil {
+		slog.Error("Failed to save boot sessions", "err", err)
+	}
+}
+
+// trackBoot adds a history sample to its boot's totals.
+func trackBoot(m *AllMetrics) {
+	if m.System.BootTime == 0 {
+		return
+	}
+	bootSessionMu.Lock()
+	defer bootSessionMu.Unlock()
+	loadBootSessions()
+
+	var cur *bootSession
+	if n := len(bootSessions); n > 0 && bootSessions[n-1].Boot == m.System.BootTime {
+		cur = bootSessions[n-1]
+	} else {
+		cur = &bootSession{Boot: m.System.BootTime, First: m.Timestamp}
+		bootSessions = append(bootSessions, cur)
+		if len(bootSessions) > maxBootSessions {
+			bootSessions = bootSessions[len(bootSessions)-maxBootSessions:]
+		}
+		bootsSavedAt = time.Time{}
+	}
+	cur.OSVersion = m.System.OSVersion
+	cur.Last = m.Timestamp
+	cur.Samples++
+	cur.CPUSum += m.CPU.UsagePercent
+	if f := strings.Fields(m.System.LoadAvg); len(f) > 0 {
+		load, _ := strconv.ParseFloat(f[0], 64)
+		cur.LoadSum += load
+	}
+	if time.Since(bootsSavedAt) >= bootSaveEvery {
+		saveBootSessions()
+		bootsSavedAt = time.Now()
+	}
+}
+
+// bootSummaries returns one summary per recorded boot, newest first.
+func bootSummaries() []bootSummary {
+	bootSessionMu.Lock()
+	loadBootSessions()
+	out := make([]bootSummary, len(bootSessions))
+	for i, b := range bootSessions {
+		out[i] = bootSummary{
+			Boot:          b.Boot,
+			OSVersion:     b.OSVersion,
+			UptimeSeconds: b.Last/1000 - b.Boot,
+			Samples:       b.Samples,
+		}
+		if b.Samples > 0 {
+			out[i].AvgCPU = math.Round(b.CPUSum/float64(b.Samples)*10) / 10
+			out[i].AvgLoad = math.Round(b.LoadSum/float64(b.Samples)*100) / 100
+		}
+	}
+	bootSessionMu.Unlock()
+	if len(out) == 0 {
+		return out
+	}
+	if m := latestMetrics(); m != nil && m.System.BootTime == out[len(out)-1].Boot {
+		out[len(out)-1].Current = true
+	}
+
+	// index of the recorded boot that t (unix s) falls in, or -1
+	bootAt := func(t int64) int {
+		return sort.Search(len(out), func(i int) bool { return out[i].Boot > t }) - 1
+	}
+	for _, rep := range monitor.ListPanicReports() {
+		i := bootAt(rep.ModTime)
+		if i >= 0 && rep.ModTime-out[i].Boot < int64(panicReportDelay/time.Second) {
+			i--
+		}
+		if i >= 0 {
+			out[i].KernelPanics++
+		}
+	}
+	for _, p := range recentPanics() {
+		if i := bootAt(p.Time); i >= 0 {
+			out[i].TalariaPanics++
+		}
+	}
+
+	// A boot that is followed by another with no shutdown in between ended
+	// in a crash, panic or forced power-off. Boots older than the power log
+	// are left as clean.
+	events := monitor.GetPowerEvents()
+	var shutdowns []int64
+	for _, e := range events {
+		if e.Kind == monitor.PowerShutdown {
+			shutdowns = append(shutdowns, e.Time)
+		}
+	}
+	for i := 0; i+1 < len(out); i++ {
+		if len(events) == 0 || events[0].Time > out[i].Boot {
+			continue
+		}
+		clean := false
+		for _, t := range shutdowns {
+			if t >= out[i].Boot && t <= out[i+1].Boot {
+				clean = true
+				break
+			}
+		}
+		out[i].Unclean = !clean
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Boot > out[j].Boot })
+	return out
+}
+
+// handleBoots serves per-boot summaries for comparing stability across OS
+// updates. Boots before this instance started recording are not listed.
+func handleBoots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bootSummaries())
+}
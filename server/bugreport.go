@@ -0,0 +1,202 @@
+package server
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"talaria/monitor"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	issueURL = "https://github.com/narlyseorg/Talaria/issues/new"
+	// Browsers and GitHub reject much longer query strings.
+	maxIssueURL = 8000
+)
+
+// Config keys whose values are replaced in the report. Matching is on the
+// yaml key, so everything below a matching key (e.g. replica_tokens) goes too.
+var reSensitiveConfigKey = regexp.MustCompile(`(?i)pass|token|secret|key|hash|salt|url|webhook|topic|user|chat|email|^to$|^from$|^id$|^host$|^dn$|^base|headers|endpoint`)
+
+// BugReportCommand implements "talaria report-bug": it prints a pre-filled
+// GitHub issue body and, with -open, opens the new-issue page via openURL.
+func BugReportCommand(args []string, version string, openURL func(string)) int {
+	fs := flag.NewFlagSet("report-bug", flag.ContinueOnError)
+	cfgPath := fs.String("config", "config.yml", "Path to config file")
+	open := fs.Bool("open", false, "Open a pre-filled GitHub issue in the browser")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	body := buildBugReport(*cfgPath, version)
+	fmt.Println(body)
+
+	if *open {
+		u := issueURL + "?" + url.Values{"body": {body}}.Encode()
+		if len(u) > maxIssueURL {
+			// Too long for a URL; open a blank issue and let the user paste.
+			u = issueURL + "?" + url.Values{"body": {"<!-- Paste the output of `talaria report-bug` here -->"}}.Encode()
+			fmt.Fprintln(os.Stderr, "The report is too long to pre-fill; copy the text above into the issue.")
+		}
+		openURL(u)
+	}
+	return 0
+}
+
+func buildBugReport(cfgPath, version string) string {
+	var b strings.Builder
+	b.WriteString("### What happened?\n\n<!-- Describe the problem and how to reproduce it -->\n\n")
+
+	b.WriteString("### Environment\n\n")
+	fmt.Fprintf(&b, "- Talaria: %s%s\n", version, buildRevision())
+	fmt.Fprintf(&b, "- Go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	sys := monitor.GetSystem()
+	fmt.Fprintf(&b, "- OS: %s\n", sys.OSVersion)
+	if hw := monitor.GetHardwareInfo(); hw.ModelName != "" {
+		fmt.Fprintf(&b, "- Model: %s (%s)\n", hw.ModelName, hw.Chip)
+	}
+
+	b.WriteString("\n### Config (redacted)\n\n")
+	if _, err := os.Stat(cfgPath); err != nil {
+		fmt.Fprintf(&b, "Config not found at %s.\n", cfgPath)
+	} else if err := LoadConfig(cfgPath); err != nil {
+		fmt.Fprintf(&b, "Config failed to load: %v\n", err)
+	} else {
+		b.WriteString("```yaml\n")
+		b.WriteString(redactedConfig())
+		b.WriteString("```\n")
+	}
+
+	b.WriteString("\n### Collectors\n\n")
+	b.WriteString(collectorStatus())
+
+	b.WriteString("\n### Recent panics\n\n")
+	var panics []panicRecord
+	if GlobalConfig != nil {
+		panics = recentPanics()
+	}
+	if len(panics) == 0 {
+		b.WriteString("None recorded.\n")
+	}
+	if len(panics) > 5 {
+		panics = panics[len(panics)-5:]
+	}
+	for _, p := range panics {
+		fmt.Fprintf(&b, "<details><summary>%s — %s: %s</summary>\n\n```\n%s```\n</details>\n",
+			time.Unix(p.Time, 0).Format("2006-01-02 15:04:05"), p.Where, redactCommand(p.Value), redactCommand(p.Stack))
+	}
+	return b.String()
+}
+
+func buildRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	var rev, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			if s.Value == "true" {
+				modified = "-dirty"
+			}
+		}
+	}
+	if len(rev) > 12 {
+		rev = rev[:12]
+	}
+	if rev == "" {
+		return ""
+	}
+	return " (" + rev + modified + ")"
+}
+
+// redactedConfig renders the loaded config with every sensitive value replaced.
+// Empty values are kept so the report still shows what is configured.
+func redactedConfig() string {
+	var node yaml.Node
+	if err := node.Encode(GlobalConfig); err != nil {
+		return fmt.Sprintf("# failed to encode config: %v\n", err)
+	}
+	redactNode(&node, false)
+	out, err := yaml.Marshal(&node)
+	if err != nil {
+		return fmt.Sprintf("# failed to encode config: %v\n", err)
+	}
+	return string(out)
+}
+
+func redactNode(n *yaml.Node, sensitive bool) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			redactNode(n.Content[i+1], sensitive || reSensitiveConfigKey.MatchString(key))
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, c := range n.Content {
+			redactNode(c, sensitive)
+		}
+	case yaml.ScalarNode:
+		if sensitive && n.Value != "" && n.Value != "0" && n.Tag != "!!bool" && n.Tag != "!!null" {
+			n.Value, n.Tag, n.Style = redactedMarker, "!!str", 0
+		}
+	}
+}
+
+// collectorStatus runs every collector once, timing it and capturing any
+// panic or subprocess error it logs.
+func collectorStatus() string {
+	var logs bytes.Buffer
+	prev, out, flags := slog.Default(), log.Writer(), log.Flags()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+	defer func() {
+		slog.SetDefault(prev)
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	}()
+
+	var b strings.Builder
+	b.WriteString("| Collector | Time | Status |\n|---|---|---|\n")
+	m := &AllMetrics{}
+	for _, c := range collectors {
+		logs.Reset()
+		start := time.Now()
+		status := "ok"
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					status = fmt.Sprintf("panic: %v", r)
+				}
+			}()
+			c.fn(m)
+		}()
+		if status == "ok" {
+			if line, _, _ := strings.Cut(strings.TrimSpace(logs.String()), "\n"); line != "" {
+				status = line
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", c.name, time.Since(start).Round(time.Millisecond),
+			strings.ReplaceAll(redactCommand(status), "|", `\|`))
+	}
+	return b.String()
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"talaria/monitor"
+	"time"
+)
+
+// Derived metrics for the built-in rules, computed from the snapshot so the
+// normal alert state machine handles them.
+const (
+	metricDiskUsedMax      = "builtin.disk_used_percent_max"
+	metricBatteryUnplugged = "builtin.battery_percent_unplugged"
+
+	sessionWatchInterval = 5 * time.Second
+)
+
+var (
+	knownLoginSessions map[string]monitor.SessionInfo // nil until the first snapshot
+	loginSessionsMu    sync.Mutex
+
+	knownLeakSuspects = make(map[int]monitor.LeakSuspect)
+	leakSuspectsMu    sync.Mutex
+)
+
+func builtinRules() []AlertRule {
+	diskClear, batteryClear := 85.0, 15.0
+	return []AlertRule{
+		{Name: "Disk almost full", Metric: metricDiskUsedMax, Op: ">", Threshold: 90, Clear: &diskClear, Severity: SeverityWarning},
+		{Name: "Battery low", Metric: metricBatteryUnplugged, Op: "<", Threshold: 10, Clear: &batteryClear, Severity: SeverityCritical},
+	}
+}
+
+func addBuiltinValues(m *AllMetrics, values map[string]float64) {
+	if m == nil {
+		return
+	}
+	if len(m.Disks) > 0 {
+		max := 0.0
+		for _, d := range m.Disks {
+			if d.UsedPct > max {
+				max = d.UsedPct
+			}
+		}
+		values[metricDiskUsedMax] = max
+	}
+	// Report a full battery while charging so a firing rule resolves on plug-in.
+	if m.Battery.HasBattery {
+		if m.Battery.Charging || m.Battery.PowerSource == "AC Power" {
+			values[metricBatteryUnplugged] = 100
+		} else {
+			values[metricBatteryUnplugged] = float64(m.Battery.Percent)
+		}
+	}
+}
+
+// checkSessions raises an alert when a login session appears and resolves it
+// when the session ends. Only remote (SSH) sessions count unless
+// alerts.sessions.include_local is set. Sessions already open at startup are
+// taken as the baseline.
+func checkSessions(sessions []monitor.SessionInfo) {
+	includeLocal := GlobalConfig.Alerts.Sessions.IncludeLocal
+	current := make(map[string]monitor.SessionInfo)
+	for _, s := range sessions {
+		if s.Host != "" || includeLocal {
+			current[s.User+"@"+s.Terminal] = s
+		}
+	}
+
+	loginSessionsMu.Lock()
+	prev := knownLoginSessions
+	knownLoginSessions = current
+	loginSessionsMu.Unlock()
+	if prev == nil {
+		return
+	}
+
+	for key, s := range current {
+		if _, ok := prev[key]; !ok {
+			raiseSessionEvent(s, true)
+		}
+	}
+	for key, s := range prev {
+		if _, ok := current[key]; !ok {
+			raiseSessionEvent(s, false)
+		}
+	}
+}
+
+// startSessionWatch polls sessions on their own cache interval so logins are
+// reported within seconds rather than on the alert evaluation tick.
+func startSessionWatch() {
+	go func() {
+		ticker := time.NewTicker(sessionWatchInterval)
+		defer ticker.Stop()
+		for ; ; <-ticker.C {
+			if !collectionIdle() {
+				checkSessions(monitor.GetSecurity().UserSessions)
+			}
+		}
+	}()
+}
+
+func raiseSessionEvent(s monitor.SessionInfo, opened bool) {
+	kind, from := "SSH session", " from "+s.Host
+	if s.Host == "" {
+		kind, from = "Local session", ""
+	}
+	e := Event{
+		Kind: "alert",
+		Fields: map[string]string{
+			"rule":     kind + " " + s.Terminal,
+			"user":     s.User,
+			"host":     s.Host,
+			"terminal": s.Terminal,
+			"login":    s.Login,
+		},
+	}
+	if opened {
+		e.Severity = SeverityWarning
+		e.Title = "New " + kind
+		e.Message = s.User + " logged in" + from + " on " + s.Terminal
+		if s.Login != "" {
+			e.Message += " at " + s.Login
+		}
+		e.Fields["state"] = alertFiring
+	} else {
+		e.Severity = SeverityInfo
+		e.Title = kind + " closed"
+		e.Message = s.User + from + " logged out of " + s.Terminal
+		e.Fields["state"] = "resolved"
+	}
+	RaiseEvent(e)
+}
+
+// checkLeakSuspects raises an alert when a process is first flagged as leaking
+// and resolves it once it stops growing or exits.
+func checkLeakSuspects(m *AllMetrics) {
+	if m == nil {
+		return
+	}
+	current := make(map[int]monitor.LeakSuspect)
+	for _, s := range m.Health.LeakSuspects {
+		current[s.PID] = s
+	}
+
+	leakSuspectsMu.Lock()
+	prev := knownLeakSuspects
+	knownLeakSuspects = current
+	leakSuspectsMu.Unlock()
+
+	for pid, s := range current {
+		if _, ok := prev[pid]; !ok {
+			raiseLeakEvent(s, true)
+		}
+	}
+	for pid, s := range prev {
+		if _, ok := current[pid]; !ok {
+			raiseLeakEvent(s, false)
+		}
+	}
+}
+
+func raiseLeakEvent(s monitor.LeakSuspect, firing bool) {
+	e := Event{
+		Kind: "alert",
+		Fields: map[string]string{
+			"rule": fmt.Sprintf("Memory leak %s (%d)", s.Name, s.PID),
+			"pid":  strconv.Itoa(s.PID),
+			"name": s.Name,
+		},
+	}
+	if firing {
+		e.Severity = SeverityWarning
+		e.Title = "Possible memory leak"
+		e.Message = fmt.Sprintf("%s (PID %d) grew from %.0f to %.0f MiB over %.1fh (%.0f MiB/h)",
+			s.Name, s.PID, s.StartMB, s.CurrentMB, s.WindowHours, s.MBPerHour)
+		e.Fields["state"] = alertFiring
+	} else {
+		e.Severity = SeverityInfo
+		e.Title = "Memory leak cleared"
+		e.Message = fmt.Sprintf("%s (PID %d) is no longer growing", s.Name, s.PID)
+		e.Fields["state"] = "resolved"
+	}
+	RaiseEvent(e)
+}
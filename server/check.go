@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Nagios plugin exit codes.
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+// checkValues is flattenMetrics plus the aliases people reach for first.
+func checkValues(m *AllMetrics) map[string]float64 {
+	values := flattenMetrics(m)
+	if total := values["storage_breakdown.total_gb"]; total > 0 {
+		values["disk.used_percent"] = values["storage_breakdown.used_gb"] / total * 100
+	}
+	return values
+}
+
+// CheckCommand implements "talaria check", a Nagios/Icinga plugin. It asks a
+// running instance for the current value (authenticated with a check
+// token) or, with -local, collects one sample itself.
+func CheckCommand(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	cfgPath := fs.String("config", "config.yml", "Path to config file")
+	metric := fs.String("metric", "", "Metric key, e.g. cpu.usage_percent or disk.used_percent")
+	warn := fs.String("warn", "", "Warning threshold")
+	crit := fs.String("crit", "", "Critical threshold")
+	below := fs.Bool("below", false, "Alert when the value drops below the thresholds (e.g. battery.percent)")
+	baseURL := fs.String("url", "", "Instance to query (default: this config's server)")
+	token := fs.String("token", "", "Check token (default: first history.check_tokens entry)")
+	local := fs.Bool("local", false, "Collect directly instead of querying a running instance")
+	if err := fs.Parse(args); err != nil {
+		return checkUnknown
+	}
+	if *metric == "" {
+		fmt.Println("UNKNOWN - -metric is required")
+		return checkUnknown
+	}
+	warnAt, err1 := parseCheckThreshold(*warn)
+	critAt, err2 := parseCheckThreshold(*crit)
+	if err := errors.Join(err1, err2); err != nil {
+		fmt.Printf("UNKNOWN - %v\n", err)
+		return checkUnknown
+	}
+	if _, err := os.Stat(*cfgPath); err == nil {
+		if err := LoadConfig(*cfgPath); err != nil {
+			fmt.Printf("UNKNOWN - failed to load config: %v\n", err)
+			return checkUnknown
+		}
+	}
+
+	var value float64
+	var err error
+	if *local {
+		value, err = collectCheckValue(*metric)
+	} else {
+		value, err = fetchCheckValue(*baseURL, *token, *metric)
+	}
+	if err != nil {
+		fmt.Printf("UNKNOWN - %v\n", err)
+		return checkUnknown
+	}
+
+	breached := func(limit *float64) bool {
+		if limit == nil {
+			return false
+		}
+		if *below {
+			return value < *limit
+		}
+		return value > *limit
+	}
+	status, code := "OK", checkOK
+	switch {
+	case breached(critAt):
+		status, code = "CRITICAL", checkCritical
+	case breached(warnAt):
+		status, code = "WARNING", checkWarning
+	}
+	v := strconv.FormatFloat(value, 'f', -1, 64)
+	fmt.Printf("%s - %s = %s | '%s'=%s;%s;%s;;\n", status, *metric, v, *metric, v, *warn, *crit)
+	return code
+}
+
+func parseCheckThreshold(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold %q", s)
+	}
+	return &v, nil
+}
+
+func collectCheckValue(metric string) (float64, error) {
+	// CPU usage is a delta between two reads, so the first sample primes it.
+	CollectAll(0)
+	time.Sleep(time.Second)
+	v, ok := checkValues(CollectAll(0))[metric]
+	if !ok {
+		return 0, fmt.Errorf("unknown metric %q", metric)
+	}
+	return v, nil
+}
+
+func fetchCheckValue(baseURL, token, metric string) (float64, error) {
+	if baseURL == "" {
+		baseURL = LocalURL()
+	}
+	if token == "" && len(GlobalConfig.History.CheckTokens) > 0 {
+		token = GlobalConfig.History.CheckTokens[0]
+	}
+	if token == "" {
+		return 0, errors.New("no check token; pass -token or -local")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/api/check?metric="+url.QueryEscape(metric), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return 0, fmt.Errorf("unknown metric %q", metric)
+	default:
+		return 0, fmt.Errorf("%s: %s", baseURL, resp.Status)
+	}
+	var body struct {
+		Value float64 `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	return body.Value, nil
+}
+
+// handleCheck serves one current value to "talaria check". Like the history
+// export it accepts a bearer token, from history.check_tokens, so monitoring
+// hosts need no login.
+func handleCheck(w http.ResponseWriter, r *http.Request) {
+	if getSessionFromRequest(r) == nil && !bearerTokenIn(r, GlobalConfig.History.CheckTokens) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="talaria"`)
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	noteDemand()
+	m := latestMetrics()
+	if m == nil {
+		http.Error(w, "No metrics collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	metric := r.URL.Query().Get("metric")
+	v, ok := checkValues(m)[metric]
+	if !ok {
+		http.Error(w, "Unknown metric", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]any{"metric": metric, "value": v, "t": m.Timestamp})
+}
@@ -3,7 +3,9 @@ package server
 import (
 	"bufio"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -16,26 +18,486 @@ import (
 
 type Config struct {
 	Server struct {
-		Host  string `yaml:"host"`
-		Port  int    `yaml:"port"`
-		Theme string `yaml:"theme"`
+		Host    string `yaml:"host"`
+		Port    int    `yaml:"port"`
+		Theme   string `yaml:"theme"`
+		DataDir string `yaml:"data_dir"` // defaults to "data" next to the config file
+
+		// Listen replaces host and port with several addresses, e.g.
+		// "127.0.0.1:8745", "[::1]:8745" or a Tailscale IP; an entry
+		// without a port uses port.
+		Listen []string `yaml:"listen"`
+
+		// A Unix socket served next to the TCP port, or alone with
+		// socket_only. Who may connect is decided by the socket's mode.
+		Socket     string `yaml:"socket"`
+		SocketMode string `yaml:"socket_mode"` // octal, default "0660"
+		SocketOnly bool   `yaml:"socket_only"`
+
+		// Sockets passed by systemd or launchd replace all of the above.
+		LaunchdSocket string `yaml:"launchd_socket"` // plist Sockets key, default "Listeners"
+
+		// Reverse proxies (IPs or CIDRs) whose X-Forwarded-For and X-Real-IP
+		// name the client for lockouts, rate limits and the audit log.
+		// cloudflared connects from localhost: list 127.0.0.1 and ::1 for it.
+		TrustedProxies []string `yaml:"trusted_proxies"`
+
+		// HTTP limits; 0 keeps the default. Event streams and WebSockets
+		// are exempt from write_timeout_seconds.
+		ReadHeaderTimeoutSeconds int   `yaml:"read_header_timeout_seconds"` // default 5
+		ReadTimeoutSeconds       int   `yaml:"read_timeout_seconds"`        // default none
+		WriteTimeoutSeconds      int   `yaml:"write_timeout_seconds"`       // default none
+		IdleTimeoutSeconds       int   `yaml:"idle_timeout_seconds"`        // default 120
+		ShutdownGraceSeconds     int   `yaml:"shutdown_grace_seconds"`      // default 2
+		MaxBodyBytes             int64 `yaml:"max_body_bytes"`              // default 16 MiB; restore keeps its own cap
 	} `yaml:"server"`
 
+	// TLS serves HTTPS on every TCP address; the Unix socket stays plain
+	// for a local reverse proxy. Renewed files are picked up within a minute.
+	// Enabled without files uses a self-signed certificate kept in the
+	// data directory.
+	TLS struct {
+		Enabled  bool   `yaml:"enabled"`
+		CertFile string `yaml:"cert_file"` // PEM, full chain
+		KeyFile  string `yaml:"key_file"`
+
+		// ClientCA lets machines authenticate with a certificate it signed
+		// instead of a password. The common name is the user and the
+		// organizational units are groups for auth.roles. "require" refuses
+		// TCP connections without one; "optional" keeps password login.
+		ClientCA   string `yaml:"client_ca"`   // PEM bundle
+		ClientAuth string `yaml:"client_auth"` // "require" (default) or "optional"
+
+		// ACME issues and renews a certificate for a public hostname.
+		// http-01 needs http_addr reachable on port 80 from the internet;
+		// dns-01 runs dns_hook with "present" or "cleanup", the record name
+		// and its value appended.
+		ACME struct {
+			Domain    string   `yaml:"domain"`
+			Email     string   `yaml:"email"`
+			Challenge string   `yaml:"challenge"` // "http-01" (default) or "dns-01"
+			DNSHook   []string `yaml:"dns_hook"`
+			HTTPAddr  string   `yaml:"http_addr"` // default ":80"
+			Directory string   `yaml:"directory"` // default Let's Encrypt production
+		} `yaml:"acme"`
+	} `yaml:"tls"`
+
 	Auth struct {
-		PasswordHash string `yaml:"password_hash"`
+		PasswordHash string            `yaml:"password_hash"`
+		Backend      string            `yaml:"backend"`      // "static" (default), "local" (macOS accounts) or "ldap"
+		Roles        map[string]string `yaml:"roles"`        // directory group → "admin" or "viewer"; the static password is always admin
+		DefaultRole  string            `yaml:"default_role"` // for users in no mapped group; empty denies them
+
+		LDAP struct {
+			URL                string `yaml:"url"`     // ldaps://host:636
+			UserDN             string `yaml:"user_dn"` // "uid=%s,ou=people,dc=example,dc=com"
+			GroupBaseDN        string `yaml:"group_base_dn"`
+			GroupAttribute     string `yaml:"group_attribute"` // "member" (default) or "memberUid"
+			InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+		} `yaml:"ldap"`
+
+		// OIDC adds single sign-on next to the configured backend; groups from
+		// GroupsClaim go through the same Roles mapping.
+		OIDC struct {
+			Enabled       bool     `yaml:"enabled"`
+			Issuer        string   `yaml:"issuer"`
+			ClientID      string   `yaml:"client_id"`
+			ClientSecret  string   `yaml:"client_secret"`
+			RedirectURL   string   `yaml:"redirect_url"` // https://host/auth/oidc/callback
+			Scopes        []string `yaml:"scopes"`       // defaults to openid, profile, email, groups
+			GroupsClaim   string   `yaml:"groups_claim"` // defaults to "groups"
+			UsernameClaim string   `yaml:"username_claim"`
+		} `yaml:"oidc"`
 	} `yaml:"auth"`
 
-	Telegram struct {
-		Enabled        bool   `yaml:"enabled"`
-		BotToken       string `yaml:"bot_token"`
-		ChatID         int64  `yaml:"chat_id"`
-		StartupMessage string `yaml:"startup_message"`
-	} `yaml:"telegram"`
+	// Telegram is the pre-"notifications" location, still read from old configs.
+	Telegram TelegramConfig `yaml:"telegram,omitempty"`
+
+	Notifications struct {
+		Telegram     TelegramConfig `yaml:"telegram"`
+		PublicTunnel bool           `yaml:"public_tunnel"` // cloudflared quick tunnel for every backend; Telegram always gets one
+
+		Discord struct {
+			Enabled        bool   `yaml:"enabled"`
+			WebhookURL     string `yaml:"webhook_url"`
+			Username       string `yaml:"username"`
+			StartupMessage string `yaml:"startup_message"`
+		} `yaml:"discord"`
+
+		Webhooks []WebhookConfig `yaml:"webhooks"`
+
+		Ntfy struct {
+			Enabled bool   `yaml:"enabled"`
+			Server  string `yaml:"server"` // defaults to https://ntfy.sh
+			Topic   string `yaml:"topic"`
+			Token   string `yaml:"token"` // optional access token
+		} `yaml:"ntfy"`
+
+		Pushover struct {
+			Enabled  bool   `yaml:"enabled"`
+			AppToken string `yaml:"app_token"`
+			UserKey  string `yaml:"user_key"`
+		} `yaml:"pushover"`
+
+		Gotify struct {
+			Enabled  bool   `yaml:"enabled"`
+			Server   string `yaml:"server"`
+			AppToken string `yaml:"app_token"`
+		} `yaml:"gotify"`
+
+		PagerDuty struct {
+			Enabled    bool   `yaml:"enabled"`
+			RoutingKey string `yaml:"routing_key"` // Events API v2 integration key
+		} `yaml:"pagerduty"`
+
+		Opsgenie struct {
+			Enabled bool   `yaml:"enabled"`
+			APIKey  string `yaml:"api_key"`
+			Region  string `yaml:"region"` // "us" (default) or "eu"
+		} `yaml:"opsgenie"`
+		Email struct {
+			Enabled  bool     `yaml:"enabled"`
+			Host     string   `yaml:"host"`
+			Port     int      `yaml:"port"` // 587 (STARTTLS) by default; 465 for implicit TLS
+			Username string   `yaml:"username"`
+			Password string   `yaml:"password"`
+			From     string   `yaml:"from"`
+			To       []string `yaml:"to"`
+		} `yaml:"email"`
+		WebPush struct {
+			Enabled bool   `yaml:"enabled"`
+			Subject string `yaml:"subject"` // contact for push services, "mailto:" or "https:" URL
+		} `yaml:"webpush"`
+	} `yaml:"notifications"`
+
+	Focus struct {
+		AllowToggle bool   `yaml:"allow_toggle"`
+		Method      string `yaml:"method"` // "shortcuts" or "defaults"
+		ShortcutOn  string `yaml:"shortcut_on"`
+		ShortcutOff string `yaml:"shortcut_off"`
+	} `yaml:"focus"`
+
+	Redaction struct {
+		Patterns        []string `yaml:"patterns"` // regexes; capture group 1 (if any) is kept
+		DisableDefaults bool     `yaml:"disable_defaults"`
+	} `yaml:"redaction"`
+
+	Screenshot struct {
+		Enabled bool `yaml:"enabled"`
+	} `yaml:"screenshot"`
+
+	ThreatIntel struct {
+		Enabled      bool     `yaml:"enabled"`
+		Files        []string `yaml:"files"` // one IP or CIDR per line
+		URLs         []string `yaml:"urls"`
+		RefreshHours int      `yaml:"refresh_hours"`
+	} `yaml:"threat_intel"`
+
+	SpeedTest struct {
+		DailyAt string `yaml:"daily_at"` // "HH:MM" local time; empty disables the schedule
+	} `yaml:"speedtest"`
+
+	DNSFlush struct {
+		Mode   string `yaml:"mode"`   // "osascript" (GUI prompt), "sudo" or "unprivileged"
+		Helper string `yaml:"helper"` // sudo mode: NOPASSWD command that flushes both caches
+	} `yaml:"dns_flush"`
+
+	Hardware struct {
+		ShowSerial bool `yaml:"show_serial"`
+	} `yaml:"hardware"`
+
+	Logging struct {
+		Level              string `yaml:"level"`  // "debug", "info", "warn", "error"
+		Format             string `yaml:"format"` // "text" (default) or "json"
+		File               string `yaml:"file"`   // append here instead of stderr; rotate with copytruncate
+		DebugRevertMinutes int    `yaml:"debug_revert_minutes"`
+
+		// Access logs every HTTP request with client IP, user, status and latency.
+		Access struct {
+			Enabled bool   `yaml:"enabled"`
+			Format  string `yaml:"format"` // "combined" (default) or "json"
+			File    string `yaml:"file"`   // default access.log in the data directory
+		} `yaml:"access"`
+	} `yaml:"logging"`
+
+	// Debug exposes pprof and runtime statistics under /api/debug/ to admins.
+	Debug struct {
+		Enabled bool `yaml:"enabled"`
+	} `yaml:"debug"`
+
+	// Collectors sets per-collector sampling intervals, e.g. processes: 2s,
+	// storage: 60s, bluetooth: 5m. Between runs the hub reuses the last
+	// value; unlisted collectors run on every tick.
+	Collectors map[string]string `yaml:"collectors"`
+
+	// Idle stops all collection once no dashboard is connected and no API
+	// poll has arrived for a while. History, alerts, exporters and the
+	// connection watchers pause with it; history records the pause as a gap.
+	Idle struct {
+		Enabled bool   `yaml:"enabled"`
+		After   string `yaml:"after"` // default 2m
+	} `yaml:"idle"`
+
+	Alerts struct {
+		Enabled         bool         `yaml:"enabled"`
+		IntervalSeconds int          `yaml:"interval_seconds"`
+		RepeatInterval  string       `yaml:"repeat_interval"` // e.g. "1h"; empty notifies once per firing
+		Builtin         bool         `yaml:"builtin"`         // disk >90%, battery <10%, new SSH sessions, kernel panics
+		MemoryLeaks     bool         `yaml:"memory_leaks"`    // processes whose memory grows steadily for hours
+		Rules           []AlertRule  `yaml:"rules"`
+		Routes          []AlertRoute `yaml:"routes"`
+
+		// Sessions reports logins as they happen, independently of the
+		// evaluation interval. alerts.builtin covers SSH sessions on its tick.
+		Sessions struct {
+			Enabled      bool `yaml:"enabled"`
+			IncludeLocal bool `yaml:"include_local"` // console and Terminal sessions too
+		} `yaml:"sessions"`
+
+		Battery struct {
+			Enabled         bool    `yaml:"enabled"`
+			MinHealth       float64 `yaml:"min_health"`        // percent of design capacity, default 80
+			CycleLevels     []int   `yaml:"cycle_levels"`      // default 500, 800, 1000
+			MaxChargingTemp float64 `yaml:"max_charging_temp"` // celsius, default 45
+		} `yaml:"battery"`
+	} `yaml:"alerts"`
+
+	History struct {
+		Disabled        bool     `yaml:"disabled"`
+		IntervalSeconds int      `yaml:"interval_seconds"`
+		Metrics         []string `yaml:"metrics"`        // flattened keys; empty uses the built-in set
+		ReplicaTokens   []string `yaml:"replica_tokens"` // bearer tokens for /api/history/export
+		CheckTokens     []string `yaml:"check_tokens"`   // bearer tokens for /api/check ("talaria check")
+		InMemory        bool     `yaml:"in_memory"`      // keep only the last 24h in memory; nothing is written to data/history
+
+		// Retention per tier, as durations or days ("30d"). Every tier is
+		// written continuously (average, min and max per bucket) and pruned
+		// hourly; a year of hourly rollups is a few MB.
+		Retention struct {
+			Raw    string `yaml:"raw"`    // every sample, default 24h
+			Minute string `yaml:"minute"` // 1-minute averages, default 30d
+			Hourly string `yaml:"hourly"` // hourly averages, default 365d
+		} `yaml:"retention"`
+
+		// Follow turns this instance into a read replica of another one.
+		Follow struct {
+			URL   string `yaml:"url"`
+			Token string `yaml:"token"`
+		} `yaml:"follow"`
+	} `yaml:"history"`
+
+	Reports struct {
+		Weekly struct {
+			Day     string `yaml:"day"` // defaults to monday
+			At      string `yaml:"at"`  // "HH:MM" local time; empty disables
+			SaveDir string `yaml:"save_dir"`
+			Email   bool   `yaml:"email"` // send through notifications.email
+		} `yaml:"weekly"`
+	} `yaml:"reports"`
+
+	// Anomaly learns hour-of-day baselines from history and raises "anomaly"
+	// events; add an alerts.routes entry with kind [anomaly] to be notified.
+	Anomaly struct {
+		Enabled    bool     `yaml:"enabled"`
+		Metrics    []string `yaml:"metrics"`     // must be recorded in history; empty uses CPU, network and disk IO
+		Sigma      float64  `yaml:"sigma"`       // standard deviations above the hour's mean, default 4
+		MinSamples int      `yaml:"min_samples"` // per hour-of-day before alerting, default 60
+	} `yaml:"anomaly"`
+
+	Digest struct {
+		DailyAt   string   `yaml:"daily_at"`  // "HH:MM" local time; empty disables
+		Notifiers []string `yaml:"notifiers"` // empty sends to every notifier that supports reports
+	} `yaml:"digest"`
+
+	ThermalBackoff struct {
+		Disabled bool `yaml:"disabled"`
+		Slowdown int  `yaml:"slowdown"` // refresh interval multiplier while hot
+	} `yaml:"thermal_backoff"`
+
+	ConnectionHistory struct {
+		Enabled         bool `yaml:"enabled"`
+		IntervalSeconds int  `yaml:"interval_seconds"`
+	} `yaml:"connection_history"`
+
+	// ListenWatch raises a "security" event when a process starts listening on
+	// a port that is not in the acknowledged baseline.
+	ListenWatch struct {
+		Enabled         bool `yaml:"enabled"`
+		IntervalSeconds int  `yaml:"interval_seconds"`
+	} `yaml:"listen_watch"`
+
+	// LANWatch raises a "device" event when an unseen MAC appears in the ARP cache.
+	LANWatch struct {
+		Enabled         bool `yaml:"enabled"`
+		IntervalSeconds int  `yaml:"interval_seconds"`
+	} `yaml:"lan_watch"`
+
+	Exporters struct {
+		// Influx pushes line protocol to an InfluxDB v1/v2 or VictoriaMetrics
+		// write endpoint, e.g. http://localhost:8086/api/v2/write?org=home&bucket=talaria.
+		Influx struct {
+			Enabled         bool     `yaml:"enabled"`
+			URL             string   `yaml:"url"`
+			Token           string   `yaml:"token"` // sent as "Authorization: Token ..."; v1 credentials go in the URL
+			IntervalSeconds int      `yaml:"interval_seconds"`
+			BatchSize       int      `yaml:"batch_size"` // lines per request, default 5000
+			Metrics         []string `yaml:"metrics"`    // key prefixes ("cpu", "memory.used"); empty exports everything
+		} `yaml:"influx"`
+
+		// OTLP posts gauges to an OpenTelemetry collector over OTLP/HTTP (JSON),
+		// e.g. http://collector:4318.
+		OTLP struct {
+			Enabled            bool              `yaml:"enabled"`
+			Endpoint           string            `yaml:"endpoint"` // "/v1/metrics" is appended if missing
+			Headers            map[string]string `yaml:"headers"`  // e.g. an API key for a hosted backend
+			ResourceAttributes map[string]string `yaml:"resource_attributes"`
+			IntervalSeconds    int               `yaml:"interval_seconds"`
+			Metrics            []string          `yaml:"metrics"`
+		} `yaml:"otlp"`
+
+		// Statsd emits gauges over UDP to StatsD (host:8125) or Graphite's
+		// plaintext listener (host:2003).
+		Statsd struct {
+			Enabled         bool     `yaml:"enabled"`
+			Address         string   `yaml:"address"`
+			Protocol        string   `yaml:"protocol"` // "statsd" (default) or "graphite"
+			Prefix          string   `yaml:"prefix"`   // default "talaria.<hostname>"
+			IntervalSeconds int      `yaml:"interval_seconds"`
+			Metrics         []string `yaml:"metrics"`
+		} `yaml:"statsd"`
+
+		// MQTT publishes CPU, memory, disk, battery, temperature and health
+		// score to <topic_prefix>/<host>/state, with Home Assistant discovery.
+		MQTT struct {
+			Enabled          bool   `yaml:"enabled"`
+			Broker           string `yaml:"broker"` // "tcp://host:1883" or "ssl://host:8883"
+			Username         string `yaml:"username"`
+			Password         string `yaml:"password"`
+			ClientID         string `yaml:"client_id"`        // default "talaria-<host>"
+			TopicPrefix      string `yaml:"topic_prefix"`     // default "talaria"
+			DiscoveryPrefix  string `yaml:"discovery_prefix"` // default "homeassistant"
+			DisableDiscovery bool   `yaml:"disable_discovery"`
+			IntervalSeconds  int    `yaml:"interval_seconds"`
+		} `yaml:"mqtt"`
+
+		// File appends one JSON object per tick to a local file for jq, DuckDB
+		// and the like. The live file is rotated to <name>-<start>.jsonl.
+		File struct {
+			Enabled         bool     `yaml:"enabled"`
+			Path            string   `yaml:"path"`        // default data/export/metrics.jsonl
+			Rotate          string   `yaml:"rotate"`      // "hourly", "daily" (default) or "none"
+			MaxSizeMB       int      `yaml:"max_size_mb"` // rotate early past this size, default 100
+			MaxFiles        int      `yaml:"max_files"`   // rotated files kept, default 14
+			Full            bool     `yaml:"full"`        // whole metrics object instead of flat keys
+			IntervalSeconds int      `yaml:"interval_seconds"`
+			Metrics         []string `yaml:"metrics"` // flat mode only
+		} `yaml:"file"`
+	} `yaml:"exporters"`
+
+	// Federation lets agents push their history to a central instance, which
+	// keeps it per host under data/fleet and serves it via /api/fleet and
+	// /api/history?host=.
+	Federation struct {
+		Tokens    []string `yaml:"tokens"`    // accepted from agents, as are tls.client_ca certificates with the admin role; setting any makes this instance a central
+		Retention string   `yaml:"retention"` // per-host history kept on the central, default 30d
+
+		Push struct {
+			URL             string   `yaml:"url"`   // central instance, e.g. https://central:8080
+			Token           string   `yaml:"token"` // optional with cert_file
+			Host            string   `yaml:"host"`  // default: short hostname
+			IntervalSeconds int      `yaml:"interval_seconds"`
+			Metrics         []string `yaml:"metrics"` // default: the history metric set
+			// Client certificate for a central that sets tls.client_ca; it must map
+			// to the admin role there.
+			CertFile string `yaml:"cert_file"`
+			KeyFile  string `yaml:"key_file"`
+		} `yaml:"push"`
+	} `yaml:"federation"`
+
+	// PushGateway accepts custom gauges at POST /api/push from scripts and
+	// other devices; they appear under "custom" in the payload, history and
+	// alert rules (e.g. custom.room_temp.room=office).
+	PushGateway struct {
+		Tokens []string `yaml:"tokens"`
+		TTL    string   `yaml:"ttl"` // drop gauges not updated for this long, default 10m
+	} `yaml:"push_gateway"`
+
+	Hooks struct {
+		Shutdown []HookConfig `yaml:"shutdown"`
+		Sleep    []HookConfig `yaml:"sleep"` // runs before system sleep; keep these well under 30s
+	} `yaml:"hooks"`
+
+	WebSocket struct {
+		Compression string `yaml:"compression"` // "auto" (tunnel/public clients only), "on" or "off"
+	} `yaml:"websocket"`
+
+	// RateLimit throttles the API per session, or per IP before login, with
+	// a token bucket for each route class: "default", "expensive"
+	// (connections, lookups, GraphQL, history, reports...), "write" (other
+	// non-GET requests) and "auth" (login). Classes override the defaults.
+	RateLimit struct {
+		Enabled bool                     `yaml:"enabled"`
+		Classes map[string]RateLimitRule `yaml:"classes"`
+	} `yaml:"rate_limit"`
+
+	// SecurityHeaders replaces the defaults, which suit the embedded
+	// dashboard; "off" drops a header. HSTS is sent over TLS only.
+	SecurityHeaders struct {
+		ContentSecurityPolicy string `yaml:"content_security_policy"`
+		PermissionsPolicy     string `yaml:"permissions_policy"`
+		HSTS                  string `yaml:"hsts"` // default "max-age=31536000"
+	} `yaml:"security_headers"`
+
+	// CORS lets pages on other origins call the API and open its
+	// WebSockets. Without allowed_origins browsers keep the same-origin
+	// policy. Session cookies are SameSite=Strict, so credentials only
+	// travel from origins on the same site. WebSockets need
+	// allow_credentials and a named origin; the terminal stays same-origin.
+	CORS struct {
+		AllowedOrigins   []string `yaml:"allowed_origins"`   // "https://ops.example.com", "https://*.example.com" or "*"
+		AllowedMethods   []string `yaml:"allowed_methods"`   // default GET, HEAD, POST, DELETE
+		AllowedHeaders   []string `yaml:"allowed_headers"`   // default Content-Type, X-CSRF-Token, Authorization
+		AllowCredentials bool     `yaml:"allow_credentials"` // cookies and client certificates; not with "*"
+		MaxAgeSeconds    int      `yaml:"max_age_seconds"`   // preflight cache, default 600
+	} `yaml:"cors"`
 }
 
-var GlobalConfig *Config
+type TelegramConfig struct {
+	Enabled        bool           `yaml:"enabled"`
+	BotToken       string         `yaml:"bot_token"`
+	ChatID         int64          `yaml:"chat_id"`
+	StartupMessage string         `yaml:"startup_message"`
+	Chats          []TelegramChat `yaml:"chats"` // replaces chat_id when set
+}
+
+type RateLimitRule struct {
+	PerMinute float64 `yaml:"per_minute"` // sustained rate
+	Burst     int     `yaml:"burst"`      // requests allowed at once
+}
+
+type TelegramChat struct {
+	ID    string   `yaml:"id"`    // numeric chat ID or "@channel"
+	Types []string `yaml:"types"` // "startup", "alerts", "reports"; empty receives everything
+}
+
+var (
+	GlobalConfig *Config
+	configPath   string
+)
+
+func dataPath(name string) string {
+	dir := GlobalConfig.Server.DataDir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(configPath), "data")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		slog.Error("Failed to create data dir", "dir", dir, "err", err)
+	}
+	return filepath.Join(dir, name)
+}
 
 func LoadConfig(path string) error {
+	configPath = path
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -107,10 +569,10 @@ func LoadConfig(path string) error {
 			defaultCfg.Server.Port = 8745
 			defaultCfg.Server.Theme = themeStr
 			defaultCfg.Auth.PasswordHash = hash
-			defaultCfg.Telegram.Enabled = tgEnabled
-			defaultCfg.Telegram.BotToken = tgToken
-			defaultCfg.Telegram.ChatID = tgChatID
-			defaultCfg.Telegram.StartupMessage = "[%s] Talaria is on Steroids 🔥"
+			defaultCfg.Notifications.Telegram.Enabled = tgEnabled
+			defaultCfg.Notifications.Telegram.BotToken = tgToken
+			defaultCfg.Notifications.Telegram.ChatID = tgChatID
+			defaultCfg.Notifications.Telegram.StartupMessage = "[%s] Talaria is on Steroids 🔥"
 
 			cfgData, _ := yaml.Marshal(defaultCfg)
 			os.WriteFile(path, cfgData, 0600)
@@ -129,6 +591,10 @@ func LoadConfig(path string) error {
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return err
 	}
+	if t := cfg.Notifications.Telegram; !t.Enabled && t.BotToken == "" && len(t.Chats) == 0 {
+		cfg.Notifications.Telegram = cfg.Telegram
+	}
+	cfg.Telegram = TelegramConfig{}
 
 	GlobalConfig = cfg
 	return nil
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"talaria/monitor"
+)
+
+const (
+	maxConnectionsPage  = 1000
+	maxEnrichedLookups  = 50 // distinct remote IPs resolved per request
+	enrichLookupTimeout = 5 * time.Second
+	enrichWorkers       = 8
+)
+
+// connectionsPage is /api/connections. Without query parameters it is every
+// active and listening socket, as the dashboard expects; the totals count
+// matches before offset and limit are applied.
+type connectionsPage struct {
+	monitor.ConnectionDetails
+	TotalActive    int `json:"total_active"`
+	TotalListening int `json:"total_listening"`
+	Offset         int `json:"offset"`
+	Limit          int `json:"limit,omitempty"`
+}
+
+// connectionFilter is parsed from the query string:
+//
+//	state    established, listen, or both comma-separated
+//	process  case-insensitive substring of the process name
+//	pid      exact process ID
+//	port     local or remote port
+//	offset, limit  page each list; limit is capped at maxConnectionsPage
+//	rdns=1   fill remote_host with the reverse DNS name
+//	geo=1    fill remote_country from whois
+type connectionFilter struct {
+	states        map[string]bool
+	process       string
+	pid           int
+	port          string
+	offset, limit int
+	rdns, geo     bool
+}
+
+func parseConnectionFilter(q url.Values) (connectionFilter, error) {
+	var f connectionFilter
+	if v := q.Get("state"); v != "" {
+		f.states = make(map[string]bool)
+		for _, s := range strings.Split(v, ",") {
+			s = strings.ToUpper(strings.TrimSpace(s))
+			if s != "ESTABLISHED" && s != "LISTEN" {
+				return f, errors.New("state must be established or listen")
+			}
+			f.states[s] = true
+		}
+	}
+	f.process = strings.ToLower(q.Get("process"))
+	var err error
+	if v := q.Get("pid"); v != "" {
+		if f.pid, err = strconv.Atoi(v); err != nil || f.pid <= 0 {
+			return f, errors.New("invalid pid")
+		}
+	}
+	if v := q.Get("port"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n <= 0 || n > 65535 {
+			return f, errors.New("invalid port")
+		}
+		f.port = v
+	}
+	if v := q.Get("offset"); v != "" {
+		if f.offset, err = strconv.Atoi(v); err != nil || f.offset < 0 {
+			return f, errors.New("invalid offset")
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.limit, err = strconv.Atoi(v); err != nil || f.limit <= 0 {
+			return f, errors.New("invalid limit")
+		}
+		f.limit = min(f.limit, maxConnectionsPage)
+	}
+	f.rdns = q.Get("rdns") == "1"
+	f.geo = q.Get("geo") == "1"
+	return f, nil
+}
+
+func (f connectionFilter) match(c monitor.ConnectionInfo) bool {
+	if f.states != nil && !f.states[c.State] {
+		return false
+	}
+	if f.pid != 0 && c.PID != f.pid {
+		return false
+	}
+	if f.process != "" && !strings.Contains(strings.ToLower(c.Process), f.process) {
+		return false
+	}
+	if f.port != "" && !strings.HasSuffix(c.Local, ":"+f.port) && !strings.HasSuffix(c.Remote, ":"+f.port) {
+		return false
+	}
+	return true
+}
+
+// apply filters and pages one list. The result is always a new slice, so
+// the connection cache behind the input is left alone.
+func (f connectionFilter) apply(list []monitor.ConnectionInfo) ([]monitor.ConnectionInfo, int) {
+	out := []monitor.ConnectionInfo{}
+	for _, c := range list {
+		if f.match(c) {
+			out = append(out, c)
+		}
+	}
+	// A stable order keeps pages from shifting between requests.
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Process != out[j].Process {
+			return out[i].Process < out[j].Process
+		}
+		if out[i].Local != out[j].Local {
+			return out[i].Local < out[j].Local
+		}
+		return out[i].Remote < out[j].Remote
+	})
+	total := len(out)
+	out = out[min(f.offset, total):]
+	if f.limit > 0 && len(out) > f.limit {
+		out = out[:f.limit]
+	}
+	return out, total
+}
+
+func handleConnections(w http.ResponseWriter, r *http.Request) {
+	f, err := parseConnectionFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// whois servers throttle aggressively; geo shares the lookup budget.
+	if f.geo && !allowDiagRun(getRealIP(r)) {
+		http.Error(w, errDiagRateLimited.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	d := annotateThreats(monitor.GetConnectionDetails())
+	page := connectionsPage{Offset: f.offset, Limit: f.limit}
+	page.Active, page.TotalActive = f.apply(d.Active)
+	page.Listening, page.TotalListening = f.apply(d.Listening)
+	if f.rdns || f.geo {
+		enrichConnections(r.Context(), page.Active, f.geo)
+	}
+
+	data, err := json.Marshal(page)
+	if err != nil {
+		slog.Error("Failed to encode connections", "err", err)
+		http.Error(w, "Failed to encode connections", http.StatusInternalServerError)
+		return
+	}
+	writeEncoded(w, r, data)
+}
+
+// enrichConnections fills remote_host, and remote_country when geo is set,
+// for public remote addresses. Answers come from the /api/lookup cache when
+// they can; the rest are resolved concurrently within enrichLookupTimeout,
+// and whatever has not finished by then is left blank.
+func enrichConnections(ctx context.Context, conns []monitor.ConnectionInfo, geo bool) {
+	ips := []string{}
+	seen := map[string]bool{}
+	for _, c := range conns {
+		addr, err := netip.ParseAddr(c.RemoteIP)
+		if err != nil || seen[c.RemoteIP] || !isPublicAddr(addr) {
+			continue
+		}
+		seen[c.RemoteIP] = true
+		ips = append(ips, c.RemoteIP)
+	}
+	if len(ips) > maxEnrichedLookups {
+		ips = ips[:maxEnrichedLookups]
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, enrichLookupTimeout)
+	defer cancel()
+	results := make(map[string]LookupResult, len(ips))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, enrichWorkers)
+	for _, ip := range ips {
+		if res, ok := cachedLookupResult(ip, geo); ok {
+			mu.Lock()
+			results[ip] = res
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			res := lookup(ctx, ip, geo)
+			if ctx.Err() == nil {
+				storeLookupResult(ip, res)
+			}
+			mu.Lock()
+			results[ip] = res
+			mu.Unlock()
+		}(ip)
+	}
+	wg.Wait()
+
+	for i := range conns {
+		res, ok := results[conns[i].RemoteIP]
+		if !ok {
+			continue
+		}
+		if len(res.PTR) > 0 {
+			conns[i].RemoteHost = strings.TrimSuffix(res.PTR[0], ".")
+		}
+		if geo && res.Whois != nil {
+			conns[i].RemoteCountry = strings.ToUpper(res.Whois.Summary["country"])
+		}
+	}
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
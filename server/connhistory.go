@@ -0,0 +1,268 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"os"
+	"sort"
+	"sync"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	defaultConnHistoryInterval = 15 * time.Second
+	connHistorySaveInterval    = 5 * time.Minute
+	maxConnHistoryEvents       = 2000
+	maxConnHistoryHosts        = 20000
+)
+
+// connHost is what we know about one process talking to one remote address.
+type connHost struct {
+	Remote    string `json:"remote"`
+	FirstSeen int64  `json:"first_seen"`
+	LastSeen  int64  `json:"last_seen"`
+	Opens     int    `json:"opens"` // times a connection appeared after none were open
+	Open      bool   `json:"open"`
+}
+
+type connHistoryEvent struct {
+	Time    int64  `json:"time"`
+	Process string `json:"process"`
+	PID     int    `json:"pid"`
+	Remote  string `json:"remote"`
+	Kind    string `json:"kind"` // "new_host", "opened", "closed"
+}
+
+var (
+	// process name → remote IP → host; PIDs change across restarts, names don't
+	connHosts        = make(map[string]map[string]*connHost)
+	connEvents       []connHistoryEvent
+	connHistoryMu    sync.Mutex
+	connHistoryDirty bool
+)
+
+func startConnectionHistory() {
+	cfg := GlobalConfig.ConnectionHistory
+	if !cfg.Enabled {
+		return
+	}
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultConnHistoryInterval
+	}
+	loadConnHistory()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		save := time.NewTicker(connHistorySaveInterval)
+		defer ticker.Stop()
+		defer save.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if !collectionIdle() {
+					sampleConnections(monitor.GetConnectionDetails(), time.Now())
+				}
+			case <-save.C:
+				saveConnHistory()
+			}
+		}
+	}()
+}
+
+func sampleConnections(d monitor.ConnectionDetails, now time.Time) {
+	current := make(map[string]map[string]int) // process → remote → pid
+	for _, c := range d.Active {
+		addr, err := netip.ParseAddr(c.RemoteIP)
+		if err != nil || addr.IsLoopback() {
+			continue
+		}
+		if current[c.Process] == nil {
+			current[c.Process] = make(map[string]int)
+		}
+		current[c.Process][addr.Unmap().String()] = c.PID
+	}
+
+	connHistoryMu.Lock()
+	defer connHistoryMu.Unlock()
+
+	ts := now.Unix()
+	record := func(proc string, pid int, remote, kind string) {
+		connEvents = append(connEvents, connHistoryEvent{Time: ts, Process: proc, PID: pid, Remote: remote, Kind: kind})
+		connHistoryDirty = true
+	}
+
+	for proc, remotes := range current {
+		hosts := connHosts[proc]
+		if hosts == nil {
+			hosts = make(map[string]*connHost)
+			connHosts[proc] = hosts
+		}
+		for remote, pid := range remotes {
+			h := hosts[remote]
+			switch {
+			case h == nil:
+				hosts[remote] = &connHost{Remote: remote, FirstSeen: ts, LastSeen: ts, Opens: 1, Open: true}
+				record(proc, pid, remote, "new_host")
+			case !h.Open:
+				h.Open, h.LastSeen = true, ts
+				h.Opens++
+				record(proc, pid, remote, "opened")
+			default:
+				h.LastSeen = ts
+			}
+		}
+	}
+
+	for proc, hosts := range connHosts {
+		for remote, h := range hosts {
+			if _, still := current[proc][remote]; h.Open && !still {
+				h.Open = false
+				record(proc, 0, remote, "closed")
+			}
+		}
+	}
+
+	if len(connEvents) > maxConnHistoryEvents {
+		connEvents = append(connEvents[:0:0], connEvents[len(connEvents)-maxConnHistoryEvents:]...)
+	}
+	pruneConnHosts()
+}
+
+// pruneConnHosts drops the least recently seen hosts once the table is full.
+func pruneConnHosts() {
+	total := 0
+	for _, hosts := range connHosts {
+		total += len(hosts)
+	}
+	if total <= maxConnHistoryHosts {
+		return
+	}
+	type ref struct {
+		proc, remote string
+		last         int64
+	}
+	refs := make([]ref, 0, total)
+	for proc, hosts := range connHosts {
+		for remote, h := range hosts {
+			refs = append(refs, ref{proc, remote, h.LastSeen})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].last < refs[j].last })
+	for _, r := range refs[:total-maxConnHistoryHosts] {
+		delete(connHosts[r.proc], r.remote)
+		if len(connHosts[r.proc]) == 0 {
+			delete(connHosts, r.proc)
+		}
+	}
+}
+
+type connHistoryFile struct {
+	Hosts  map[string]map[string]*connHost `json:"hosts"`
+	Events []connHistoryEvent              `json:"events"`
+}
+
+func loadConnHistory() {
+	data, err := os.ReadFile(dataPath("connhistory.json"))
+	if err != nil {
+		return
+	}
+	var f connHistoryFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		slog.Warn("Ignoring corrupt connection history", "err", err)
+		return
+	}
+	connHistoryMu.Lock()
+	if f.Hosts != nil {
+		connHosts = f.Hosts
+	}
+	// Nothing is known to be open after a restart.
+	for _, hosts := range connHosts {
+		for _, h := range hosts {
+			h.Open = false
+		}
+	}
+	connEvents = f.Events
+	connHistoryMu.Unlock()
+}
+
+func saveConnHistory() {
+	connHistoryMu.Lock()
+	if !connHistoryDirty {
+		connHistoryMu.Unlock()
+		return
+	}
+	data, err := json.Marshal(connHistoryFile{Hosts: connHosts, Events: connEvents})
+	connHistoryDirty = false
+	connHistoryMu.Unlock()
+	if err != nil {
+		return
+	}
+
+	path := dataPath("connhistory.json")
+	if err := os.WriteFile(path+".tmp", data, 0600); err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		slog.Error("Failed to save connection history", "err", err)
+	}
+}
+
+// handleConnectionHistory lists processes with their remote-host counts, or
+// with ?process= the hosts and open/close timeline of one process.
+func handleConnectionHistory(w http.ResponseWriter, r *http.Request) {
+	if !GlobalConfig.ConnectionHistory.Enabled {
+		http.Error(w, "Connection history is disabled", http.StatusNotFound)
+		return
+	}
+	proc := r.URL.Query().Get("process")
+
+	connHistoryMu.Lock()
+	defer connHistoryMu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	if proc == "" {
+		type summary struct {
+			Process     string `json:"process"`
+			Hosts       int    `json:"hosts"`
+			Open        int    `json:"open"`
+			LastNewHost int64  `json:"last_new_host"`
+		}
+		list := []summary{}
+		for name, hosts := range connHosts {
+			s := summary{Process: name, Hosts: len(hosts)}
+			for _, h := range hosts {
+				if h.Open {
+					s.Open++
+				}
+				if h.FirstSeen > s.LastNewHost {
+					s.LastNewHost = h.FirstSeen
+				}
+			}
+			list = append(list, s)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].LastNewHost > list[j].LastNewHost })
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	hosts := []connHost{}
+	for _, h := range connHosts[proc] {
+		hosts = append(hosts, *h)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].FirstSeen > hosts[j].FirstSeen })
+	events := []connHistoryEvent{}
+	for _, e := range connEvents {
+		if e.Process == proc {
+			events = append(events, e)
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"process": proc,
+		"hosts":   hosts,
+		"events":  events,
+	})
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	defaultCORSMethods = []string{"GET", "HEAD", "POST", "DELETE"}
+	defaultCORSHeaders = []string{"Content-Type", "X-CSRF-Token", "Authorization"}
+)
+
+const defaultCORSMaxAge = 600
+
+// corsOriginAllowed matches origin against cors.allowed_origins. A pattern
+// may hold one "*" for a run of subdomain labels, as in
+// "https://*.example.com".
+func corsOriginAllowed(origin string) bool {
+	return slices.ContainsFunc(GlobalConfig.CORS.AllowedOrigins, func(p string) bool {
+		return corsOriginMatches(p, origin)
+	})
+}
+
+func corsOriginMatches(pattern, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if pattern == "*" || strings.EqualFold(pattern, origin) {
+		return true
+	}
+	prefix, suffix, ok := strings.Cut(strings.ToLower(pattern), "*")
+	o := strings.ToLower(origin)
+	return ok && len(o) > len(prefix)+len(suffix) && strings.HasPrefix(o, prefix) && strings.HasSuffix(o, suffix) &&
+		!strings.ContainsAny(o[len(prefix):len(o)-len(suffix)], "/:")
+}
+
+// CORSMiddleware answers preflight requests and adds the CORS headers for
+// allowed origins. Preflights never reach AuthMiddleware, since browsers
+// send them without credentials. With no allowed origins it does nothing.
+func CORSMiddleware(next http.Handler) http.Handler {
+	cfg := GlobalConfig.CORS
+	if len(cfg.AllowedOrigins) == 0 {
+		return next
+	}
+	credentials := cfg.AllowCredentials
+	if credentials && slices.Contains(cfg.AllowedOrigins, "*") {
+		slog.Warn(`cors.allow_credentials is ignored with allowed_origins "*"`)
+		credentials = false
+	}
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	maxAge := cfg.MaxAgeSeconds
+	if maxAge <= 0 {
+		maxAge = defaultCORSMaxAge
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		if !corsOriginAllowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		if credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if !slices.ContainsFunc(methods, func(m string) bool {
+				return strings.EqualFold(m, r.Header.Get("Access-Control-Request-Method"))
+			}) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			h.Set("Access-Control-Allow-Methods", allowMethods)
+			h.Set("Access-Control-Allow-Headers", allowHeaders)
+			h.Set("Access-Control-Max-Age", strconv.Itoa(maxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkWSOrigin is the WebSocket origin check: the dashboard's own origin,
+// as gorilla allows by default, or one cors.allowed_origins names when
+// cors.allow_credentials is set. Browsers send cookies and client
+// certificates on cross-site handshakes, so "*" never counts here.
+func checkWSOrigin(r *http.Request) bool {
+	if sameOrigin(r) {
+		return true
+	}
+	if !GlobalConfig.CORS.AllowCredentials {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	return slices.ContainsFunc(GlobalConfig.CORS.AllowedOrigins, func(p string) bool {
+		return p != "*" && corsOriginMatches(p, origin)
+	})
+}
+
+// sameOrigin is gorilla's default check, used on its own for the terminal.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
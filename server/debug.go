@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/metrics"
+	"strings"
+	"time"
+)
+
+// Profiling endpoints for diagnosing Talaria itself. They exist only while
+// debug.enabled is set and, being under /api/debug/, only for admins:
+//
+//	/api/debug/pprof/          net/http/pprof (go tool pprof accepts the URLs)
+//	/api/debug/runtime         goroutines, heap and GC statistics as JSON
+
+var startedAt = time.Now()
+
+type runtimeStats struct {
+	GoVersion    string  `json:"go_version"`
+	GOOS         string  `json:"goos"`
+	GOARCH       string  `json:"goarch"`
+	GOMAXPROCS   int     `json:"gomaxprocs"`
+	NumCPU       int     `json:"num_cpu"`
+	Goroutines   int     `json:"goroutines"`
+	CgoCalls     int64   `json:"cgo_calls"`
+	UptimeSecs   int64   `json:"uptime_seconds"`
+	HeapAlloc    uint64  `json:"heap_alloc_bytes"`
+	HeapInuse    uint64  `json:"heap_inuse_bytes"`
+	HeapIdle     uint64  `json:"heap_idle_bytes"`
+	HeapReleased uint64  `json:"heap_released_bytes"`
+	HeapObjects  uint64  `json:"heap_objects"`
+	StackInuse   uint64  `json:"stack_inuse_bytes"`
+	Sys          uint64  `json:"sys_bytes"`
+	TotalAlloc   uint64  `json:"total_alloc_bytes"`
+	Mallocs      uint64  `json:"mallocs"`
+	Frees        uint64  `json:"frees"`
+	NextGC       uint64  `json:"next_gc_bytes"`
+	NumGC        uint32  `json:"num_gc"`
+	NumForcedGC  uint32  `json:"num_forced_gc"`
+	GCCPUPercent float64 `json:"gc_cpu_percent"`
+	PauseTotalMs float64 `json:"gc_pause_total_ms"`
+	LastGC       int64   `json:"last_gc,omitempty"`      // unix ms
+	RecentPauses []int64 `json:"recent_pauses_us"`       // newest first
+	GCPercent    int     `json:"gc_percent"`             // GOGC; 0 when off
+	MemoryLimit  int64   `json:"memory_limit,omitempty"` // GOMEMLIMIT in bytes
+}
+
+func handleDebug(w http.ResponseWriter, r *http.Request) {
+	if !GlobalConfig.Debug.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.Path == "/api/debug/runtime" {
+		handleRuntimeStats(w, r)
+		return
+	}
+	name, ok := strings.CutPrefix(r.URL.Path, "/api/debug/pprof/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch name {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		// Index serves both the listing and the named profiles, keyed on
+		// the path below /debug/pprof/.
+		r2 := new(http.Request)
+		*r2 = *r
+		u := *r.URL
+		u.Path = "/debug/pprof/" + name
+		u.RawPath = ""
+		r2.URL = &u
+		pprof.Index(w, r2)
+	}
+}
+
+func handleRuntimeStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	// runtime/metrics reads GOGC and GOMEMLIMIT without changing them.
+	settings := []metrics.Sample{{Name: "/gc/gogc:percent"}, {Name: "/gc/gomemlimit:bytes"}}
+	metrics.Read(settings)
+
+	s := runtimeStats{
+		GoVersion:    runtime.Version(),
+		GOOS:         runtime.GOOS,
+		GOARCH:       runtime.GOARCH,
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		NumCPU:       runtime.NumCPU(),
+		Goroutines:   runtime.NumGoroutine(),
+		CgoCalls:     runtime.NumCgoCall(),
+		UptimeSecs:   int64(time.Since(startedAt).Seconds()),
+		HeapAlloc:    ms.HeapAlloc,
+		HeapInuse:    ms.HeapInuse,
+		HeapIdle:     ms.HeapIdle,
+		HeapReleased: ms.HeapReleased,
+		HeapObjects:  ms.HeapObjects,
+		StackInuse:   ms.StackInuse,
+		Sys:          ms.Sys,
+		TotalAlloc:   ms.TotalAlloc,
+		Mallocs:      ms.Mallocs,
+		Frees:        ms.Frees,
+		NextGC:       ms.NextGC,
+		NumGC:        ms.NumGC,
+		NumForcedGC:  ms.NumForcedGC,
+		GCCPUPercent: ms.GCCPUFraction * 100,
+		PauseTotalMs: float64(ms.PauseTotalNs) / 1e6,
+		RecentPauses: []int64{},
+		GCPercent:    int(settings[0].Value.Uint64()),
+	}
+	if ms.LastGC > 0 {
+		s.LastGC = int64(ms.LastGC / 1e6)
+	}
+	// PauseNs is a ring buffer; the newest pause is at (NumGC+255)%256.
+	for i := uint32(0); i < min(ms.NumGC, 16); i++ {
+		s.RecentPauses = append(s.RecentPauses, int64(ms.PauseNs[(ms.NumGC-1-i)%256]/1e3))
+	}
+	if limit := settings[1].Value.Uint64(); limit < math.MaxInt64 {
+		s.MemoryLimit = int64(limit)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
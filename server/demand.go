@@ -0,0 +1,63 @@
+package server
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const defaultIdleAfter = 2 * time.Minute
+
+var (
+	lastDemand atomic.Int64 // unix ms of the last viewer activity
+	idling     atomic.Bool
+)
+
+func init() {
+	noteDemand() // startup counts, so schedules get a first sample
+}
+
+// noteDemand records that someone is looking: a connected dashboard (each
+// hub tick) or a poll of the metrics API.
+func noteDemand() {
+	lastDemand.Store(time.Now().UnixMilli())
+	if idling.Swap(false) {
+		slog.Info("Viewer activity, resuming collection")
+	}
+}
+
+func idleAfter() time.Duration {
+	if v := GlobalConfig.Idle.After; v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		slog.Warn("idle.after is not a duration", "after", v, "using", defaultIdleAfter)
+	}
+	return defaultIdleAfter
+}
+
+// collectionIdle reports whether idle.enabled is set and nobody has asked
+// for metrics within idle.after. Background loops skip their tick while it
+// holds, so no collector or helper subprocess runs.
+func collectionIdle() bool {
+	if !GlobalConfig.Idle.Enabled {
+		return false
+	}
+	after := idleAfter()
+	if time.Since(time.UnixMilli(lastDemand.Load())) < after {
+		return false
+	}
+	if !idling.Swap(true) {
+		slog.Info("No viewers, pausing collection", "idle", after)
+	}
+	return true
+}
+
+// backgroundMetrics is latestMetrics for loops on their own schedule: while
+// idle it returns nil instead of collecting.
+func backgroundMetrics() *AllMetrics {
+	if collectionIdle() {
+		return nil
+	}
+	return latestMetrics()
+}
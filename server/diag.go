@@ -0,0 +1,266 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	diagRunsPerMinute = 10
+	diagTimeout       = 60 * time.Second
+	maxPingCount      = 20
+)
+
+var (
+	diagHostRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.:-]{0,252}$`)
+
+	diagRuns   = make(map[string][]time.Time) // IP → recent run times
+	diagRunsMu sync.Mutex
+
+	diagUpgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 4096,
+		CheckOrigin:     checkWSOrigin,
+	}
+
+	errDiagRateLimited = errors.New("rate limit exceeded, try again in a minute")
+)
+
+type diagRequest struct {
+	Tool  string `json:"tool"` // "ping", "traceroute", "port"
+	Host  string `json:"host"`
+	Count int    `json:"count,omitempty"`
+	Port  int    `json:"port,omitempty"`
+}
+
+type diagMsg struct {
+	Type     string `json:"type"` // "line", "done", "error"
+	Data     string `json:"data,omitempty"`
+	ExitCode int    `json:"exit_code"`
+}
+
+func allowDiagRun(ip string) bool {
+	diagRunsMu.Lock()
+	defer diagRunsMu.Unlock()
+
+	cutoff := time.Now().Add(-time.Minute)
+	recent := diagRuns[ip][:0]
+	for _, t := range diagRuns[ip] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= diagRunsPerMinute {
+		diagRuns[ip] = recent
+		return false
+	}
+	diagRuns[ip] = append(recent, time.Now())
+	return true
+}
+
+func (req *diagRequest) auditParams() map[string]string {
+	params := map[string]string{"host": req.Host}
+	if req.Tool == "port" {
+		params["port"] = strconv.Itoa(req.Port)
+	}
+	return params
+}
+
+func (req *diagRequest) validate() error {
+	if !diagHostRegex.MatchString(req.Host) {
+		return errors.New("invalid host")
+	}
+	switch req.Tool {
+	case "ping":
+		if req.Count <= 0 {
+			req.Count = 4
+		}
+		if req.Count > maxPingCount {
+			req.Count = maxPingCount
+		}
+	case "traceroute":
+	case "port":
+		if req.Port < 1 || req.Port > 65535 {
+			return errors.New("invalid port")
+		}
+	default:
+		return errors.New("unknown tool")
+	}
+	return nil
+}
+
+func (req *diagRequest) command(ctx context.Context) *exec.Cmd {
+	if req.Tool == "traceroute" {
+		return exec.CommandContext(ctx, "traceroute", "-n", "-w", "2", "-q", "1", "-m", "30", req.Host)
+	}
+	return exec.CommandContext(ctx, "ping", "-n", "-c", strconv.Itoa(req.Count), req.Host)
+}
+
+func checkPort(host string, port int) map[string]interface{} {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	result := map[string]interface{}{
+		"host":       host,
+		"port":       port,
+		"open":       err == nil,
+		"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result["error"] = err.Error()
+	} else {
+		conn.Close()
+	}
+	return result
+}
+
+// runDiag executes req and feeds each output line to emit; it returns the exit code.
+func runDiag(ctx context.Context, req diagRequest, emit func(string)) (int, error) {
+	if req.Tool == "port" {
+		data, _ := json.Marshal(checkPort(req.Host, req.Port))
+		emit(string(data))
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagTimeout)
+	defer cancel()
+
+	cmd := req.command(ctx)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return -1, err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return -1, err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		emit(scanner.Text())
+	}
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return -1, err
+	}
+	return 0, nil
+}
+
+func handleDiag(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := diagRequest{Host: q.Get("host")}
+	switch r.URL.Path {
+	case "/api/diag/ping":
+		req.Tool = "ping"
+	case "/api/diag/traceroute":
+		req.Tool = "traceroute"
+	case "/api/diag/port":
+		req.Tool = "port"
+	}
+	req.Count, _ = strconv.Atoi(q.Get("count"))
+	req.Port, _ = strconv.Atoi(q.Get("port"))
+
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !allowDiagRun(getRealIP(r)) {
+		http.Error(w, errDiagRateLimited.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	auditLog(r, "diag."+req.Tool, req.auditParams(), "ok")
+	w.Header().Set("Content-Type", "application/json")
+	if req.Tool == "port" {
+		json.NewEncoder(w).Encode(checkPort(req.Host, req.Port))
+		return
+	}
+
+	start := time.Now()
+	var lines []string
+	code, err := runDiag(r.Context(), req, func(line string) { lines = append(lines, line) })
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to run %s: %v", req.Tool, err), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tool":        req.Tool,
+		"host":        req.Host,
+		"output":      lines,
+		"exit_code":   code,
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+}
+
+// ServeDiag streams a single diagnostic run: the client sends one diagRequest
+// and receives "line" messages followed by "done" (or "error").
+func ServeDiag(w http.ResponseWriter, r *http.Request) {
+	conn, err := diagUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("Diag WebSocket upgrade failed", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+
+	var req diagRequest
+	if err := conn.ReadJSON(&req); err != nil {
+		return
+	}
+
+	send := func(m diagMsg) error {
+		conn.SetWriteDeadline(time.Now().Add(writeWait))
+		return conn.WriteJSON(m)
+	}
+
+	if err := req.validate(); err != nil {
+		send(diagMsg{Type: "error", Data: err.Error()})
+		return
+	}
+	if !allowDiagRun(getRealIP(r)) {
+		send(diagMsg{Type: "error", Data: errDiagRateLimited.Error()})
+		return
+	}
+	auditLog(r, "diag."+req.Tool, req.auditParams(), "ok")
+
+	// Kill the command when the client goes away so a long traceroute stops early.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn.SetReadDeadline(time.Time{})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	code, err := runDiag(ctx, req, func(line string) {
+		send(diagMsg{Type: "line", Data: line})
+	})
+	if err != nil {
+		send(diagMsg{Type: "error", Data: err.Error()})
+		return
+	}
+	send(diagMsg{Type: "done", ExitCode: code})
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const digestWindow = 24 * time.Hour
+
+// reportNotifier is implemented by backends that can take a free-form
+// report; incident tools (PagerDuty, Opsgenie) deliberately don't.
+type reportNotifier interface {
+	Report(title, text string) error
+}
+
+type metricSummary struct {
+	Avg   float64 `json:"avg"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	First float64 `json:"first"`
+	Last  float64 `json:"last"`
+}
+
+type digest struct {
+	Host    string                   `json:"host"`
+	From    int64                    `json:"from"`
+	To      int64                    `json:"to"`
+	Uptime  string                   `json:"uptime"`
+	Samples int                      `json:"samples"`
+	Metrics map[string]metricSummary `json:"metrics"`
+	Alerts  []Event                  `json:"alerts"`
+}
+
+func summarize(samples []historySample, key string) (metricSummary, bool) {
+	s := metricSummary{Min: math.Inf(1), Max: math.Inf(-1)}
+	n := 0
+	for _, p := range samples {
+		v, ok := p.V[key]
+		if !ok {
+			continue
+		}
+		if n == 0 {
+			s.First = v
+		}
+		s.Last = v
+		s.Avg += v
+		s.Min = math.Min(s.Min, v)
+		s.Max = math.Max(s.Max, v)
+		n++
+	}
+	if n == 0 {
+		return metricSummary{}, false
+	}
+	s.Avg /= float64(n)
+	return s, true
+}
+
+func buildDigest(now time.Time) digest {
+	from := now.Add(-digestWindow)
+	samples := historyRange(from.UnixMilli(), now.UnixMilli())
+
+	d := digest{From: from.Unix(), To: now.Unix(), Samples: len(samples), Metrics: make(map[string]metricSummary)}
+	d.Host, _ = os.Hostname()
+	if m := latestMetrics(); m != nil {
+		d.Uptime = m.System.Uptime
+	}
+	for _, key := range []string{"cpu.usage_percent", "memory.used_percent", "storage_breakdown.used_gb", "health.health_score"} {
+		if s, ok := summarize(samples, key); ok {
+			d.Metrics[key] = s
+		}
+	}
+
+	d.Alerts = []Event{}
+	for _, e := range recentEvents(0) {
+		if e.Kind == "alert" && e.Fields["state"] == alertFiring && e.Time >= d.From {
+			d.Alerts = append(d.Alerts, e)
+		}
+	}
+	return d
+}
+
+func (d digest) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Uptime: %s\n", d.Uptime)
+	if s, ok := d.Metrics["cpu.usage_percent"]; ok {
+		fmt.Fprintf(&b, "CPU: avg %.1f%%, peak %.1f%%\n", s.Avg, s.Max)
+	}
+	if s, ok := d.Metrics["memory.used_percent"]; ok {
+		fmt.Fprintf(&b, "Memory: avg %.1f%%, peak %.1f%%\n", s.Avg, s.Max)
+	}
+	if s, ok := d.Metrics["storage_breakdown.used_gb"]; ok {
+		fmt.Fprintf(&b, "Disk: %+.1f GB (%.1f GB used)\n", s.Last-s.First, s.Last)
+	}
+	if s, ok := d.Metrics["health.health_score"]; ok {
+		fmt.Fprintf(&b, "Health score: %.0f → %.0f (low %.0f)\n", s.First, s.Last, s.Min)
+	}
+	if d.Samples == 0 {
+		b.WriteString("No history recorded in the last 24h\n")
+	}
+
+	if len(d.Alerts) == 0 {
+		b.WriteString("Alerts: none")
+	} else {
+		fmt.Fprintf(&b, "Alerts: %d", len(d.Alerts))
+		for i, e := range d.Alerts {
+			if i == 5 {
+				fmt.Fprintf(&b, "\n• … and %d more", len(d.Alerts)-5)
+				break
+			}
+			fmt.Fprintf(&b, "\n• %s %s", time.Unix(e.Time, 0).Format("15:04"), e.Title)
+		}
+	}
+	return b.String()
+}
+
+func sendDigest(d digest) error {
+	title := "Daily digest for " + d.Host
+	text := d.text()
+	want := GlobalConfig.Digest.Notifiers
+
+	sent := 0
+	for _, n := range enabledNotifiers() {
+		rn, ok := n.(reportNotifier)
+		if !ok {
+			continue
+		}
+		if len(want) > 0 && !slices.ContainsFunc(want, func(w string) bool { return w == n.Name() || strings.HasPrefix(n.Name(), w+" ") }) {
+			continue
+		}
+		if err := rn.Report(title, text); err != nil {
+			slog.Warn("Digest delivery failed", "notifier", n.Name(), "err", err)
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return fmt.Errorf("no notifier accepted the digest")
+	}
+	return nil
+}
+
+func startDigestSchedule() {
+	at := GlobalConfig.Digest.DailyAt
+	if at == "" {
+		return
+	}
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		slog.Warn("Daily digest disabled: invalid digest.daily_at", "daily_at", at, "err", err)
+		return
+	}
+
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 1)
+			}
+			time.Sleep(time.Until(next))
+
+			if err := sendDigest(buildDigest(time.Now())); err != nil {
+				slog.Warn("Daily digest failed", "err", err)
+			}
+		}
+	}()
+}
+
+// handleDigest previews the digest (GET) or sends it right away (POST).
+func handleDigest(w http.ResponseWriter, r *http.Request) {
+	d := buildDigest(time.Now())
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := sendDigest(d); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"digest": d,
+		"text":   d.text(),
+	})
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	discordColorCritical = 0xE74C3C
+	discordColorWarning  = 0xF1C40F
+	discordColorInfo     = 0x3498DB
+	discordColorResolved = 0x2ECC71
+)
+
+var notifyClient = &http.Client{Timeout: 10 * time.Second}
+
+type discordNotifier struct {
+	webhookURL     string
+	username       string
+	startupMessage string
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+func (d *discordNotifier) Name() string { return "discord" }
+
+func (d *discordNotifier) Startup(info startupInfo) error {
+	msg := d.startupMessage
+	if msg == "" {
+		msg = "Talaria is up and running."
+	}
+	embed := discordEmbed{
+		Title:       "Talaria started",
+		Description: msg,
+		URL:         info.PublicURL,
+		Color:       discordColorInfo,
+		Fields:      []discordField{{Name: "Local", Value: info.LocalURL, Inline: true}},
+		Timestamp:   info.Time.UTC().Format(time.RFC3339),
+		Footer:      &discordFooter{Text: info.Hostname},
+	}
+	if info.PublicURL != "" {
+		embed.Fields = append(embed.Fields, discordField{Name: "Public", Value: info.PublicURL, Inline: true})
+	}
+	return d.post(embed)
+}
+
+func (d *discordNotifier) Notify(e Event) error {
+	color := discordColorInfo
+	switch {
+	case e.Fields["state"] == "resolved":
+		color = discordColorResolved
+	case e.Severity == SeverityCritical:
+		color = discordColorCritical
+	case e.Severity == SeverityWarning:
+		color = discordColorWarning
+	}
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]discordField, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, discordField{Name: k, Value: e.Fields[k], Inline: true})
+	}
+
+	hostname, _ := os.Hostname()
+	return d.post(discordEmbed{
+		Title:       fmt.Sprintf("[%s] %s", e.Severity, e.Title),
+		Description: e.Message,
+		Color:       color,
+		Fields:      fields,
+		Timestamp:   time.Unix(e.Time, 0).UTC().Format(time.RFC3339),
+		Footer:      &discordFooter{Text: hostname},
+	})
+}
+
+func (d *discordNotifier) post(embed discordEmbed) error {
+	username := d.username
+	if username == "" {
+		username = "Talaria"
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"username": username,
+		"embeds":   []discordEmbed{embed},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := notifyClient.Post(d.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("discord webhook error: %s", resp.Status)
+	}
+	return nil
+}
+
+func (d *discordNotifier) Report(title, text string) error {
+	return d.post(discordEmbed{
+		Title:       title,
+		Description: text,
+		Color:       discordColorInfo,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	})
+}
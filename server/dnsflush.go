@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	flushDNSMu       sync.Mutex
+	lastFlushDNSTime time.Time
+)
+
+type dnsFlushStep struct {
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	Output  string `json:"output,omitempty"`
+}
+
+type dnsFlushStatus struct {
+	OK      bool           `json:"ok"`
+	Mode    string         `json:"mode"`
+	Message string         `json:"message"`
+	Steps   []dnsFlushStep `json:"steps"`
+}
+
+func handleFlushDNS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flushDNSMu.Lock()
+	if time.Since(lastFlushDNSTime) < 30*time.Second {
+		flushDNSMu.Unlock()
+		http.Error(w, "Rate limit exceeded. Please wait 30 seconds.", http.StatusTooManyRequests)
+		return
+	}
+	lastFlushDNSTime = time.Now()
+	flushDNSMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	status, code := flushDNS(ctx)
+	result := "ok"
+	if !status.OK {
+		result = "failed: " + status.Message
+	}
+	auditLog(r, "dns.flush", map[string]string{"mode": status.Mode}, result)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+	if status.OK {
+		slog.Info("DNS cache flushed", "mode", status.Mode)
+	}
+}
+
+func flushDNS(ctx context.Context) (dnsFlushStatus, int) {
+	mode := GlobalConfig.DNSFlush.Mode
+	if mode == "" {
+		mode = "osascript"
+	}
+	// Root needs neither a dialog nor sudo.
+	if os.Getuid() == 0 {
+		mode = "root"
+	}
+	status := dnsFlushStatus{Mode: mode}
+
+	switch mode {
+	case "osascript":
+		script := `do shell script "dscacheutil -flushcache; killall -HUP mDNSResponder" with administrator privileges`
+		step := runFlushStep(ctx, "osascript", "-e", script)
+		status.Steps = append(status.Steps, step)
+		if !step.OK {
+			if strings.Contains(step.Output, "User canceled") || step.Output == "" {
+				status.Message = "User cancelled authentication"
+				return status, http.StatusUnauthorized
+			}
+			status.Message = "Failed to flush DNS: " + step.Output
+			return status, http.StatusInternalServerError
+		}
+
+	case "sudo":
+		if helper := GlobalConfig.DNSFlush.Helper; helper != "" {
+			status.Steps = append(status.Steps, runFlushStep(ctx, "sudo", "-n", helper))
+		} else {
+			status.Steps = append(status.Steps,
+				runFlushStep(ctx, "sudo", "-n", "/usr/bin/dscacheutil", "-flushcache"),
+				runFlushStep(ctx, "sudo", "-n", "/usr/bin/killall", "-HUP", "mDNSResponder"))
+		}
+
+	case "root":
+		status.Steps = append(status.Steps,
+			runFlushStep(ctx, "/usr/bin/dscacheutil", "-flushcache"),
+			runFlushStep(ctx, "/usr/bin/killall", "-HUP", "mDNSResponder"))
+
+	case "unprivileged":
+		// Without privileges only the Directory Services cache can be cleared;
+		// mDNSResponder keeps its own cache until it is signalled.
+		status.Steps = append(status.Steps, runFlushStep(ctx, "/usr/bin/dscacheutil", "-flushcache"))
+
+	default:
+		status.Message = "Unknown dns_flush.mode " + mode
+		return status, http.StatusInternalServerError
+	}
+
+	ok := 0
+	for _, s := range status.Steps {
+		if s.OK {
+			ok++
+		}
+	}
+	switch {
+	case ok == len(status.Steps):
+		status.OK = true
+		status.Message = "DNS cache flushed"
+		if mode == "unprivileged" {
+			status.Message = "Directory cache flushed (mDNSResponder not signalled without privileges)"
+		}
+		return status, http.StatusOK
+	case ok > 0:
+		status.Message = "DNS cache partially flushed"
+	default:
+		status.Message = "Failed to flush DNS"
+	}
+	return status, http.StatusInternalServerError
+}
+
+func runFlushStep(ctx context.Context, name string, args ...string) dnsFlushStep {
+	step := dnsFlushStep{Command: name + " " + strings.Join(args, " ")}
+	if name == "osascript" {
+		step.Command = "osascript (administrator prompt)"
+	}
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	step.Output = strings.TrimSpace(string(out))
+	step.OK = err == nil
+	if err != nil && step.Output == "" && name != "osascript" {
+		step.Output = err.Error()
+	}
+	return step
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type emailNotifier struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func newEmailNotifier() *emailNotifier {
+	cfg := GlobalConfig.Notifications.Email
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	from := cfg.From
+	if from == "" {
+		from = cfg.Username
+	}
+	return &emailNotifier{host: cfg.Host, port: port, username: cfg.Username, password: cfg.Password, from: from, to: cfg.To}
+}
+
+func (n *emailNotifier) Name() string { return "email" }
+
+func (n *emailNotifier) Startup(startupInfo) error { return nil }
+
+func (n *emailNotifier) Notify(e Event) error {
+	hostname, _ := os.Hostname()
+	body := e.Message + "\n\nHost: " + hostname + "\nTime: " + time.Unix(e.Time, 0).Format(time.RFC1123)
+	return n.send(fmt.Sprintf("[%s] %s", e.Severity, e.Title), "text/plain", body)
+}
+
+func (n *emailNotifier) Report(title, text string) error {
+	return n.send(title, "text/plain", text)
+}
+
+// send delivers one message to every recipient. Port 465 uses implicit TLS;
+// anything else upgrades with STARTTLS when the server offers it.
+func (n *emailNotifier) send(subject, contentType, body string) error {
+	if n.host == "" || len(n.to) == 0 {
+		return errors.New("email: host and to are required")
+	}
+
+	var msg bytes.Buffer
+	hostname, _ := os.Hostname()
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s.%s@%s>\r\n", strconv.FormatInt(time.Now().UnixNano(), 36), generateToken(6), hostname)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&msg)
+	qp.Write([]byte(body))
+	qp.Close()
+
+	addr := net.JoinHostPort(n.host, strconv.Itoa(n.port))
+	tlsConfig := &tls.Config{ServerName: n.host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	if n.port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	c, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && n.port != 465 {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if n.username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted link.
+		if err := c.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.from); err != nil {
+		return err
+	}
+	for _, rcpt := range n.to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
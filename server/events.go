@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+
+	maxEvents = 200
+)
+
+type Event struct {
+	ID       uint64            `json:"id"`
+	Time     int64             `json:"time"`
+	Kind     string            `json:"kind"` // "security", "alert", "system", ...
+	Severity string            `json:"severity"`
+	Title    string            `json:"title"`
+	Message  string            `json:"message"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+var (
+	events      []Event
+	eventSeq    uint64
+	eventsMu    sync.Mutex
+	eventSinks  []func(Event)
+	eventSinkMu sync.RWMutex
+)
+
+func subscribeEvents(fn func(Event)) {
+	eventSinkMu.Lock()
+	eventSinks = append(eventSinks, fn)
+	eventSinkMu.Unlock()
+}
+
+func RaiseEvent(e Event) {
+	if e.Time == 0 {
+		e.Time = time.Now().Unix()
+	}
+	if e.Severity == "" {
+		e.Severity = SeverityInfo
+	}
+
+	eventsMu.Lock()
+	eventSeq++
+	e.ID = eventSeq
+	events = append(events, e)
+	if len(events) > maxEvents {
+		events = events[len(events)-maxEvents:]
+	}
+	eventsMu.Unlock()
+
+	level := slog.LevelInfo
+	switch e.Severity {
+	case SeverityWarning:
+		level = slog.LevelWarn
+	case SeverityCritical:
+		level = slog.LevelError
+	}
+	slog.Log(context.Background(), level, e.Title, "kind", e.Kind, "event_id", e.ID, "message", e.Message)
+
+	eventSinkMu.RLock()
+	sinks := eventSinks
+	eventSinkMu.RUnlock()
+	for _, sink := range sinks {
+		go func(sink func(Event)) {
+			defer func() {
+				if r := recover(); r != nil {
+					recordPanic("event sink", r)
+				}
+			}()
+			sink(e)
+		}(sink)
+	}
+}
+
+func recentEvents(since uint64) []Event {
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+
+	out := []Event{}
+	for _, e := range events {
+		if e.ID > since {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recentEvents(since))
+}
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	defaultFederationInterval = 10 * time.Second
+	maxFederationPending      = 8640 // a day of 10s samples while the central is down
+	federationBatch           = 500
+	defaultFleetRetention     = 30 * 24 * time.Hour
+)
+
+var fleetHostRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// federationPush is the body an agent POSTs to /api/federation/push.
+type federationPush struct {
+	Host    string          `json:"host"`
+	HostID  string          `json:"host_id"`
+	Model   string          `json:"model,omitempty"`
+	Samples []historySample `json:"samples"`
+}
+
+// startFederationPush sends this instance's samples to a central Talaria.
+// Unsent samples are kept (up to maxFederationPending) and go out in order
+// once the central is reachable again.
+func startFederationPush() {
+	cfg := GlobalConfig.Federation.Push
+	if cfg.URL == "" {
+		return
+	}
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultFederationInterval
+	}
+	host := cfg.Host
+	if host == "" {
+		host, _ = os.Hostname()
+		host = strings.Split(host, ".")[0]
+	}
+	if !fleetHostRegex.MatchString(host) {
+		slog.Warn("Federation push disabled: host name must be letters, digits, '.', '_' or '-'", "host", host)
+		return
+	}
+	keys := cfg.Metrics
+	if len(keys) == 0 {
+		keys = historyKeys()
+	}
+	url := strings.TrimSuffix(cfg.URL, "/") + "/api/federation/push"
+	client := notifyClient
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			slog.Warn("Federation push disabled: client certificate", "err", err)
+			return
+		}
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.TLSClientConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		client = &http.Client{Timeout: notifyClient.Timeout, Transport: tr}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var pending []historySample
+		failing := false
+		for range ticker.C {
+			m := backgroundMetrics()
+			if m == nil {
+				continue
+			}
+			s := historySample{T: m.Timestamp, V: make(map[string]float64)}
+			for k, v := range flattenMetrics(m) {
+				if metricSelected(k, keys) {
+					s.V[k] = v
+				}
+			}
+			pending = append(pending, s)
+			if len(pending) > maxFederationPending {
+				pending = append(pending[:0:0], pending[len(pending)-maxFederationPending:]...)
+			}
+
+			id := monitor.GetHostIdentity()
+			for len(pending) > 0 {
+				n := min(len(pending), federationBatch)
+				err := pushFederation(client, url, cfg.Token, federationPush{Host: host, HostID: id.HostID, Model: id.ModelName, Samples: pending[:n]})
+				if err != nil {
+					if !failing {
+						slog.Warn("Federation push failing, buffering", "url", cfg.URL, "err", err)
+					}
+					failing = true
+					break
+				}
+				if failing {
+					slog.Info("Federation push recovered", "url", cfg.URL)
+					failing = false
+				}
+				pending = pending[n:]
+			}
+		}
+	}()
+}
+
+func pushFederation(client *http.Client, url, token string, body federationPush) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// fleetHost is one agent as seen by the central instance. Its samples live in
+// their own history store under data/fleet/<host>.
+type fleetHost struct {
+	Host     string             `json:"host"`
+	HostID   string             `json:"host_id"`
+	Model    string             `json:"model,omitempty"`
+	LastSeen int64              `json:"last_seen"` // unix ms of the newest sample
+	Latest   map[string]float64 `json:"latest"`
+
+	store *historyStore
+}
+
+var (
+	fleet   = make(map[string]*fleetHost)
+	fleetMu sync.Mutex
+)
+
+// fleetHostFor returns the entry for host, opening its store on first use.
+// Callers hold fleetMu.
+func fleetHostFor(host string) (*fleetHost, error) {
+	if h := fleet[host]; h != nil {
+		return h, nil
+	}
+	store, err := openHistoryStore(filepath.Join(dataPath("fleet"), host), 0)
+	if err != nil {
+		return nil, err
+	}
+	h := &fleetHost{Host: host, store: store}
+	fleet[host] = h
+	return h, nil
+}
+
+// startFleet reopens the stores of hosts that pushed before a restart and
+// prunes their segments past federation.retention.
+func startFleet() {
+	if len(GlobalConfig.Federation.Tokens) == 0 && GlobalConfig.TLS.ClientCA == "" {
+		return
+	}
+	dirs, _ := os.ReadDir(dataPath("fleet"))
+	fleetMu.Lock()
+	for _, d := range dirs {
+		if d.IsDir() && fleetHostRegex.MatchString(d.Name()) {
+			if h, err := fleetHostFor(d.Name()); err == nil {
+				h.store.mu.Lock()
+				h.LastSeen = h.store.last
+				h.store.mu.Unlock()
+			}
+		}
+	}
+	fleetMu.Unlock()
+
+	retention := defaultFleetRetention
+	if v := GlobalConfig.Federation.Retention; v != "" {
+		d, err := parseRetention(v)
+		if err != nil {
+			slog.Warn("Invalid federation.retention", "err", err, "using", retention)
+		} else {
+			retention = d
+		}
+	}
+	go func() {
+		for {
+			pruneFleet(time.Now().Add(-retention))
+			time.Sleep(historyCompactEvery)
+		}
+	}()
+}
+
+func pruneFleet(cutoff time.Time) {
+	fleetMu.Lock()
+	stores := make([]*historyStore, 0, len(fleet))
+	for _, h := range fleet {
+		stores = append(stores, h.store)
+	}
+	fleetMu.Unlock()
+	for _, s := range stores {
+		for _, day := range s.segments() {
+			start, _ := time.Parse(historySegmentLayout, day)
+			if start.Add(24 * time.Hour).After(cutoff) {
+				break
+			}
+			if err := s.remove(day); err != nil {
+				slog.Warn("Fleet retention failed", "err", err)
+			}
+		}
+	}
+}
+
+// fleetRange returns a federated host's samples with from <= T <= to.
+func fleetRange(host string, from, to int64) []historySample {
+	fleetMu.Lock()
+	h := fleet[host]
+	fleetMu.Unlock()
+	if h == nil {
+		return nil
+	}
+	return h.store.read(from, to)
+}
+
+// federationCertOK accepts an agent's client certificate when it maps to the
+// admin role, since a push can write history for any host name.
+func federationCertOK(r *http.Request) bool {
+	s := clientCertSession(r)
+	return s != nil && s.role == roleAdmin
+}
+
+// handleFederationPush receives samples from agents. It authenticates with
+// federation.tokens or an admin client certificate rather than a session,
+// like the history export.
+func handleFederationPush(w http.ResponseWriter, r *http.Request) {
+	if !bearerTokenIn(r, GlobalConfig.Federation.Tokens) && !federationCertOK(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="talaria"`)
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body federationPush
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<20)).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !fleetHostRegex.MatchString(body.Host) {
+		http.Error(w, "Invalid host", http.StatusBadRequest)
+		return
+	}
+	sort.Slice(body.Samples, func(i, j int) bool { return body.Samples[i].T < body.Samples[j].T })
+
+	fleetMu.Lock()
+	defer fleetMu.Unlock()
+	h, err := fleetHostFor(body.Host)
+	if err != nil {
+		slog.Error("Fleet store failed", "host", body.Host, "err", err)
+		http.Error(w, "Failed to store samples", http.StatusInternalServerError)
+		return
+	}
+	if h.HostID != "" && body.HostID != "" && h.HostID != body.HostID {
+		slog.Warn("Fleet host changed hardware ID", "host", body.Host, "old", h.HostID, "new", body.HostID)
+	}
+	h.HostID, h.Model = body.HostID, body.Model
+	for _, s := range body.Samples {
+		if err := h.store.append(s); err != nil {
+			http.Error(w, "Failed to store samples", http.StatusInternalServerError)
+			return
+		}
+		if s.T >= h.LastSeen {
+			h.LastSeen, h.Latest = s.T, s.V
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleFleet lists federated hosts with their newest values. Their history is
+// served by /api/history?host=<host>.
+func handleFleet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fleetHosts())
+}
+
+// fleetHosts returns a copy of the federated hosts, sorted by name.
+func fleetHosts() []fleetHost {
+	fleetMu.Lock()
+	out := make([]fleetHost, 0, len(fleet))
+	for _, h := range fleet {
+		out = append(out, *h)
+	}
+	fleetMu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+	return out
+}
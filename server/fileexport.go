@@ -0,0 +1,174 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFileExportInterval = 10 * time.Second
+	defaultFileExportMaxSize  = 100 << 20
+	defaultFileExportMaxFiles = 14
+	fileExportStampLayout     = "20060102-1504"
+)
+
+// jsonlFile is the live export file plus what is needed to decide when to
+// rotate it.
+type jsonlFile struct {
+	path     string
+	period   time.Duration // 0 rotates on size only
+	maxSize  int64
+	maxFiles int
+
+	f       *os.File
+	size    int64
+	started time.Time
+}
+
+// startFileExport appends one JSON line per tick to exporters.file.path. In
+// the default flat mode each line is {"t": <unix ms>, "host": ..., "<key>":
+// <value>, ...}, which DuckDB's read_json_auto and jq take as-is.
+func startFileExport() {
+	cfg := GlobalConfig.Exporters.File
+	if !cfg.Enabled {
+		return
+	}
+	var period time.Duration
+	switch cfg.Rotate {
+	case "", "daily":
+		period = 24 * time.Hour
+	case "hourly":
+		period = time.Hour
+	case "none":
+	default:
+		slog.Warn("File exporter disabled: exporters.file.rotate is not hourly, daily or none", "rotate", cfg.Rotate)
+		return
+	}
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultFileExportInterval
+	}
+	out := &jsonlFile{
+		path:     cfg.Path,
+		period:   period,
+		maxSize:  int64(cfg.MaxSizeMB) << 20,
+		maxFiles: cfg.MaxFiles,
+	}
+	if out.path == "" {
+		out.path = filepath.Join(dataPath("export"), "metrics.jsonl")
+	}
+	if out.maxSize <= 0 {
+		out.maxSize = defaultFileExportMaxSize
+	}
+	if out.maxFiles <= 0 {
+		out.maxFiles = defaultFileExportMaxFiles
+	}
+	host, _ := os.Hostname()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		failing := false
+		for range ticker.C {
+			m := backgroundMetrics()
+			if m == nil {
+				continue
+			}
+			var line []byte
+			var err error
+			if cfg.Full {
+				line, err = json.Marshal(m)
+			} else {
+				row := map[string]interface{}{"t": m.Timestamp, "host": host}
+				for k, v := range flattenMetrics(m) {
+					if metricSelected(k, cfg.Metrics) {
+						row[k] = v
+					}
+				}
+				line, err = json.Marshal(row)
+			}
+			if err == nil {
+				err = out.write(append(line, '\n'), time.Now())
+			}
+			switch {
+			case err != nil && !failing:
+				slog.Warn("File export failing", "path", out.path, "err", err)
+			case err == nil && failing:
+				slog.Info("File export recovered", "path", out.path)
+			}
+			failing = err != nil
+		}
+	}()
+}
+
+func (j *jsonlFile) write(line []byte, now time.Time) error {
+	if j.f != nil && (j.size+int64(len(line)) > j.maxSize ||
+		j.period > 0 && now.Truncate(j.period) != j.started.Truncate(j.period)) {
+		if err := j.rotate(); err != nil {
+			return err
+		}
+	}
+	if j.f == nil {
+		if err := j.open(now); err != nil {
+			return err
+		}
+	}
+	n, err := j.f.Write(line)
+	j.size += int64(n)
+	return err
+}
+
+// open appends to an existing live file, so a restart continues it instead
+// of rotating early. Its age is taken from the modification time.
+func (j *jsonlFile) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	j.f, j.size, j.started = f, fi.Size(), now
+	if fi.Size() > 0 {
+		j.started = fi.ModTime()
+	}
+	return nil
+}
+
+// rotate renames the live file to <name>-<start>.jsonl and drops the oldest
+// rotated files beyond maxFiles.
+func (j *jsonlFile) rotate() error {
+	j.f.Close()
+	j.f = nil
+	ext := filepath.Ext(j.path)
+	base := strings.TrimSuffix(j.path, ext)
+	dst := base + "-" + j.started.Format(fileExportStampLayout) + ext
+	for i := 1; ; i++ {
+		if _, err := os.Stat(dst); os.IsNotExist(err) {
+			break
+		}
+		dst = base + "-" + j.started.Format(fileExportStampLayout) + "." + strconv.Itoa(i) + ext
+	}
+	if err := os.Rename(j.path, dst); err != nil {
+		return err
+	}
+
+	rotated, _ := filepath.Glob(base + "-*" + ext)
+	sort.Strings(rotated)
+	for len(rotated) > j.maxFiles {
+		os.Remove(rotated[0])
+		rotated = rotated[1:]
+	}
+	return nil
+}
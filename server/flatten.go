@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// flattenMetrics maps every numeric leaf of m to a dotted key such as
+// "cpu.usage_percent" or "disks.0.used_percent". Booleans become 0/1;
+// strings and the process list are skipped.
+func flattenMetrics(m *AllMetrics) map[string]float64 {
+	out := make(map[string]float64)
+	if m == nil {
+		return out
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return out
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return out
+	}
+	delete(tree, "processes")
+	flattenInto(out, "", tree)
+	return out
+}
+
+func flattenInto(out map[string]float64, prefix string, v interface{}) {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			flattenInto(out, join(k), child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flattenInto(out, join(strconv.Itoa(i)), child)
+		}
+	case float64:
+		out[prefix] = v
+	case bool:
+		if v {
+			out[prefix] = 1
+		} else {
+			out[prefix] = 0
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	defaultFocusShortcutOn  = "Talaria Focus On"
+	defaultFocusShortcutOff = "Talaria Focus Off"
+)
+
+func handleFocus(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(monitor.GetFocus())
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !GlobalConfig.Focus.AllowToggle {
+		http.Error(w, "Focus toggling is disabled in config", http.StatusForbidden)
+		return
+	}
+
+	enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+	if err != nil {
+		http.Error(w, "Invalid enabled value", http.StatusBadRequest)
+		return
+	}
+
+	err = setDoNotDisturb(enabled)
+	auditLog(r, "focus", map[string]string{"enabled": strconv.FormatBool(enabled)}, auditResult(err))
+	if err != nil {
+		slog.Warn("Focus toggle failed", "err", err)
+		http.Error(w, fmt.Sprintf("Failed to toggle Focus: %v", err), http.StatusInternalServerError)
+		return
+	}
+	monitor.InvalidateFocus()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(monitor.GetFocus())
+}
+
+func setDoNotDisturb(enabled bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cfg := GlobalConfig.Focus
+	if cfg.Method == "defaults" {
+		val := "false"
+		if enabled {
+			val = "true"
+		}
+		if out, err := exec.CommandContext(ctx, "defaults", "-currentHost", "write",
+			"com.apple.notificationcenterui", "doNotDisturb", "-boolean", val).CombinedOutput(); err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+		}
+		exec.CommandContext(ctx, "killall", "NotificationCenter").Run()
+		return nil
+	}
+
+	shortcut := cfg.ShortcutOff
+	if shortcut == "" {
+		shortcut = defaultFocusShortcutOff
+	}
+	if enabled {
+		shortcut = cfg.ShortcutOn
+		if shortcut == "" {
+			shortcut = defaultFocusShortcutOn
+		}
+	}
+	if out, err := exec.CommandContext(ctx, "shortcuts", "run", shortcut).CombinedOutput(); err != nil {
+		return fmt.Errorf("shortcut %q: %v: %s", shortcut, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
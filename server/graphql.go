@@ -0,0 +1,641 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// /api/graphql answers GraphQL queries over the same data as the REST
+// endpoints, so a dashboard can fetch exactly the fields it draws, and the
+// history behind them, in one round trip:
+//
+//	{
+//	  metrics { timestamp cpu { usage_percent } memory { used_percent } }
+//	  load: history(metric: "cpu.usage_percent", from: 1760000000000, step: "5m") {
+//	    series { metric points }
+//	  }
+//	}
+//
+// Field names are the JSON keys of the REST payloads and are checked against
+// the Go types behind them. A field selected without a sub-selection returns
+// its whole subtree, and every list field takes a limit argument. Only the
+// metrics sections that are selected get collected. Introspection is not
+// supported; /api/schema and /api/openapi.json describe the types.
+
+type gqlRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+type gqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+type gqlResponse struct {
+	Data   *gqlObject `json:"data,omitempty"`
+	Errors []gqlError `json:"errors,omitempty"`
+}
+
+// gqlResult documents the response shape in /api/openapi.json.
+type gqlResult struct {
+	Data   map[string]interface{} `json:"data,omitempty"`
+	Errors []gqlError             `json:"errors,omitempty"`
+}
+
+// gqlObject keeps the keys of a result in selection order.
+type gqlObject []gqlEntry
+
+type gqlEntry struct {
+	key   string
+	value interface{}
+}
+
+func (o gqlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(e.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		val, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// gqlHistory is the result of the history field.
+type gqlHistory struct {
+	Metric string        `json:"metric"`
+	From   int64         `json:"from"`
+	To     int64         `json:"to"`
+	Step   int64         `json:"step"`
+	Agg    string        `json:"agg"`
+	Series []querySeries `json:"series"`
+	Gaps   []historyGap  `json:"gaps"`
+}
+
+// gqlField is a root query field. resolve gets the field's arguments with
+// variables substituted and its sub-selection, already expanded.
+type gqlField struct {
+	typ     reflect.Type
+	args    []string
+	resolve func(args map[string]interface{}, sel []gqlSelection) (interface{}, error)
+}
+
+var gqlRoots = map[string]gqlField{
+	"metrics": {
+		typ:     reflect.TypeOf(AllMetrics{}),
+		resolve: gqlMetrics,
+	},
+	"history": {
+		typ:     reflect.TypeOf(gqlHistory{}),
+		args:    []string{"metric", "from", "to", "step", "agg", "host"},
+		resolve: gqlHistoryRange,
+	},
+	"events": {
+		typ:  reflect.TypeOf([]Event{}),
+		args: []string{"since"},
+		resolve: func(args map[string]interface{}, _ []gqlSelection) (interface{}, error) {
+			since, err := gqlInt(args, "since")
+			if since < 0 {
+				err = errors.New("since must not be negative")
+			}
+			return recentEvents(uint64(since)), err
+		},
+	},
+	"boots": {
+		typ: reflect.TypeOf([]bootSummary{}),
+		resolve: func(map[string]interface{}, []gqlSelection) (interface{}, error) {
+			return bootSummaries(), nil
+		},
+	},
+	"fleet": {
+		typ: reflect.TypeOf([]fleetHost{}),
+		resolve: func(map[string]interface{}, []gqlSelection) (interface{}, error) {
+			return fleetHosts(), nil
+		},
+	},
+	"schemaVersion": {
+		typ: reflect.TypeOf(""),
+		resolve: func(map[string]interface{}, []gqlSelection) (interface{}, error) {
+			return metricsSchemaVersion, nil
+		},
+	},
+}
+
+// gqlMetrics collects the selected sections, or serves the hub's snapshot
+// when it is fresh.
+func gqlMetrics(_ map[string]interface{}, sel []gqlSelection) (interface{}, error) {
+	noteDemand()
+	data := freshHTTPMetrics()
+	if data == nil && sel != nil {
+		topics := make(map[string]bool)
+		for _, f := range sel {
+			if t := sectionTopic(f.name); knownTopic(t) {
+				topics[t] = true
+			}
+		}
+		data = filteredMetrics(topics)
+	} else if data == nil {
+		data = getCachedHTTPMetrics()
+	}
+	if data == nil {
+		return nil, errors.New("failed to collect metrics")
+	}
+	return json.RawMessage(data), nil
+}
+
+// gqlHistoryRange takes the same parameters as /api/history; step is ms or
+// a duration string like "5m".
+func gqlHistoryRange(args map[string]interface{}, _ []gqlSelection) (interface{}, error) {
+	q := historyQuery{}
+	var err error
+	if q.Metric, err = gqlString(args, "metric"); err != nil {
+		return nil, err
+	}
+	if q.Host, err = gqlString(args, "host"); err != nil {
+		return nil, err
+	}
+	if q.Agg, err = gqlString(args, "agg"); err != nil {
+		return nil, err
+	}
+	if q.From, err = gqlInt(args, "from"); err != nil {
+		return nil, err
+	}
+	if q.From == 0 {
+		q.From = time.Now().Add(-24 * time.Hour).UnixMilli()
+	}
+	if q.To, err = gqlInt(args, "to"); err != nil {
+		return nil, err
+	}
+	if s, ok := args["step"].(string); ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid step %q", s)
+		}
+		q.Step = d.Milliseconds()
+	} else if q.Step, err = gqlInt(args, "step"); err != nil {
+		return nil, err
+	}
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+	return gqlHistory{
+		Metric: q.Metric, From: q.From, To: q.To, Step: q.Step, Agg: q.Agg,
+		Series: q.run(), Gaps: q.gaps(),
+	}, nil
+}
+
+func gqlInt(args map[string]interface{}, name string) (int64, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return v, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("argument %s must be an integer", name)
+}
+
+func gqlString(args map[string]interface{}, name string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("argument %s must be a string", name)
+}
+
+func handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req gqlRequest
+	switch r.Method {
+	case http.MethodGet:
+		qs := r.URL.Query()
+		req.Query = qs.Get("query")
+		req.OperationName = qs.Get("operationName")
+		if v := qs.Get("variables"); v != "" {
+			if err := gqlDecode(strings.NewReader(v), &req.Variables); err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, "invalid variables: "+err.Error())
+				return
+			}
+		}
+	case http.MethodPost:
+		body := http.MaxBytesReader(w, r.Body, 256<<10)
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+			data, err := io.ReadAll(body)
+			if err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, "invalid request body")
+				return
+			}
+			req.Query = string(data)
+		} else if err := gqlDecode(body, &req); err != nil {
+			writeGraphQLError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp, err := executeGraphQL(req)
+	if err != nil {
+		writeGraphQLError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	writeEncoded(w, r, data)
+}
+
+// gqlDecode keeps numbers as json.Number so integer variables stay exact.
+func gqlDecode(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func writeGraphQLError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(gqlResponse{Errors: []gqlError{{Message: msg}}})
+}
+
+// gqlExec carries the state of one query: its fragments, variables and the
+// field errors gathered so far.
+type gqlExec struct {
+	fragments map[string]*gqlFragment
+	variables map[string]interface{}
+	errors    []gqlError
+}
+
+// executeGraphQL returns an error for documents that cannot run at all;
+// errors in individual root fields null that field and are reported in the
+// response alongside the rest of the data.
+func executeGraphQL(req gqlRequest) (*gqlResponse, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, errors.New("query is required")
+	}
+	doc, err := parseGraphQL(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	var op *gqlOperation
+	for _, o := range doc.operations {
+		if req.OperationName == "" || o.name == req.OperationName {
+			if op != nil {
+				return nil, errors.New("operationName is required when the document has several operations")
+			}
+			op = o
+		}
+	}
+	if op == nil {
+		return nil, fmt.Errorf("no operation named %q", req.OperationName)
+	}
+
+	ex := &gqlExec{fragments: doc.fragments, variables: make(map[string]interface{})}
+	for name, def := range op.variables {
+		ex.variables[name] = def
+		if v, ok := req.Variables[name]; ok {
+			ex.variables[name] = v
+		}
+	}
+	fields, err := ex.collect(op.selection, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data := gqlObject{}
+	for _, f := range fields {
+		root, ok := gqlRoots[f.name]
+		if f.name == "__typename" {
+			data = append(data, gqlEntry{f.key, "Query"})
+			continue
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q on Query", f.name)
+		}
+		data = append(data, gqlEntry{f.key, ex.resolveRoot(f, root)})
+	}
+	return &gqlResponse{Data: &data, Errors: ex.errors}, nil
+}
+
+func (ex *gqlExec) resolveRoot(f gqlCollected, root gqlField) interface{} {
+	fail := func(err error) interface{} {
+		ex.errors = append(ex.errors, gqlError{Message: err.Error(), Path: []interface{}{f.key}})
+		return nil
+	}
+	args, err := ex.arguments(f.args, append(root.args, "limit"))
+	if err != nil {
+		return fail(err)
+	}
+	sub, err := ex.collect(f.selection, nil)
+	if err != nil {
+		return fail(err)
+	}
+	var names []gqlSelection
+	for _, s := range sub {
+		names = append(names, gqlSelection{name: s.name})
+	}
+	if f.selection == nil {
+		names = nil
+	}
+	v, err := root.resolve(args, names)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Results are projected over their JSON form, so they carry exactly the
+	// keys and encodings the REST endpoints use.
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fail(err)
+	}
+	var tree interface{}
+	if err := gqlDecode(bytes.NewReader(data), &tree); err != nil {
+		return fail(err)
+	}
+	out, err := ex.project(tree, root.typ, f.selection, args)
+	if err != nil {
+		return fail(err)
+	}
+	return out
+}
+
+// gqlCollected is a field after fragments and directives are applied. Fields
+// sharing a response key are merged, their sub-selections concatenated.
+type gqlCollected struct {
+	key, name string
+	args      map[string]gqlValue
+	selection []gqlSelection
+}
+
+// collect walks at most gqlMaxCollected selections, so fragments spread
+// over and over cannot blow a small document up exponentially.
+func (ex *gqlExec) collect(sel []gqlSelection, visited map[string]bool) ([]gqlCollected, error) {
+	var out []gqlCollected
+	index := map[string]int{}
+	budget := gqlMaxCollected
+	var walk func(sel []gqlSelection) error
+	walk = func(sel []gqlSelection) error {
+		for _, s := range sel {
+			if budget--; budget < 0 {
+				return fmt.Errorf("query selects more than %d fields", gqlMaxCollected)
+			}
+			ok, err := ex.included(s.directives)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			switch {
+			case s.spread != "":
+				frag := ex.fragments[s.spread]
+				if frag == nil {
+					return fmt.Errorf("unknown fragment %q", s.spread)
+				}
+				if visited[s.spread] {
+					return fmt.Errorf("fragment %q spreads itself", s.spread)
+				}
+				if visited == nil {
+					visited = map[string]bool{}
+				}
+				visited[s.spread] = true
+				err := walk(frag.selection)
+				delete(visited, s.spread)
+				if err != nil {
+					return err
+				}
+			case s.inline:
+				if err := walk(s.selection); err != nil {
+					return err
+				}
+			default:
+				key := s.alias
+				if key == "" {
+					key = s.name
+				}
+				if i, ok := index[key]; ok {
+					if out[i].name != s.name {
+						return fmt.Errorf("fields %q and %q both use the response key %q", out[i].name, s.name, key)
+					}
+					out[i].selection = append(out[i].selection, s.selection...)
+					continue
+				}
+				index[key] = len(out)
+				out = append(out, gqlCollected{key: key, name: s.name, args: s.args, selection: s.selection})
+			}
+		}
+		return nil
+	}
+	return out, walk(sel)
+}
+
+func (ex *gqlExec) included(directives []gqlDirective) (bool, error) {
+	for _, d := range directives {
+		if d.name != "include" && d.name != "skip" {
+			return false, fmt.Errorf("unknown directive @%s", d.name)
+		}
+		v, err := ex.value(d.args["if"])
+		if err != nil {
+			return false, err
+		}
+		cond, ok := v.(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s needs a boolean if argument", d.name)
+		}
+		if cond == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// arguments substitutes variables and rejects names not in allowed.
+func (ex *gqlExec) arguments(args map[string]gqlValue, allowed []string) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(args))
+	for name, v := range args {
+		known := false
+		for _, a := range allowed {
+			known = known || a == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown argument %q", name)
+		}
+		val, err := ex.value(v)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = val
+	}
+	return out, nil
+}
+
+func (ex *gqlExec) value(v gqlValue) (interface{}, error) {
+	switch v := v.(type) {
+	case gqlVariable:
+		val, ok := ex.variables[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", v)
+		}
+		return val, nil
+	case []gqlValue:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			var err error
+			if out[i], err = ex.value(e); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case map[string]gqlValue:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			var err error
+			if out[k], err = ex.value(e); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// project trims a JSON tree to the selection, checking field names against
+// the Go type t the tree was encoded from. args are the field's own
+// arguments; limit truncates a list.
+func (ex *gqlExec) project(v interface{}, t reflect.Type, sel []gqlSelection, args map[string]interface{}) (interface{}, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if list, ok := v.([]interface{}); ok {
+		if args["limit"] != nil {
+			n, err := gqlInt(args, "limit")
+			if err != nil || n < 0 {
+				return nil, errors.New("limit must be a non-negative integer")
+			}
+			list = list[:min(int(n), len(list))]
+		}
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		out := make([]interface{}, len(list))
+		for i, e := range list {
+			var err error
+			if out[i], err = ex.project(e, elem, sel, nil); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	if args["limit"] != nil {
+		return nil, errors.New("limit only applies to lists")
+	}
+	if sel == nil {
+		return v, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		if v == nil {
+			return nil, nil
+		}
+		return nil, errors.New("cannot select fields of a scalar")
+	}
+
+	fields, err := ex.collect(sel, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := make(gqlObject, 0, len(fields))
+	for _, f := range fields {
+		if f.name == "__typename" {
+			out = append(out, gqlEntry{f.key, gqlTypeName(t)})
+			continue
+		}
+		ft, err := gqlFieldType(t, f.name)
+		if err != nil {
+			return nil, err
+		}
+		fargs, err := ex.arguments(f.args, []string{"limit"})
+		if err != nil {
+			return nil, err
+		}
+		val, err := ex.project(obj[f.name], ft, f.selection, fargs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		out = append(out, gqlEntry{f.key, val})
+	}
+	return out, nil
+}
+
+// gqlFieldType returns the type of the JSON key name in t. Maps and
+// interfaces accept any key; their values are not type checked.
+func gqlFieldType(t reflect.Type, name string) (reflect.Type, error) {
+	if t == nil || t.Kind() == reflect.Interface {
+		return nil, nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem(), nil
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				if ft, err := gqlFieldType(f.Type, name); err == nil {
+					return ft, nil
+				}
+				continue
+			}
+			tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if tag == "" {
+				tag = f.Name
+			}
+			if tag == name {
+				return f.Type, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown field %q on %s", name, gqlTypeName(t))
+}
+
+func gqlTypeName(t reflect.Type) string {
+	if t != nil && t.Kind() == reflect.Struct && t.Name() != "" {
+		return t.Name()
+	}
+	return "Object"
+}
+
+// gqlQueryParams documents the GET form in /api/openapi.json.
+var gqlQueryParams = []apiParam{
+	{name: "query", desc: "GraphQL document", required: true},
+	{name: "variables", desc: "JSON object of variable values"},
+	{name: "operationName", desc: "Operation to run when the document has several"},
+}
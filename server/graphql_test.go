@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGraphQL(t *testing.T) {
+	doc, err := parseGraphQL(`
+		query Q($n: Int = 5) {
+			m: metrics { cpu { usage_percent } ...Mem }
+			history(limit: $n) @include(if: true) { t }
+			... on Query { __typename }
+		}
+		fragment Mem on Metrics { memory { used_percent } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.operations) != 1 || doc.operations[0].name != "Q" {
+		t.Fatalf("operations = %+v", doc.operations)
+	}
+	op := doc.operations[0]
+	if op.variables["n"] != int64(5) {
+		t.Errorf("default for $n = %#v", op.variables["n"])
+	}
+	sel := op.selection
+	if len(sel) != 3 {
+		t.Fatalf("got %d selections", len(sel))
+	}
+	if sel[0].alias != "m" || sel[0].name != "metrics" || sel[0].selection[1].spread != "Mem" {
+		t.Errorf("aliased field = %+v", sel[0])
+	}
+	if sel[1].args["limit"] != gqlVariable("n") || len(sel[1].directives) != 1 {
+		t.Errorf("history field = %+v", sel[1])
+	}
+	if !sel[2].inline {
+		t.Errorf("inline fragment = %+v", sel[2])
+	}
+	if doc.fragments["Mem"] == nil {
+		t.Error("fragment Mem missing")
+	}
+}
+
+func TestParseGraphQLDepth(t *testing.T) {
+	deep := strings.Repeat("a {", gqlMaxDepth+1) + "b" + strings.Repeat("}", gqlMaxDepth+1)
+	if _, err := parseGraphQL("{" + deep + "}"); err == nil {
+		t.Error("deeply nested selection accepted")
+	}
+	list := strings.Repeat("[", gqlMaxDepth+1) + strings.Repeat("]", gqlMaxDepth+1)
+	if _, err := parseGraphQL("{ a(x: " + list + ") }"); err == nil {
+		t.Error("deeply nested list accepted")
+	}
+	ok := strings.Repeat("a {", gqlMaxDepth-1) + "b" + strings.Repeat("}", gqlMaxDepth-1)
+	if _, err := parseGraphQL("{" + ok + "}"); err != nil {
+		t.Errorf("nesting within the limit rejected: %v", err)
+	}
+}
+
+func TestExecuteGraphQLTypename(t *testing.T) {
+	resp, err := executeGraphQL(gqlRequest{Query: `{ kind: __typename skipped: __typename @skip(if: true) }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*resp.Data) != 1 || (*resp.Data)[0] != (gqlEntry{"kind", "Query"}) {
+		t.Errorf("data = %+v", *resp.Data)
+	}
+}
+
+func TestExecuteGraphQLFragmentCycle(t *testing.T) {
+	_, err := executeGraphQL(gqlRequest{Query: `
+		{ ...A }
+		fragment A on Query { ...B }
+		fragment B on Query { ...A }`})
+	if err == nil || !strings.Contains(err.Error(), "spreads itself") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestExecuteGraphQLFragmentFanOut(t *testing.T) {
+	// Each fragment spreads the next twice: 2^30 walks without a budget.
+	var b strings.Builder
+	b.WriteString("{ ...F0 }\n")
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&b, "fragment F%d on Query { ...F%d ...F%d }\n", i, i+1, i+1)
+	}
+	b.WriteString("fragment F30 on Query { __typename }\n")
+
+	start := time.Now()
+	_, err := executeGraphQL(gqlRequest{Query: b.String()})
+	if err == nil || !strings.Contains(err.Error(), "more than") {
+		t.Errorf("err = %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("rejecting took %v", d)
+	}
+}
@@ -0,0 +1,503 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This is the subset of GraphQL that /api/graphql needs: query operations
+// with variables, aliases, arguments, fragments, inline fragments and the
+// @include/@skip directives. Mutations, subscriptions and introspection are
+// not supported.
+
+const (
+	gqlMaxDepth     = 32    // nested selection sets, lists and objects
+	gqlMaxCollected = 10000 // selections one collect may walk, fragments expanded
+)
+
+type gqlToken struct {
+	kind string // "name", "int", "float", "string", "punct", "eof"
+	text string
+	pos  int
+}
+
+type gqlLexer struct {
+	src  string
+	pos  int
+	peek *gqlToken
+}
+
+func (l *gqlLexer) next() (gqlToken, error) {
+	if l.peek != nil {
+		t := *l.peek
+		l.peek = nil
+		return t, nil
+	}
+	return l.scan()
+}
+
+func (l *gqlLexer) lookahead() (gqlToken, error) {
+	if l.peek == nil {
+		t, err := l.scan()
+		if err != nil {
+			return t, err
+		}
+		l.peek = &t
+	}
+	return *l.peek, nil
+}
+
+func (l *gqlLexer) scan() (gqlToken, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return l.token()
+		}
+	}
+	return gqlToken{kind: "eof", pos: l.pos}, nil
+}
+
+func (l *gqlLexer) token() (gqlToken, error) {
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return gqlToken{kind: "punct", text: "...", pos: start}, nil
+	case strings.IndexByte("!$()=:@[]{}", c) >= 0:
+		l.pos++
+		return gqlToken{kind: "punct", text: string(c), pos: start}, nil
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for l.pos < len(l.src) && isGQLNameChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return gqlToken{kind: "name", text: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || c >= '0' && c <= '9':
+		l.pos++
+		kind := "int"
+		for l.pos < len(l.src) {
+			d := l.src[l.pos]
+			if d == '.' || d == 'e' || d == 'E' || (d == '+' || d == '-') && kind == "float" {
+				kind = "float"
+			} else if d < '0' || d > '9' {
+				break
+			}
+			l.pos++
+		}
+		return gqlToken{kind: kind, text: l.src[start:l.pos], pos: start}, nil
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			end := strings.Index(l.src[l.pos+3:], `"""`)
+			if end < 0 {
+				return gqlToken{}, fmt.Errorf("unterminated block string at %d", start)
+			}
+			l.pos += end + 6
+			return gqlToken{kind: "string", text: strings.TrimSpace(l.src[start+3 : l.pos-3]), pos: start}, nil
+		}
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return gqlToken{}, fmt.Errorf("unterminated string at %d", start)
+		}
+		l.pos++
+		s, err := strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			return gqlToken{}, fmt.Errorf("invalid string at %d", start)
+		}
+		return gqlToken{kind: "string", text: s, pos: start}, nil
+	}
+	return gqlToken{}, fmt.Errorf("unexpected character %q at %d", c, start)
+}
+
+func isGQLNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+type gqlDocument struct {
+	operations []*gqlOperation
+	fragments  map[string]*gqlFragment
+}
+
+type gqlOperation struct {
+	name      string
+	variables map[string]gqlValue // defaults
+	selection []gqlSelection
+}
+
+type gqlFragment struct {
+	selection []gqlSelection
+}
+
+// gqlSelection is a field, a fragment spread (spread set) or an inline
+// fragment (inline set).
+type gqlSelection struct {
+	alias, name string
+	args        map[string]gqlValue
+	directives  []gqlDirective
+	selection   []gqlSelection
+	spread      string
+	inline      bool
+}
+
+type gqlDirective struct {
+	name string
+	args map[string]gqlValue
+}
+
+// gqlValue is a literal (Go string, int64, float64, bool, nil, []gqlValue,
+// map[string]gqlValue) or a gqlVariable.
+type gqlValue interface{}
+
+type gqlVariable string
+
+type gqlParser struct {
+	lex   *gqlLexer
+	depth int
+}
+
+func (p *gqlParser) nest() error {
+	if p.depth++; p.depth > gqlMaxDepth {
+		return fmt.Errorf("document nested deeper than %d", gqlMaxDepth)
+	}
+	return nil
+}
+
+func parseGraphQL(src string) (*gqlDocument, error) {
+	p := &gqlParser{lex: &gqlLexer{src: src}}
+	doc := &gqlDocument{fragments: make(map[string]*gqlFragment)}
+	for {
+		t, err := p.lex.lookahead()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.kind == "eof":
+			if len(doc.operations) == 0 {
+				return nil, fmt.Errorf("no operation in document")
+			}
+			return doc, nil
+		case t.kind == "punct" && t.text == "{":
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &gqlOperation{selection: sel})
+		case t.kind == "name" && t.text == "query":
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case t.kind == "name" && t.text == "fragment":
+			name, frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = frag
+		case t.kind == "name" && (t.text == "mutation" || t.text == "subscription"):
+			return nil, fmt.Errorf("%s operations are not supported", t.text)
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+		}
+	}
+}
+
+func (p *gqlParser) expect(text string) error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	if t.kind != "punct" || t.text != text {
+		return fmt.Errorf("expected %q at %d, found %q", text, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *gqlParser) name() (string, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return "", err
+	}
+	if t.kind != "name" {
+		return "", fmt.Errorf("expected a name at %d, found %q", t.pos, t.text)
+	}
+	return t.text, nil
+}
+
+func (p *gqlParser) at(text string) bool {
+	t, err := p.lex.lookahead()
+	return err == nil && t.kind == "punct" && t.text == text
+}
+
+func (p *gqlParser) operation() (*gqlOperation, error) {
+	p.lex.next() // "query"
+	op := &gqlOperation{variables: make(map[string]gqlValue)}
+	if t, err := p.lex.lookahead(); err == nil && t.kind == "name" {
+		op.name = t.text
+		p.lex.next()
+	}
+	if p.at("(") {
+		p.lex.next()
+		for !p.at(")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if err := p.skipType(); err != nil {
+				return nil, err
+			}
+			op.variables[name] = nil
+			if p.at("=") {
+				p.lex.next()
+				if op.variables[name], err = p.value(true); err != nil {
+					return nil, err
+				}
+			}
+		}
+		p.lex.next()
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sel, err := p.selectionSet()
+	op.selection = sel
+	return op, err
+}
+
+// skipType consumes a variable type; values are checked where they are used.
+func (p *gqlParser) skipType() error {
+	if p.at("[") {
+		p.lex.next()
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.at("!") {
+		p.lex.next()
+	}
+	return nil
+}
+
+func (p *gqlParser) fragment() (string, *gqlFragment, error) {
+	p.lex.next() // "fragment"
+	name, err := p.name()
+	if err != nil {
+		return "", nil, err
+	}
+	if on, err := p.name(); err != nil || on != "on" {
+		return "", nil, fmt.Errorf("expected \"on\" after fragment %s", name)
+	}
+	if _, err := p.name(); err != nil {
+		return "", nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return "", nil, err
+	}
+	sel, err := p.selectionSet()
+	return name, &gqlFragment{selection: sel}, err
+}
+
+func (p *gqlParser) selectionSet() ([]gqlSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.nest(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+	var out []gqlSelection
+	for !p.at("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sel)
+	}
+	p.lex.next()
+	if len(out) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+	return out, nil
+}
+
+func (p *gqlParser) selection() (gqlSelection, error) {
+	var sel gqlSelection
+	var err error
+	if p.at("...") {
+		p.lex.next()
+		t, err := p.lex.lookahead()
+		if err != nil {
+			return sel, err
+		}
+		if t.kind == "name" && t.text != "on" {
+			p.lex.next()
+			sel.spread = t.text
+			sel.directives, err = p.directives()
+			return sel, err
+		}
+		sel.inline = true
+		if t.kind == "name" {
+			p.lex.next()
+			if _, err := p.name(); err != nil {
+				return sel, err
+			}
+		}
+		if sel.directives, err = p.directives(); err != nil {
+			return sel, err
+		}
+		sel.selection, err = p.selectionSet()
+		return sel, err
+	}
+
+	if sel.name, err = p.name(); err != nil {
+		return sel, err
+	}
+	if p.at(":") {
+		p.lex.next()
+		sel.alias = sel.name
+		if sel.name, err = p.name(); err != nil {
+			return sel, err
+		}
+	}
+	if p.at("(") {
+		if sel.args, err = p.arguments(); err != nil {
+			return sel, err
+		}
+	}
+	if sel.directives, err = p.directives(); err != nil {
+		return sel, err
+	}
+	if p.at("{") {
+		sel.selection, err = p.selectionSet()
+	}
+	return sel, err
+}
+
+func (p *gqlParser) arguments() (map[string]gqlValue, error) {
+	p.lex.next() // "("
+	args := make(map[string]gqlValue)
+	for !p.at(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	p.lex.next()
+	return args, nil
+}
+
+func (p *gqlParser) directives() ([]gqlDirective, error) {
+	var out []gqlDirective
+	for p.at("@") {
+		p.lex.next()
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		d := gqlDirective{name: name}
+		if p.at("(") {
+			if d.args, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func (p *gqlParser) value(constant bool) (gqlValue, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t.kind {
+	case "int":
+		return strconv.ParseInt(t.text, 10, 64)
+	case "float":
+		return strconv.ParseFloat(t.text, 64)
+	case "string":
+		return t.text, nil
+	case "name":
+		switch t.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t.text, nil // enum values are passed on as strings
+	}
+	if t.text == "[" || t.text == "{" {
+		if err := p.nest(); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+	}
+	switch t.text {
+	case "$":
+		if constant {
+			return nil, fmt.Errorf("variable not allowed at %d", t.pos)
+		}
+		name, err := p.name()
+		return gqlVariable(name), err
+	case "[":
+		list := []gqlValue{}
+		for !p.at("]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.lex.next()
+		return list, nil
+	case "{":
+		obj := make(map[string]gqlValue)
+		for !p.at("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		p.lex.next()
+		return obj, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
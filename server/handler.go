@@ -1,18 +1,16 @@
 package server
 
 import (
-	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"os/exec"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"talaria/monitor"
 	"time"
 )
@@ -21,29 +19,43 @@ import (
 var staticFiles embed.FS
 
 type AllMetrics struct {
-	CPU          monitor.CPUMetrics          `json:"cpu"`
-	Memory       monitor.MemoryMetrics       `json:"memory"`
-	Disks        []monitor.DiskInfo          `json:"disks"`
-	StorageBreak monitor.StorageBreakdown    `json:"storage_breakdown"`
-	DiskIO       monitor.DiskIOMetrics       `json:"disk_io"`
-	Network      monitor.NetworkMetrics      `json:"network"`
-	Battery      monitor.BatteryMetrics      `json:"battery"`
-	Processes    []monitor.ProcessInfo       `json:"processes"`
-	System       monitor.SystemMetrics       `json:"system"`
-	Thermal      monitor.ThermalMetrics      `json:"thermal"`
-	GPU          monitor.GPUMetrics          `json:"gpu"`
-	Security     monitor.SecurityMetrics     `json:"security"`
-	Connect      monitor.ConnectivityMetrics `json:"connectivity"`
-	Health       monitor.HealthMetrics       `json:"health"`
-	Timestamp    int64                       `json:"timestamp"`
-	ClientCount  int                         `json:"client_count"`
+	CPU          monitor.CPUMetrics          `json:"cpu" desc:"Processor load"`
+	Memory       monitor.MemoryMetrics       `json:"memory" desc:"Physical memory and swap"`
+	Disks        []monitor.DiskInfo          `json:"disks" desc:"Mounted volumes"`
+	StorageBreak monitor.StorageBreakdown    `json:"storage_breakdown" desc:"Boot volume usage by category" interval:"5s"`
+	DiskIO       monitor.DiskIOMetrics       `json:"disk_io" desc:"Disk throughput"`
+	Network      monitor.NetworkMetrics      `json:"network" desc:"Network traffic and addressing"`
+	Battery      monitor.BatteryMetrics      `json:"battery" desc:"Battery state and health" interval:"3s"`
+	Processes    []monitor.ProcessInfo       `json:"processes" desc:"Top processes"`
+	System       monitor.SystemMetrics       `json:"system" desc:"Host and OS information"`
+	Thermal      monitor.ThermalMetrics      `json:"thermal" desc:"Thermal pressure"`
+	GPU          monitor.GPUMetrics          `json:"gpu" desc:"Graphics processor load" interval:"2s"`
+	Security     monitor.SecurityMetrics     `json:"security" desc:"Sessions and lock state"`
+	Connect      monitor.ConnectivityMetrics `json:"connectivity" desc:"Connections, VPN and Bluetooth" interval:"2s"`
+	Health       monitor.HealthMetrics       `json:"health" desc:"Security posture, backups and kernel errors"`
+	Custom       map[string]float64          `json:"custom,omitempty" desc:"Gauges submitted to /api/push, keyed name or name.label=value"`
+	Schema       string                      `json:"schema_version" desc:"Payload schema version, see /api/schema"`
+	Timestamp    int64                       `json:"timestamp" unit:"unix ms" desc:"Collection time"`
+	Seq          uint64                      `json:"seq" desc:"Monotonic collection counter, resets on restart"`
+	CollectMs    float64                     `json:"collect_ms" unit:"ms" desc:"Time spent in collectors"`
+	ClientCount  int                         `json:"client_count" unit:"count" desc:"Connected dashboards"`
+	CollectedAt  map[string]int64            `json:"collected_at" unit:"unix ms" desc:"When each section, or separately cached field, was last refreshed"`
 }
 
+const defaultHTTPMetricsTTL = 500 * time.Millisecond
+
+// liveSections are read fresh on every collection; everything else reports
+// the time its cache was last filled.
+var liveSections = []string{"cpu", "memory", "disk_io", "network", "system", "thermal", "security", "health"}
+
 var (
 	cachedHTTPMetrics     *AllMetrics
 	cachedHTTPMetricsJSON []byte
 	lastHTTPMetricsTime   time.Time
+	httpMetricsTTL        time.Duration
 	httpMetricsMux        sync.Mutex
+
+	collectSeq atomic.Uint64
 )
 
 func safeGo(wg *sync.WaitGroup, fn func()) {
@@ -51,76 +63,142 @@ func safeGo(wg *sync.WaitGroup, fn func()) {
 		defer wg.Done()
 		defer func() {
 			if r := recover(); r != nil {
-				log.Printf("Panic in background task: %v", r)
+				recordPanic("background task", r)
 			}
 		}()
 		fn()
 	}()
 }
 
+// collectors fill one section of AllMetrics each and run concurrently.
+var collectors = []struct {
+	name string
+	fn   func(m *AllMetrics)
+}{
+	{"cpu", func(m *AllMetrics) { m.CPU = monitor.GetCPU() }},
+	{"memory", func(m *AllMetrics) { m.Memory = monitor.GetMemory() }},
+	{"disks", func(m *AllMetrics) { m.Disks = monitor.GetDisks() }},
+	{"storage", func(m *AllMetrics) { m.StorageBreak = monitor.GetStorageBreakdown() }},
+	{"diskio", func(m *AllMetrics) { m.DiskIO = monitor.GetDiskIO() }},
+	{"network", func(m *AllMetrics) { m.Network = monitor.GetNetwork() }},
+	{"battery", func(m *AllMetrics) { m.Battery = monitor.GetBattery() }},
+	{"processes", func(m *AllMetrics) { m.Processes = redactProcesses(monitor.GetProcesses()) }},
+	{"system", func(m *AllMetrics) { m.System = monitor.GetSystem() }},
+	{"thermal", func(m *AllMetrics) { m.Thermal = monitor.GetThermal() }},
+	{"gpu", func(m *AllMetrics) { m.GPU = monitor.GetGPU() }},
+	{"security", func(m *AllMetrics) { m.Security = monitor.GetSecurity() }},
+	{"connectivity", func(m *AllMetrics) { m.Connect = monitor.GetConnectivity() }},
+	{"health", func(m *AllMetrics) { m.Health = monitor.GetHealth() }},
+}
+
 func CollectAll(clientCount int) *AllMetrics {
+	return collectTopics(clientCount, nil)
+}
+
+// collectTopics runs only the collectors named in topics, or all of them if
+// topics is nil.
+func collectTopics(clientCount int, topics map[string]bool) *AllMetrics {
 	m := &AllMetrics{}
 	var wg sync.WaitGroup
+	start := time.Now()
 
-	wg.Add(14)
-
-	safeGo(&wg, func() { m.CPU = monitor.GetCPU() })
-	safeGo(&wg, func() { m.Memory = monitor.GetMemory() })
-	safeGo(&wg, func() { m.Disks = monitor.GetDisks() })
-	safeGo(&wg, func() { m.StorageBreak = monitor.GetStorageBreakdown() })
-	safeGo(&wg, func() { m.DiskIO = monitor.GetDiskIO() })
-	safeGo(&wg, func() { m.Network = monitor.GetNetwork() })
-	safeGo(&wg, func() { m.Battery = monitor.GetBattery() })
-	safeGo(&wg, func() { m.Processes = monitor.GetProcesses() })
-	safeGo(&wg, func() { m.System = monitor.GetSystem() })
-	safeGo(&wg, func() { m.Thermal = monitor.GetThermal() })
-	safeGo(&wg, func() { m.GPU = monitor.GetGPU() })
-	safeGo(&wg, func() { m.Security = monitor.GetSecurity() })
-	safeGo(&wg, func() { m.Connect = monitor.GetConnectivity() })
-	safeGo(&wg, func() { m.Health = monitor.GetHealth() })
+	for _, c := range collectors {
+		if topics != nil && !topics[c.name] {
+			continue
+		}
+		wg.Add(1)
+		safeGo(&wg, traced(c.name, func() { collectSection(c.name, c.fn, m) }))
+	}
 
 	wg.Wait()
 
+	m.Custom = customGaugeValues()
+	m.Schema = metricsSchemaVersion
 	m.Timestamp = time.Now().UnixMilli()
+	m.Seq = collectSeq.Add(1)
+	m.CollectMs = float64(time.Since(start).Microseconds()) / 1000
 	m.ClientCount = clientCount
+	m.CollectedAt = monitor.CollectedAt()
+	for _, name := range liveSections {
+		if topics != nil && !topics[sectionTopic(name)] {
+			continue
+		}
+		m.CollectedAt[name] = m.Timestamp
+	}
+	markSampledAt(m.CollectedAt)
+	if topics == nil || sparklineTopics(topics) {
+		recordSparklines(m)
+	}
 
 	return m
 }
 
 func getCachedHTTPMetrics() []byte {
-	httpMetricsMux.Lock()
-	if time.Since(lastHTTPMetricsTime) < 500*time.Millisecond && cachedHTTPMetricsJSON != nil {
-		data := cachedHTTPMetricsJSON
-		httpMetricsMux.Unlock()
+	if data := freshHTTPMetrics(); data != nil {
 		return data
 	}
-	httpMetricsMux.Unlock()
 
 	metrics := CollectAll(0)
 	data, err := json.Marshal(metrics)
 	if err != nil {
-		log.Printf("Error encoding metrics: %v", err)
+		slog.Error("Failed to encode metrics", "err", err)
 		return nil
 	}
 
+	shareMetrics(metrics, data, defaultHTTPMetricsTTL)
+	return data
+}
+
+// freshHTTPMetrics returns the shared snapshot, or nil once it has expired.
+func freshHTTPMetrics() []byte {
+	httpMetricsMux.Lock()
+	defer httpMetricsMux.Unlock()
+	if time.Since(lastHTTPMetricsTime) < httpMetricsTTL {
+		return cachedHTTPMetricsJSON
+	}
+	return nil
+}
+
+// shareMetrics makes a full collection the snapshot HTTP handlers and
+// background loops serve until ttl passes. The hub publishes each of its
+// collections here, so a dashboard and a poller share one collection.
+func shareMetrics(metrics *AllMetrics, data []byte, ttl time.Duration) {
 	httpMetricsMux.Lock()
 	cachedHTTPMetrics = metrics
 	cachedHTTPMetricsJSON = data
 	lastHTTPMetricsTime = time.Now()
+	httpMetricsTTL = ttl
 	httpMetricsMux.Unlock()
+}
 
-	return data
+// latestMetrics returns the most recent snapshot, collecting one if stale.
+func latestMetrics() *AllMetrics {
+	getCachedHTTPMetrics()
+	httpMetricsMux.Lock()
+	defer httpMetricsMux.Unlock()
+	return cachedHTTPMetrics
 }
 
 func handleMetrics(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Talaria-Host-ID", monitor.GetHostIdentity().HostID)
 
-	data := getCachedHTTPMetrics()
+	noteDemand()
+	topics, err := topicFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var data []byte
+	if topics != nil {
+		data = filteredMetrics(topics)
+	} else {
+		data = getCachedHTTPMetrics()
+	}
 	if data == nil {
 		http.Error(w, "Failed to collect metrics", http.StatusInternalServerError)
 		return
 	}
-	w.Write(data)
+	writeEncoded(w, r, data)
 }
 
 func handleKill(w http.ResponseWriter, r *http.Request) {
@@ -141,40 +219,27 @@ func handleKill(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	currentUID := os.Getuid()
-
-	importPath := "github.com/shirou/gopsutil/v4/process"
-	_ = importPath // Just to show we'd need it; actually monitor package already has it. We will use the standard library for basic checks or exec if gopsutil isn't directly imported here.
-
-	out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "uid=").Output()
-	if err != nil || len(out) == 0 {
-		http.Error(w, "Process not found or access denied", http.StatusNotFound)
-		return
-	}
-
-	targetUIDStr := strings.TrimSpace(string(out))
-	targetUID, err := strconv.Atoi(targetUIDStr)
-	if err != nil {
-		http.Error(w, "Failed to determine process ownership", http.StatusInternalServerError)
-		return
-	}
-
-	if currentUID != 0 && targetUID != currentUID {
-		log.Printf("Security Violation: Attempted to kill process %d owned by UID %d from Talaria running as UID %d", pid, targetUID, currentUID)
-		http.Error(w, "Unauthorized: You can only kill your own processes", http.StatusForbidden)
+	params := processAuditParams(pid)
+	if err := checkProcessOwnership(pid, "kill"); err != nil {
+		auditLog(r, "process.kill", params, auditResult(err))
+		writeProcessError(w, err)
 		return
 	}
 
 	proc, err := os.FindProcess(pid)
 	if err != nil {
+		auditLog(r, "process.kill", params, auditResult(err))
 		http.Error(w, "Process not found", http.StatusNotFound)
 		return
 	}
 
 	if err := proc.Kill(); err != nil {
+		auditLog(r, "process.kill", params, auditResult(err))
 		http.Error(w, fmt.Sprintf("Failed to kill process: %v", err), http.StatusInternalServerError)
 		return
 	}
+	forgetSuspended(pid)
+	auditLog(r, "process.kill", params, "ok")
 
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, "Process %d killed", pid)
@@ -183,68 +248,32 @@ func handleKill(w http.ResponseWriter, r *http.Request) {
 func handleExport(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=talaria-metrics-%d.json", time.Now().Unix()))
+	w.Header().Set("X-Talaria-Host-ID", monitor.GetHostIdentity().HostID)
 
-	data := getCachedHTTPMetrics()
-	if data == nil {
-		http.Error(w, "Failed to collect metrics", http.StatusInternalServerError)
-		return
-	}
-	w.Write(data)
-}
-
-var (
-	flushDNSMu       sync.Mutex
-	lastFlushDNSTime time.Time
-)
-
-func handleFlushDNS(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	noteDemand()
+	topics, err := topicFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	flushDNSMu.Lock()
-	if time.Since(lastFlushDNSTime) < 30*time.Second {
-		flushDNSMu.Unlock()
-		http.Error(w, "Rate limit exceeded. Please wait 30 seconds.", http.StatusTooManyRequests)
-		return
+	var data []byte
+	if topics != nil {
+		data = filteredMetrics(topics)
+	} else {
+		data = getCachedHTTPMetrics()
 	}
-	lastFlushDNSTime = time.Now()
-	flushDNSMu.Unlock()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	script := `do shell script "dscacheutil -flushcache; killall -HUP mDNSResponder" with administrator privileges`
-	out, err := exec.CommandContext(ctx, "osascript", "-e", script).CombinedOutput()
-	if err != nil {
-		msg := strings.TrimSpace(string(out))
-		if strings.Contains(msg, "User canceled") || strings.Contains(err.Error(), "exit status 1") && msg == "" {
-			http.Error(w, "User cancelled authentication", http.StatusUnauthorized)
-		} else {
-			http.Error(w, fmt.Sprintf("Failed to flush DNS: %s", msg), http.StatusInternalServerError)
-		}
+	if data == nil {
+		http.Error(w, "Failed to collect metrics", http.StatusInternalServerError)
 		return
 	}
-
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprint(w, "DNS cache flushed")
-	log.Println("DNS cache flushed successfully")
-}
-
-func handleConnections(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	data := monitor.GetConnectionDetails()
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("Error encoding connections: %v", err)
-	}
+	w.Write(data)
 }
 
 func RecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("PANIC in HTTP handler: %v", err)
+				recordPanic("HTTP handler "+r.URL.Path, err)
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusInternalServerError)
 				json.NewEncoder(w).Encode(map[string]interface{}{
@@ -273,20 +302,70 @@ func NewRouter(hub *Hub) http.Handler {
 
 	protected.HandleFunc("/api/metrics", handleMetrics)
 	protected.HandleFunc("/api/kill", handleKill)
+	protected.HandleFunc("/api/process/batch", handleProcessBatch)
+	protected.HandleFunc("/api/process/suspend", handleSuspend)
+	protected.HandleFunc("/api/process/resume", handleResume)
+	protected.HandleFunc("/api/process/suspended", handleSuspendedList)
 	protected.HandleFunc("/api/export", handleExport)
 	protected.HandleFunc("/api/flushdns", handleFlushDNS)
 	protected.HandleFunc("/api/connections", handleConnections)
+	protected.HandleFunc("/api/connections/history", handleConnectionHistory)
+	protected.HandleFunc("/api/lan/devices", handleLANDevices)
+	protected.HandleFunc("/api/listeners", handleListeners)
+	protected.HandleFunc("/api/listeners/", handleListeners)
 	protected.HandleFunc("/api/config", handleConfig)
+	protected.HandleFunc("/api/focus", handleFocus)
+	protected.HandleFunc("/api/screenshot", handleScreenshot)
+	protected.HandleFunc("/api/events", handleEvents)
+	protected.HandleFunc("/api/speedtest", handleSpeedTest)
+	protected.HandleFunc("/api/diag/ping", handleDiag)
+	protected.HandleFunc("/api/diag/traceroute", handleDiag)
+	protected.HandleFunc("/api/diag/port", handleDiag)
+	protected.HandleFunc("/api/lookup", handleLookup)
+	protected.HandleFunc("/api/hardware", handleHardware)
+	protected.HandleFunc("/api/admin/logging", handleAdminLogging)
+	protected.HandleFunc("/api/admin/backup", handleBackup)
+	protected.HandleFunc("/api/admin/restore", handleRestore)
+	protected.HandleFunc("/api/debug/", handleDebug)
+	protected.HandleFunc("/api/audit", handleAudit)
+	protected.HandleFunc("/api/alerts", handleAlerts)
+	protected.HandleFunc("/api/alerts/test", handleAlertsTest)
+	protected.HandleFunc("/api/history", handleHistory)
+	protected.HandleFunc("/api/graphql", handleGraphQL)
+	protected.HandleFunc("/api/anomaly", handleAnomaly)
+	protected.HandleFunc("/api/digest", handleDigest)
+	protected.HandleFunc("/api/reports/weekly", handleWeeklyReport)
+	protected.HandleFunc("/api/report", handleSystemReport)
+	protected.HandleFunc("/api/snapshots", handleSnapshots)
+	protected.HandleFunc("/api/snapshots/", handleSnapshots)
+	protected.HandleFunc("/api/fleet", handleFleet)
+	protected.HandleFunc("/api/boots", handleBoots)
+	protected.HandleFunc("/api/push/key", handlePushKey)
+	protected.HandleFunc("/api/push/subscribe", handlePushSubscription)
+	protected.HandleFunc("/api/push/unsubscribe", handlePushSubscription)
+	protected.HandleFunc("/api/restart-self", handleRestartSelf)
+	protected.HandleFunc("/api/uptime/calendar", handleUptimeCalendar)
+	protected.HandleFunc("/api/schema", handleSchema)
+	protected.HandleFunc("/api/openapi.json", handleOpenAPI)
+	protected.HandleFunc("/api/clients", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hub.Clients())
+	})
 
+	protected.HandleFunc("/api/stream", func(w http.ResponseWriter, r *http.Request) {
+		ServeSSE(hub, w, r)
+	})
 	protected.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		ServeWs(hub, w, r)
 	})
 
 	protected.HandleFunc("/ws/terminal", ServeTerminal)
+	protected.HandleFunc("/ws/diag", ServeDiag)
 
 	staticFS, err := fs.Sub(staticFiles, "static")
 	if err != nil {
-		log.Fatalf("Failed to create sub filesystem: %v", err)
+		slog.Error("Failed to create sub filesystem", "err", err)
+		os.Exit(1)
 	}
 	protected.Handle("/", http.FileServer(http.FS(staticFS)))
 
@@ -294,7 +373,14 @@ func NewRouter(hub *Hub) http.Handler {
 	root.HandleFunc("/api/login", handleLogin)
 	root.HandleFunc("/api/logout", handleLogout)
 	root.HandleFunc("/api/auth/check", handleAuthCheck)
+	root.HandleFunc("/api/history/export", handleHistoryExport)
+	root.HandleFunc("/api/check", handleCheck)
+	root.HandleFunc("/api/federation/push", handleFederationPush)
+	root.HandleFunc("/api/push", handlePushGateway)
+	root.HandleFunc("/api/docs", handleAPIDocs)
+	root.HandleFunc("/auth/oidc/login", handleOIDCLogin)
+	root.HandleFunc("/auth/oidc/callback", handleOIDCCallback)
 	root.Handle("/", AuthMiddleware(protected))
 
-	return RecoveryMiddleware(root)
+	return AccessLogMiddleware(RecoveryMiddleware(CORSMiddleware(apiVersions(RateLimitMiddleware(BodyLimitMiddleware(root))))))
 }
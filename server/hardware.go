@@ -0,0 +1,25 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"talaria/monitor"
+)
+
+func handleHardware(w http.ResponseWriter, r *http.Request) {
+	info := monitor.GetHardwareInfo()
+	if !GlobalConfig.Hardware.ShowSerial {
+		info.Serial = redactSerial(info.Serial)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// redactSerial keeps just enough of the serial to tell machines apart in a fleet list.
+func redactSerial(serial string) string {
+	if len(serial) <= 6 {
+		return strings.Repeat("*", len(serial))
+	}
+	return serial[:3] + strings.Repeat("*", len(serial)-6) + serial[len(serial)-3:]
+}
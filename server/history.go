@@ -0,0 +1,249 @@
+package server
+
+import (
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHistoryInterval = 10 * time.Second
+	maxHistorySamples      = 8640 // 24h at the default interval
+)
+
+// Keys recorded when history.metrics is empty.
+var defaultHistoryMetrics = []string{
+	"cpu.usage_percent",
+	"memory.used_percent",
+	"memory.swap_used_mb",
+	"disk_io.read_mbps",
+	"disk_io.write_mbps",
+	"network.bytes_in_rate",
+	"network.bytes_out_rate",
+	"gpu.utilization",
+	"battery.percent",
+	"thermal.cpu_temp",
+	"storage_breakdown.used_gb",
+	"health.health_score",
+}
+
+// historySample is one recorded point. T (unix ms) doubles as the replication
+// cursor, so it stays meaningful across restarts. In rollup tiers V holds the
+// bucket averages and T the bucket start.
+type historySample struct {
+	T   int64              `json:"t"`
+	V   map[string]float64 `json:"v"`
+	Min map[string]float64 `json:"min,omitempty"` // rollups only
+	Max map[string]float64 `json:"max,omitempty"` // rollups only
+
+	Boot int64 `json:"boot,omitempty"` // raw samples: boot session, see bootSession
+}
+
+var (
+	history       []historySample
+	historyMu     sync.RWMutex
+	historyNotify = make(chan struct{}) // closed and replaced on every append
+)
+
+func historyKeys() []string {
+	if keys := GlobalConfig.History.Metrics; len(keys) > 0 {
+		return keys
+	}
+	return defaultHistoryMetrics
+}
+
+func startHistory() {
+	if GlobalConfig.History.Disabled {
+		return
+	}
+	interval := time.Duration(GlobalConfig.History.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultHistoryInterval
+	}
+	if !GlobalConfig.History.InMemory {
+		store, err := openHistoryStore(dataPath("history"), 0)
+		if err != nil {
+			slog.Warn("History will not survive restarts", "err", err)
+		} else {
+			historyDB = store
+			openHistoryRollups()
+			loadRecentHistory(interval)
+			loadHistoryGaps()
+			startHistoryPruning()
+		}
+	}
+	var last int64
+	if historyDB != nil {
+		historyDB.mu.Lock()
+		last = historyDB.last
+		historyDB.mu.Unlock()
+	}
+	gaps := newGapTracker(interval, last)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			m := backgroundMetrics()
+			if m != nil {
+				gaps.observe(m.Timestamp)
+			} else if idling.Load() {
+				gaps.reason = "idle"
+			}
+			recordHistory(m)
+		}
+	}()
+}
+
+func recordHistory(m *AllMetrics) {
+	if m == nil {
+		return
+	}
+	flat := flattenMetrics(m)
+	s := historySample{T: m.Timestamp, V: make(map[string]float64), Boot: m.System.BootTime}
+	for _, k := range historyKeys() {
+		if v, ok := flat[k]; ok {
+			s.V[k] = v
+		}
+	}
+	for k, v := range m.Custom {
+		s.V["custom."+k] = v
+	}
+	appendHistory(s)
+	trackBoot(m)
+}
+
+func appendHistory(s historySample) {
+	historyMu.Lock()
+	if n := len(history); n > 0 && s.T <= history[n-1].T {
+		historyMu.Unlock()
+		return
+	}
+	history = append(history, s)
+	if len(history) > maxHistorySamples {
+		history = append(history[:0:0], history[len(history)-maxHistorySamples:]...)
+	}
+	close(historyNotify)
+	historyNotify = make(chan struct{})
+	historyMu.Unlock()
+
+	if historyDB != nil {
+		if err := historyDB.append(s); err != nil {
+			slog.Error("Failed to persist history", "err", err)
+		}
+		feedRollups(s)
+	}
+}
+
+// loadRecentHistory fills the in-memory window from disk after a restart.
+func loadRecentHistory(interval time.Duration) {
+	now := time.Now()
+	samples := historyDB.read(now.Add(-maxHistorySamples*interval).UnixMilli(), now.UnixMilli())
+	if len(samples) > maxHistorySamples {
+		samples = samples[len(samples)-maxHistorySamples:]
+	}
+	historyMu.Lock()
+	history = samples
+	historyMu.Unlock()
+}
+
+// historyRange returns the samples with from <= T <= to (unix ms). Ranges
+// older than the in-memory window are read from disk.
+func historyRange(from, to int64) []historySample {
+	historyMu.RLock()
+	oldest := int64(math.MaxInt64)
+	if len(history) > 0 {
+		oldest = history[0].T
+	}
+	i := sort.Search(len(history), func(i int) bool { return history[i].T >= from })
+	j := sort.Search(len(history), func(i int) bool { return history[i].T > to })
+	var recent []historySample
+	if i < j {
+		recent = make([]historySample, j-i)
+		copy(recent, history[i:j])
+	}
+	historyMu.RUnlock()
+
+	if historyDB == nil || from >= oldest {
+		return recent
+	}
+	return append(readPersistedHistory(from, min(to, oldest-1)), recent...)
+}
+
+// historyRangeStep is historyRange for a query bucketed by step ms. It reads
+// the coarsest rollup tier no wider than step, which keeps month-long ranges
+// to a few thousand rows; the tier's open bucket comes from raw samples.
+func historyRangeStep(from, to, step int64) []historySample {
+	tier := -1
+	for i, s := range historyRollups {
+		if s.step.Milliseconds() <= step {
+			tier = i
+		}
+	}
+	if tier < 0 {
+		return historyRange(from, to)
+	}
+	stores := historyRollups[tier:]
+	stores[0].mu.Lock()
+	open := stores[0].last + stores[0].step.Milliseconds()
+	stores[0].mu.Unlock()
+	if open > to {
+		return readTiers(stores, from, to)
+	}
+	if open <= from {
+		return historyRange(from, to)
+	}
+	return append(readTiers(stores, from, open-1), historyRange(open, to)...)
+}
+
+// historySince returns up to limit samples newer than cursor, plus a channel
+// that closes when the next sample arrives. A cursor older than the
+// in-memory window is caught up from disk first.
+func historySince(cursor int64, limit int) ([]historySample, <-chan struct{}) {
+	historyMu.RLock()
+	oldest := int64(math.MaxInt64)
+	if len(history) > 0 {
+		oldest = history[0].T
+	}
+	i := sort.Search(len(history), func(i int) bool { return history[i].T > cursor })
+	end := len(history)
+	if limit > 0 && end-i > limit {
+		end = i + limit
+	}
+	out := make([]historySample, end-i)
+	copy(out, history[i:end])
+	notify := historyNotify
+	historyMu.RUnlock()
+
+	if historyDB == nil || cursor >= oldest-1 {
+		return out, notify
+	}
+	older := persistedSince(cursor, min(oldest-1, time.Now().UnixMilli()), limit)
+	out = append(older, out...)
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, notify
+}
+
+// persistedSince reads samples after cursor up to to from disk a day at a
+// time, stopping once limit is reached, so a replica starting from 0 does
+// not load months of history per batch.
+func persistedSince(cursor, to int64, limit int) []historySample {
+	start := cursor + 1
+	first := int64(math.MaxInt64)
+	for _, s := range append([]*historyStore{historyDB}, historyRollups...) {
+		if t := s.first(); t != 0 {
+			first = min(first, t)
+		}
+	}
+	start = max(start, first)
+	var out []historySample
+	for start <= to && (limit <= 0 || len(out) < limit) {
+		end := min(to, start+24*time.Hour.Milliseconds()-1)
+		out = append(out, readPersistedHistory(start, end)...)
+		start = end + 1
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A gap is reported once the history ticker falls this many intervals
+// behind, so ordinary scheduling jitter never counts.
+const historyGapFactor = 3
+
+// historyGap marks a period with no samples: the machine was asleep,
+// Talaria was not running, or collection was idle. From and To are the
+// samples on either side.
+type historyGap struct {
+	From   int64  `json:"from"`
+	To     int64  `json:"to"`
+	Reason string `json:"reason"` // "sleep", "restart" or "idle"
+}
+
+var (
+	historyGaps   []historyGap
+	historyGapsMu sync.Mutex
+)
+
+func historyGapsPath() string {
+	return filepath.Join(dataPath("history"), "gaps.ndjson")
+}
+
+// gapTracker notices when consecutive history samples are too far apart.
+// The first sample after startup is compared with the newest persisted one,
+// which is how restarts (and sleep while Talaria was stopped) show up.
+type gapTracker struct {
+	threshold int64
+	prev      int64
+	reason    string
+}
+
+func newGapTracker(interval time.Duration, last int64) *gapTracker {
+	return &gapTracker{threshold: historyGapFactor * interval.Milliseconds(), prev: last, reason: "restart"}
+}
+
+func (g *gapTracker) observe(t int64) {
+	if t <= g.prev {
+		return
+	}
+	if g.prev > 0 && t-g.prev > g.threshold {
+		recordHistoryGap(historyGap{From: g.prev, To: t, Reason: g.reason})
+	}
+	g.prev, g.reason = t, "sleep"
+}
+
+func recordHistoryGap(gap historyGap) {
+	historyGapsMu.Lock()
+	historyGaps = append(historyGaps, gap)
+	historyGapsMu.Unlock()
+	slog.Info("History gap", "duration", time.Duration(gap.To-gap.From)*time.Millisecond, "reason", gap.Reason)
+
+	if historyDB == nil {
+		return
+	}
+	line, _ := json.Marshal(gap)
+	f, err := os.OpenFile(historyGapsPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err == nil {
+		_, err = f.Write(append(line, '\n'))
+		f.Close()
+	}
+	if err != nil {
+		slog.Error("Failed to persist history gap", "err", err)
+	}
+}
+
+// loadHistoryGaps reads the persisted gaps, dropping those that end before
+// the oldest sample still on disk.
+func loadHistoryGaps() {
+	f, err := os.Open(historyGapsPath())
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	oldest := historyDB.first()
+	var gaps []historyGap
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var g historyGap
+		if json.Unmarshal(scanner.Bytes(), &g) == nil && g.To >= oldest {
+			gaps = append(gaps, g)
+		}
+	}
+	historyGapsMu.Lock()
+	historyGaps = gaps
+	historyGapsMu.Unlock()
+}
+
+// historyGapsIn returns the gaps overlapping [from, to].
+func historyGapsIn(from, to int64) []historyGap {
+	historyGapsMu.Lock()
+	defer historyGapsMu.Unlock()
+	out := []historyGap{}
+	for _, g := range historyGaps {
+		if g.To >= from && g.From <= to {
+			out = append(out, g)
+		}
+	}
+	return out
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// historyStore persists samples as append-only NDJSON segments, one file per
+// UTC day ("20261016.ndjson"). A torn last line after a crash is skipped on
+// read, so no recovery step is needed.
+type historyStore struct {
+	dir  string
+	step time.Duration // bucket width of a rollup tier; 0 for raw samples
+
+	mu   sync.Mutex
+	f    *os.File
+	day  string
+	last int64 // T of the newest sample written, to keep segments ordered
+}
+
+const historySegmentLayout = "20060102"
+
+var (
+	historyDB          *historyStore   // raw samples; nil when history.in_memory is set
+	historyRollups     []*historyStore // coarser tiers, finest first
+	historyRollupState []*rollup       // open bucket of each rollup tier
+)
+
+func openHistoryStore(dir string, step time.Duration) (*historyStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &historyStore{dir: dir, step: step}
+	if segs := s.segments(); len(segs) > 0 {
+		newest := s.readSegment(segs[len(segs)-1], 0, 1<<62)
+		if n := len(newest); n > 0 {
+			s.last = newest[n-1].T
+		}
+	}
+	return s, nil
+}
+
+func (s *historyStore) append(smp historySample) error {
+	line, err := json.Marshal(smp)
+	if err != nil {
+		return err
+	}
+	day := time.UnixMilli(smp.T).UTC().Format(historySegmentLayout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if smp.T <= s.last {
+		return nil
+	}
+	if s.f == nil || day != s.day {
+		if s.f != nil {
+			s.f.Close()
+		}
+		f, err := os.OpenFile(filepath.Join(s.dir, day+".ndjson"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			s.f = nil
+			return err
+		}
+		s.f, s.day = f, day
+	}
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.last = smp.T
+	return nil
+}
+
+// segments returns the segment days on disk, oldest first.
+func (s *historyStore) segments() []string {
+	matches, _ := filepath.Glob(filepath.Join(s.dir, "*.ndjson"))
+	days := make([]string, 0, len(matches))
+	for _, m := range matches {
+		day := strings.TrimSuffix(filepath.Base(m), ".ndjson")
+		if _, err := time.Parse(historySegmentLayout, day); err == nil {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	return days
+}
+
+// read returns the persisted samples with from <= T <= to (unix ms).
+func (s *historyStore) read(from, to int64) []historySample {
+	first := time.UnixMilli(from).UTC().Format(historySegmentLayout)
+	last := time.UnixMilli(to).UTC().Format(historySegmentLayout)
+	var out []historySample
+	for _, day := range s.segments() {
+		if day < first || day > last {
+			continue
+		}
+		out = append(out, s.readSegment(day, from, to)...)
+	}
+	return out
+}
+
+func (s *historyStore) readSegment(day string, from, to int64) []historySample {
+	f, err := os.Open(filepath.Join(s.dir, day+".ndjson"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var out []historySample
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var smp historySample
+		if json.Unmarshal(scanner.Bytes(), &smp) != nil {
+			continue
+		}
+		if smp.T >= from && smp.T <= to {
+			out = append(out, smp)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		slog.Warn("Unreadable history segment", "day", day, "err", err)
+	}
+	return out
+}
+
+// first returns the T of the oldest persisted sample, or 0 if there is none.
+func (s *historyStore) first() int64 {
+	for _, day := range s.segments() {
+		f, err := os.Open(filepath.Join(s.dir, day+".ndjson"))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64<<10), 1<<20)
+		for scanner.Scan() {
+			var smp historySample
+			if json.Unmarshal(scanner.Bytes(), &smp) == nil {
+				f.Close()
+				return smp.T
+			}
+		}
+		f.Close()
+	}
+	return 0
+}
+
+func (s *historyStore) remove(day string) error {
+	s.mu.Lock()
+	if s.day == day && s.f != nil {
+		s.f.Close()
+		s.f, s.day = nil, ""
+	}
+	s.mu.Unlock()
+	return os.Remove(filepath.Join(s.dir, day+".ndjson"))
+}
+
+// readPersistedHistory merges the tiers: each coarser tier only fills the
+// time before the next finer tier begins.
+func readPersistedHistory(from, to int64) []historySample {
+	return readTiers(append([]*historyStore{historyDB}, historyRollups...), from, to)
+}
+
+// readTiers merges stores, finest first, the same way.
+func readTiers(stores []*historyStore, from, to int64) []historySample {
+	var out []historySample
+	end := to
+	for _, s := range stores {
+		start := s.first()
+		if start == 0 {
+			continue
+		}
+		if lo := max(from, start); lo <= end {
+			out = append(s.read(lo, end), out...)
+		}
+		if end = min(end, start-1); end < from {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"talaria/monitor"
+	"time"
+)
+
+const defaultHookTimeout = 10 * time.Second
+
+// HookConfig is a command run on a lifecycle event. Command is an argv list
+// executed without a shell.
+type HookConfig struct {
+	Name           string   `yaml:"name"`
+	Command        []string `yaml:"command"`
+	TimeoutSeconds int      `yaml:"timeout_seconds"`
+}
+
+func runHooks(phase string, hooks []HookConfig) {
+	for _, h := range hooks {
+		if len(h.Command) == 0 {
+			continue
+		}
+		timeout := time.Duration(h.TimeoutSeconds) * time.Second
+		if timeout <= 0 {
+			timeout = defaultHookTimeout
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+		cmd.Env = append(os.Environ(), "TALARIA_HOOK_PHASE="+phase)
+		start := time.Now()
+		out, err := cmd.CombinedOutput()
+		cancel()
+
+		result := "ok"
+		exitCode := 0
+		if err != nil {
+			var exitErr *exec.ExitError
+			switch {
+			case errors.Is(ctx.Err(), context.DeadlineExceeded):
+				result = "timeout"
+				exitCode = -1
+			case errors.As(err, &exitErr):
+				result = "failed"
+				exitCode = exitErr.ExitCode()
+			default:
+				result = "error: " + err.Error()
+				exitCode = -1
+			}
+			slog.Warn("Hook failed", "phase", phase, "hook", h.Name, "result", result, "output", truncate(string(out), 500))
+		} else {
+			slog.Info("Hook ran", "phase", phase, "hook", h.Name, "duration", time.Since(start).Round(time.Millisecond))
+		}
+
+		auditLog(nil, "hook", map[string]string{
+			"phase":       phase,
+			"name":        h.Name,
+			"exit_code":   strconv.Itoa(exitCode),
+			"duration_ms": strconv.FormatInt(time.Since(start).Milliseconds(), 10),
+		}, result)
+	}
+}
+
+// RunShutdownHooks runs hooks.shutdown; main calls it during graceful shutdown.
+func RunShutdownHooks() {
+	runHooks("shutdown", GlobalConfig.Hooks.Shutdown)
+}
+
+func startSleepHooks() {
+	if len(GlobalConfig.Hooks.Sleep) == 0 {
+		return
+	}
+	monitor.WatchSystemPower(func(event string) {
+		if event == monitor.PowerSleep {
+			runHooks("sleep", GlobalConfig.Hooks.Sleep)
+		}
+	})
+}
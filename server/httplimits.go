@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultShutdownGrace     = 2 * time.Second
+	defaultMaxBodyBytes      = 16 << 20
+)
+
+func secondsOr(n int, def time.Duration) time.Duration {
+	if n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	return def
+}
+
+// ConfigureHTTPServer applies the server section's timeouts to srv and
+// tags Unix socket connections for the rate limiter.
+func ConfigureHTTPServer(srv *http.Server) {
+	cfg := GlobalConfig.Server
+	srv.ReadHeaderTimeout = secondsOr(cfg.ReadHeaderTimeoutSeconds, defaultReadHeaderTimeout)
+	srv.ReadTimeout = secondsOr(cfg.ReadTimeoutSeconds, 0)
+	srv.WriteTimeout = secondsOr(cfg.WriteTimeoutSeconds, 0)
+	srv.IdleTimeout = secondsOr(cfg.IdleTimeoutSeconds, defaultIdleTimeout)
+	srv.ConnContext = tagUnixConn
+}
+
+// ShutdownGrace is how long in-flight requests get to finish on shutdown.
+func ShutdownGrace() time.Duration {
+	return secondsOr(GlobalConfig.Server.ShutdownGraceSeconds, defaultShutdownGrace)
+}
+
+// BodyLimitMiddleware caps every request body at server.max_body_bytes.
+// Handlers keep their own, usually smaller, limits; restore uploads a whole
+// backup and is bounded by maxRestoreSize instead.
+func BodyLimitMiddleware(next http.Handler) http.Handler {
+	limit := GlobalConfig.Server.MaxBodyBytes
+	if limit <= 0 {
+		limit = defaultMaxBodyBytes
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/admin/restore" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.ContentLength > limit {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -2,9 +2,12 @@ package server
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"talaria/monitor"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -17,10 +20,12 @@ type Hub struct {
 
 	unregister chan *Client
 
-	incoming chan []byte
+	incoming chan clientMessage
 
-	ticker *time.Ticker
-	quit   chan struct{}
+	ticker    *time.Ticker
+	rate      time.Duration // fastest rate any client asked for
+	throttled bool          // thermal backoff active
+	quit      chan struct{}
 
 	mu sync.RWMutex
 }
@@ -30,16 +35,54 @@ type Client struct {
 
 	conn *websocket.Conn
 
-	send chan *websocket.PreparedMessage
+	send    chan *websocket.PreparedMessage
+	replies chan *websocket.PreparedMessage // per-client answers, e.g. history queries
+	done    chan struct{}                   // closed when the read pump exits
+	events  chan []byte                     // JSON payloads for an SSE client; nil on WebSocket
+
+	id          string
+	remoteAddr  string
+	userAgent   string
+	connectedAt time.Time
+	compressed  bool // permessage-deflate negotiated
+	msgpack     bool // ?encoding=msgpack: binary frames instead of JSON text
+
+	topics   map[string]bool // nil receives every section; guarded by hub.mu
+	topicKey string          // sorted topics, groups clients sharing a payload
+	rate     time.Duration   // set_rate; 0 is defaultRefreshRate. Guarded by hub.mu
+	due      time.Time       // next broadcast this client wants
+
+	rttNanos atomic.Int64 // last ping/pong round trip
+	queries  atomic.Int32 // history queries in flight
+}
+
+// clientMessage is a command a client sent over its connection.
+type clientMessage struct {
+	client *Client
+	data   []byte
+}
+
+const defaultRefreshRate = time.Second
+
+type ClientInfo struct {
+	ID          string  `json:"id"`
+	RemoteAddr  string  `json:"remote_addr"`
+	UserAgent   string  `json:"user_agent"`
+	ConnectedAt int64   `json:"connected_at"`
+	RTTMs       float64 `json:"rtt_ms"` // -1 until the first pong arrives
+	Compression bool    `json:"compression"`
+	Transport   string  `json:"transport"` // "websocket" or "sse"
+	RateMs      int64   `json:"rate_ms"`
 }
 
 func NewHub() *Hub {
 	return &Hub{
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
-		incoming:   make(chan []byte, 16),
+		incoming:   make(chan clientMessage, 16),
 		clients:    make(map[*Client]bool),
-		ticker:     time.NewTicker(1 * time.Second),
+		ticker:     time.NewTicker(defaultRefreshRate),
+		rate:       defaultRefreshRate,
 		quit:       make(chan struct{}),
 	}
 }
@@ -55,14 +98,15 @@ func (h *Hub) Run() {
 			h.mu.Lock()
 			h.clients[client] = true
 			h.mu.Unlock()
+			h.retune()
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
+				h.drop(client)
 			}
 			h.mu.Unlock()
+			h.retune()
 
 		case msg := <-h.incoming:
 
@@ -70,47 +114,44 @@ func (h *Hub) Run() {
 				Action string `json:"action"`
 				Rate   int    `json:"rate"` // milliseconds
 			}
-			if err := json.Unmarshal(msg, &cmd); err == nil {
+			if err := json.Unmarshal(msg.data, &cmd); err == nil {
 				switch cmd.Action {
 				case "set_rate":
 
-					if cmd.Rate >= 250 && cmd.Rate <= 10000 {
-						h.ticker.Reset(time.Duration(cmd.Rate) * time.Millisecond)
-						log.Printf("Refresh rate changed to %dms", cmd.Rate)
+					if validRefreshRate(cmd.Rate) {
+						h.mu.Lock()
+						msg.client.rate = time.Duration(cmd.Rate) * time.Millisecond
+						msg.client.due = time.Time{}
+						h.mu.Unlock()
+						h.retune()
 					}
 				}
 			}
 
-		case <-h.ticker.C:
+		case now := <-h.ticker.C:
 
 			h.mu.RLock()
 			count := len(h.clients)
+			due := h.dueClients(now)
+			topics := wantedTopics(due)
 			h.mu.RUnlock()
-
 			if count > 0 {
-				metrics := CollectAll(count)
-				data, err := json.Marshal(metrics)
-				if err != nil {
-					log.Printf("JSON marshal error: %v", err)
-					continue
-				}
+				noteDemand()
+			}
 
-				pm, err := websocket.NewPreparedMessage(websocket.TextMessage, data)
-				if err != nil {
-					log.Printf("PreparedMessage error: %v", err)
-					continue
+			if len(due) > 0 {
+				metrics := collectTopics(count, topics)
+				thermal := metrics.Thermal.ThermalState
+				if topics != nil && !topics["thermal"] {
+					thermal = monitor.GetThermal().ThermalState
 				}
-
-				h.mu.Lock()
-				for client := range h.clients {
-					select {
-					case client.send <- pm:
-					default:
-						close(client.send)
-						delete(h.clients, client)
-					}
+				h.adjustForThermal(thermal)
+				if data := h.broadcast(metrics, due, now); data != nil && topics == nil {
+					// Good until the next tick; allow for jitter.
+					shareMetrics(metrics, data, h.interval()+defaultHTTPMetricsTTL)
 				}
-				h.mu.Unlock()
+			} else if h.throttled {
+				h.adjustForThermal(monitor.GetThermal().ThermalState)
 			}
 
 		case <-h.quit:
@@ -119,11 +160,155 @@ func (h *Hub) Run() {
 	}
 }
 
+// retune sets the collection rate to the fastest any client wants. Slower
+// clients are served every few ticks; see dueClients.
+func (h *Hub) retune() {
+	rate := time.Duration(0)
+	h.mu.RLock()
+	for c := range h.clients {
+		if r := c.refreshRate(); rate == 0 || r < rate {
+			rate = r
+		}
+	}
+	h.mu.RUnlock()
+	if rate == 0 {
+		rate = defaultRefreshRate
+	}
+	if rate != h.rate {
+		h.rate = rate
+		h.ticker.Reset(h.interval())
+		slog.Info("Refresh rate changed", "rate", rate)
+	}
+}
+
+func validRefreshRate(ms int) bool {
+	return ms >= 250 && ms <= 10000
+}
+
+func (c *Client) refreshRate() time.Duration {
+	if c.rate > 0 {
+		return c.rate
+	}
+	return defaultRefreshRate
+}
+
+// dueClients lists the clients whose next update falls within half a tick of
+// now, so tick jitter does not push a client a whole tick late. Callers hold
+// h.mu.
+func (h *Hub) dueClients(now time.Time) []*Client {
+	slack := h.interval() / 2
+	var due []*Client
+	for c := range h.clients {
+		if !now.Add(slack).Before(c.due) {
+			due = append(due, c)
+		}
+	}
+	return due
+}
+
+// broadcast sends metrics to the due clients, marshalling once per distinct
+// set of subscribed topics, and returns the full JSON payload.
+func (h *Hub) broadcast(metrics *AllMetrics, due []*Client, now time.Time) []byte {
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		slog.Error("Failed to encode metrics", "err", err)
+		return nil
+	}
+
+	var sections map[string]json.RawMessage
+	payloads := make(map[string][]byte)
+	prepared := make(map[string]*websocket.PreparedMessage)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, client := range due {
+		if !h.clients[client] {
+			continue // left while we were collecting
+		}
+		client.due = now.Add(client.refreshRate())
+		key := "*"
+		if client.topics != nil {
+			key = client.topicKey
+		}
+		payload, ok := payloads[key]
+		if !ok {
+			payload = data
+			if client.topics != nil {
+				if sections == nil {
+					if err := json.Unmarshal(data, &sections); err != nil {
+						slog.Error("Failed to split metrics into sections", "err", err)
+						return data
+					}
+				}
+				if payload, err = filterSections(sections, client.topics); err != nil {
+					slog.Error("Failed to encode subscribed sections", "err", err)
+					continue
+				}
+			}
+			payloads[key] = payload
+		}
+
+		if client.events != nil {
+			select {
+			case client.events <- payload:
+			default:
+				h.drop(client)
+			}
+			continue
+		}
+
+		if client.msgpack {
+			key += "|msgpack"
+		}
+		pm := prepared[key]
+		if pm == nil {
+			if pm, err = client.prepare(payload); err != nil {
+				slog.Error("Failed to prepare WebSocket message", "err", err)
+				continue
+			}
+			prepared[key] = pm
+		}
+		select {
+		case client.send <- pm:
+		default:
+			h.drop(client)
+		}
+	}
+	return data
+}
+
+// drop removes a client and closes its outgoing channel, which ends its
+// writer. Callers hold h.mu.
+func (h *Hub) drop(client *Client) {
+	delete(h.clients, client)
+	if client.events != nil {
+		close(client.events)
+	} else {
+		close(client.send)
+	}
+}
+
+// prepare frames a JSON document in the client's negotiated encoding.
+func (c *Client) prepare(data []byte) (*websocket.PreparedMessage, error) {
+	if !c.msgpack {
+		return websocket.NewPreparedMessage(websocket.TextMessage, data)
+	}
+	packed, err := jsonToMsgpack(data)
+	if err != nil {
+		return nil, err
+	}
+	return websocket.NewPreparedMessage(websocket.BinaryMessage, packed)
+}
+
 func (h *Hub) Stop() {
 	close(h.quit)
 
 	h.mu.Lock()
 	for client := range h.clients {
+		if client.conn == nil {
+			h.drop(client)
+			continue
+		}
 
 		if uc := client.conn.UnderlyingConn(); uc != nil {
 			if tc, ok := uc.(*net.TCPConn); ok {
@@ -135,6 +320,39 @@ func (h *Hub) Stop() {
 	h.mu.Unlock()
 }
 
+func (h *Hub) Clients() []ClientInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list := make([]ClientInfo, 0, len(h.clients))
+	for c := range h.clients {
+		list = append(list, c.info())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ConnectedAt < list[j].ConnectedAt })
+	return list
+}
+
+func (c *Client) info() ClientInfo {
+	transport := "websocket"
+	if c.events != nil {
+		transport = "sse"
+	}
+	rtt := -1.0
+	if ns := c.rttNanos.Load(); ns > 0 {
+		rtt = float64(ns) / float64(time.Millisecond)
+	}
+	return ClientInfo{
+		ID:          c.id,
+		RemoteAddr:  c.remoteAddr,
+		UserAgent:   c.userAgent,
+		ConnectedAt: c.connectedAt.UnixMilli(),
+		RTTMs:       rtt,
+		Compression: c.compressed,
+		Transport:   transport,
+		RateMs:      c.refreshRate().Milliseconds(),
+	}
+}
+
 func (h *Hub) ClientCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// Incident backends open an incident when an alert fires and resolve the same
+// incident when it clears, keyed by host and rule name.
+
+func incidentKey(e Event) string {
+	hostname, _ := os.Hostname()
+	if rule := e.Fields["rule"]; rule != "" {
+		return "talaria/" + hostname + "/" + rule
+	}
+	return "talaria/" + hostname + "/" + e.Kind + "/" + e.Title
+}
+
+func incidentResolves(e Event) bool {
+	return e.Kind == "alert" && e.Fields["state"] == "resolved"
+}
+
+type pagerDutyNotifier struct {
+	routingKey string
+}
+
+func (p *pagerDutyNotifier) Name() string { return "pagerduty" }
+
+func (p *pagerDutyNotifier) Startup(startupInfo) error { return nil }
+
+func (p *pagerDutyNotifier) Notify(e Event) error {
+	body := map[string]interface{}{
+		"routing_key":  p.routingKey,
+		"dedup_key":    incidentKey(e),
+		"event_action": "trigger",
+	}
+	if incidentResolves(e) {
+		body["event_action"] = "resolve"
+	} else {
+		// PagerDuty derives urgency from severity when the service is set to
+		// "based on alert severity".
+		severity := "info"
+		switch e.Severity {
+		case SeverityCritical:
+			severity = "critical"
+		case SeverityWarning:
+			severity = "warning"
+		}
+		hostname, _ := os.Hostname()
+		body["payload"] = map[string]interface{}{
+			"summary":        e.Title + ": " + e.Message,
+			"source":         hostname,
+			"severity":       severity,
+			"component":      e.Kind,
+			"custom_details": e.Fields,
+		}
+	}
+
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequest(http.MethodPost, "https://events.pagerduty.com/v2/enqueue", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return pushSend(req)
+}
+
+type opsgenieNotifier struct {
+	apiKey string
+	region string
+}
+
+func (o *opsgenieNotifier) Name() string { return "opsgenie" }
+
+func (o *opsgenieNotifier) Startup(startupInfo) error { return nil }
+
+func (o *opsgenieNotifier) Notify(e Event) error {
+	base := "https://api.opsgenie.com/v2/alerts"
+	if o.region == "eu" {
+		base = "https://api.eu.opsgenie.com/v2/alerts"
+	}
+	hostname, _ := os.Hostname()
+	alias := incidentKey(e)
+
+	var endpoint string
+	var body map[string]interface{}
+	if incidentResolves(e) {
+		endpoint = base + "/" + url.PathEscape(alias) + "/close?identifierType=alias"
+		body = map[string]interface{}{"source": hostname, "note": e.Message}
+	} else {
+		priority := "P5"
+		switch e.Severity {
+		case SeverityCritical:
+			priority = "P1"
+		case SeverityWarning:
+			priority = "P3"
+		}
+		endpoint = base
+		body = map[string]interface{}{
+			"message":     truncate(e.Title, 130),
+			"alias":       alias,
+			"description": e.Message,
+			"priority":    priority,
+			"source":      hostname,
+			"details":     e.Fields,
+			"tags":        []string{"talaria", e.Kind},
+		}
+	}
+
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+o.apiKey)
+	return pushSend(req)
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultInfluxInterval  = 10 * time.Second
+	defaultInfluxBatchSize = 5000
+	maxInfluxBuffered      = 100000 // lines kept while the endpoint is down
+	maxInfluxBackoff       = 5 * time.Minute
+)
+
+// startInfluxExport writes line protocol to exporters.influx.url every tick.
+// Points that fail to send stay buffered (oldest dropped past
+// maxInfluxBuffered) and are retried with backoff.
+func startInfluxExport() {
+	cfg := GlobalConfig.Exporters.Influx
+	if !cfg.Enabled || cfg.URL == "" {
+		return
+	}
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultInfluxInterval
+	}
+	batch := cfg.BatchSize
+	if batch <= 0 {
+		batch = defaultInfluxBatchSize
+	}
+	host, _ := os.Hostname()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var pending []string
+		var retryAt time.Time
+		backoff := interval
+		for range ticker.C {
+			m := backgroundMetrics()
+			if m == nil {
+				continue
+			}
+			pending = append(pending, influxLines(flattenMetrics(m), host, m.Timestamp, cfg.Metrics)...)
+			if len(pending) > maxInfluxBuffered {
+				pending = append(pending[:0:0], pending[len(pending)-maxInfluxBuffered:]...)
+			}
+			if time.Now().Before(retryAt) {
+				continue
+			}
+
+			for len(pending) > 0 {
+				n := min(len(pending), batch)
+				if err := influxWrite(cfg.URL, cfg.Token, pending[:n]); err != nil {
+					slog.Warn("Influx export failed", "err", err, "buffered", len(pending), "retry_in", backoff)
+					retryAt = time.Now().Add(backoff)
+					backoff = min(backoff*2, maxInfluxBackoff)
+					break
+				}
+				pending = pending[n:]
+				backoff = interval
+			}
+		}
+	}()
+}
+
+// influxLines renders one point per section, e.g.
+// "cpu,host=mbp usage_percent=12.5,load_1=1.2 1760000000000000000".
+func influxLines(values map[string]float64, host string, tsMillis int64, prefixes []string) []string {
+	fields := make(map[string][]string)
+	for key, v := range values {
+		if !metricSelected(key, prefixes) {
+			continue
+		}
+		section, field, ok := strings.Cut(key, ".")
+		if !ok {
+			continue
+		}
+		fields[section] = append(fields[section], influxEscape(field)+"="+strconv.FormatFloat(v, 'f', -1, 64))
+	}
+
+	sections := make([]string, 0, len(fields))
+	for s := range fields {
+		sections = append(sections, s)
+	}
+	sort.Strings(sections)
+
+	ts := strconv.FormatInt(tsMillis*int64(time.Millisecond), 10)
+	lines := make([]string, 0, len(sections))
+	for _, s := range sections {
+		sort.Strings(fields[s])
+		lines = append(lines, influxEscape(s)+",host="+influxEscape(host)+" "+strings.Join(fields[s], ",")+" "+ts)
+	}
+	return lines
+}
+
+// metricSelected reports whether key matches one of the configured prefixes
+// (exact key or a dotted prefix); an empty list selects everything except
+// bookkeeping timestamps.
+func metricSelected(key string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return !strings.HasPrefix(key, "collected_at.")
+	}
+	for _, p := range prefixes {
+		if key == p || strings.HasPrefix(key, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+var influxEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+func influxEscape(s string) string {
+	return influxEscaper.Replace(s)
+}
+
+// influxWrite posts lines to an InfluxDB v2 (/api/v2/write?bucket=...), v1
+// (/write?db=...) or VictoriaMetrics (/write) endpoint.
+func influxWrite(url, token string, lines []string) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(strings.Join(lines, "\n")+"\n"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// InstanceInfo is written into the lock file so a second process started
+// against the same config can tell the user where the first one lives.
+type InstanceInfo struct {
+	PID       int    `json:"pid"`
+	Started   int64  `json:"started"`
+	URL       string `json:"url"`
+	PublicURL string `json:"public_url,omitempty"`
+}
+
+var (
+	ErrInstanceRunning = errors.New("another Talaria instance is already running with this config")
+
+	instanceLock   *os.File
+	instanceInfo   InstanceInfo
+	instanceLockMu sync.Mutex
+)
+
+// The lock sits next to the config rather than in the data dir so it works
+// before the config (and data_dir) has been read.
+func instanceLockPath(configPath string) string {
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+	return configPath + ".lock"
+}
+
+// AcquireInstanceLock takes an exclusive lock for configPath and holds it
+// until the process exits. If another process holds it, its info is returned
+// along with ErrInstanceRunning.
+func AcquireInstanceLock(configPath, url string) (*InstanceInfo, error) {
+	f, err := os.OpenFile(instanceLockPath(configPath), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		info := readInstanceInfo(f)
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return info, ErrInstanceRunning
+		}
+		return nil, err
+	}
+
+	instanceLockMu.Lock()
+	defer instanceLockMu.Unlock()
+	instanceLock = f
+	instanceInfo = InstanceInfo{PID: os.Getpid(), Started: time.Now().Unix(), URL: url}
+	return nil, writeInstanceInfo()
+}
+
+// RunningInstance reports whether another process holds the lock for
+// configPath without taking it.
+func RunningInstance(configPath string) (*InstanceInfo, bool) {
+	f, err := os.Open(instanceLockPath(configPath))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err == nil {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		return nil, false
+	}
+	return readInstanceInfo(f), true
+}
+
+// InstanceResponding checks that the instance's HTTP server answers at all;
+// the auth check is public so no credentials are needed.
+func InstanceResponding(info *InstanceInfo) bool {
+	if info == nil || info.URL == "" {
+		return false
+	}
+	client := http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(info.URL + "/api/auth/check")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+func setInstancePublicURL(u string) {
+	instanceLockMu.Lock()
+	defer instanceLockMu.Unlock()
+	if instanceLock == nil || instanceInfo.PublicURL == u {
+		return
+	}
+	instanceInfo.PublicURL = u
+	writeInstanceInfo()
+}
+
+func writeInstanceInfo() error {
+	data, err := json.Marshal(instanceInfo)
+	if err != nil {
+		return err
+	}
+	if err := instanceLock.Truncate(0); err != nil {
+		return err
+	}
+	_, err = instanceLock.WriteAt(data, 0)
+	return err
+}
+
+func readInstanceInfo(f *os.File) *InstanceInfo {
+	data, err := io.ReadAll(io.LimitReader(f, 4096))
+	if err != nil {
+		return nil
+	}
+	var info InstanceInfo
+	if json.Unmarshal(data, &info) != nil {
+		return nil
+	}
+	return &info
+}
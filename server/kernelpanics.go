@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	kernelPanicInterval = time.Minute
+	// On the first run, reports this recent are still announced: the panic
+	// that just rebooted the machine is written before Talaria starts.
+	kernelPanicFreshness = time.Hour
+)
+
+// startKernelPanicWatch announces new panic reports in DiagnosticReports and
+// panic lines in the kernel error log as critical events.
+func startKernelPanicWatch() {
+	seen, firstRun := loadSeenPanics()
+	seenLines := make(map[string]bool)
+	for _, line := range monitor.KernelPanicLines() {
+		seenLines[line] = true
+	}
+
+	go func() {
+		ticker := time.NewTicker(kernelPanicInterval)
+		defer ticker.Stop()
+		for ; ; <-ticker.C {
+			changed := false
+			onDisk := make(map[string]bool)
+			for _, rep := range monitor.ListPanicReports() {
+				onDisk[rep.Path] = true
+				if seen[rep.Path] {
+					continue
+				}
+				seen[rep.Path], changed = true, true
+				if firstRun && time.Since(time.Unix(rep.ModTime, 0)) > kernelPanicFreshness {
+					continue
+				}
+				raiseKernelPanic(filepath.Base(rep.Path), time.Unix(rep.ModTime, 0), rep.Lines)
+			}
+			firstRun = false
+			for path := range seen {
+				if !onDisk[path] {
+					delete(seen, path)
+					changed = true
+				}
+			}
+			if changed {
+				saveSeenPanics(seen)
+			}
+
+			var fresh []string
+			for _, line := range monitor.KernelPanicLines() {
+				if !seenLines[line] {
+					seenLines[line] = true
+					fresh = append(fresh, line)
+				}
+			}
+			if len(fresh) > 0 {
+				raiseKernelPanic("kernel log", time.Now(), fresh)
+			}
+		}
+	}()
+}
+
+func raiseKernelPanic(source string, at time.Time, lines []string) {
+	msg := "Kernel panic reported at " + at.Format("2006-01-02 15:04:05")
+	if len(lines) > 0 {
+		msg += ":\n" + strings.Join(lines, "\n")
+	}
+	RaiseEvent(Event{
+		Kind:     "system",
+		Severity: SeverityCritical,
+		Title:    "Kernel panic",
+		Message:  msg,
+		Fields: map[string]string{
+			"source": source,
+			"time":   fmt.Sprint(at.Unix()),
+		},
+	})
+}
+
+func loadSeenPanics() (map[string]bool, bool) {
+	seen := make(map[string]bool)
+	data, err := os.ReadFile(dataPath("kernel_panics_seen.json"))
+	if err != nil {
+		return seen, true
+	}
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		slog.Warn("Ignoring corrupt kernel panic state", "err", err)
+		return seen, true
+	}
+	for _, p := range paths {
+		seen[p] = true
+	}
+	return seen, false
+}
+
+func saveSeenPanics(seen map[string]bool) {
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	data, err := json.Marshal(paths)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(dataPath("kernel_panics_seen.json"), data, 0600); err != nil {
+		slog.Error("Failed to save kernel panic state", "err", err)
+	}
+}
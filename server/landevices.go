@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"talaria/monitor"
+	"time"
+)
+
+const defaultLANWatchInterval = time.Minute
+
+type lanDevice struct {
+	MAC       string `json:"mac"`
+	IP        string `json:"ip"`
+	Interface string `json:"interface"`
+	Network   string `json:"network,omitempty"` // Wi-Fi SSID when first seen
+	FirstSeen int64  `json:"first_seen"`
+	LastSeen  int64  `json:"last_seen"`
+}
+
+var (
+	lanDevices      map[string]*lanDevice // by MAC; nil until loaded or baselined
+	lanDevicesMu    sync.Mutex
+	lanDevicesDirty bool
+)
+
+// startLANWatch polls the ARP cache and raises an event the first time a MAC
+// address is seen. Without a saved device list the first scan is the baseline.
+func startLANWatch() {
+	cfg := GlobalConfig.LANWatch
+	if !cfg.Enabled {
+		return
+	}
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultLANWatchInterval
+	}
+	loadLANDevices()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for ; ; <-ticker.C {
+			if collectionIdle() {
+				continue
+			}
+			var ssid string
+			if m := latestMetrics(); m != nil {
+				ssid = m.Network.WiFiSSID
+			}
+			sampleLANDevices(monitor.GetLANNeighbors(), ssid, time.Now())
+			saveLANDevices()
+		}
+	}()
+}
+
+func sampleLANDevices(neighbors []monitor.LANNeighbor, ssid string, now time.Time) {
+	lanDevicesMu.Lock()
+	baseline := lanDevices == nil
+	if baseline && len(neighbors) == 0 {
+		// arp failed or we are offline; don't take an empty baseline
+		lanDevicesMu.Unlock()
+		return
+	}
+	if baseline {
+		lanDevices = make(map[string]*lanDevice)
+	}
+	var joined []lanDevice
+	ts := now.Unix()
+	for _, n := range neighbors {
+		d := lanDevices[n.MAC]
+		isNew := d == nil
+		if isNew {
+			d = &lanDevice{MAC: n.MAC, Network: ssid, FirstSeen: ts}
+			lanDevices[n.MAC] = d
+		}
+		d.IP, d.Interface, d.LastSeen = n.IP, n.Interface, ts
+		lanDevicesDirty = true
+		if isNew && !baseline {
+			joined = append(joined, *d)
+		}
+	}
+	lanDevicesMu.Unlock()
+
+	for _, d := range joined {
+		raiseDeviceJoined(d)
+	}
+}
+
+func raiseDeviceJoined(d lanDevice) {
+	where := d.Interface
+	if d.Network != "" {
+		where = fmt.Sprintf("%s (%s)", d.Interface, d.Network)
+	}
+	RaiseEvent(Event{
+		Kind:     "device",
+		Severity: SeverityWarning,
+		Title:    "New device on the network",
+		Message:  fmt.Sprintf("%s joined at %s on %s", d.MAC, d.IP, where),
+		Fields: map[string]string{
+			"mac":       d.MAC,
+			"ip":        d.IP,
+			"interface": d.Interface,
+			"ssid":      d.Network,
+		},
+	})
+}
+
+func loadLANDevices() {
+	data, err := os.ReadFile(dataPath("lan_devices.json"))
+	if err != nil {
+		return
+	}
+	var devices map[string]*lanDevice
+	if err := json.Unmarshal(data, &devices); err != nil {
+		slog.Warn("Ignoring corrupt LAN device list", "err", err)
+		return
+	}
+	lanDevicesMu.Lock()
+	lanDevices = devices
+	lanDevicesMu.Unlock()
+}
+
+func saveLANDevices() {
+	lanDevicesMu.Lock()
+	if !lanDevicesDirty {
+		lanDevicesMu.Unlock()
+		return
+	}
+	data, err := json.Marshal(lanDevices)
+	lanDevicesDirty = false
+	lanDevicesMu.Unlock()
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(dataPath("lan_devices.json"), data, 0600); err != nil {
+		slog.Error("Failed to save LAN devices", "err", err)
+	}
+}
+
+// handleLANDevices lists every device seen on the local network, newest first.
+func handleLANDevices(w http.ResponseWriter, r *http.Request) {
+	lanDevicesMu.Lock()
+	out := make([]lanDevice, 0, len(lanDevices))
+	for _, d := range lanDevices {
+		out = append(out, *d)
+	}
+	lanDevicesMu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].FirstSeen > out[j].FirstSeen })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": GlobalConfig.LANWatch.Enabled,
+		"devices": out,
+	})
+}
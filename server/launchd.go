@@ -0,0 +1,46 @@
+package server
+
+/*
+#include <launch.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// launchdListeners checks in with launchd for the sockets declared under
+// name in the job's plist. Outside launchd, or without such a socket, there
+// are none.
+func launchdListeners(name string) ([]net.Listener, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	var fds *C.int
+	var count C.size_t
+	if rc := C.launch_activate_socket(cname, &fds, &count); rc != 0 {
+		err := syscall.Errno(rc)
+		if errors.Is(err, syscall.ESRCH) || errors.Is(err, syscall.ENOENT) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer C.free(unsafe.Pointer(fds))
+
+	var lns []net.Listener
+	for _, fd := range unsafe.Slice(fds, int(count)) {
+		ln, err := fileListener(int(fd), "launchd")
+		if err != nil {
+			for _, l := range lns {
+				l.Close()
+			}
+			return nil, err
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
@@ -0,0 +1,360 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A minimal LDAPv3 client: simple bind plus one equality search for group
+// membership. Just enough BER to avoid pulling in a directory library.
+
+const (
+	ldapTimeout = 10 * time.Second
+
+	berBoolean    = 0x01
+	berInteger    = 0x02
+	berOctets     = 0x04
+	berEnumerated = 0x0a
+	berSequence   = 0x30
+	berSet        = 0x31
+
+	ldapBindRequest     = 0x60
+	ldapBindResponse    = 0x61
+	ldapUnbindRequest   = 0x42
+	ldapSearchRequest   = 0x63
+	ldapSearchEntry     = 0x64
+	ldapSearchDone      = 0x65
+	ldapSimpleAuth      = 0x80
+	ldapFilterEquality  = 0xa3
+	ldapResultSuccess   = 0
+	ldapResultBadCreds  = 49
+	ldapScopeSubtree    = 2
+	ldapMaxMessageBytes = 1 << 20
+)
+
+type ldapBackend struct {
+	url                string // ldap://host[:389] or ldaps://host[:636]
+	userDN             string // "uid=%s,ou=people,dc=example,dc=com"
+	groupBaseDN        string
+	groupAttr          string // "member" (user DN) or "memberUid" (username)
+	insecureSkipVerify bool
+}
+
+func (l *ldapBackend) Name() string { return "ldap" }
+
+func (l *ldapBackend) Authenticate(ctx context.Context, user, password string) (identity, error) {
+	if password == "" || !strings.Contains(l.userDN, "%s") {
+		// An empty password is an anonymous bind, which servers accept.
+		return identity{}, errInvalidCredentials
+	}
+	conn, err := l.dial(ctx)
+	if err != nil {
+		return identity{}, err
+	}
+	defer conn.Close()
+
+	c := &ldapConn{conn: conn, r: bufio.NewReader(conn)}
+	dn := fmt.Sprintf(l.userDN, user)
+	if err := c.bind(dn, password); err != nil {
+		return identity{}, err
+	}
+	defer c.unbind()
+
+	id := identity{User: user}
+	if l.groupBaseDN == "" {
+		return id, nil
+	}
+	attr, value := l.groupAttr, dn
+	if attr == "" {
+		attr = "member"
+	}
+	if strings.EqualFold(attr, "memberUid") {
+		value = user
+	}
+	id.Groups, err = c.searchGroups(l.groupBaseDN, attr, value)
+	return id, err
+}
+
+func (l *ldapBackend) dial(ctx context.Context) (net.Conn, error) {
+	u, err := url.Parse(l.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap url: %w", err)
+	}
+	d := net.Dialer{Timeout: ldapTimeout}
+	host := u.Host
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(host, "389")
+		}
+		conn, err = d.DialContext(ctx, "tcp", host)
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(host, "636")
+		}
+		td := tls.Dialer{NetDialer: &d, Config: &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: l.insecureSkipVerify,
+		}}
+		conn, err = td.DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("unsupported ldap scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(ldapTimeout))
+	return conn, nil
+}
+
+type ldapConn struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	msgID int
+}
+
+func (c *ldapConn) send(op []byte) (int, error) {
+	c.msgID++
+	_, err := c.conn.Write(berTLV(berSequence, berInt(berInteger, c.msgID), op))
+	return c.msgID, err
+}
+
+// recv returns the protocol op of the next message addressed to id.
+func (c *ldapConn) recv(id int) (byte, []byte, error) {
+	for {
+		tag, msg, err := berRead(c.r)
+		if err != nil {
+			return 0, nil, err
+		}
+		if tag != berSequence {
+			return 0, nil, errors.New("ldap: malformed message")
+		}
+		_, idBytes, rest, err := berNext(msg)
+		if err != nil {
+			return 0, nil, err
+		}
+		opTag, op, _, err := berNext(rest)
+		if err != nil {
+			return 0, nil, err
+		}
+		if berParseInt(idBytes) == id {
+			return opTag, op, nil
+		}
+	}
+}
+
+func (c *ldapConn) bind(dn, password string) error {
+	id, err := c.send(berTLV(ldapBindRequest,
+		berInt(berInteger, 3),
+		berTLV(berOctets, []byte(dn)),
+		berTLV(ldapSimpleAuth, []byte(password)),
+	))
+	if err != nil {
+		return err
+	}
+	tag, op, err := c.recv(id)
+	if err != nil {
+		return err
+	}
+	if tag != ldapBindResponse {
+		return errors.New("ldap: unexpected bind response")
+	}
+	code, msg := ldapResult(op)
+	switch code {
+	case ldapResultSuccess:
+		return nil
+	case ldapResultBadCreds:
+		return errInvalidCredentials
+	}
+	return fmt.Errorf("ldap bind failed (%d): %s", code, msg)
+}
+
+func (c *ldapConn) unbind() {
+	c.msgID++
+	c.conn.Write(berTLV(berSequence, berInt(berInteger, c.msgID), []byte{ldapUnbindRequest, 0}))
+}
+
+// searchGroups returns the cn of every group under base whose attr equals value.
+func (c *ldapConn) searchGroups(base, attr, value string) ([]string, error) {
+	id, err := c.send(berTLV(ldapSearchRequest,
+		berTLV(berOctets, []byte(base)),
+		berInt(berEnumerated, ldapScopeSubtree),
+		berInt(berEnumerated, 0), // never deref aliases
+		berInt(berInteger, 500),  // size limit
+		berInt(berInteger, int(ldapTimeout/time.Second)),
+		berTLV(berBoolean, []byte{0}),
+		berTLV(ldapFilterEquality, berTLV(berOctets, []byte(attr)), berTLV(berOctets, []byte(value))),
+		berTLV(berSequence, berTLV(berOctets, []byte("cn"))),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []string
+	for {
+		tag, op, err := c.recv(id)
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case ldapSearchEntry:
+			if name := ldapEntryCN(op); name != "" {
+				groups = append(groups, name)
+			}
+		case ldapSearchDone:
+			if code, msg := ldapResult(op); code != ldapResultSuccess {
+				return groups, fmt.Errorf("ldap search failed (%d): %s", code, msg)
+			}
+			return groups, nil
+		}
+	}
+}
+
+func ldapResult(op []byte) (int, string) {
+	_, code, rest, err := berNext(op)
+	if err != nil {
+		return -1, "malformed result"
+	}
+	_, _, rest, _ = berNext(rest) // matchedDN
+	_, msg, _, _ := berNext(rest)
+	return berParseInt(code), string(msg)
+}
+
+func ldapEntryCN(op []byte) string {
+	_, dn, rest, err := berNext(op)
+	if err != nil {
+		return ""
+	}
+	_, attrs, _, _ := berNext(rest)
+	for len(attrs) > 0 {
+		var attr []byte
+		_, attr, attrs, err = berNext(attrs)
+		if err != nil {
+			break
+		}
+		_, name, vals, _ := berNext(attr)
+		if !strings.EqualFold(string(name), "cn") {
+			continue
+		}
+		_, set, _, _ := berNext(vals)
+		if _, v, _, err := berNext(set); err == nil {
+			return string(v)
+		}
+	}
+	// Fall back to the first RDN value: "cn=admins,ou=groups,..." → "admins".
+	rdn, _, _ := strings.Cut(string(dn), ",")
+	_, v, _ := strings.Cut(rdn, "=")
+	return v
+}
+
+func berTLV(tag byte, parts ...[]byte) []byte {
+	var content []byte
+	for _, p := range parts {
+		content = append(content, p...)
+	}
+	out := []byte{tag}
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n < 0x100:
+		out = append(out, 0x81, byte(n))
+	case n < 0x10000:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, content...)
+}
+
+func berInt(tag byte, n int) []byte {
+	b := []byte{byte(n)}
+	for n >>= 8; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return berTLV(tag, b)
+}
+
+func berParseInt(b []byte) int {
+	n := 0
+	for _, c := range b {
+		n = n<<8 | int(c)
+	}
+	return n
+}
+
+func berLength(first byte, next func() (byte, error)) (int, error) {
+	if first < 0x80 {
+		return int(first), nil
+	}
+	count := int(first & 0x7f)
+	if count == 0 || count > 3 {
+		return 0, errors.New("ber: unsupported length")
+	}
+	n := 0
+	for i := 0; i < count; i++ {
+		b, err := next()
+		if err != nil {
+			return 0, err
+		}
+		n = n<<8 | int(b)
+	}
+	return n, nil
+}
+
+func berRead(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := berLength(first, r.ReadByte)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n > ldapMaxMessageBytes {
+		return 0, nil, errors.New("ldap: message too large")
+	}
+	content := make([]byte, n)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return 0, nil, err
+	}
+	return tag, content, nil
+}
+
+// berNext splits the first element off data.
+func berNext(data []byte) (tag byte, content, rest []byte, err error) {
+	if len(data) < 2 {
+		return 0, nil, nil, errors.New("ber: truncated")
+	}
+	tag = data[0]
+	pos := 2
+	n, err := berLength(data[1], func() (byte, error) {
+		if pos >= len(data) {
+			return 0, errors.New("ber: truncated")
+		}
+		pos++
+		return data[pos-1], nil
+	})
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if pos+n > len(data) {
+		return 0, nil, nil, errors.New("ber: truncated")
+	}
+	return tag, data[pos : pos+n], data[pos+n:], nil
+}
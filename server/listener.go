@@ -2,9 +2,14 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 )
 
 func NewListener(addr string) (net.Listener, error) {
@@ -24,3 +29,132 @@ func NewListener(addr string) (net.Listener, error) {
 	}
 	return lc.Listen(context.Background(), "tcp", addr)
 }
+
+// ListenAddrs is server.listen, or host and port when it is empty.
+func ListenAddrs() ([]string, error) {
+	cfg := GlobalConfig.Server
+	if len(cfg.Listen) == 0 {
+		return []string{net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))}, nil
+	}
+	addrs := make([]string, 0, len(cfg.Listen))
+	for _, a := range cfg.Listen {
+		a = strings.TrimSpace(a)
+		if _, port, err := net.SplitHostPort(a); err == nil {
+			if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+				return nil, fmt.Errorf("server.listen: invalid port in %q", a)
+			}
+			addrs = append(addrs, a)
+			continue
+		}
+		// A bare host or IP, bracketed or not.
+		host := strings.TrimSuffix(strings.TrimPrefix(a, "["), "]")
+		if host == "" || cfg.Port == 0 {
+			return nil, fmt.Errorf("server.listen: %q needs a port", a)
+		}
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(cfg.Port)))
+	}
+	return addrs, nil
+}
+
+// LocalURL is where this machine reaches the server: the first listen
+// address, with a wildcard host replaced by localhost.
+func LocalURL() string {
+	host, port := "localhost", strconv.Itoa(GlobalConfig.Server.Port)
+	if addrs, err := ListenAddrs(); err == nil {
+		host, port, _ = net.SplitHostPort(addrs[0])
+		if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+			host = "localhost"
+		}
+	}
+	return urlScheme() + "://" + net.JoinHostPort(host, port)
+}
+
+func listenPort() int {
+	if addrs, err := ListenAddrs(); err == nil {
+		if _, port, err := net.SplitHostPort(addrs[0]); err == nil {
+			n, _ := strconv.Atoi(port)
+			return n
+		}
+	}
+	return GlobalConfig.Server.Port
+}
+
+// Listeners opens every listen address and the Unix socket the server
+// section asks for, unless a service manager has passed sockets in. If any
+// fails, those already open are closed again.
+func Listeners() ([]net.Listener, error) {
+	if lns, err := activatedListeners(); err != nil || len(lns) > 0 {
+		return lns, err
+	}
+	cfg := GlobalConfig.Server
+	if cfg.SocketOnly && cfg.Socket == "" {
+		return nil, errors.New("server.socket_only needs server.socket")
+	}
+	var lns []net.Listener
+	fail := func(err error) ([]net.Listener, error) {
+		for _, l := range lns {
+			l.Close()
+		}
+		return nil, err
+	}
+	if !cfg.SocketOnly {
+		addrs, err := ListenAddrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ln, err := NewListener(addr)
+			if err != nil {
+				return fail(err)
+			}
+			lns = append(lns, ln)
+		}
+	}
+	if cfg.Socket != "" {
+		ln, err := NewUnixListener(cfg.Socket, cfg.SocketMode)
+		if err != nil {
+			return fail(err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// NewUnixListener listens on path with the octal mode given, "0660" when
+// empty. A socket left behind by an instance that died is replaced; one that
+// still answers is not. The socket is removed again when the listener
+// closes.
+func NewUnixListener(path, mode string) (net.Listener, error) {
+	if mode == "" {
+		mode = "0660"
+	}
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm > 0777 {
+		return nil, fmt.Errorf("server.socket_mode %q is not an octal file mode", mode)
+	}
+
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Connecting needs write permission, which the usual umask withholds
+	// from group and others until the mode is applied.
+	if err := os.Chmod(path, os.FileMode(perm)); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"talaria/monitor"
+	"time"
+)
+
+const defaultListenWatchInterval = 30 * time.Second
+
+// listener is one process listening on one address. Keys use the process name
+// rather than the PID so restarts of a known daemon stay quiet.
+type listener struct {
+	Key          string `json:"key"`
+	Process      string `json:"process"`
+	PID          int    `json:"pid"`
+	Address      string `json:"address"` // "*:22" for all interfaces, else "127.0.0.1:631"
+	FirstSeen    int64  `json:"first_seen"`
+	LastSeen     int64  `json:"last_seen"`
+	Acknowledged bool   `json:"acknowledged"`
+	Open         bool   `json:"open"`
+}
+
+var (
+	listeners      map[string]*listener // nil until loaded or baselined
+	listenersMu    sync.Mutex
+	listenersDirty bool
+)
+
+func startListenWatch() {
+	cfg := GlobalConfig.ListenWatch
+	if !cfg.Enabled {
+		return
+	}
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultListenWatchInterval
+	}
+	loadListeners()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for ; ; <-ticker.C {
+			if collectionIdle() {
+				continue
+			}
+			sampleListeners(monitor.GetConnectionDetails().Listening, time.Now())
+			saveListeners()
+		}
+	}()
+}
+
+// listenAddress folds wildcard binds together so IPv4 and IPv6 listeners on
+// the same port share a key.
+func listenAddress(local string) string {
+	host, port, err := net.SplitHostPort(local)
+	if err != nil {
+		return local
+	}
+	if host == "" || host == "*" || host == "0.0.0.0" || host == "::" {
+		host = "*"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func sampleListeners(current []monitor.ConnectionInfo, now time.Time) {
+	listenersMu.Lock()
+	baseline := listeners == nil
+	if baseline && len(current) == 0 {
+		listenersMu.Unlock()
+		return
+	}
+	if baseline {
+		listeners = make(map[string]*listener)
+	}
+
+	ts := now.Unix()
+	seen := make(map[string]bool)
+	var added []listener
+	for _, c := range current {
+		addr := listenAddress(c.Local)
+		key := c.Process + "|" + addr
+		seen[key] = true
+		l := listeners[key]
+		if l == nil {
+			l = &listener{Key: key, Process: c.Process, Address: addr, FirstSeen: ts, Acknowledged: baseline}
+			listeners[key] = l
+			if !baseline {
+				added = append(added, *l)
+			}
+		}
+		if !l.Open || l.PID != c.PID {
+			listenersDirty = true
+		}
+		l.PID, l.LastSeen, l.Open = c.PID, ts, true
+	}
+	for key, l := range listeners {
+		if l.Open && !seen[key] {
+			l.Open = false
+			listenersDirty = true
+		}
+	}
+	listenersMu.Unlock()
+
+	for _, l := range added {
+		raiseNewListener(l)
+	}
+}
+
+func raiseNewListener(l listener) {
+	exposure := "on " + l.Address
+	if strings.HasPrefix(l.Address, "*:") {
+		exposure = "on all interfaces, port " + strings.TrimPrefix(l.Address, "*:")
+	}
+	RaiseEvent(Event{
+		Kind:     "security",
+		Severity: SeverityWarning,
+		Title:    "New listening port",
+		Message:  fmt.Sprintf("%s (PID %d) started listening %s", l.Process, l.PID, exposure),
+		Fields: map[string]string{
+			"process": l.Process,
+			"pid":     strconv.Itoa(l.PID),
+			"address": l.Address,
+			"key":     l.Key,
+		},
+	})
+}
+
+func loadListeners() {
+	data, err := os.ReadFile(dataPath("listeners.json"))
+	if err != nil {
+		return
+	}
+	var saved map[string]*listener
+	if err := json.Unmarshal(data, &saved); err != nil {
+		slog.Warn("Ignoring corrupt listener baseline", "err", err)
+		return
+	}
+	for _, l := range saved {
+		l.Open = false
+	}
+	listenersMu.Lock()
+	listeners = saved
+	listenersMu.Unlock()
+}
+
+func saveListeners() {
+	listenersMu.Lock()
+	if !listenersDirty {
+		listenersMu.Unlock()
+		return
+	}
+	data, err := json.Marshal(listeners)
+	listenersDirty = false
+	listenersMu.Unlock()
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(dataPath("listeners.json"), data, 0600); err != nil {
+		slog.Error("Failed to save listener baseline", "err", err)
+	}
+}
+
+// handleListeners lists known listeners, unacknowledged first (GET).
+// POST /api/listeners/ack with {"keys": [...]} or {"all": true} accepts them
+// into the baseline; POST /api/listeners/baseline forgets everything and
+// re-baselines from the current sockets.
+func handleListeners(w http.ResponseWriter, r *http.Request) {
+	if !GlobalConfig.ListenWatch.Enabled {
+		http.Error(w, "Listening port watch is disabled", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/listeners":
+		listenersMu.Lock()
+		out := make([]listener, 0, len(listeners))
+		for _, l := range listeners {
+			out = append(out, *l)
+		}
+		listenersMu.Unlock()
+		sort.Slice(out, func(i, j int) bool {
+			if out[i].Acknowledged != out[j].Acknowledged {
+				return !out[i].Acknowledged
+			}
+			return out[i].FirstSeen > out[j].FirstSeen
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/api/listeners/ack":
+		var body struct {
+			Keys []string `json:"keys"`
+			All  bool     `json:"all"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		acked := 0
+		listenersMu.Lock()
+		for _, l := range listeners {
+			if l.Acknowledged {
+				continue
+			}
+			if body.All || slices.Contains(body.Keys, l.Key) {
+				l.Acknowledged = true
+				acked++
+			}
+		}
+		listenersDirty = listenersDirty || acked > 0
+		listenersMu.Unlock()
+		saveListeners()
+		auditLog(r, "listeners.ack", map[string]string{"count": strconv.Itoa(acked)}, "ok")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"acknowledged": acked})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/api/listeners/baseline":
+		listenersMu.Lock()
+		listeners = nil
+		listenersMu.Unlock()
+		sampleListeners(monitor.GetConnectionDetails().Listening, time.Now())
+		saveListeners()
+		auditLog(r, "listeners.baseline", nil, "ok")
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Talaria logs through log/slog. The level is the one /api/admin/logging
+// changes at runtime; installing the handler as the default also routes the
+// standard log package into it, so dependencies' output lands in the same
+// stream and format.
+
+// configuredLevel reports the live log level to slog handlers.
+type configuredLevel struct{}
+
+func (configuredLevel) Level() slog.Level {
+	switch logLevel.Load() {
+	case levelDebug:
+		return slog.LevelDebug
+	case levelWarn:
+		return slog.LevelWarn
+	case levelError:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// SetupLogging installs the handler chosen by logging.format and
+// logging.file. A daemonized instance has no stdout to speak of, so without
+// a file it logs to talaria.log in the data directory.
+func SetupLogging() error {
+	cfg := GlobalConfig.Logging
+	var out io.Writer = os.Stderr
+	path := cfg.File
+	if path == "" && os.Getenv("TALARIA_BACKGROUND") == "1" {
+		path = dataPath("talaria.log")
+	}
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("logging.file: %w", err)
+		}
+		out = f
+	}
+
+	opts := &slog.HandlerOptions{Level: configuredLevel{}}
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		h = slog.NewTextHandler(out, opts)
+	case "json":
+		h = slog.NewJSONHandler(out, opts)
+	default:
+		return fmt.Errorf("logging.format %q is not text or json", cfg.Format)
+	}
+	applyConfiguredLogLevel()
+	slog.SetDefault(slog.New(h))
+	return nil
+}
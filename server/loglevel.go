@@ -0,0 +1,187 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	levelDebug int32 = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var (
+	levelNames = map[string]int32{"debug": levelDebug, "info": levelInfo, "warn": levelWarn, "error": levelError}
+
+	// "commands" traces every subprocess the monitor package spawns.
+	knownCollectors = []string{
+		"cpu", "memory", "disks", "storage", "diskio", "network", "battery", "processes",
+		"system", "thermal", "gpu", "security", "connectivity", "health", "commands",
+	}
+
+	logLevel atomic.Int32
+
+	debugCollectors   = make(map[string]bool)
+	loggingRevertAt   time.Time
+	loggingRevertTask *time.Timer
+	loggingMu         sync.RWMutex
+)
+
+func init() {
+	logLevel.Store(levelInfo)
+}
+
+func levelName(l int32) string {
+	for name, v := range levelNames {
+		if v == l {
+			return name
+		}
+	}
+	return "info"
+}
+
+func configuredLogLevel() int32 {
+	if l, ok := levelNames[strings.ToLower(GlobalConfig.Logging.Level)]; ok {
+		return l
+	}
+	return levelInfo
+}
+
+func applyConfiguredLogLevel() {
+	logLevel.Store(configuredLogLevel())
+}
+
+func collectorDebug(name string) bool {
+	if logLevel.Load() <= levelDebug {
+		return true
+	}
+	loggingMu.RLock()
+	defer loggingMu.RUnlock()
+	return debugCollectors[name]
+}
+
+// traced wraps a collector so its duration is logged while debugging is on for it.
+func traced(name string, fn func()) func() {
+	return func() {
+		if !collectorDebug(name) {
+			fn()
+			return
+		}
+		start := time.Now()
+		fn()
+		slog.Info("Collector trace", "collector", name, "took", time.Since(start).Round(time.Microsecond))
+	}
+}
+
+func revertLogging() {
+	loggingMu.Lock()
+	debugCollectors = make(map[string]bool)
+	loggingRevertAt = time.Time{}
+	loggingRevertTask = nil
+	loggingMu.Unlock()
+
+	applyConfiguredLogLevel()
+	monitor.SetCommandTracing(false)
+	slog.Info("Logging reverted", "level", levelName(logLevel.Load()))
+}
+
+func loggingState() map[string]interface{} {
+	loggingMu.RLock()
+	defer loggingMu.RUnlock()
+
+	collectors := make([]string, 0, len(debugCollectors))
+	for c := range debugCollectors {
+		collectors = append(collectors, c)
+	}
+	sort.Strings(collectors)
+
+	var revertAt int64
+	if !loggingRevertAt.IsZero() {
+		revertAt = loggingRevertAt.Unix()
+	}
+	return map[string]interface{}{
+		"level":            levelName(logLevel.Load()),
+		"debug_collectors": collectors,
+		"known_collectors": knownCollectors,
+		"revert_at":        revertAt,
+	}
+}
+
+func handleAdminLogging(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Level      string   `json:"level"`
+			Collectors []string `json:"collectors"`
+			Duration   int      `json:"duration"` // seconds; 0 uses the configured default
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		level, ok := levelNames[strings.ToLower(req.Level)]
+		if req.Level == "" {
+			level, ok = logLevel.Load(), true
+		}
+		if !ok {
+			http.Error(w, "Unknown level", http.StatusBadRequest)
+			return
+		}
+		collectors := make(map[string]bool)
+		for _, c := range req.Collectors {
+			known := false
+			for _, k := range knownCollectors {
+				known = known || k == c
+			}
+			if !known {
+				http.Error(w, fmt.Sprintf("Unknown collector %q", c), http.StatusBadRequest)
+				return
+			}
+			collectors[c] = true
+		}
+
+		d := time.Duration(req.Duration) * time.Second
+		if d <= 0 {
+			d = time.Duration(GlobalConfig.Logging.DebugRevertMinutes) * time.Minute
+			if d <= 0 {
+				d = 30 * time.Minute
+			}
+		}
+
+		loggingMu.Lock()
+		debugCollectors = collectors
+		if loggingRevertTask != nil {
+			loggingRevertTask.Stop()
+		}
+		loggingRevertAt = time.Now().Add(d)
+		loggingRevertTask = time.AfterFunc(d, revertLogging)
+		loggingMu.Unlock()
+
+		logLevel.Store(level)
+		monitor.SetCommandTracing(collectors["commands"] || level == levelDebug)
+
+		auditLog(r, "logging", map[string]string{
+			"level":      levelName(level),
+			"collectors": strings.Join(req.Collectors, ","),
+			"duration":   d.String(),
+		}, "ok")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loggingState())
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	whoisIANA     = "whois.iana.org"
+	whoisTimeout  = 10 * time.Second
+	maxWhoisBytes = 64 << 10
+	lookupTTL     = 10 * time.Minute
+)
+
+type LookupResult struct {
+	Query string   `json:"query"`
+	IsIP  bool     `json:"is_ip"`
+	PTR   []string `json:"ptr,omitempty"`
+	A     []string `json:"a,omitempty"`
+	AAAA  []string `json:"aaaa,omitempty"`
+	CNAME string   `json:"cname,omitempty"`
+	MX    []string `json:"mx,omitempty"`
+	TXT   []string `json:"txt,omitempty"`
+	Whois *Whois   `json:"whois,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+type Whois struct {
+	Server  string            `json:"server"`
+	Summary map[string]string `json:"summary"`
+	Raw     string            `json:"raw"`
+}
+
+type cachedLookup struct {
+	result LookupResult
+	at     time.Time
+}
+
+var (
+	lookupCache   = make(map[string]cachedLookup)
+	lookupCacheMu sync.Mutex
+
+	whoisSummaryKeys = map[string]string{
+		"orgname":              "org",
+		"org-name":             "org",
+		"organization":         "org",
+		"org":                  "org",
+		"owner":                "org",
+		"netname":              "netname",
+		"descr":                "description",
+		"country":              "country",
+		"cidr":                 "cidr",
+		"inetnum":              "range",
+		"netrange":             "range",
+		"registrar":            "registrar",
+		"creation date":        "created",
+		"created":              "created",
+		"registry expiry date": "expires",
+		"abuse-mailbox":        "abuse",
+		"orgabuseemail":        "abuse",
+	}
+)
+
+func handleLookup(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if !diagHostRegex.MatchString(q) {
+		http.Error(w, "Invalid query", http.StatusBadRequest)
+		return
+	}
+	wantWhois := r.URL.Query().Get("whois") != "0"
+
+	if res, ok := cachedLookupResult(q, wantWhois); ok {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	// WHOIS servers throttle aggressively; share the diagnostics budget.
+	if wantWhois && !allowDiagRun(getRealIP(r)) {
+		http.Error(w, errDiagRateLimited.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	res := lookup(ctx, q, wantWhois)
+	storeLookupResult(q, res)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func cachedLookupResult(q string, wantWhois bool) (LookupResult, bool) {
+	lookupCacheMu.Lock()
+	c, ok := lookupCache[q]
+	lookupCacheMu.Unlock()
+	if ok && time.Since(c.at) < lookupTTL && (c.result.Whois != nil || !wantWhois) {
+		return c.result, true
+	}
+	return LookupResult{}, false
+}
+
+func storeLookupResult(q string, res LookupResult) {
+	lookupCacheMu.Lock()
+	lookupCache[q] = cachedLookup{result: res, at: time.Now()}
+	for k, v := range lookupCache {
+		if time.Since(v.at) > lookupTTL {
+			delete(lookupCache, k)
+		}
+	}
+	lookupCacheMu.Unlock()
+}
+
+func lookup(ctx context.Context, q string, wantWhois bool) LookupResult {
+	res := LookupResult{Query: q}
+	resolver := net.DefaultResolver
+
+	if addr, err := netip.ParseAddr(q); err == nil {
+		res.IsIP = true
+		res.PTR, _ = resolver.LookupAddr(ctx, addr.String())
+	} else {
+		ips, err := resolver.LookupIPAddr(ctx, q)
+		if err != nil {
+			res.Error = err.Error()
+		}
+		for _, ip := range ips {
+			if ip.IP.To4() != nil {
+				res.A = append(res.A, ip.IP.String())
+			} else {
+				res.AAAA = append(res.AAAA, ip.IP.String())
+			}
+		}
+		if cname, err := resolver.LookupCNAME(ctx, q); err == nil && strings.TrimSuffix(cname, ".") != q {
+			res.CNAME = cname
+		}
+		if mxs, err := resolver.LookupMX(ctx, q); err == nil {
+			for _, mx := range mxs {
+				res.MX = append(res.MX, mx.Host)
+			}
+		}
+		res.TXT, _ = resolver.LookupTXT(ctx, q)
+	}
+
+	if wantWhois {
+		if wh, err := whois(ctx, q); err == nil {
+			res.Whois = wh
+		} else if res.Error == "" {
+			res.Error = "whois: " + err.Error()
+		}
+	}
+	return res
+}
+
+// whois asks IANA which registry is authoritative and follows a single referral.
+func whois(ctx context.Context, q string) (*Whois, error) {
+	server := whoisIANA
+	raw, err := whoisQuery(ctx, server, q)
+	if err != nil {
+		return nil, err
+	}
+	if refer := whoisField(raw, "refer"); refer != "" {
+		if referred, err := whoisQuery(ctx, refer, q); err == nil {
+			server, raw = refer, referred
+		}
+	}
+
+	summary := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		key, val, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		name, known := whoisSummaryKeys[strings.ToLower(strings.TrimSpace(key))]
+		val = strings.TrimSpace(val)
+		if known && val != "" {
+			if _, seen := summary[name]; !seen {
+				summary[name] = val
+			}
+		}
+	}
+	return &Whois{Server: server, Summary: summary, Raw: raw}, nil
+}
+
+func whoisQuery(ctx context.Context, server, q string) (string, error) {
+	d := net.Dialer{Timeout: whoisTimeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(server, "43"))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(whoisTimeout))
+
+	if _, err := io.WriteString(conn, q+"\r\n"); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(io.LimitReader(conn, maxWhoisBytes))
+	if err != nil && len(data) == 0 {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func whoisField(raw, field string) string {
+	for _, line := range strings.Split(raw, "\n") {
+		key, val, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), field) {
+			return strings.TrimSpace(val)
+		}
+	}
+	return ""
+}
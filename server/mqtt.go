@@ -0,0 +1,369 @@
+package server
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	defaultMQTTInterval = 30 * time.Second
+	mqttRetryMax        = 5 * time.Minute
+)
+
+// mqttSensor is one Home Assistant entity. Values go out together as a JSON
+// object on <prefix>/<node>/state; each entity picks its field with a
+// value_template.
+type mqttSensor struct {
+	id          string
+	name        string
+	unit        string
+	deviceClass string
+	icon        string
+	value       func(v map[string]float64) (float64, bool)
+}
+
+func mqttKey(key string) func(map[string]float64) (float64, bool) {
+	return func(v map[string]float64) (float64, bool) {
+		x, ok := v[key]
+		return x, ok
+	}
+}
+
+var mqttSensors = []mqttSensor{
+	{"cpu_usage", "CPU usage", "%", "", "mdi:cpu-64-bit", mqttKey("cpu.usage_percent")},
+	{"memory_used", "Memory used", "%", "", "mdi:memory", mqttKey("memory.used_percent")},
+	{"disk_used", "Disk used", "%", "", "mdi:harddisk", func(v map[string]float64) (float64, bool) {
+		total := v["storage_breakdown.total_gb"]
+		if total <= 0 {
+			return 0, false
+		}
+		return v["storage_breakdown.used_gb"] / total * 100, true
+	}},
+	{"disk_free", "Disk free", "GB", "data_size", "", mqttKey("storage_breakdown.free_gb")},
+	{"battery", "Battery", "%", "battery", "", func(v map[string]float64) (float64, bool) {
+		if v["battery.has_battery"] == 0 {
+			return 0, false
+		}
+		return v["battery.percent"], true
+	}},
+	{"cpu_temp", "CPU temperature", "°C", "temperature", "", func(v map[string]float64) (float64, bool) {
+		x := v["thermal.cpu_temp"]
+		return x, x > 0
+	}},
+	{"health_score", "Health score", "", "", "mdi:heart-pulse", mqttKey("health.health_score")},
+}
+
+// startMQTTExport publishes key metrics to an MQTT broker and, unless
+// disabled, retained Home Assistant discovery configs so the entities appear
+// without YAML. The connection is re-established with backoff when it drops.
+func startMQTTExport() {
+	cfg := GlobalConfig.Exporters.MQTT
+	if !cfg.Enabled || cfg.Broker == "" {
+		return
+	}
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultMQTTInterval
+	}
+	host, _ := os.Hostname()
+	node := mqttNodeID(host)
+	prefix := strings.TrimSuffix(cfg.TopicPrefix, "/")
+	if prefix == "" {
+		prefix = "talaria"
+	}
+	base := prefix + "/" + node
+
+	go func() {
+		backoff := 5 * time.Second
+		for {
+			started := time.Now()
+			err := runMQTT(cfg.Broker, cfg.Username, cfg.Password, cfg.ClientID, node, host, base, interval)
+			if time.Since(started) > mqttRetryMax {
+				backoff = 5 * time.Second
+			}
+			slog.Warn("MQTT connection failed", "broker", cfg.Broker, "err", err, "retry_in", backoff)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, mqttRetryMax)
+		}
+	}()
+}
+
+// mqttNodeID turns a hostname into a topic- and entity-id-safe node id.
+func mqttNodeID(host string) string {
+	host = strings.ToLower(strings.Split(host, ".")[0])
+	id := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, host)
+	if id == "" {
+		id = "mac"
+	}
+	return id
+}
+
+// runMQTT holds one broker session until it fails.
+func runMQTT(broker, user, pass, clientID, node, host, base string, interval time.Duration) error {
+	if clientID == "" {
+		clientID = "talaria-" + node
+	}
+	status := base + "/status"
+	keepAlive := max(2*interval, time.Minute)
+
+	c, err := dialMQTT(broker, mqttConnectOptions{
+		clientID:    clientID,
+		username:    user,
+		password:    pass,
+		keepAlive:   keepAlive,
+		willTopic:   status,
+		willMessage: "offline",
+	})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	slog.Info("MQTT connected", "broker", broker, "client_id", clientID)
+
+	// Nothing is subscribed, so the only inbound packets are PINGRESPs; the
+	// reader just notices when the broker goes away. Publishing every interval
+	// satisfies the keep-alive, which is at least twice as long.
+	closed := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, c)
+		if err == nil {
+			err = io.EOF
+		}
+		closed <- err
+	}()
+
+	if err := mqttPublish(c, status, []byte("online"), true); err != nil {
+		return err
+	}
+	if !GlobalConfig.Exporters.MQTT.DisableDiscovery {
+		if err := publishHADiscovery(c, node, host, base); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if m := backgroundMetrics(); m == nil {
+			// Nothing to publish yet; keep the session alive.
+			if _, err := c.Write([]byte{0xC0, 0}); err != nil {
+				return err
+			}
+		} else {
+			values := flattenMetrics(m)
+			state := make(map[string]float64, len(mqttSensors))
+			for _, s := range mqttSensors {
+				if v, ok := s.value(values); ok {
+					state[s.id] = math.Round(v*10) / 10
+				}
+			}
+			payload, _ := json.Marshal(state)
+			if err := mqttPublish(c, base+"/state", payload, false); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ticker.C:
+		case err := <-closed:
+			return err
+		}
+	}
+}
+
+// publishHADiscovery announces each sensor under
+// homeassistant/sensor/<node>/<id>/config, grouped into one device.
+func publishHADiscovery(c net.Conn, node, host, base string) error {
+	discovery := strings.TrimSuffix(GlobalConfig.Exporters.MQTT.DiscoveryPrefix, "/")
+	if discovery == "" {
+		discovery = "homeassistant"
+	}
+	hw := monitor.GetHardwareInfo()
+	device := map[string]any{
+		"identifiers":  []string{"talaria_" + node},
+		"name":         host,
+		"manufacturer": "Apple",
+		"model":        hw.ModelName,
+	}
+	for _, s := range mqttSensors {
+		cfg := map[string]any{
+			"name":               s.name,
+			"unique_id":          "talaria_" + node + "_" + s.id,
+			"object_id":          "talaria_" + node + "_" + s.id,
+			"state_topic":        base + "/state",
+			"value_template":     "{{ value_json." + s.id + " }}",
+			"availability_topic": base + "/status",
+			"state_class":        "measurement",
+			"device":             device,
+		}
+		if s.unit != "" {
+			cfg["unit_of_measurement"] = s.unit
+		}
+		if s.deviceClass != "" {
+			cfg["device_class"] = s.deviceClass
+		}
+		if s.icon != "" {
+			cfg["icon"] = s.icon
+		}
+		payload, _ := json.Marshal(cfg)
+		if err := mqttPublish(c, fmt.Sprintf("%s/sensor/%s/%s/config", discovery, node, s.id), payload, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type mqttConnectOptions struct {
+	clientID, username, password string
+	keepAlive                    time.Duration
+	willTopic, willMessage       string
+}
+
+// dialMQTT opens an MQTT 3.1.1 session. Brokers are given as
+// "tcp://host:1883", "mqtt://host" or "ssl://host:8883" ("mqtts" and "tls"
+// also select TLS).
+func dialMQTT(broker string, opts mqttConnectOptions) (net.Conn, error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid broker URL %q", broker)
+	}
+	secure := false
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		secure = true
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if secure {
+			port = "8883"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var c net.Conn
+	if secure {
+		c, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		c, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.Write(mqttConnectPacket(opts)); err != nil {
+		c.Close()
+		return nil, err
+	}
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(c, ack); err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.SetDeadline(time.Time{})
+	if ack[0] != 0x20 || ack[1] != 2 {
+		c.Close()
+		return nil, errors.New("unexpected reply to CONNECT")
+	}
+	if ack[3] != 0 {
+		c.Close()
+		return nil, fmt.Errorf("broker refused connection: %s", mqttConnackReason(ack[3]))
+	}
+	return c, nil
+}
+
+func mqttConnackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad username or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("code %d", code)
+}
+
+func mqttConnectPacket(o mqttConnectOptions) []byte {
+	flags := byte(0x02) // clean session
+	var payload []byte
+	payload = mqttAppendString(payload, o.clientID)
+	if o.willTopic != "" {
+		flags |= 0x04 | 0x20 // will, retained
+		payload = mqttAppendString(payload, o.willTopic)
+		payload = mqttAppendString(payload, o.willMessage)
+	}
+	if o.username != "" {
+		flags |= 0x80
+		payload = mqttAppendString(payload, o.username)
+		if o.password != "" {
+			flags |= 0x40
+			payload = mqttAppendString(payload, o.password)
+		}
+	}
+	keep := min(int(o.keepAlive/time.Second), 0xFFFF)
+
+	var body []byte
+	body = mqttAppendString(body, "MQTT")
+	body = append(body, 4, flags, byte(keep>>8), byte(keep))
+	body = append(body, payload...)
+	return mqttPacket(0x10, body)
+}
+
+// mqttPublish sends a QoS 0 PUBLISH.
+func mqttPublish(c net.Conn, topic string, payload []byte, retain bool) error {
+	header := byte(0x30)
+	if retain {
+		header |= 0x01
+	}
+	body := mqttAppendString(nil, topic)
+	body = append(body, payload...)
+	c.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.Write(mqttPacket(header, body))
+	return err
+}
+
+func mqttPacket(header byte, body []byte) []byte {
+	out := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+func mqttAppendString(b []byte, s string) []byte {
+	return append(append(b, byte(len(s)>>8), byte(len(s))), s...)
+}
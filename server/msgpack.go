@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MessagePack is offered alongside JSON for clients that want smaller
+// payloads and cheaper parsing. Documents are transcoded from their JSON form,
+// so both encodings carry the same keys, omissions and field names; only the
+// framing differs. JSON stays the default:
+//
+//	REST: Accept: application/msgpack (Content-Type and Vary: Accept follow)
+//	/ws:  ?encoding=msgpack, after which every message is a binary frame
+const msgpackContentType = "application/msgpack"
+
+// wantsMsgpack reports whether the Accept header asks for MessagePack ahead
+// of JSON.
+func wantsMsgpack(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		switch mt {
+		case msgpackContentType, "application/x-msgpack", "application/vnd.msgpack":
+			return true
+		case "application/json", "*/*", "application/*":
+			return false
+		}
+	}
+	return false
+}
+
+// writeEncoded writes JSON-encoded data in the encoding the request asked for.
+func writeEncoded(w http.ResponseWriter, r *http.Request, data []byte) {
+	w.Header().Add("Vary", "Accept")
+	if wantsMsgpack(r) {
+		packed, err := jsonToMsgpack(data)
+		if err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+		writeBody(w, r, msgpackContentType, packed)
+		return
+	}
+	writeBody(w, r, "application/json", data)
+}
+
+func jsonToMsgpack(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	if err := msgpackWrite(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func msgpackWrite(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			msgpackInt(buf, i)
+		} else if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			buf.WriteByte(0xcf)
+			buf.Write(binary.BigEndian.AppendUint64(nil, u))
+		} else if f, err := v.Float64(); err == nil {
+			buf.WriteByte(0xcb)
+			buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+		} else {
+			return err
+		}
+	case string:
+		msgpackHeader(buf, len(v), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		msgpackHeader(buf, len(v), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, e := range v {
+			if err := msgpackWrite(buf, e); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		msgpackHeader(buf, len(v), 0x80, 15, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			msgpackWrite(buf, k)
+			if err := msgpackWrite(buf, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+	return nil
+}
+
+func msgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 127:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.Write([]byte{0xd0, byte(i)})
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	default:
+		buf.WriteByte(0xd3)
+		buf.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
+
+// msgpackHeader writes a string, array or map header: the fix form for n up
+// to fixMax, then the 8-bit (if the family has one), 16-bit and 32-bit forms.
+func msgpackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, c8, c16, c32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case c8 != 0 && n <= math.MaxUint8:
+		buf.Write([]byte{c8, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(c16)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(c32)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
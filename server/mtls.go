@@ -0,0 +1,50 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+func configureClientAuth(tc *tls.Config) error {
+	cfg := GlobalConfig.TLS
+	data, err := os.ReadFile(cfg.ClientCA)
+	if err != nil {
+		return fmt.Errorf("tls.client_ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("tls.client_ca: no certificates in %s", cfg.ClientCA)
+	}
+	tc.ClientCAs = pool
+	switch cfg.ClientAuth {
+	case "", "require":
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return fmt.Errorf("tls.client_auth %q is not require or optional", cfg.ClientAuth)
+	}
+	return nil
+}
+
+// clientCertSession authenticates a request by its verified client
+// certificate. The role comes from auth.roles like any directory login; a
+// certificate that maps to no role is treated as no credentials at all.
+func clientCertSession(r *http.Request) *session {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	leaf := r.TLS.VerifiedChains[0][0]
+	id := identity{User: leaf.Subject.CommonName, Groups: leaf.Subject.OrganizationalUnit}
+	if id.User == "" {
+		return nil
+	}
+	role, err := roleFor(id)
+	if err != nil {
+		return nil
+	}
+	return &session{user: "cert:" + id.User, role: role, created: leaf.NotBefore, viaCert: true}
+}
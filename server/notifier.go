@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+)
+
+// Notifier is an outbound notification backend configured under
+// "notifications" in config.yml.
+type Notifier interface {
+	Name() string
+	Startup(info startupInfo) error
+}
+
+// eventNotifier is implemented by backends that also deliver alerts.
+type eventNotifier interface {
+	Notifier
+	Notify(e Event) error
+}
+
+type startupInfo struct {
+	Time      time.Time
+	Hostname  string
+	LocalURL  string
+	PublicURL string // cloudflared quick tunnel, empty if unavailable or not meant for this backend
+}
+
+func enabledNotifiers() []Notifier {
+	cfg := GlobalConfig.Notifications
+	var list []Notifier
+	if cfg.Telegram.Enabled {
+		list = append(list, &telegramNotifier{cfg: cfg.Telegram})
+	}
+	if cfg.Discord.Enabled && cfg.Discord.WebhookURL != "" {
+		list = append(list, &discordNotifier{
+			webhookURL:     cfg.Discord.WebhookURL,
+			username:       cfg.Discord.Username,
+			startupMessage: cfg.Discord.StartupMessage,
+		})
+	}
+	if cfg.Ntfy.Enabled && cfg.Ntfy.Topic != "" {
+		list = append(list, &ntfyNotifier{server: cfg.Ntfy.Server, topic: cfg.Ntfy.Topic, token: cfg.Ntfy.Token})
+	}
+	if cfg.Pushover.Enabled && cfg.Pushover.AppToken != "" && cfg.Pushover.UserKey != "" {
+		list = append(list, &pushoverNotifier{appToken: cfg.Pushover.AppToken, userKey: cfg.Pushover.UserKey})
+	}
+	if cfg.Gotify.Enabled && cfg.Gotify.Server != "" && cfg.Gotify.AppToken != "" {
+		list = append(list, &gotifyNotifier{server: cfg.Gotify.Server, appToken: cfg.Gotify.AppToken})
+	}
+	if cfg.PagerDuty.Enabled && cfg.PagerDuty.RoutingKey != "" {
+		list = append(list, &pagerDutyNotifier{routingKey: cfg.PagerDuty.RoutingKey})
+	}
+	if cfg.Opsgenie.Enabled && cfg.Opsgenie.APIKey != "" {
+		list = append(list, &opsgenieNotifier{apiKey: cfg.Opsgenie.APIKey, region: cfg.Opsgenie.Region})
+	}
+	if cfg.Email.Enabled && cfg.Email.Host != "" && len(cfg.Email.To) > 0 {
+		list = append(list, newEmailNotifier())
+	}
+	if cfg.WebPush.Enabled {
+		list = append(list, webPushNotifier{})
+	}
+	for _, wh := range cfg.Webhooks {
+		if wh.Enabled && wh.URL != "" {
+			list = append(list, &webhookNotifier{cfg: wh})
+		}
+	}
+	return list
+}
+
+// NotifyStartup announces the dashboard on every enabled backend. The public
+// tunnel is opened for Telegram, as it always was, or for everyone with
+// notifications.public_tunnel.
+func NotifyStartup() {
+	list := enabledNotifiers()
+	if len(list) == 0 {
+		return
+	}
+
+	cfg := GlobalConfig.Notifications
+	go func() {
+		port := listenPort()
+		hostname, _ := os.Hostname()
+		publicURL := ""
+		if cfg.PublicTunnel || cfg.Telegram.Enabled {
+			publicURL = startTunnel(port)
+		}
+		if publicURL != "" {
+			setInstancePublicURL(publicURL)
+		}
+		base := startupInfo{
+			Time:     time.Now(),
+			Hostname: hostname,
+			LocalURL: fmt.Sprintf("%s://%s:%d", urlScheme(), getLocalIP(), port),
+		}
+		for _, n := range list {
+			info := base
+			if _, telegram := n.(*telegramNotifier); telegram || cfg.PublicTunnel {
+				info.PublicURL = publicURL
+			}
+			if err := n.Startup(info); err != nil {
+				slog.Warn("Startup notification failed", "notifier", n.Name(), "err", err)
+			}
+		}
+	}()
+}
+
+// Event kinds that reach notifiers without a route; anything else needs a
+// critical severity or an explicit alerts.routes entry.
+var notifiableKinds = []string{"alert", "device", "public_ip"}
+
+// notifiable decides which events leave the machine without a route.
+func notifiable(e Event) bool {
+	return slices.Contains(notifiableKinds, e.Kind) || e.Severity == SeverityCritical
+}
+
+func dispatchEvent(e Event) {
+	routes, explicit := routeEvent(e)
+	if !notifiable(e) && !explicit {
+		return
+	}
+	for _, n := range enabledNotifiers() {
+		en, ok := n.(eventNotifier)
+		if !ok {
+			continue
+		}
+		if len(routes) > 0 && !slices.ContainsFunc(routes, func(r AlertRoute) bool { return r.targets(n.Name()) }) {
+			continue
+		}
+		if err := en.Notify(e); err != nil {
+			slog.Warn("Notification failed", "notifier", n.Name(), "err", err)
+		}
+	}
+}
@@ -3,17 +3,21 @@ package server
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"html"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"os/exec"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
-
-	"github.com/fatih/color"
 )
 
 func telegramGetChatID(token string) (int64, error) {
@@ -42,11 +46,11 @@ func telegramGetChatID(token string) (int64, error) {
 	return result.Result[0].Message.Chat.ID, nil
 }
 
-func telegramSend(token string, chatID int64, text string, localURL string, publicURL string) error {
+func telegramSend(token string, chatID string, text string, localURL string, publicURL string) error {
 	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", token)
 
 	form := url.Values{
-		"chat_id":    {fmt.Sprintf("%d", chatID)},
+		"chat_id":    {chatID},
 		"text":       {text},
 		"parse_mode": {"HTML"},
 	}
@@ -89,81 +93,165 @@ func getLocalIP() string {
 	return conn.LocalAddr().(*net.UDPAddr).IP.String()
 }
 
-func NotifyTelegramStart() {
-	if !GlobalConfig.Telegram.Enabled {
-		return
+// startTunnel (re)starts a cloudflared quick tunnel and waits briefly for its
+// public URL; it returns "" if cloudflared is missing or slow.
+func startTunnel(port int) string {
+	origin := fmt.Sprintf("%s://localhost:%d", urlScheme(), port)
+	exec.Command("pkill", "-f", "cloudflared tunnel --url "+origin).Run()
+
+	args := []string{"tunnel", "--url", origin}
+	if TLSEnabled() {
+		// The certificate is for the public name, not localhost.
+		args = append(args, "--no-tls-verify")
 	}
+	cmd := exec.Command("cloudflared", args...)
+	stderr, err := cmd.StderrPipe()
 
-	go func() {
-		chatID := GlobalConfig.Telegram.ChatID
-		// Automatically fetch Chat ID if enabled but not configured
-		if chatID == 0 {
-			fetchedID, err := telegramGetChatID(GlobalConfig.Telegram.BotToken)
-			if err != nil {
-				color.New(color.FgYellow).Printf("  [TELEGRAM] System notify skipped: %v\n", err)
-				return
-			}
-			chatID = fetchedID
-			fmt.Print("  ")
-			color.New(color.FgHiCyan, color.Bold).Print("[TELEGRAM]")
-			color.New(color.FgHiBlack).Printf(" Chat ID automatically resolved to: ")
-			color.New(color.FgGreen).Printf("%d\n", chatID)
-			color.New(color.FgHiBlack).Printf("             Please save this in config.yml for next time.\n")
-		}
+	publicURL := ""
+	if err == nil {
+		if err := cmd.Start(); err == nil {
 
-		port := GlobalConfig.Server.Port
-		ip := getLocalIP()
-		localURL := fmt.Sprintf("http://%s:%d", ip, port)
-		now := time.Now().Format("02/01/2006 15:04")
-
-		exec.Command("pkill", "-f", fmt.Sprintf("cloudflared tunnel --url http://localhost:%d", port)).Run()
-
-		cmd := exec.Command("cloudflared", "tunnel", "--url", fmt.Sprintf("http://localhost:%d", port))
-		stderr, err := cmd.StderrPipe()
-
-		publicURL := ""
-		if err == nil {
-			if err := cmd.Start(); err == nil {
-
-				urlChan := make(chan string, 1)
-				go func() {
-					scanner := bufio.NewScanner(stderr)
-					re := regexp.MustCompile(`https://[a-zA-Z0-9-]+\.trycloudflare\.com`)
-					for scanner.Scan() {
-						line := scanner.Text()
-						if match := re.FindString(line); match != "" {
-							urlChan <- match
-							break
-						}
+			urlChan := make(chan string, 1)
+			go func() {
+				scanner := bufio.NewScanner(stderr)
+				re := regexp.MustCompile(`https://[a-zA-Z0-9-]+\.trycloudflare\.com`)
+				for scanner.Scan() {
+					line := scanner.Text()
+					if match := re.FindString(line); match != "" {
+						urlChan <- match
+						break
 					}
-				}()
+				}
+			}()
 
-				select {
-				case publicURL = <-urlChan:
+			select {
+			case publicURL = <-urlChan:
 
-				case <-time.After(15 * time.Second):
+			case <-time.After(15 * time.Second):
 
-				}
 			}
 		}
+	}
+	return publicURL
+}
 
-		msgTemplate := GlobalConfig.Telegram.StartupMessage
-		if msgTemplate == "" {
-			msgTemplate = "[%s] Talaria is on Steroids 🔥"
-		}
+type telegramNotifier struct {
+	cfg TelegramConfig
+}
 
-		verbCount := strings.Count(msgTemplate, "%s")
-		var msg string
-		if verbCount >= 3 {
-			msg = fmt.Sprintf(msgTemplate, now, publicURL, localURL)
-		} else if verbCount == 1 {
-			msg = fmt.Sprintf(msgTemplate, now)
-		} else {
-			msg = msgTemplate
+func (t *telegramNotifier) Name() string { return "telegram" }
+
+// resolvedTelegramChatID remembers an auto-detected chat so alerts don't poll
+// getUpdates every time.
+var resolvedTelegramChatID atomic.Int64
+
+func (t *telegramNotifier) chatID() (int64, error) {
+	if t.cfg.ChatID != 0 {
+		return t.cfg.ChatID, nil
+	}
+	if id := resolvedTelegramChatID.Load(); id != 0 {
+		return id, nil
+	}
+	id, err := telegramGetChatID(t.cfg.BotToken)
+	if err != nil {
+		return 0, err
+	}
+	resolvedTelegramChatID.Store(id)
+	return id, nil
+}
+
+// destinations returns the chats that take messages of kind ("startup",
+// "alerts", "reports"), falling back to the single chat_id when no chats are
+// listed.
+func (t *telegramNotifier) destinations(kind string) ([]string, error) {
+	if len(t.cfg.Chats) > 0 {
+		var ids []string
+		for _, c := range t.cfg.Chats {
+			if c.ID != "" && (len(c.Types) == 0 || slices.Contains(c.Types, kind)) {
+				ids = append(ids, c.ID)
+			}
 		}
+		return ids, nil
+	}
+	id, err := t.chatID()
+	if err != nil {
+		return nil, err
+	}
+	return []string{strconv.FormatInt(id, 10)}, nil
+}
 
-		if err := telegramSend(GlobalConfig.Telegram.BotToken, chatID, msg, localURL, publicURL); err != nil {
-			log.Printf("Telegram notify failed: %v", err)
+func (t *telegramNotifier) send(ids []string, text, localURL, publicURL string) error {
+	var errs []error
+	for _, id := range ids {
+		if err := telegramSend(t.cfg.BotToken, id, text, localURL, publicURL); err != nil {
+			errs = append(errs, fmt.Errorf("chat %s: %w", id, err))
 		}
-	}()
+	}
+	return errors.Join(errs...)
+}
+
+func (t *telegramNotifier) Startup(info startupInfo) error {
+	// Automatically fetch Chat ID if enabled but not configured
+	ids, err := t.destinations("startup")
+	if err != nil {
+		slog.Warn("Telegram startup notification skipped", "err", err)
+		return nil
+	}
+	if len(t.cfg.Chats) == 0 && t.cfg.ChatID == 0 {
+		slog.Info("Telegram chat ID resolved automatically; save it in config.yml", "chat_id", ids[0])
+	}
+
+	now := info.Time.Format("02/01/2006 15:04")
+
+	msgTemplate := t.cfg.StartupMessage
+	if msgTemplate == "" {
+		msgTemplate = "[%s] Talaria is on Steroids 🔥"
+	}
+
+	verbCount := strings.Count(msgTemplate, "%s")
+	var msg string
+	if verbCount >= 3 {
+		msg = fmt.Sprintf(msgTemplate, now, info.PublicURL, info.LocalURL)
+	} else if verbCount == 1 {
+		msg = fmt.Sprintf(msgTemplate, now)
+	} else {
+		msg = msgTemplate
+	}
+
+	return t.send(ids, msg, info.LocalURL, info.PublicURL)
+}
+
+func (t *telegramNotifier) Notify(e Event) error {
+	ids, err := t.destinations("alerts")
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	icon := "ℹ️"
+	switch {
+	case e.Fields["state"] == "resolved":
+		icon = "✅"
+	case e.Severity == SeverityCritical:
+		icon = "🔴"
+	case e.Severity == SeverityWarning:
+		icon = "🟠"
+	}
+
+	hostname, _ := os.Hostname()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s <b>%s</b>\n%s", icon, html.EscapeString(e.Title), html.EscapeString(e.Message))
+	if e.Fields["value"] != "" {
+		fmt.Fprintf(&b, "\nValue: <code>%s</code>", html.EscapeString(e.Fields["value"]))
+	}
+	fmt.Fprintf(&b, "\n<i>%s · %s</i>", html.EscapeString(hostname), time.Unix(e.Time, 0).Format("02/01/2006 15:04"))
+
+	return t.send(ids, b.String(), "", "")
+}
+
+func (t *telegramNotifier) Report(title, text string) error {
+	ids, err := t.destinations("reports")
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	return t.send(ids, "📊 <b>"+html.EscapeString(title)+"</b>\n"+html.EscapeString(text), "", "")
 }
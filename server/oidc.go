@@ -0,0 +1,418 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	oidcStateCookie = "talaria_oidc_state"
+	oidcStateTTL    = 10 * time.Minute
+	oidcClockSkew   = time.Minute
+	maxOIDCBody     = 1 << 20
+)
+
+type oidcProvider struct {
+	Issuer        string `json:"issuer"`
+	AuthEndpoint  string `json:"authorization_endpoint"`
+	TokenEndpoint string `json:"token_endpoint"`
+	JWKSURI       string `json:"jwks_uri"`
+}
+
+type oidcPending struct {
+	verifier string
+	nonce    string
+	created  time.Time
+}
+
+var (
+	oidcDiscovery   *oidcProvider
+	oidcDiscoveryAt time.Time
+	oidcKeys        = make(map[string]crypto.PublicKey) // kid → key
+	oidcKeysAt      time.Time
+	oidcMu          sync.Mutex
+
+	oidcStates   = make(map[string]oidcPending) // state → pending login
+	oidcStatesMu sync.Mutex
+
+	oidcClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+func oidcGetJSON(u string, v interface{}) error {
+	resp, err := oidcClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxOIDCBody)).Decode(v)
+}
+
+func oidcProviderConfig() (*oidcProvider, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+
+	if oidcDiscovery != nil && time.Since(oidcDiscoveryAt) < time.Hour {
+		return oidcDiscovery, nil
+	}
+	issuer := strings.TrimSuffix(GlobalConfig.Auth.OIDC.Issuer, "/")
+	var p oidcProvider
+	if err := oidcGetJSON(issuer+"/.well-known/openid-configuration", &p); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(p.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", p.Issuer, issuer)
+	}
+	oidcDiscovery, oidcDiscoveryAt = &p, time.Now()
+	return oidcDiscovery, nil
+}
+
+// oidcKey returns the signing key for kid, refetching the JWKS (at most once
+// a minute) when the provider has rotated keys.
+func oidcKey(p *oidcProvider, kid string) (crypto.PublicKey, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+
+	if k, ok := oidcKeys[kid]; ok {
+		return k, nil
+	}
+	if time.Since(oidcKeysAt) < time.Minute {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	oidcKeysAt = time.Now()
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := oidcGetJSON(p.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil || len(e) > 4 {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if err1 != nil || err2 != nil || k.Crv != "P-256" {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	oidcKeys = keys
+
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// verifyIDToken checks the signature (RS256 or ES256) and standard claims of
+// a compact JWT and returns its claims.
+func verifyIDToken(p *oidcProvider, raw, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id_token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	hb, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(hb, &header) != nil {
+		return nil, errors.New("malformed id_token header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed id_token signature")
+	}
+
+	key, err := oidcKey(p, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" || rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) != nil {
+			return nil, errors.New("invalid id_token signature")
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(sig) != 64 ||
+			!ecdsa.Verify(k, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			return nil, errors.New("invalid id_token signature")
+		}
+	default:
+		return nil, errors.New("unsupported signing key")
+	}
+
+	pb, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed id_token payload")
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(pb, &claims); err != nil {
+		return nil, errors.New("malformed id_token payload")
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != strings.TrimSuffix(p.Issuer, "/") {
+		return nil, errors.New("id_token issuer mismatch")
+	}
+	if !oidcAudienceOK(claims["aud"], GlobalConfig.Auth.OIDC.ClientID) {
+		return nil, errors.New("id_token audience mismatch")
+	}
+	exp, _ := claims["exp"].(float64)
+	if time.Now().Add(-oidcClockSkew).After(time.Unix(int64(exp), 0)) {
+		return nil, errors.New("id_token expired")
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+	return claims, nil
+}
+
+func oidcAudienceOK(aud interface{}, clientID string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == clientID
+	case []interface{}:
+		for _, v := range a {
+			if s, _ := v.(string); s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func oidcClaimStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	cfg := GlobalConfig.Auth.OIDC
+	if !cfg.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+	p, err := oidcProviderConfig()
+	if err != nil {
+		slog.Error("OIDC discovery failed", "err", err)
+		http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	state := generateToken(16)
+	pending := oidcPending{verifier: generateToken(32), nonce: generateToken(16), created: time.Now()}
+	oidcStatesMu.Lock()
+	for k, v := range oidcStates {
+		if time.Since(v.created) > oidcStateTTL {
+			delete(oidcStates, k)
+		}
+	}
+	oidcStates[state] = pending
+	oidcStatesMu.Unlock()
+
+	// The callback is a cross-site navigation, so this cookie must be Lax.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/auth/oidc/",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email", "groups"}
+	}
+	challenge := sha256.Sum256([]byte(pending.verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {cfg.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {pending.nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(p.AuthEndpoint, "?") {
+		sep = "&"
+	}
+	http.Redirect(w, r, p.AuthEndpoint+sep+q.Encode(), http.StatusFound)
+}
+
+func handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	cfg := GlobalConfig.Auth.OIDC
+	if !cfg.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+	ip := getRealIP(r)
+	if _, _, allowed := checkRateLimit(ip); !allowed {
+		http.Error(w, "Too many attempts. Try again later.", http.StatusTooManyRequests)
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "Sign-in failed: "+e, http.StatusUnauthorized)
+		return
+	}
+	state := q.Get("state")
+	c, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || c.Value != state {
+		http.Error(w, "Invalid sign-in state", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: "/auth/oidc/", MaxAge: -1})
+
+	oidcStatesMu.Lock()
+	pending, ok := oidcStates[state]
+	delete(oidcStates, state)
+	oidcStatesMu.Unlock()
+	if !ok || time.Since(pending.created) > oidcStateTTL {
+		http.Error(w, "Sign-in expired, please try again", http.StatusBadRequest)
+		return
+	}
+
+	p, err := oidcProviderConfig()
+	if err != nil {
+		http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+	claims, err := oidcExchange(p, q.Get("code"), pending)
+	if err != nil {
+		recordFailedAttempt(ip)
+		slog.Warn("OIDC login failed", "ip", ip, "err", err)
+		http.Error(w, "Sign-in failed", http.StatusUnauthorized)
+		return
+	}
+
+	id := identity{User: oidcUsername(claims, cfg.UsernameClaim)}
+	groupsClaim := cfg.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	id.Groups = oidcClaimStrings(claims[groupsClaim])
+
+	role, err := roleFor(id)
+	if err != nil {
+		recordFailedAttempt(ip)
+		slog.Warn("OIDC user has no Talaria role", "user", id.User)
+		http.Error(w, "Account is not allowed to use Talaria", http.StatusForbidden)
+		return
+	}
+
+	clearAttempts(ip)
+	setSessionCookies(w, r, createSession(id.User, role))
+	auditLog(r, "oidc-login", map[string]string{"user": id.User, "role": role}, "ok")
+
+	// Redirect from our own page so the Strict session cookie is sent.
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprint(w, `<!doctype html><meta http-equiv="refresh" content="0;url=/"><a href="/">Continue</a>`)
+}
+
+func oidcExchange(p *oidcProvider, code string, pending oidcPending) (map[string]interface{}, error) {
+	cfg := GlobalConfig.Auth.OIDC
+	if code == "" {
+		return nil, errors.New("missing code")
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {cfg.RedirectURL},
+		"code_verifier": {pending.verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+
+	resp, err := oidcClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var tok struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOIDCBody)).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("token endpoint: %s", resp.Status)
+	}
+	if tok.Error != "" || tok.IDToken == "" {
+		return nil, fmt.Errorf("token endpoint: %s %s", resp.Status, tok.Error)
+	}
+	return verifyIDToken(p, tok.IDToken, pending.nonce)
+}
+
+func oidcUsername(claims map[string]interface{}, claim string) string {
+	for _, k := range []string{claim, "preferred_username", "email", "sub"} {
+		if s, _ := claims[k].(string); k != "" && s != "" {
+			if len(s) > 128 {
+				s = s[:128]
+			}
+			return s
+		}
+	}
+	return "oidc-user"
+}
@@ -0,0 +1,317 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"talaria/monitor"
+)
+
+// apiOperation documents one method of one route for /api/openapi.json.
+// Request and response bodies are given as Go values whose types are turned
+// into schemas, so the document follows the structs it describes.
+type apiOperation struct {
+	method   string
+	path     string
+	summary  string
+	auth     string // "session" (default), "token", "session+token" or "public"
+	params   []apiParam
+	body     interface{} // JSON request body
+	response interface{} // JSON 200 response; nil for none or non-JSON
+	produces string      // content type of a non-JSON response
+}
+
+type apiParam struct {
+	name     string
+	desc     string
+	required bool
+}
+
+type metricsRef struct{} // stands for the shared Metrics schema
+
+var metricsFilterParams = []apiParam{
+	{"include", "Comma-separated sections to collect, e.g. cpu,memory,disks", false},
+	{"exclude", "Comma-separated sections to leave out", false},
+}
+
+var apiOperations = []apiOperation{
+	{method: "POST", path: "/api/login", summary: "Start a session; sets the session and CSRF cookies", auth: "public",
+		body: struct {
+			Username string `json:"username" desc:"Empty for the built-in password login"`
+			Password string `json:"password"`
+		}{}},
+	{method: "POST", path: "/api/logout", summary: "End the session", auth: "public"},
+	{method: "GET", path: "/api/auth/check", summary: "Whether the session cookie is valid", auth: "public"},
+	{method: "GET", path: "/auth/oidc/login", summary: "Redirect to the OIDC provider", auth: "public"},
+	{method: "GET", path: "/auth/oidc/callback", summary: "OIDC redirect target; starts a session", auth: "public"},
+
+	{method: "GET", path: "/api/metrics", summary: "Current metrics payload", params: metricsFilterParams, response: metricsRef{}},
+	{method: "GET", path: "/api/stream", summary: "The /ws payload as Server-Sent Events, for proxies that block WebSockets",
+		params: []apiParam{
+			{"topics", "Comma-separated sections, e.g. cpu,memory", false},
+			{"rate", "Milliseconds between updates, 250-10000 (default 1000)", false},
+		},
+		produces: "text/event-stream"},
+	{method: "GET", path: "/api/export", summary: "Current metrics payload as a download", params: metricsFilterParams, response: metricsRef{}},
+	{method: "GET", path: "/api/schema", summary: "Payload field list, or a JSON Schema with format=jsonschema",
+		params: []apiParam{{"format", "Empty or jsonschema", false}}},
+	{method: "GET", path: "/api/openapi.json", summary: "This document"},
+	{method: "GET", path: "/api/docs", summary: "Interactive explorer for this document", auth: "public", produces: "text/html"},
+	{method: "GET", path: "/api/hardware", summary: "Hardware inventory", response: monitor.HardwareInfo{}},
+	{method: "GET", path: "/api/clients", summary: "Connected dashboards", response: []ClientInfo{}},
+
+	{method: "GET", path: "/api/history", summary: "Downsampled history series with sleep/restart gaps",
+		params: []apiParam{
+			{"metric", "Key or key prefix, e.g. cpu", true},
+			{"from", "Unix ms, default 24h ago", false},
+			{"to", "Unix ms, default now", false},
+			{"step", "Bucket width in ms or as a duration like 5m", false},
+			{"agg", "avg (default), min or max", false},
+			{"host", "A federated host", false},
+		},
+		response: struct {
+			From   int64         `json:"from"`
+			To     int64         `json:"to"`
+			Step   int64         `json:"step"`
+			Series []querySeries `json:"series"`
+			Gaps   []historyGap  `json:"gaps"`
+		}{}},
+	{method: "GET", path: "/api/history/export", summary: "Raw samples as NDJSON, optionally following new ones", auth: "session+token",
+		params:   []apiParam{{"cursor", "Unix ms; samples after it", false}, {"follow", "1 keeps the stream open", false}},
+		produces: "application/x-ndjson"},
+	{method: "GET", path: "/api/graphql", summary: "GraphQL query over metrics, history, events, boots and fleet",
+		params: gqlQueryParams, response: gqlResult{}},
+	{method: "POST", path: "/api/graphql", summary: "GraphQL query; viewers may use it too",
+		body: gqlRequest{}, response: gqlResult{}},
+	{method: "GET", path: "/api/check", summary: "One metric value for monitoring checks", auth: "session+token",
+		params: []apiParam{{"metric", "Flattened key", true}}},
+	{method: "GET", path: "/api/boots", summary: "Per-boot stability summaries", response: []bootSummary{}},
+	{method: "GET", path: "/api/uptime/calendar", summary: "Daily availability for a month",
+		params: []apiParam{{"month", "YYYY-MM, default this month", false}}, response: []uptimeDay{}},
+	{method: "GET", path: "/api/anomaly", summary: "Anomaly detector baselines"},
+	{method: "GET", path: "/api/events", summary: "Recent events",
+		params: []apiParam{{"since", "Only events with a larger ID", false}}, response: []Event{}},
+
+	{method: "GET", path: "/api/alerts", summary: "Alert rules and their state"},
+	{method: "POST", path: "/api/alerts/test", summary: "Replay alert rules against recorded history",
+		body: struct {
+			Rules []AlertRule `json:"rules"`
+		}{}},
+	{method: "GET", path: "/api/digest", summary: "Preview the daily digest"},
+	{method: "POST", path: "/api/digest", summary: "Send the daily digest now"},
+	{method: "GET", path: "/api/reports/weekly", summary: "Weekly report as HTML", produces: "text/html"},
+	{method: "POST", path: "/api/reports/weekly", summary: "Send the weekly report now"},
+	{method: "GET", path: "/api/report", summary: "Full system report download",
+		params: []apiParam{{"format", "html (default) or pdf", false}}, produces: "text/html"},
+
+	{method: "GET", path: "/api/snapshots", summary: "List snapshots", response: []snapshotInfo{}},
+	{method: "POST", path: "/api/snapshots", summary: "Capture a named snapshot",
+		body: struct {
+			Name string `json:"name"`
+		}{}, response: snapshotInfo{}},
+	{method: "GET", path: "/api/snapshots/{name}", summary: "One snapshot", response: metricsSnapshot{}},
+	{method: "DELETE", path: "/api/snapshots/{name}", summary: "Delete a snapshot"},
+	{method: "GET", path: "/api/snapshots/diff", summary: "Compare two snapshots, or one with now",
+		params: []apiParam{{"from", "Snapshot name", true}, {"to", "Snapshot name, default now", false}}, response: snapshotDiff{}},
+
+	{method: "POST", path: "/api/kill", summary: "Terminate a process", params: []apiParam{{"pid", "Process ID", true}}},
+	{method: "POST", path: "/api/process/batch", summary: "Apply actions to several processes",
+		body: struct {
+			Operations []processOp `json:"operations"`
+		}{}, response: []processOpResult{}},
+	{method: "POST", path: "/api/process/suspend", summary: "Stop a process, resuming it after a while",
+		params: []apiParam{{"pid", "Process ID", true}, {"duration", "Seconds until it resumes", false}}},
+	{method: "POST", path: "/api/process/resume", summary: "Resume a suspended process", params: []apiParam{{"pid", "Process ID", true}}},
+	{method: "GET", path: "/api/process/suspended", summary: "Processes suspended by Talaria", response: []suspendedProc{}},
+
+	{method: "GET", path: "/api/connections", summary: "Active and listening connections, filtered and paged",
+		params: []apiParam{
+			{"state", "established, listen, or both comma-separated", false},
+			{"process", "Substring of the process name", false},
+			{"pid", "Process ID", false},
+			{"port", "Local or remote port", false},
+			{"offset", "Skip this many entries of each list", false},
+			{"limit", "Entries per list, at most 1000", false},
+			{"rdns", "1 adds remote_host", false},
+			{"geo", "1 adds remote_country from whois", false},
+		},
+		response: connectionsPage{}},
+	{method: "GET", path: "/api/connections/history", summary: "Hosts each process has talked to",
+		params: []apiParam{{"process", "Process name", false}}},
+	{method: "GET", path: "/api/lan/devices", summary: "Devices seen on the local network", response: []lanDevice{}},
+	{method: "GET", path: "/api/listeners", summary: "Listening ports and whether each is known"},
+	{method: "POST", path: "/api/listeners/ack", summary: "Accept new listeners into the baseline",
+		body: struct {
+			Keys []string `json:"keys"`
+			All  bool     `json:"all"`
+		}{}},
+	{method: "POST", path: "/api/listeners/baseline", summary: "Forget the baseline and start learning again"},
+	{method: "POST", path: "/api/flushdns", summary: "Flush the DNS cache"},
+	{method: "GET", path: "/api/lookup", summary: "Reverse DNS, geolocation and optional whois for an address",
+		params: []apiParam{{"q", "IP address or host", true}, {"whois", "1 adds whois", false}}, response: LookupResult{}},
+	{method: "GET", path: "/api/diag/ping", summary: "Ping a host", params: []apiParam{{"host", "", true}, {"count", "", false}}},
+	{method: "GET", path: "/api/diag/traceroute", summary: "Traceroute to a host", params: []apiParam{{"host", "", true}}},
+	{method: "GET", path: "/api/diag/port", summary: "Check a TCP port", params: []apiParam{{"host", "", true}, {"port", "", true}}},
+	{method: "GET", path: "/api/speedtest", summary: "Past speed test results", response: []SpeedTestResult{}},
+	{method: "POST", path: "/api/speedtest", summary: "Run a speed test now", response: SpeedTestResult{}},
+
+	{method: "GET", path: "/api/focus", summary: "Focus / Do Not Disturb state"},
+	{method: "POST", path: "/api/focus", summary: "Turn Focus on or off", params: []apiParam{{"enabled", "true or false", true}}},
+	{method: "GET", path: "/api/screenshot", summary: "Screen capture",
+		params: []apiParam{{"display", "Display number", false}, {"format", "png or jpeg", false}}, produces: "image/png"},
+	{method: "GET", path: "/api/config", summary: "Effective configuration, secrets removed"},
+
+	{method: "GET", path: "/api/fleet", summary: "Federated hosts and their newest values", response: []fleetHost{}},
+	{method: "POST", path: "/api/federation/push", summary: "Samples from an agent (federation.tokens or an admin client certificate)", auth: "token",
+		body: federationPush{}},
+	{method: "GET", path: "/api/push", summary: "Current custom gauges", auth: "session+token", response: []customGauge{}},
+	{method: "POST", path: "/api/push", summary: "Submit custom gauges, one object or an array (push_gateway.tokens)", auth: "token",
+		body: []customGauge{}},
+
+	{method: "GET", path: "/api/push/key", summary: "Web Push VAPID public key"},
+	{method: "POST", path: "/api/push/subscribe", summary: "Register a Web Push subscription"},
+	{method: "POST", path: "/api/push/unsubscribe", summary: "Remove a Web Push subscription"},
+
+	{method: "GET", path: "/api/admin/logging", summary: "Log level and traced collectors"},
+	{method: "POST", path: "/api/admin/logging", summary: "Change the log level, optionally for a while",
+		body: struct {
+			Level      string   `json:"level"`
+			Collectors []string `json:"collectors"`
+			Duration   string   `json:"duration"`
+		}{}},
+	{method: "GET", path: "/api/admin/backup", summary: "Config and history as a tar.gz",
+		params: []apiParam{{"exclude_secrets", "1 strips passwords, tokens and keys", false}}, produces: "application/gzip"},
+	{method: "POST", path: "/api/admin/restore", summary: "Restore a backup archive (request body) and restart"},
+	{method: "GET", path: "/api/debug/runtime", summary: "Goroutine, heap and GC statistics; needs debug.enabled", response: runtimeStats{}},
+	{method: "GET", path: "/api/debug/pprof/", summary: "net/http/pprof index; needs debug.enabled", produces: "text/html"},
+	{method: "GET", path: "/api/audit", summary: "Privileged actions, newest first; admins only",
+		params: []apiParam{
+			{"since", "Unix seconds", false},
+			{"action", "Action prefix, e.g. process.", false},
+			{"user", "Acting user", false},
+			{"limit", "Default 200, max 5000", false},
+		},
+		response: []auditEntry{}},
+	{method: "POST", path: "/api/restart-self", summary: "Restart Talaria",
+		body: struct {
+			Confirm bool `json:"confirm"`
+		}{}},
+}
+
+var (
+	openAPIDoc     []byte
+	openAPIDocOnce sync.Once
+)
+
+func buildOpenAPI() map[string]interface{} {
+	paths := make(map[string]map[string]interface{})
+	for _, op := range apiOperations {
+		path := op.path
+		if strings.HasPrefix(path, "/api/") {
+			path = apiV1Prefix + strings.TrimPrefix(path, "/api")
+		}
+		item := paths[path]
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+
+		o := map[string]interface{}{"summary": op.summary}
+		if d := findDeprecation(path); d != nil {
+			o["deprecated"] = true
+		}
+		switch op.auth {
+		case "public":
+			o["security"] = []interface{}{}
+		case "token":
+			o["security"] = []interface{}{map[string]interface{}{"bearer": []string{}}}
+		case "session+token":
+			o["security"] = []interface{}{
+				map[string]interface{}{"session": []string{}},
+				map[string]interface{}{"bearer": []string{}},
+			}
+		}
+
+		var params []interface{}
+		if strings.Contains(op.path, "{name}") {
+			params = append(params, map[string]interface{}{"name": "name", "in": "path", "required": true, "schema": map[string]string{"type": "string"}})
+		}
+		for _, p := range op.params {
+			param := map[string]interface{}{"name": p.name, "in": "query", "required": p.required, "schema": map[string]string{"type": "string"}}
+			if p.desc != "" {
+				param["description"] = p.desc
+			}
+			params = append(params, param)
+		}
+		if len(params) > 0 {
+			o["parameters"] = params
+		}
+		if op.body != nil {
+			o["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": openAPISchema(op.body)}},
+			}
+		}
+
+		ok := map[string]interface{}{"description": "OK"}
+		switch {
+		case op.response != nil:
+			ok["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": openAPISchema(op.response)}}
+		case op.produces != "":
+			ok["content"] = map[string]interface{}{op.produces: map[string]interface{}{}}
+		}
+		o["responses"] = map[string]interface{}{
+			"200": ok,
+			"401": map[string]interface{}{"description": "Not signed in, or the token is not accepted"},
+			"403": map[string]interface{}{"description": "Admin role or CSRF token required"},
+		}
+		item[strings.ToLower(op.method)] = o
+	}
+
+	metrics := jsonSchema(reflect.TypeOf(AllMetrics{}))
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":   "Talaria",
+			"version": metricsSchemaVersion,
+			"description": "Paths without /v1 are deprecated aliases of the /api/v1 ones. " +
+				"Session endpoints need the talaria_session cookie from POST /api/login; " +
+				"writes also need the talaria_csrf cookie value in an X-CSRF-Token header, and most need the admin role. " +
+				"Live metrics stream over the /ws WebSocket as the same Metrics payload. " +
+				"/api/metrics and /api/history answer in MessagePack for Accept: application/msgpack, and /ws?encoding=msgpack sends binary frames.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{"Metrics": metrics},
+			"securitySchemes": map[string]interface{}{
+				"session": map[string]interface{}{"type": "apiKey", "in": "cookie", "name": sessionCookie},
+				"bearer":  map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"session": []string{}}},
+	}
+}
+
+func openAPISchema(v interface{}) map[string]interface{} {
+	if _, ok := v.(metricsRef); ok {
+		return map[string]interface{}{"$ref": "#/components/schemas/Metrics"}
+	}
+	return jsonSchema(reflect.TypeOf(v))
+}
+
+// handleAPIDocs serves the API explorer. The page holds no data itself; it
+// loads the OpenAPI document and calls endpoints with the visitor's session.
+func handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	setSecurityHeaders(w, r)
+	http.ServeFileFS(w, r, staticFiles, "static/docs.html")
+}
+
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	openAPIDocOnce.Do(func() {
+		openAPIDoc, _ = json.Marshal(buildOpenAPI())
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDoc)
+}
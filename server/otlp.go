@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultOTLPInterval = 30 * time.Second
+
+// startOTLPExport posts each tick's metrics as OTLP gauges using the
+// OTLP/HTTP JSON encoding, which collectors accept on :4318/v1/metrics.
+// gRPC and protobuf would pull in the OpenTelemetry SDK, so they are not
+// offered; point a collector's otlp receiver at the HTTP port instead.
+func startOTLPExport() {
+	cfg := GlobalConfig.Exporters.OTLP
+	if !cfg.Enabled || cfg.Endpoint == "" {
+		return
+	}
+	url := strings.TrimSuffix(cfg.Endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/metrics") {
+		url += "/v1/metrics"
+	}
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultOTLPInterval
+	}
+	resource := otlpResource()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		failing := false
+		for range ticker.C {
+			m := backgroundMetrics()
+			if m == nil {
+				continue
+			}
+			body, err := json.Marshal(otlpPayload(resource, flattenMetrics(m), m.Timestamp, cfg.Metrics))
+			if err != nil {
+				continue
+			}
+			err = otlpPost(url, cfg.Headers, body)
+			switch {
+			case err != nil && !failing:
+				slog.Warn("OTLP export failing", "url", url, "err", err)
+			case err == nil && failing:
+				slog.Info("OTLP export recovered", "url", url)
+			}
+			failing = err != nil
+		}
+	}()
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func otlpAttr(key, value string) otlpAttribute {
+	a := otlpAttribute{Key: key}
+	a.Value.StringValue = value
+	return a
+}
+
+// otlpResource follows the host.*, os.* and service.* semantic conventions.
+// Configured attributes are added last and may override the defaults.
+func otlpResource() []otlpAttribute {
+	host, _ := os.Hostname()
+	attrs := map[string]string{
+		"service.name": "talaria",
+		"host.name":    host,
+		"host.arch":    runtime.GOARCH,
+		"os.type":      runtime.GOOS,
+	}
+	if m := latestMetrics(); m != nil && m.System.OSVersion != "" {
+		attrs["os.description"] = m.System.OSVersion
+	}
+	for k, v := range GlobalConfig.Exporters.OTLP.ResourceAttributes {
+		attrs[k] = v
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]otlpAttribute, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, otlpAttr(k, attrs[k]))
+	}
+	return out
+}
+
+type otlpDataPoint struct {
+	TimeUnixNano string  `json:"timeUnixNano"`
+	AsDouble     float64 `json:"asDouble"`
+}
+
+type otlpMetric struct {
+	Name  string `json:"name"`
+	Gauge struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	} `json:"gauge"`
+}
+
+// otlpPayload builds an ExportMetricsServiceRequest with one gauge per
+// flattened key, named "talaria.<key>".
+func otlpPayload(resource []otlpAttribute, values map[string]float64, tsMillis int64, prefixes []string) map[string]any {
+	ts := strconv.FormatInt(tsMillis*int64(time.Millisecond), 10)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if metricSelected(k, prefixes) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	metrics := make([]otlpMetric, 0, len(keys))
+	for _, k := range keys {
+		m := otlpMetric{Name: "talaria." + k}
+		m.Gauge.DataPoints = []otlpDataPoint{{TimeUnixNano: ts, AsDouble: values[k]}}
+		metrics = append(metrics, m)
+	}
+	return map[string]any{
+		"resourceMetrics": []any{map[string]any{
+			"resource": map[string]any{"attributes": resource},
+			"scopeMetrics": []any{map[string]any{
+				"scope":   map[string]string{"name": "talaria"},
+				"metrics": metrics,
+			}},
+		}},
+	}
+}
+
+func otlpPost(url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
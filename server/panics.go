@@ -0,0 +1,69 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const maxRecordedPanics = 20
+
+// panicRecord is one recovered panic, kept in data/panics.ndjson so that
+// "talaria report-bug" can include it after the fact.
+type panicRecord struct {
+	Time  int64  `json:"time"`
+	Where string `json:"where"`
+	Value string `json:"value"`
+	Stack string `json:"stack"`
+}
+
+var panicsMu sync.Mutex
+
+func recordPanic(where string, r interface{}) {
+	slog.Error("Panic", "where", where, "value", r)
+	rec := panicRecord{Time: time.Now().Unix(), Where: where, Value: fmt.Sprint(r), Stack: string(debug.Stack())}
+	if GlobalConfig == nil {
+		return
+	}
+
+	panicsMu.Lock()
+	defer panicsMu.Unlock()
+	records := recentPanics()
+	if len(records) >= maxRecordedPanics {
+		records = records[len(records)-maxRecordedPanics+1:]
+	}
+	records = append(records, rec)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, p := range records {
+		enc.Encode(p)
+	}
+	if err := os.WriteFile(dataPath("panics.ndjson"), buf.Bytes(), 0600); err != nil {
+		slog.Error("Failed to record panic", "err", err)
+	}
+}
+
+// recentPanics returns the recorded panics, oldest first.
+func recentPanics() []panicRecord {
+	f, err := os.Open(dataPath("panics.ndjson"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var records []panicRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var p panicRecord
+		if json.Unmarshal(scanner.Bytes(), &p) == nil {
+			records = append(records, p)
+		}
+	}
+	return records
+}
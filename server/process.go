@@ -0,0 +1,200 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"talaria/monitor"
+)
+
+const maxBatchOps = 100
+
+type processError struct {
+	status int
+	msg    string
+}
+
+func (e *processError) Error() string { return e.msg }
+
+func writeProcessError(w http.ResponseWriter, err error) {
+	var pe *processError
+	if errors.As(err, &pe) {
+		http.Error(w, pe.msg, pe.status)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// checkProcessOwnership refuses to act on processes owned by other users
+// unless Talaria itself runs as root.
+func checkProcessOwnership(pid int, verb string) error {
+	if pid <= 0 {
+		return &processError{http.StatusBadRequest, "Invalid pid"}
+	}
+	if isSelfProcess(pid) {
+		slog.Warn("Refused to signal Talaria's own process", "action", verb, "pid", pid)
+		return &processError{http.StatusForbidden, fmt.Sprintf("Refusing to %s Talaria itself; use /api/restart-self instead", verb)}
+	}
+
+	out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "uid=").Output()
+	if err != nil || len(out) == 0 {
+		return &processError{http.StatusNotFound, "Process not found or access denied"}
+	}
+
+	targetUID, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return &processError{http.StatusInternalServerError, "Failed to determine process ownership"}
+	}
+
+	currentUID := os.Getuid()
+	if currentUID != 0 && targetUID != currentUID {
+		slog.Warn("Security violation: attempt to signal another user's process", "action", verb, "pid", pid, "owner_uid", targetUID, "uid", currentUID)
+		return &processError{http.StatusForbidden, fmt.Sprintf("Unauthorized: You can only %s your own processes", verb)}
+	}
+	return nil
+}
+
+type processOp struct {
+	PID      int    `json:"pid"`
+	Action   string `json:"action"`             // "kill", "stop", "cont", "renice", "suspend"
+	Nice     *int   `json:"nice,omitempty"`     // renice only, -20..20
+	Duration int    `json:"duration,omitempty"` // suspend only, seconds until auto-resume
+}
+
+type processOpResult struct {
+	PID    int    `json:"pid"`
+	Action string `json:"action"`
+	OK     bool   `json:"ok"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (op processOp) validate() error {
+	if op.PID <= 0 {
+		return fmt.Errorf("invalid pid %d", op.PID)
+	}
+	switch op.Action {
+	case "kill", "stop", "cont":
+	case "renice":
+		if op.Nice == nil || *op.Nice < -20 || *op.Nice > 20 {
+			return fmt.Errorf("renice of pid %d needs nice between -20 and 20", op.PID)
+		}
+	case "suspend":
+		if op.Duration <= 0 || time.Duration(op.Duration)*time.Second > maxSuspendDuration {
+			return fmt.Errorf("suspend of pid %d needs a duration between 1 and %d seconds", op.PID, int(maxSuspendDuration.Seconds()))
+		}
+	default:
+		return fmt.Errorf("unknown action %q", op.Action)
+	}
+	return nil
+}
+
+func (op processOp) apply() error {
+	if op.Action == "suspend" {
+		_, err := suspendProcess(op.PID, time.Duration(op.Duration)*time.Second)
+		return err
+	}
+	if err := checkProcessOwnership(op.PID, op.Action); err != nil {
+		return err
+	}
+
+	var err error
+	switch op.Action {
+	case "kill":
+		err = syscall.Kill(op.PID, syscall.SIGKILL)
+		forgetSuspended(op.PID)
+	case "stop":
+		err = syscall.Kill(op.PID, syscall.SIGSTOP)
+	case "cont":
+		err = syscall.Kill(op.PID, syscall.SIGCONT)
+		forgetSuspended(op.PID)
+	case "renice":
+		err = syscall.Setpriority(syscall.PRIO_PROCESS, op.PID, *op.Nice)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+			status = http.StatusForbidden
+		} else if errors.Is(err, syscall.ESRCH) {
+			status = http.StatusNotFound
+		}
+		return &processError{status, fmt.Sprintf("Failed to %s process: %v", op.Action, err)}
+	}
+	return nil
+}
+
+// processAuditParams names the process before it is signalled, while the
+// PID still resolves.
+func processAuditParams(pid int) map[string]string {
+	return map[string]string{"pid": strconv.Itoa(pid), "process": monitor.ResolveProcessName(int32(pid))}
+}
+
+func handleProcessBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Operations []processOp `json:"operations"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOps {
+		http.Error(w, fmt.Sprintf("Batch must contain 1-%d operations", maxBatchOps), http.StatusBadRequest)
+		return
+	}
+
+	// Reject malformed batches up front so nothing runs half-validated.
+	for _, op := range req.Operations {
+		if err := op.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	results := make([]processOpResult, 0, len(req.Operations))
+	succeeded := 0
+	for _, op := range req.Operations {
+		res := processOpResult{PID: op.PID, Action: op.Action, OK: true, Status: http.StatusOK}
+		params := processAuditParams(op.PID)
+		params["batch"] = "true"
+		if op.Action == "suspend" {
+			params["duration"] = strconv.Itoa(op.Duration)
+		} else if op.Action == "renice" {
+			params["nice"] = strconv.Itoa(*op.Nice)
+		}
+		err := op.apply()
+		auditLog(r, "process."+op.Action, params, auditResult(err))
+		if err != nil {
+			res.OK = false
+			res.Error = err.Error()
+			res.Status = http.StatusInternalServerError
+			var pe *processError
+			if errors.As(err, &pe) {
+				res.Status = pe.status
+			}
+		} else {
+			succeeded++
+		}
+		results = append(results, res)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	})
+}
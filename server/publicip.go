@@ -0,0 +1,51 @@
+package server
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"talaria/monitor"
+)
+
+var (
+	lastPublicIP   string
+	lastPublicIPMu sync.Mutex
+)
+
+// startPublicIPWatch raises a "public_ip" event whenever the public address
+// changes. The last address is kept on disk so a change while Talaria was not
+// running is reported on the first refresh after startup.
+func startPublicIPWatch() {
+	if data, err := os.ReadFile(dataPath("public_ip")); err == nil {
+		lastPublicIP = strings.TrimSpace(string(data))
+	}
+	monitor.OnPublicIPChange(publicIPChanged)
+}
+
+func publicIPChanged(ip string) {
+	lastPublicIPMu.Lock()
+	old := lastPublicIP
+	lastPublicIP = ip
+	lastPublicIPMu.Unlock()
+	if ip == old {
+		return
+	}
+	if err := os.WriteFile(dataPath("public_ip"), []byte(ip+"\n"), 0600); err != nil {
+		slog.Error("Failed to save public IP", "err", err)
+	}
+	if old == "" {
+		return
+	}
+
+	RaiseEvent(Event{
+		Kind:     "public_ip",
+		Severity: SeverityWarning,
+		Title:    "Public IP changed",
+		Message:  "Public IP changed from " + old + " to " + ip,
+		Fields: map[string]string{
+			"old": old,
+			"new": ip,
+		},
+	})
+}
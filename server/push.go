@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Phone push backends. Each maps event severity onto its own priority scale.
+
+func pushSend(req *http.Request) error {
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+func startupText(info startupInfo) string {
+	text := "Talaria is up on " + info.Hostname + "\nLocal: " + info.LocalURL
+	if info.PublicURL != "" {
+		text += "\nPublic: " + info.PublicURL
+	}
+	return text
+}
+
+func pushPriority(e Event, critical, warning, info int) int {
+	switch {
+	case e.Fields["state"] == "resolved":
+		return info
+	case e.Severity == SeverityCritical:
+		return critical
+	case e.Severity == SeverityWarning:
+		return warning
+	}
+	return info
+}
+
+type ntfyNotifier struct {
+	server string
+	topic  string
+	token  string
+}
+
+func (n *ntfyNotifier) Name() string { return "ntfy" }
+
+func (n *ntfyNotifier) post(title, message string, priority int, tags, click string) error {
+	server := n.server
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(server, "/")+"/"+url.PathEscape(n.topic), strings.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", title)
+	req.Header.Set("Priority", strconv.Itoa(priority))
+	if tags != "" {
+		req.Header.Set("Tags", tags)
+	}
+	if click != "" {
+		req.Header.Set("Click", click)
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	return pushSend(req)
+}
+
+func (n *ntfyNotifier) Startup(info startupInfo) error {
+	return n.post("Talaria started", startupText(info), 3, "rocket", info.PublicURL)
+}
+
+func (n *ntfyNotifier) Notify(e Event) error {
+	tags := "warning"
+	if e.Fields["state"] == "resolved" {
+		tags = "white_check_mark"
+	} else if e.Severity == SeverityCritical {
+		tags = "rotating_light"
+	}
+	return n.post(e.Title, e.Message, pushPriority(e, 5, 4, 3), tags, "")
+}
+
+func (n *ntfyNotifier) Report(title, text string) error {
+	return n.post(title, text, 2, "bar_chart", "")
+}
+
+type pushoverNotifier struct {
+	appToken string
+	userKey  string
+}
+
+func (p *pushoverNotifier) Name() string { return "pushover" }
+
+func (p *pushoverNotifier) post(title, message string, priority int, link string) error {
+	form := url.Values{
+		"token":    {p.appToken},
+		"user":     {p.userKey},
+		"title":    {title},
+		"message":  {message},
+		"priority": {strconv.Itoa(priority)},
+	}
+	if link != "" {
+		form.Set("url", link)
+	}
+	req, err := http.NewRequest(http.MethodPost, "https://api.pushover.net/1/messages.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return pushSend(req)
+}
+
+func (p *pushoverNotifier) Startup(info startupInfo) error {
+	return p.post("Talaria started", startupText(info), -1, info.PublicURL)
+}
+
+func (p *pushoverNotifier) Notify(e Event) error {
+	return p.post(e.Title, e.Message, pushPriority(e, 1, 0, -1), "")
+}
+
+func (p *pushoverNotifier) Report(title, text string) error {
+	return p.post(title, text, -1, "")
+}
+
+type gotifyNotifier struct {
+	server   string
+	appToken string
+}
+
+func (g *gotifyNotifier) Name() string { return "gotify" }
+
+func (g *gotifyNotifier) post(title, message string, priority int) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"title":    title,
+		"message":  message,
+		"priority": priority,
+	})
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(g.server, "/")+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.appToken)
+	return pushSend(req)
+}
+
+func (g *gotifyNotifier) Startup(info startupInfo) error {
+	return g.post("Talaria started", startupText(info), 2)
+}
+
+func (g *gotifyNotifier) Notify(e Event) error {
+	return g.post(e.Title, e.Message, pushPriority(e, 8, 5, 2))
+}
+
+func (g *gotifyNotifier) Report(title, text string) error {
+	return g.post(title, text, 2)
+}
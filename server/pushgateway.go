@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCustomGaugeTTL = 10 * time.Minute
+	maxCustomGauges       = 1000
+)
+
+var (
+	customNameRegex  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+	customLabelRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+)
+
+// customGauge is one series submitted to /api/push. Its key in the payload
+// is "name", or "name.k1=v1.k2=v2" with labels sorted by name.
+type customGauge struct {
+	Name    string            `json:"name"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Value   float64           `json:"value"`
+	Updated int64             `json:"updated"` // unix ms
+}
+
+var (
+	customGauges   = make(map[string]*customGauge)
+	customGaugesMu sync.Mutex
+)
+
+func (g *customGauge) key() string {
+	names := make([]string, 0, len(g.Labels))
+	for k := range g.Labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	key := g.Name
+	for _, k := range names {
+		key += "." + k + "=" + g.Labels[k]
+	}
+	return key
+}
+
+func (g *customGauge) validate() error {
+	if !customNameRegex.MatchString(g.Name) {
+		return fmt.Errorf("name %q must be letters, digits and '_', not starting with a digit", g.Name)
+	}
+	for k, v := range g.Labels {
+		if !customLabelRegex.MatchString(k) || !customLabelRegex.MatchString(v) {
+			return fmt.Errorf("label %s=%s: names and values must be letters, digits, '_' or '-'", k, v)
+		}
+	}
+	if math.IsNaN(g.Value) || math.IsInf(g.Value, 0) {
+		return fmt.Errorf("%s: value must be finite", g.Name)
+	}
+	return nil
+}
+
+func customGaugeTTL() time.Duration {
+	if v := GlobalConfig.PushGateway.TTL; v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		slog.Warn("push_gateway.ttl is not a duration", "ttl", v, "using", defaultCustomGaugeTTL)
+	}
+	return defaultCustomGaugeTTL
+}
+
+// customGaugeValues returns the live gauges for AllMetrics.Custom, dropping
+// any not updated within the TTL so a dead script's last value does not
+// linger.
+func customGaugeValues() map[string]float64 {
+	customGaugesMu.Lock()
+	defer customGaugesMu.Unlock()
+	if len(customGauges) == 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-customGaugeTTL()).UnixMilli()
+	out := make(map[string]float64, len(customGauges))
+	for k, g := range customGauges {
+		if g.Updated < cutoff {
+			delete(customGauges, k)
+			continue
+		}
+		out[k] = g.Value
+	}
+	return out
+}
+
+// handlePushGateway accepts custom gauges from scripts and other devices:
+//
+//	POST /api/push {"name": "room_temp", "labels": {"room": "office"}, "value": 21.5}
+//
+// or a JSON array of the same. Writes need a push_gateway token; GET lists
+// the current gauges for a token or a signed-in session.
+func handlePushGateway(w http.ResponseWriter, r *http.Request) {
+	tokenOK := bearerTokenIn(r, GlobalConfig.PushGateway.Tokens)
+	switch {
+	case r.Method == http.MethodGet && (tokenOK || getSessionFromRequest(r) != nil):
+		customGaugeValues()
+		customGaugesMu.Lock()
+		out := make([]customGauge, 0, len(customGauges))
+		for _, g := range customGauges {
+			out = append(out, *g)
+		}
+		customGaugesMu.Unlock()
+		sort.Slice(out, func(i, j int) bool { return out[i].key() < out[j].key() })
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+		return
+	case r.Method == http.MethodPost && tokenOK:
+	case r.Method == http.MethodGet || r.Method == http.MethodPost:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="talaria"`)
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var gauges []customGauge
+	var err error
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '[' {
+		err = json.Unmarshal(body, &gauges)
+	} else {
+		gauges = make([]customGauge, 1)
+		err = json.Unmarshal(body, &gauges[0])
+	}
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for i := range gauges {
+		if err := gauges[i].validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	customGaugesMu.Lock()
+	defer customGaugesMu.Unlock()
+	for _, g := range gauges {
+		key := g.key()
+		if customGauges[key] == nil && len(customGauges) >= maxCustomGauges {
+			http.Error(w, fmt.Sprintf("Too many series (limit %d)", maxCustomGauges), http.StatusTooManyRequests)
+			return
+		}
+		g.Updated = now
+		customGauges[key] = &g
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
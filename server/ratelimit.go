@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rateClassDefault   = "default"
+	rateClassExpensive = "expensive"
+	rateClassWrite     = "write"
+	rateClassAuth      = "auth"
+
+	rateBucketIdle = 10 * time.Minute
+)
+
+var defaultRateLimits = map[string]RateLimitRule{
+	rateClassDefault:   {PerMinute: 600, Burst: 120},
+	rateClassExpensive: {PerMinute: 30, Burst: 10},
+	rateClassWrite:     {PerMinute: 60, Burst: 20},
+	rateClassAuth:      {PerMinute: 20, Burst: 10},
+}
+
+// Endpoints that shell out, do network lookups or scan history; a prefix
+// ending in "/" covers the subtree.
+var expensivePaths = []string{
+	"/api/connections", "/api/connections/history", "/api/lookup", "/api/graphql",
+	"/api/history", "/api/history/export", "/api/export", "/api/report", "/api/reports/weekly",
+	"/api/screenshot", "/api/speedtest", "/api/lan/devices", "/api/uptime/calendar",
+	"/api/audit", "/api/admin/backup", "/api/admin/restore", "/api/debug/",
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	rules map[string]RateLimitRule
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket // class + client → bucket
+	lastPrune time.Time
+}
+
+// allow takes a token from the client's bucket for class. When the bucket
+// is empty it reports how long until the next token.
+func (l *rateLimiter) allow(class, client string, now time.Time) (bool, time.Duration) {
+	rule := l.rules[class]
+	rate := rule.PerMinute / 60
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) > time.Minute {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > rateBucketIdle {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	key := class + "\x00" + client
+	b := l.buckets[key]
+	if b == nil {
+		b = &tokenBucket{tokens: float64(rule.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if rate <= 0 {
+		return false, time.Minute
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+func rateClass(r *http.Request) string {
+	path := r.URL.Path
+	if path == "/api/login" || strings.HasPrefix(path, "/auth/") {
+		return rateClassAuth
+	}
+	for _, p := range expensivePaths {
+		if path == p || strings.HasSuffix(p, "/") && strings.HasPrefix(path, p) {
+			return rateClassExpensive
+		}
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		return rateClassWrite
+	}
+	return rateClassDefault
+}
+
+// RateLimitMiddleware applies rate_limit to the API, WebSocket upgrades and
+// OIDC endpoints; the static dashboard files are not counted.
+func RateLimitMiddleware(next http.Handler) http.Handler {
+	cfg := GlobalConfig.RateLimit
+	if !cfg.Enabled {
+		return next
+	}
+	l := &rateLimiter{rules: make(map[string]RateLimitRule), buckets: make(map[string]*tokenBucket)}
+	for class, rule := range defaultRateLimits {
+		l.rules[class] = rule
+	}
+	for class, rule := range cfg.Classes {
+		if _, ok := defaultRateLimits[class]; !ok {
+			slog.Warn("Unknown rate_limit class ignored", "class", class)
+			continue
+		}
+		if rule.Burst <= 0 {
+			rule.Burst = max(1, int(rule.PerMinute/6))
+		}
+		l.rules[class] = rule
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if !strings.HasPrefix(path, "/api/") && !strings.HasPrefix(path, "/ws") && !strings.HasPrefix(path, "/auth/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		client := rateLimitClient(r)
+		if s := getSessionFromRequest(r); s != nil {
+			client = "user:" + s.user + "\x00" + s.token
+		}
+		class := rateClass(r)
+		if ok, wait := l.allow(class, client, time.Now()); !ok {
+			secs := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":       "Rate limit exceeded",
+				"class":       class,
+				"retry_after": secs,
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type unixSocketKey struct{}
+
+// tagUnixConn records which Unix socket a connection came in on; its peers
+// have no address, so they share that socket's buckets.
+func tagUnixConn(ctx context.Context, c net.Conn) context.Context {
+	if _, ok := c.(*net.UnixConn); ok {
+		return context.WithValue(ctx, unixSocketKey{}, c.LocalAddr().String())
+	}
+	return ctx
+}
+
+// rateLimitClient keys requests made before login.
+func rateLimitClient(r *http.Request) string {
+	if sock, ok := r.Context().Value(unixSocketKey{}).(string); ok {
+		return "unix:" + sock
+	}
+	return "ip:" + getRealIP(r)
+}
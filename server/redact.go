@@ -0,0 +1,75 @@
+package server
+
+import (
+	"log/slog"
+	"regexp"
+	"sync"
+	"talaria/monitor"
+)
+
+const redactedMarker = "[REDACTED]"
+
+var defaultRedactionPatterns = []string{
+	`(?i)((?:^|\s)-{0,2}[\w.-]*(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credentials?)[\w.-]*(?:=|:|\s+))\S+`,
+	`(?i)(authorization:\s*(?:bearer|basic|token)\s+)\S+`,
+	`([a-zA-Z][a-zA-Z0-9+.-]*://[^:/\s@]+:)[^@/\s]+`,
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	`\b(?:ghp|gho|ghs|ghu|github_pat)_[A-Za-z0-9_]{20,}\b`,
+	`\bxox[abpr]-[A-Za-z0-9-]{10,}\b`,
+}
+
+type redactRule struct {
+	re      *regexp.Regexp
+	replace string
+}
+
+var (
+	redactRules     []redactRule
+	redactRulesOnce sync.Once
+)
+
+func compileRedactionRules() {
+	var patterns []string
+	if !GlobalConfig.Redaction.DisableDefaults {
+		patterns = append(patterns, defaultRedactionPatterns...)
+	}
+	patterns = append(patterns, GlobalConfig.Redaction.Patterns...)
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			slog.Warn("Ignoring invalid redaction pattern", "pattern", p, "err", err)
+			continue
+		}
+		replace := redactedMarker
+		if re.NumSubexp() > 0 {
+			replace = "${1}" + redactedMarker
+		}
+		redactRules = append(redactRules, redactRule{re: re, replace: replace})
+	}
+}
+
+func redactCommand(cmd string) string {
+	if cmd == "" {
+		return cmd
+	}
+	redactRulesOnce.Do(compileRedactionRules)
+	for _, rule := range redactRules {
+		cmd = rule.re.ReplaceAllString(cmd, rule.replace)
+	}
+	return cmd
+}
+
+// The monitor package shares its backing array with the process cache, so
+// redaction always works on a copy.
+func redactProcesses(procs []monitor.ProcessInfo) []monitor.ProcessInfo {
+	if procs == nil {
+		return nil
+	}
+	out := make([]monitor.ProcessInfo, len(procs))
+	for i, p := range procs {
+		p.Command = redactCommand(p.Command)
+		out[i] = p
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	replicaBatch     = 1000
+	replicaKeepalive = 30 * time.Second
+	replicaRetry     = 30 * time.Second
+)
+
+// handleHistoryExport streams samples newer than ?cursor= (unix ms) as NDJSON.
+// With ?follow=1 the response stays open and new samples are pushed as they
+// are recorded; blank lines are keepalives.
+func handleHistoryExport(w http.ResponseWriter, r *http.Request) {
+	if getSessionFromRequest(r) == nil && !bearerTokenIn(r, GlobalConfig.History.ReplicaTokens) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="talaria"`)
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cursor, _ := strconv.ParseInt(r.URL.Query().Get("cursor"), 10, 64)
+	follow := r.URL.Query().Get("follow") == "1"
+	if follow {
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-store")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	keepalive := time.NewTicker(replicaKeepalive)
+	defer keepalive.Stop()
+	for {
+		samples, next := historySince(cursor, replicaBatch)
+		for _, s := range samples {
+			if err := enc.Encode(s); err != nil {
+				return
+			}
+			cursor = s.T
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if len(samples) == replicaBatch {
+			continue
+		}
+		if !follow {
+			return
+		}
+
+		select {
+		case <-next:
+		case <-keepalive.C:
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// startReplicaFollower mirrors another instance's history into a local NDJSON
+// archive, resuming from the last archived sample.
+func startReplicaFollower() {
+	cfg := GlobalConfig.History.Follow
+	if cfg.URL == "" {
+		return
+	}
+	archive := dataPath("replica.ndjson")
+	cursor := lastArchivedCursor(archive)
+
+	go func() {
+		for {
+			n, err := followOnce(context.Background(), cfg.URL, cfg.Token, archive, &cursor)
+			if err != nil {
+				slog.Warn("History follower failed", "err", err, "archived", n)
+			}
+			time.Sleep(replicaRetry)
+		}
+	}()
+}
+
+func followOnce(ctx context.Context, base, token, archive string, cursor *int64) (int, error) {
+	u := strings.TrimSuffix(base, "/") + "/api/history/export?follow=1&cursor=" + strconv.FormatInt(*cursor, 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("upstream: %s", resp.Status)
+	}
+
+	f, err := os.OpenFile(archive, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var s historySample
+		if err := json.Unmarshal(line, &s); err != nil || s.T <= *cursor {
+			continue
+		}
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			return n, err
+		}
+		*cursor = s.T
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return n, err
+	}
+	return n, fmt.Errorf("upstream closed the stream")
+}
+
+func lastArchivedCursor(path string) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	var cursor int64
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var s historySample
+		if json.Unmarshal(scanner.Bytes(), &s) == nil && s.T > cursor {
+			cursor = s.T
+		}
+	}
+	return cursor
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	reportWindow      = 7 * 24 * time.Hour
+	reportChartPoints = 168 // one per hour over a week
+	reportChartWidth  = 640
+	reportChartHeight = 120
+)
+
+type reportChart struct {
+	Label   string
+	Unit    string
+	Summary metricSummary
+	SVG     template.HTML
+}
+
+type weeklyReport struct {
+	Host      string
+	Model     string
+	Chip      string
+	OSVersion string
+	Uptime    string
+	From, To  time.Time
+	Samples   int
+	Charts    []reportChart
+	Alerts    []Event
+	Generated time.Time
+}
+
+var reportMetrics = []struct{ key, label, unit string }{
+	{"cpu.usage_percent", "CPU usage", "%"},
+	{"memory.used_percent", "Memory used", "%"},
+	{"storage_breakdown.used_gb", "Disk used", "GB"},
+	{"health.health_score", "Health score", ""},
+	{"battery.percent", "Battery", "%"},
+	{"thermal.cpu_temp", "CPU temperature", "°C"},
+}
+
+func buildWeeklyReport(now time.Time) weeklyReport {
+	from := now.Add(-reportWindow)
+	samples := historyRange(from.UnixMilli(), now.UnixMilli())
+
+	rep := weeklyReport{From: from, To: now, Samples: len(samples), Generated: now}
+	rep.Host, _ = os.Hostname()
+	hw := monitor.GetHardwareInfo()
+	rep.Model, rep.Chip = hw.ModelName, hw.Chip
+	if m := latestMetrics(); m != nil {
+		rep.OSVersion, rep.Uptime = m.System.OSVersion, m.System.Uptime
+	}
+
+	for _, rm := range reportMetrics {
+		s, ok := summarize(samples, rm.key)
+		if !ok {
+			continue
+		}
+		rep.Charts = append(rep.Charts, reportChart{
+			Label:   rm.label,
+			Unit:    rm.unit,
+			Summary: s,
+			SVG:     svgChart(samples, rm.key, from, now),
+		})
+	}
+
+	for _, e := range recentEvents(0) {
+		if e.Kind == "alert" && e.Fields["state"] == alertFiring && e.Time >= from.Unix() {
+			rep.Alerts = append(rep.Alerts, e)
+		}
+	}
+	return rep
+}
+
+// svgChart draws key as an inline SVG line, averaging samples into fixed
+// buckets so a week of 10s samples stays a few KB. Gaps (no samples in a
+// bucket) break the line.
+func svgChart(samples []historySample, key string, from, to time.Time) template.HTML {
+	span := to.Sub(from)
+	sums := make([]float64, reportChartPoints)
+	counts := make([]int, reportChartPoints)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range samples {
+		v, ok := s.V[key]
+		if !ok {
+			continue
+		}
+		i := int(float64(time.UnixMilli(s.T).Sub(from)) / float64(span) * reportChartPoints)
+		if i < 0 || i >= reportChartPoints {
+			continue
+		}
+		sums[i] += v
+		counts[i]++
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	if lo > hi {
+		return ""
+	}
+	if hi-lo < 1 {
+		hi = lo + 1
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		reportChartWidth, reportChartHeight, reportChartWidth, reportChartHeight)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#f6f8fa"/>`)
+	var line []string
+	flush := func() {
+		if len(line) > 1 {
+			fmt.Fprintf(&b, `<polyline fill="none" stroke="#0a84ff" stroke-width="1.5" points="%s"/>`, strings.Join(line, " "))
+		}
+		line = line[:0]
+	}
+	for i := range sums {
+		if counts[i] == 0 {
+			flush()
+			continue
+		}
+		x := float64(i) / float64(reportChartPoints-1) * reportChartWidth
+		y := reportChartHeight - 4 - (sums[i]/float64(counts[i])-lo)/(hi-lo)*(reportChartHeight-8)
+		line = append(line, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	flush()
+	fmt.Fprintf(&b, `<text x="4" y="12" font-size="10" fill="#666">%.1f</text>`, hi)
+	fmt.Fprintf(&b, `<text x="4" y="%d" font-size="10" fill="#666">%.1f</text>`, reportChartHeight-4, lo)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"f1":   func(v float64) string { return fmt.Sprintf("%.1f", v) },
+	"date": func(t time.Time) string { return t.Format("Mon 2 Jan 2006") },
+	"unix": func(ts int64) string { return time.Unix(ts, 0).Format("Mon 2 Jan 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Talaria weekly report — {{.Host}}</title></head>
+<body style="font-family:-apple-system,Helvetica,Arial,sans-serif;color:#1d1d1f;max-width:680px;margin:24px auto">
+<h2 style="margin-bottom:4px">{{.Host}}</h2>
+<div style="color:#666">{{date .From}} – {{date .To}}</div>
+<table style="margin:16px 0;font-size:14px">
+{{if .Model}}<tr><td style="color:#666;padding-right:12px">Model</td><td>{{.Model}}</td></tr>{{end}}
+{{if .Chip}}<tr><td style="color:#666;padding-right:12px">Chip</td><td>{{.Chip}}</td></tr>{{end}}
+{{if .OSVersion}}<tr><td style="color:#666;padding-right:12px">OS</td><td>{{.OSVersion}}</td></tr>{{end}}
+<tr><td style="color:#666;padding-right:12px">Uptime</td><td>{{.Uptime}}</td></tr>
+</table>
+{{if not .Samples}}<p>No history was recorded in this period.</p>{{end}}
+{{range .Charts}}
+<h3 style="margin:20px 0 4px">{{.Label}}</h3>
+<div style="color:#666;font-size:13px">avg {{f1 .Summary.Avg}}{{.Unit}} · min {{f1 .Summary.Min}}{{.Unit}} · max {{f1 .Summary.Max}}{{.Unit}}</div>
+{{.SVG}}
+{{end}}
+<h3 style="margin:20px 0 4px">Alerts</h3>
+{{if .Alerts}}<ul style="font-size:14px">{{range .Alerts}}<li>{{unix .Time}} — {{.Title}}: {{.Message}}</li>{{end}}</ul>
+{{else}}<p style="font-size:14px">No alerts fired.</p>{{end}}
+<p style="color:#999;font-size:12px;margin-top:32px">Generated by Talaria on {{.Generated.Format "2006-01-02 15:04"}}</p>
+</body></html>
+`))
+
+func renderWeeklyReport(rep weeklyReport) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, rep); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// deliverWeeklyReport saves the report under reports.weekly.save_dir and/or
+// emails it, depending on configuration.
+func deliverWeeklyReport(now time.Time) error {
+	cfg := GlobalConfig.Reports.Weekly
+	rep := buildWeeklyReport(now)
+	html, err := renderWeeklyReport(rep)
+	if err != nil {
+		return err
+	}
+
+	delivered := false
+	if cfg.SaveDir != "" {
+		if err := os.MkdirAll(cfg.SaveDir, 0700); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("talaria-%s-%s.html", rep.Host, now.Format("2006-01-02"))
+		if err := os.WriteFile(filepath.Join(cfg.SaveDir, name), html, 0600); err != nil {
+			return err
+		}
+		delivered = true
+	}
+	if cfg.Email {
+		if !GlobalConfig.Notifications.Email.Enabled {
+			return fmt.Errorf("reports.weekly.email is set but notifications.email is disabled")
+		}
+		subject := fmt.Sprintf("Talaria weekly report — %s", rep.Host)
+		if err := newEmailNotifier().send(subject, "text/html", string(html)); err != nil {
+			return err
+		}
+		delivered = true
+	}
+	if !delivered {
+		return fmt.Errorf("set reports.weekly.save_dir or reports.weekly.email")
+	}
+	return nil
+}
+
+func startWeeklyReport() {
+	cfg := GlobalConfig.Reports.Weekly
+	if cfg.At == "" {
+		return
+	}
+	clock, err := time.Parse("15:04", cfg.At)
+	if err != nil {
+		slog.Warn("Weekly report disabled: invalid reports.weekly.at", "at", cfg.At, "err", err)
+		return
+	}
+	day := time.Monday
+	if cfg.Day != "" {
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(d.String(), cfg.Day) {
+				day, found = d, true
+			}
+		}
+		if !found {
+			slog.Warn("Weekly report disabled: invalid reports.weekly.day", "day", cfg.Day)
+			return
+		}
+	}
+
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+			next = next.AddDate(0, 0, (int(day)-int(next.Weekday())+7)%7)
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 7)
+			}
+			time.Sleep(time.Until(next))
+
+			if err := deliverWeeklyReport(time.Now()); err != nil {
+				slog.Warn("Weekly report failed", "err", err)
+			}
+		}
+	}()
+}
+
+// handleWeeklyReport renders the report for the last 7 days (GET) or
+// generates and delivers it now (POST).
+func handleWeeklyReport(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		html, err := renderWeeklyReport(buildWeeklyReport(time.Now()))
+		if err != nil {
+			http.Error(w, "Failed to render report", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(html)
+	case http.MethodPost:
+		if err := deliverWeeklyReport(time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const historyCompactEvery = time.Hour
+
+// historyTier is one retention level. Every tier covers the same span up to
+// now at its own resolution: each raw sample feeds the minute tier, each
+// finished minute bucket feeds the hourly tier, RRD-style. Segments are
+// deleted once they fall outside their tier's retention.
+type historyTier struct {
+	name      string
+	step      time.Duration
+	retention time.Duration
+}
+
+var defaultHistoryTiers = []historyTier{
+	{"raw", 0, 24 * time.Hour},
+	{"1m", time.Minute, 30 * 24 * time.Hour},
+	{"1h", time.Hour, 365 * 24 * time.Hour},
+}
+
+// parseRetention accepts Go durations plus a "d" suffix for days ("30d").
+func parseRetention(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid retention %q", s)
+	}
+	return d, nil
+}
+
+func historyTiers() []historyTier {
+	cfg := GlobalConfig.History.Retention
+	tiers := append([]historyTier(nil), defaultHistoryTiers...)
+	for i, v := range []string{cfg.Raw, cfg.Minute, cfg.Hourly} {
+		if v == "" {
+			continue
+		}
+		d, err := parseRetention(v)
+		if err != nil {
+			slog.Warn("Invalid history retention", "tier", tiers[i].name, "err", err, "using", tiers[i].retention)
+			continue
+		}
+		tiers[i].retention = d
+	}
+	return tiers
+}
+
+// openHistoryRollups opens the rollup tiers under data/history/<name> and
+// catches each one up with the tier below it, so buckets missed while
+// Talaria was stopped (or written before tiers were continuous) are filled.
+func openHistoryRollups() {
+	src := historyDB
+	for _, t := range historyTiers()[1:] {
+		s, err := openHistoryStore(filepath.Join(historyDB.dir, t.name), t.step)
+		if err != nil {
+			slog.Error("History rollups disabled", "tier", t.name, "err", err)
+			return
+		}
+		r := newRollup(t.step)
+		from := int64(0)
+		if s.last > 0 {
+			from = s.last + t.step.Milliseconds()
+		}
+		for _, smp := range src.read(from, time.Now().UnixMilli()) {
+			if b, ok := r.add(smp); ok {
+				if err := s.append(b); err != nil {
+					slog.Error("History backfill failed", "tier", t.name, "err", err)
+					break
+				}
+			}
+		}
+		historyRollups = append(historyRollups, s)
+		historyRollupState = append(historyRollupState, r)
+		src = s
+	}
+}
+
+// feedRollups passes a new raw sample up the tiers. Only the history ticker
+// calls it, after openHistoryRollups has finished.
+func feedRollups(s historySample) {
+	for i, store := range historyRollups {
+		b, ok := historyRollupState[i].add(s)
+		if !ok {
+			return
+		}
+		if err := store.append(b); err != nil {
+			slog.Error("History rollup failed", "step", store.step, "err", err)
+			return
+		}
+		s = b
+	}
+}
+
+func startHistoryPruning() {
+	go func() {
+		for {
+			pruneHistory(time.Now())
+			time.Sleep(historyCompactEvery)
+		}
+	}()
+}
+
+// pruneHistory deletes every whole day that has aged out of its tier, so a
+// tier keeps up to a day more than its retention.
+func pruneHistory(now time.Time) {
+	tiers := historyTiers()
+	for i, s := range append([]*historyStore{historyDB}, historyRollups...) {
+		cutoff := now.Add(-tiers[i].retention)
+		for _, day := range s.segments() {
+			start, _ := time.Parse(historySegmentLayout, day)
+			if start.Add(24 * time.Hour).After(cutoff) {
+				break
+			}
+			if err := s.remove(day); err != nil {
+				slog.Warn("History retention failed", "err", err)
+			}
+		}
+	}
+}
+
+// rollup aggregates samples into step-wide buckets with the average, min and
+// max of each key. Input that is itself rolled up keeps its min and max, so
+// hourly extremes are the true extremes of the raw samples.
+type rollup struct {
+	width  int64
+	bucket int64 // start of the open bucket, unix ms
+	sums   map[string]float64
+	counts map[string]int
+	mins   map[string]float64
+	maxs   map[string]float64
+}
+
+func newRollup(step time.Duration) *rollup {
+	return &rollup{width: step.Milliseconds(), bucket: -1}
+}
+
+// add folds s into its bucket and returns the previous bucket when s is the
+// first sample of a new one.
+func (r *rollup) add(s historySample) (historySample, bool) {
+	var done historySample
+	var ok bool
+	if b := s.T - s.T%r.width; b != r.bucket {
+		if b < r.bucket {
+			return done, false
+		}
+		done, ok = r.flush()
+		r.bucket = b
+	}
+	if r.sums == nil {
+		r.sums, r.counts = make(map[string]float64), make(map[string]int)
+		r.mins, r.maxs = make(map[string]float64), make(map[string]float64)
+	}
+	for k, v := range s.V {
+		lo, hi := v, v
+		if m, ok := s.Min[k]; ok {
+			lo = m
+		}
+		if m, ok := s.Max[k]; ok {
+			hi = m
+		}
+		if r.counts[k] == 0 || lo < r.mins[k] {
+			r.mins[k] = lo
+		}
+		if r.counts[k] == 0 || hi > r.maxs[k] {
+			r.maxs[k] = hi
+		}
+		r.sums[k] += v
+		r.counts[k]++
+	}
+	return done, ok
+}
+
+func (r *rollup) flush() (historySample, bool) {
+	if len(r.sums) == 0 {
+		return historySample{}, false
+	}
+	s := historySample{
+		T:   r.bucket,
+		V:   make(map[string]float64, len(r.sums)),
+		Min: r.mins,
+		Max: r.maxs,
+	}
+	for k, sum := range r.sums {
+		s.V[k] = sum / float64(r.counts[k])
+	}
+	r.sums, r.counts, r.mins, r.maxs = nil, nil, nil, nil
+	return s, true
+}
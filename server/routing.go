@@ -0,0 +1,74 @@
+package server
+
+import (
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// AlertRoute sends matching events to a subset of notifiers. Routes are tried
+// in order and the first match wins unless it sets continue; events no route
+// matches go to every notifier.
+type AlertRoute struct {
+	Severity  []string `yaml:"severity"`  // any of; empty matches all
+	Kind      []string `yaml:"kind"`      // "alert", "security", ...
+	Rule      string   `yaml:"rule"`      // glob on the alert rule name
+	Metric    string   `yaml:"metric"`    // glob, e.g. "disks.*"
+	Host      string   `yaml:"host"`      // glob on this machine's hostname
+	Notifiers []string `yaml:"notifiers"` // notifier names; "webhook" covers every webhook
+	Continue  bool     `yaml:"continue"`
+}
+
+func globMatch(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
+
+func (r AlertRoute) matches(e Event, hostname string) bool {
+	if len(r.Severity) > 0 && !slices.Contains(r.Severity, e.Severity) {
+		return false
+	}
+	if len(r.Kind) > 0 && !slices.Contains(r.Kind, e.Kind) {
+		return false
+	}
+	if r.Rule != "" && !globMatch(r.Rule, e.Fields["rule"]) {
+		return false
+	}
+	if r.Metric != "" && !globMatch(r.Metric, e.Fields["metric"]) {
+		return false
+	}
+	return globMatch(r.Host, hostname)
+}
+
+func (r AlertRoute) targets(name string) bool {
+	for _, n := range r.Notifiers {
+		if n == name || strings.HasPrefix(name, n+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// routeEvent returns the routes that apply to e. explicit is set when one of
+// them names e's kind, which lets events that are not normally notifiable
+// (e.g. security) be sent on purpose.
+func routeEvent(e Event) (routes []AlertRoute, explicit bool) {
+	hostname, _ := os.Hostname()
+	for _, r := range GlobalConfig.Alerts.Routes {
+		if !r.matches(e, hostname) {
+			continue
+		}
+		routes = append(routes, r)
+		if len(r.Kind) > 0 {
+			explicit = true
+		}
+		if !r.Continue {
+			break
+		}
+	}
+	return routes, explicit
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"talaria/monitor"
+	"time"
+)
+
+// collectorIntervals holds the collectors config, parsed. A nil or missing
+// entry means the collector runs on every tick.
+var collectorIntervals atomic.Pointer[map[string]time.Duration]
+
+// sampledSection is the last result of a collector with its own interval,
+// kept as a sparse AllMetrics holding only that collector's section.
+type sampledSection struct {
+	m    *AllMetrics
+	keys []string // JSON keys of the fields it sets, for collected_at
+	at   time.Time
+}
+
+var (
+	sampled   = make(map[string]*sampledSection)
+	sampledMu sync.Mutex
+)
+
+func applyCollectorIntervals() {
+	intervals := make(map[string]time.Duration)
+	for name, v := range GlobalConfig.Collectors {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			slog.Warn("Ignoring collector interval that is not a duration like 30s or 5m", "collector", name, "interval", v)
+			continue
+		}
+		switch {
+		case name == "bluetooth":
+			monitor.SetBluetoothInterval(d)
+		case isKnownCollector(name) && name != "commands":
+			intervals[name] = d
+		default:
+			slog.Warn("Ignoring interval for unknown collector", "collector", name,
+				"known", "bluetooth, "+strings.Join(knownCollectors[:len(knownCollectors)-1], ", "))
+		}
+	}
+	collectorIntervals.Store(&intervals)
+}
+
+func isKnownCollector(name string) bool {
+	for _, c := range knownCollectors {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// collectSection runs fn into m, or, for a collector with a configured
+// interval that has not elapsed, copies in its previous result instead.
+func collectSection(name string, fn func(m *AllMetrics), m *AllMetrics) {
+	var interval time.Duration
+	if iv := collectorIntervals.Load(); iv != nil {
+		interval = (*iv)[name]
+	}
+	if interval <= 0 {
+		fn(m)
+		return
+	}
+
+	sampledMu.Lock()
+	s := sampled[name]
+	sampledMu.Unlock()
+	if s == nil || time.Since(s.at) >= interval {
+		s = &sampledSection{m: &AllMetrics{}, at: time.Now()}
+		fn(s.m)
+		s.keys = sectionKeys(s.m)
+		sampledMu.Lock()
+		sampled[name] = s
+		sampledMu.Unlock()
+	}
+	mergeSection(m, s.m)
+}
+
+// markSampledAt sets collected_at for sampled sections to when their value
+// was actually collected rather than the time of this tick.
+func markSampledAt(collectedAt map[string]int64) {
+	sampledMu.Lock()
+	defer sampledMu.Unlock()
+	for _, s := range sampled {
+		for _, key := range s.keys {
+			collectedAt[key] = s.at.UnixMilli()
+		}
+	}
+}
+
+// mergeSection copies the fields src has set into dst. Each collector fills
+// distinct fields, so concurrent merges into the same dst do not overlap.
+func mergeSection(dst, src *AllMetrics) {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for i := 0; i < s.NumField(); i++ {
+		if !s.Field(i).IsZero() {
+			d.Field(i).Set(s.Field(i))
+		}
+	}
+}
+
+func sectionKeys(m *AllMetrics) []string {
+	v, t := reflect.ValueOf(m).Elem(), reflect.TypeOf(*m)
+	var keys []string
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Field(i).IsZero() {
+			keys = append(keys, strings.Split(t.Field(i).Tag.Get("json"), ",")[0])
+		}
+	}
+	return keys
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// metricsSchemaVersion is sent as schema_version in every payload. Within a
+// major version fields are only added, never removed, retyped or renamed; a
+// renamed field keeps its old key alongside the new one until the next major
+// version. The minor version counts additions.
+const metricsSchemaVersion = "1.0"
+
+// intervalPerCollection marks fields recomputed on every collection tick
+// (the dashboard refresh rate, 1s by default).
+const intervalPerCollection = "collection"
+
+// schemaField describes one field of AllMetrics. Unit, description,
+// interval and deprecation come from the unit, desc, interval and deprecated
+// struct tags; an interval is inherited from the enclosing struct field when
+// not set. A deprecated tag names the replacement, e.g. deprecated:"use
+// memory.used_percent".
+type schemaField struct {
+	Path        string `json:"path"` // dotted, "[]" marks array elements: "disks[].used_percent"
+	Type        string `json:"type"` // "number", "integer", "boolean", "string", "object", "array"
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description,omitempty"`
+	Interval    string `json:"interval"`
+	Deprecated  string `json:"deprecated,omitempty"`
+}
+
+var (
+	metricsSchema     []schemaField
+	metricsSchemaOnce sync.Once
+)
+
+func buildSchema() []schemaField {
+	metricsSchemaOnce.Do(func() {
+		metricsSchema = schemaWalk(reflect.TypeOf(AllMetrics{}), "", intervalPerCollection, nil)
+	})
+	return metricsSchema
+}
+
+func schemaType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return t.Kind().String()
+}
+
+func schemaWalk(t reflect.Type, prefix, interval string, out []schemaField) []schemaField {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		// Embedded structs are flattened into the parent, as encoding/json does.
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			out = schemaWalk(f.Type, prefix, interval, out)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		iv := interval
+		if tag := f.Tag.Get("interval"); tag != "" {
+			iv = tag
+		}
+
+		out = append(out, schemaField{
+			Path:        path,
+			Type:        schemaType(f.Type),
+			Unit:        f.Tag.Get("unit"),
+			Description: f.Tag.Get("desc"),
+			Interval:    iv,
+			Deprecated:  f.Tag.Get("deprecated"),
+		})
+
+		ft := f.Type
+		if ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+			path += "[]"
+		}
+		if ft.Kind() == reflect.Struct {
+			out = schemaWalk(ft, path, iv, out)
+		}
+	}
+	return out
+}
+
+// jsonSchema renders t as a JSON Schema (draft 2020-12) node.
+func jsonSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		props := make(map[string]interface{})
+		jsonSchemaFields(t, props)
+		return map[string]interface{}{"type": "object", "properties": props}
+	case reflect.Interface:
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{"type": schemaType(t)}
+}
+
+func jsonSchemaFields(t reflect.Type, props map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			jsonSchemaFields(f.Type, props)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		node := jsonSchema(f.Type)
+		if d := f.Tag.Get("desc"); d != "" {
+			node["description"] = d
+		}
+		if u := f.Tag.Get("unit"); u != "" {
+			node["x-unit"] = u
+		}
+		if d := f.Tag.Get("deprecated"); d != "" {
+			node["deprecated"] = true
+			node["x-deprecated"] = d
+		}
+		props[name] = node
+	}
+}
+
+// handleSchema describes the metrics payload. The default is a flat field
+// list; ?format=jsonschema returns a JSON Schema document for validators and
+// code generators.
+func handleSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Query().Get("format") {
+	case "":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"version": metricsSchemaVersion,
+			"fields":  buildSchema(),
+		})
+	case "jsonschema":
+		doc := jsonSchema(reflect.TypeOf(AllMetrics{}))
+		doc["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+		doc["$id"] = "talaria/metrics/v" + metricsSchemaVersion
+		doc["title"] = "Talaria metrics payload"
+		doc["version"] = metricsSchemaVersion
+		json.NewEncoder(w).Encode(doc)
+	default:
+		http.Error(w, "format must be empty or jsonschema", http.StatusBadRequest)
+	}
+}
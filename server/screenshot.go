@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func handleScreenshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !GlobalConfig.Screenshot.Enabled {
+		http.Error(w, "Screen capture is disabled in config", http.StatusForbidden)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "jpg" {
+		format = "png"
+	}
+	args := []string{"-x", "-t", format}
+
+	params := map[string]string{"format": format}
+	if d := r.URL.Query().Get("display"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid display", http.StatusBadRequest)
+			return
+		}
+		args = append(args, "-D", strconv.Itoa(n))
+		params["display"] = d
+	}
+
+	tmp, err := os.CreateTemp("", "talaria-screen-*."+format)
+	if err != nil {
+		http.Error(w, "Failed to allocate capture file", http.StatusInternalServerError)
+		return
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "screencapture", append(args, tmpPath)...).CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		auditLog(r, "screenshot", params, "failed: "+err.Error())
+		http.Error(w, fmt.Sprintf("Screen capture failed: %s", msg), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := os.ReadFile(tmpPath)
+	if err != nil || len(data) == 0 {
+		// screencapture exits 0 but writes nothing when Screen Recording permission is missing
+		auditLog(r, "screenshot", params, "failed: empty capture")
+		http.Error(w, "Screen capture produced no image (check Screen Recording permission)", http.StatusInternalServerError)
+		return
+	}
+
+	auditLog(r, "screenshot", params, "ok")
+
+	contentType := "image/png"
+	if format == "jpg" {
+		contentType = "image/jpeg"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
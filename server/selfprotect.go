@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+var restartCh = make(chan struct{}, 1)
+
+// RestartRequested fires once an admin asks the server to re-exec itself.
+func RestartRequested() <-chan struct{} {
+	return restartCh
+}
+
+// isSelfProcess reports whether pid is Talaria itself or, when running as a
+// daemon, the process that launched it.
+func isSelfProcess(pid int) bool {
+	if pid == os.Getpid() {
+		return true
+	}
+	ppid := os.Getppid()
+	return os.Getenv("TALARIA_BACKGROUND") == "1" && ppid > 1 && pid == ppid
+}
+
+func handleRestartSelf(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Confirm string `json:"confirm"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil || req.Confirm != "restart" {
+		http.Error(w, `Send {"confirm":"restart"} to restart the server`, http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case restartCh <- struct{}{}:
+	default:
+		http.Error(w, "Restart already in progress", http.StatusConflict)
+		return
+	}
+	auditLog(r, "restart-self", map[string]string{"pid": strconv.Itoa(os.Getpid())}, "ok")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":      true,
+		"message": "Restarting; reconnect in a few seconds",
+	})
+}
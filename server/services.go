@@ -0,0 +1,30 @@
+package server
+
+// StartServices launches the background workers that run independently of
+// connected dashboards.
+func StartServices(hub *Hub) {
+	applyConfiguredLogLevel()
+	applyCollectorIntervals()
+	subscribeEvents(dispatchEvent)
+	startThreatIntel()
+	startSpeedTestSchedule()
+	startDigestSchedule()
+	startWeeklyReport()
+	startWebPush()
+	startAlerts()
+	startHistory()
+	startAnomalyDetector()
+	startReplicaFollower()
+	startSleepHooks()
+	startConnectionHistory()
+	startLANWatch()
+	startListenWatch()
+	startPublicIPWatch()
+	startInfluxExport()
+	startOTLPExport()
+	startStatsdExport()
+	startMQTTExport()
+	startFileExport()
+	startFederationPush()
+	startFleet()
+}
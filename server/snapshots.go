@@ -0,0 +1,277 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"talaria/monitor"
+	"time"
+)
+
+var snapshotNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// metricsSnapshot is a named point-in-time capture for before/after audits.
+// Processes and listeners are kept as name sets since PIDs do not survive a
+// reboot or reinstall.
+type metricsSnapshot struct {
+	Name      string      `json:"name"`
+	Created   int64       `json:"created"`
+	Metrics   *AllMetrics `json:"metrics"`
+	Processes []string    `json:"processes"` // distinct executable names
+	Listeners []string    `json:"listeners"` // "process|addr", see listenAddress
+}
+
+type snapshotDelta struct {
+	Name   string  `json:"name"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Delta  float64 `json:"delta"`
+}
+
+type setDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type snapshotDiff struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Seconds   int64           `json:"seconds"`
+	Processes setDiff         `json:"processes"`
+	Listeners setDiff         `json:"listeners"`
+	Disks     []snapshotDelta `json:"disks"`   // used GB per mount point
+	Storage   []snapshotDelta `json:"storage"` // boot volume total and per category, GB
+	Memory    snapshotDelta   `json:"memory"`  // used MB
+}
+
+func takeSnapshot(name string) *metricsSnapshot {
+	m := CollectAll(0)
+	s := &metricsSnapshot{Name: name, Created: time.Now().Unix(), Metrics: m}
+
+	seen := make(map[string]bool)
+	for _, p := range monitor.AllProcesses() {
+		if p.Name != "" && !seen[p.Name] {
+			seen[p.Name] = true
+			s.Processes = append(s.Processes, p.Name)
+		}
+	}
+	sort.Strings(s.Processes)
+
+	seen = make(map[string]bool)
+	for _, c := range monitor.GetConnectionDetails().Listening {
+		key := c.Process + "|" + listenAddress(c.Local)
+		if !seen[key] {
+			seen[key] = true
+			s.Listeners = append(s.Listeners, key)
+		}
+	}
+	sort.Strings(s.Listeners)
+	return s
+}
+
+func snapshotPath(name string) string {
+	return filepath.Join(dataPath("snapshots"), name+".json")
+}
+
+func saveSnapshot(s *metricsSnapshot) error {
+	if err := os.MkdirAll(dataPath("snapshots"), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(snapshotPath(s.Name), data, 0600)
+}
+
+func loadSnapshot(name string) (*metricsSnapshot, error) {
+	data, err := os.ReadFile(snapshotPath(name))
+	if err != nil {
+		return nil, err
+	}
+	var s metricsSnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Metrics == nil {
+		s.Metrics = &AllMetrics{}
+	}
+	return &s, nil
+}
+
+func diffSets(before, after []string) setDiff {
+	d := setDiff{Added: []string{}, Removed: []string{}}
+	for _, v := range after {
+		if !slices.Contains(before, v) {
+			d.Added = append(d.Added, v)
+		}
+	}
+	for _, v := range before {
+		if !slices.Contains(after, v) {
+			d.Removed = append(d.Removed, v)
+		}
+	}
+	return d
+}
+
+func snapshotChange(name string, before, after float64) snapshotDelta {
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	return snapshotDelta{Name: name, Before: round(before), After: round(after), Delta: round(after - before)}
+}
+
+// diffByName pairs named values from both sides; a name missing on one side
+// counts as zero there.
+func diffByName(before, after map[string]float64) []snapshotDelta {
+	names := make(map[string]bool)
+	for k := range before {
+		names[k] = true
+	}
+	for k := range after {
+		names[k] = true
+	}
+	out := make([]snapshotDelta, 0, len(names))
+	for k := range names {
+		out = append(out, snapshotChange(k, before[k], after[k]))
+	}
+	sort.Slice(out, func(i, j int) bool { return math.Abs(out[i].Delta) > math.Abs(out[j].Delta) })
+	return out
+}
+
+func diffSnapshots(a, b *metricsSnapshot) snapshotDiff {
+	d := snapshotDiff{
+		From:      a.Name,
+		To:        b.Name,
+		Seconds:   b.Created - a.Created,
+		Processes: diffSets(a.Processes, b.Processes),
+		Listeners: diffSets(a.Listeners, b.Listeners),
+		Memory:    snapshotChange("used_mb", float64(a.Metrics.Memory.UsedMB), float64(b.Metrics.Memory.UsedMB)),
+	}
+
+	disks := func(m *AllMetrics) map[string]float64 {
+		out := make(map[string]float64)
+		for _, disk := range m.Disks {
+			out[disk.MountPoint] = disk.UsedGB
+		}
+		return out
+	}
+	d.Disks = diffByName(disks(a.Metrics), disks(b.Metrics))
+
+	storage := func(m *AllMetrics) map[string]float64 {
+		out := map[string]float64{"used": m.StorageBreak.UsedGB}
+		for _, c := range m.StorageBreak.Categories {
+			out[c.Name] = c.Size
+		}
+		return out
+	}
+	d.Storage = diffByName(storage(a.Metrics), storage(b.Metrics))
+	return d
+}
+
+type snapshotInfo struct {
+	Name    string `json:"name"`
+	Created int64  `json:"created"`
+}
+
+func listSnapshots() []snapshotInfo {
+	matches, _ := filepath.Glob(filepath.Join(dataPath("snapshots"), "*.json"))
+	out := make([]snapshotInfo, 0, len(matches))
+	for _, path := range matches {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		if s, err := loadSnapshot(name); err == nil {
+			out = append(out, snapshotInfo{Name: s.Name, Created: s.Created})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created > out[j].Created })
+	return out
+}
+
+// handleSnapshots manages named snapshots:
+//
+//	GET    /api/snapshots                 list
+//	POST   /api/snapshots {"name": "..."} capture now
+//	GET    /api/snapshots/<name>          full snapshot
+//	DELETE /api/snapshots/<name>
+//	GET    /api/snapshots/diff?from=a&to=b (to defaults to "now", a live capture)
+func handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/snapshots"), "/")
+
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(listSnapshots())
+
+	case name == "" && r.Method == http.MethodPost:
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !snapshotNameRegex.MatchString(body.Name) || body.Name == "diff" || body.Name == "now" {
+			http.Error(w, "Name must be 1-64 letters, digits, '_' or '-'", http.StatusBadRequest)
+			return
+		}
+		s := takeSnapshot(body.Name)
+		if err := saveSnapshot(s); err != nil {
+			http.Error(w, "Failed to save snapshot", http.StatusInternalServerError)
+			return
+		}
+		auditLog(r, "snapshot.create", map[string]string{"name": body.Name}, "ok")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(snapshotInfo{Name: s.Name, Created: s.Created})
+
+	case name == "diff" && r.Method == http.MethodGet:
+		q := r.URL.Query()
+		var sides [2]*metricsSnapshot
+		for i, n := range []string{q.Get("from"), q.Get("to")} {
+			if n == "" || n == "now" {
+				if i == 0 {
+					http.Error(w, "from is required", http.StatusBadRequest)
+					return
+				}
+				sides[i] = takeSnapshot("now")
+				continue
+			}
+			if !snapshotNameRegex.MatchString(n) {
+				http.Error(w, "Snapshot not found", http.StatusNotFound)
+				return
+			}
+			s, err := loadSnapshot(n)
+			if err != nil {
+				http.Error(w, "Snapshot not found: "+n, http.StatusNotFound)
+				return
+			}
+			sides[i] = s
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(diffSnapshots(sides[0], sides[1]))
+
+	case snapshotNameRegex.MatchString(name) && r.Method == http.MethodGet:
+		s, err := loadSnapshot(name)
+		if err != nil {
+			http.Error(w, "Snapshot not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s)
+
+	case snapshotNameRegex.MatchString(name) && r.Method == http.MethodDelete:
+		if err := os.Remove(snapshotPath(name)); err != nil {
+			http.Error(w, "Snapshot not found", http.StatusNotFound)
+			return
+		}
+		auditLog(r, "snapshot.delete", map[string]string{"name": name}, "ok")
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import "sync"
+
+const sparklineLen = 60 // matches the dashboard's SparkChart width
+
+// sparklineKeys are the series behind the dashboard's live charts.
+var sparklineKeys = []string{
+	"cpu.usage_percent",
+	"memory.used_percent",
+	"network.bytes_in_rate",
+	"network.bytes_out_rate",
+	"disk_io.read_mbps",
+	"disk_io.write_mbps",
+	"gpu.utilization",
+}
+
+// sparklineMsg is sent once when a dashboard connects so its charts start
+// with recent trend lines instead of empty. Series are aligned with T.
+type sparklineMsg struct {
+	Type   string               `json:"type"` // always "sparklines"
+	T      []int64              `json:"t"`
+	Series map[string][]float64 `json:"series"`
+}
+
+var (
+	sparkT      []int64
+	sparkValues [][]float64 // per sample, in sparklineKeys order
+	sparkMu     sync.Mutex
+)
+
+// recordSparklines keeps the last sparklineLen collections. CollectAll runs
+// for both the hub and HTTP snapshots, so the buffer fills even while no
+// dashboard is open.
+// sparklineTopics reports whether topics covers every sparkline series, so a
+// partial collection does not record zeros for the sections it skipped.
+func sparklineTopics(topics map[string]bool) bool {
+	return topics["cpu"] && topics["memory"] && topics["network"] && topics["diskio"] && topics["gpu"]
+}
+
+func recordSparklines(m *AllMetrics) {
+	v := []float64{
+		m.CPU.UsagePercent,
+		m.Memory.UsedPercent,
+		m.Network.BytesInRate,
+		m.Network.BytesOutRate,
+		m.DiskIO.ReadMBps,
+		m.DiskIO.WriteMBps,
+		float64(m.GPU.Utilization),
+	}
+	sparkMu.Lock()
+	defer sparkMu.Unlock()
+	if n := len(sparkT); n > 0 && m.Timestamp <= sparkT[n-1] {
+		return
+	}
+	sparkT = append(sparkT, m.Timestamp)
+	sparkValues = append(sparkValues, v)
+	if len(sparkT) > sparklineLen {
+		sparkT = append(sparkT[:0:0], sparkT[len(sparkT)-sparklineLen:]...)
+		sparkValues = append(sparkValues[:0:0], sparkValues[len(sparkValues)-sparklineLen:]...)
+	}
+}
+
+func sparklineSnapshot() sparklineMsg {
+	sparkMu.Lock()
+	defer sparkMu.Unlock()
+	msg := sparklineMsg{
+		Type:   "sparklines",
+		T:      append([]int64{}, sparkT...),
+		Series: make(map[string][]float64, len(sparklineKeys)),
+	}
+	for i, key := range sparklineKeys {
+		s := make([]float64, len(sparkValues))
+		for j, v := range sparkValues {
+			s[j] = v[i]
+		}
+		msg.Series[key] = s
+	}
+	return msg
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const maxSpeedTestResults = 500
+
+type SpeedTestResult struct {
+	Time           int64   `json:"time"`
+	DownloadMbps   float64 `json:"download_mbps"`
+	UploadMbps     float64 `json:"upload_mbps"`
+	BaseRTTMs      float64 `json:"base_rtt_ms"`
+	Responsiveness float64 `json:"responsiveness_rpm"`
+	Interface      string  `json:"interface"`
+	Trigger        string  `json:"trigger"` // "manual" or "schedule"
+	Error          string  `json:"error,omitempty"`
+}
+
+var (
+	speedTestMu      sync.Mutex // held while a test runs
+	speedTestStoreMu sync.Mutex
+
+	errSpeedTestRunning = errors.New("a speed test is already running")
+)
+
+func runSpeedTest(trigger string) (SpeedTestResult, error) {
+	if !speedTestMu.TryLock() {
+		return SpeedTestResult{}, errSpeedTestRunning
+	}
+	defer speedTestMu.Unlock()
+
+	res := SpeedTestResult{Time: time.Now().Unix(), Trigger: trigger}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "networkQuality", "-c").Output()
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		var raw struct {
+			DL         float64 `json:"dl_throughput"` // bits/s
+			UL         float64 `json:"ul_throughput"`
+			BaseRTT    float64 `json:"base_rtt"`
+			RPM        float64 `json:"responsiveness"`
+			DLRPM      float64 `json:"dl_responsiveness"`
+			Interface  string  `json:"interface_name"`
+			ErrorCode  int     `json:"error_code"`
+			ErrorDescr string  `json:"error_description"`
+		}
+		if err := json.Unmarshal(out, &raw); err != nil {
+			res.Error = fmt.Sprintf("unparseable networkQuality output: %v", err)
+		} else {
+			res.DownloadMbps = raw.DL / 1e6
+			res.UploadMbps = raw.UL / 1e6
+			res.BaseRTTMs = raw.BaseRTT
+			res.Responsiveness = raw.RPM
+			if res.Responsiveness == 0 {
+				res.Responsiveness = raw.DLRPM
+			}
+			res.Interface = raw.Interface
+			if raw.ErrorCode != 0 {
+				res.Error = raw.ErrorDescr
+			}
+		}
+	}
+
+	if err := appendSpeedTestResult(res); err != nil {
+		slog.Error("Failed to store speed test result", "err", err)
+	}
+	return res, nil
+}
+
+func loadSpeedTestResults() []SpeedTestResult {
+	results := []SpeedTestResult{}
+	data, err := os.ReadFile(dataPath("speedtest.json"))
+	if err != nil {
+		return results
+	}
+	json.Unmarshal(data, &results)
+	return results
+}
+
+func appendSpeedTestResult(res SpeedTestResult) error {
+	speedTestStoreMu.Lock()
+	defer speedTestStoreMu.Unlock()
+
+	results := append(loadSpeedTestResults(), res)
+	if len(results) > maxSpeedTestResults {
+		results = results[len(results)-maxSpeedTestResults:]
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath("speedtest.json"), data, 0600)
+}
+
+func handleSpeedTest(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		speedTestStoreMu.Lock()
+		results := loadSpeedTestResults()
+		speedTestStoreMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(results)
+
+	case http.MethodPost:
+		res, err := runSpeedTest("manual")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func startSpeedTestSchedule() {
+	at := GlobalConfig.SpeedTest.DailyAt
+	if at == "" {
+		return
+	}
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		slog.Warn("Scheduled speed test disabled: invalid speedtest.daily_at", "daily_at", at, "err", err)
+		return
+	}
+
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 1)
+			}
+			time.Sleep(time.Until(next))
+
+			if res, err := runSpeedTest("schedule"); err == nil && res.Error != "" {
+				slog.Warn("Scheduled speed test failed", "err", res.Error)
+			}
+		}
+	}()
+}
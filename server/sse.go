@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServeSSE streams the WebSocket payload as Server-Sent Events for networks
+// whose proxies block WebSocket upgrades. SSE clients join the hub like
+// WebSocket ones, so they count toward client_count and keep collection
+// running. ?topics=cpu,memory and ?rate=ms stand in for the subscribe and
+// set_rate commands; since SSE is one-way they are fixed for the connection.
+//
+// Metrics arrive as unnamed "message" events; the sparkline seed is sent
+// first as a "sparklines" event.
+func ServeSSE(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rc := http.NewResponseController(w)
+
+	client := &Client{
+		hub:         hub,
+		events:      make(chan []byte, 16),
+		id:          generateToken(6),
+		remoteAddr:  getRealIP(r),
+		userAgent:   r.UserAgent(),
+		connectedAt: time.Now(),
+	}
+	if ms, err := strconv.Atoi(r.URL.Query().Get("rate")); err == nil && validRefreshRate(ms) {
+		client.rate = time.Duration(ms) * time.Millisecond
+	}
+	if t := r.URL.Query().Get("topics"); t != "" {
+		client.subscribe("subscribe", strings.Split(t, ","))
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx would otherwise hold events back
+	w.WriteHeader(http.StatusOK)
+	rc.SetWriteDeadline(time.Time{}) // a stream outlives server.write_timeout_seconds
+
+	seed, _ := json.Marshal(sparklineSnapshot())
+	fmt.Fprintf(w, "retry: 3000\nevent: sparklines\ndata: %s\n\n", seed)
+	if err := rc.Flush(); err != nil {
+		slog.Warn("SSE flush failed", "err", err)
+		return
+	}
+
+	select {
+	case hub.register <- client:
+	case <-hub.quit:
+		return
+	}
+	defer func() {
+		select {
+		case hub.unregister <- client:
+		case <-hub.quit:
+		}
+	}()
+
+	keepalive := time.NewTicker(pingPeriod)
+	defer keepalive.Stop()
+	for {
+		var err error
+		select {
+		case data, ok := <-client.events:
+			if !ok {
+				return
+			}
+			rc.SetWriteDeadline(time.Now().Add(writeWait))
+			_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		case <-keepalive.C:
+			rc.SetWriteDeadline(time.Now().Add(writeWait))
+			_, err = w.Write([]byte(": keepalive\n\n"))
+		case <-r.Context().Done():
+			return
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// API explorer for /api/docs: renders /api/v1/openapi.json and sends requests
+// with the signed-in session, adding the CSRF header for writes.
+(function () {
+  const SPEC = "/api/v1/openapi.json";
+  const intro = document.getElementById("intro");
+  const list = document.getElementById("ops");
+  const filter = document.getElementById("filter");
+  let spec = null;
+
+  function el(tag, attrs, ...children) {
+    const e = document.createElement(tag);
+    for (const [k, v] of Object.entries(attrs || {})) {
+      if (k === "class") e.className = v;
+      else if (k.startsWith("on")) e.addEventListener(k.slice(2), v);
+      else e.setAttribute(k, v);
+    }
+    for (const c of children) if (c != null) e.append(c);
+    return e;
+  }
+
+  function cookie(name) {
+    const m = document.cookie.match("(?:^|; )" + name + "=([^;]*)");
+    return m ? decodeURIComponent(m[1]) : "";
+  }
+
+  function resolve(schema) {
+    if (schema && schema.$ref) {
+      const name = schema.$ref.split("/").pop();
+      return spec.components.schemas[name] || {};
+    }
+    return schema || {};
+  }
+
+  // example builds a placeholder body from a schema, skipping read-only
+  // bookkeeping such as timestamps the server fills in.
+  function example(schema, depth) {
+    schema = resolve(schema);
+    if (depth > 4) return null;
+    switch (schema.type) {
+      case "object": {
+        if (!schema.properties) return {};
+        const out = {};
+        for (const [k, v] of Object.entries(schema.properties)) {
+          if (k === "updated") continue;
+          out[k] = example(v, depth + 1);
+        }
+        return out;
+      }
+      case "array": return [example(schema.items, depth + 1)];
+      case "integer": case "number": return 0;
+      case "boolean": return false;
+      case "string": return "";
+    }
+    return null;
+  }
+
+  function render() {
+    list.replaceChildren();
+    const q = filter.value.trim().toLowerCase();
+    const groups = {};
+    for (const [path, item] of Object.entries(spec.paths)) {
+      for (const [method, op] of Object.entries(item)) {
+        const text = (method + " " + path + " " + (op.summary || "")).toLowerCase();
+        if (q && !text.includes(q)) continue;
+        const group = path.replace(/^\/api\/v1\//, "").replace(/^\//, "").split("/")[0];
+        (groups[group] = groups[group] || []).push([path, method.toUpperCase(), op]);
+      }
+    }
+    for (const name of Object.keys(groups).sort()) {
+      list.append(el("h2", {}, name));
+      for (const [path, method, op] of groups[name]) list.append(operation(path, method, op));
+    }
+  }
+
+  function operation(path, method, op) {
+    const inputs = {};
+    const params = el("div", { class: "params" });
+    for (const p of op.parameters || []) {
+      const id = "p-" + method + path + "-" + p.name;
+      params.append(el("label", { for: id, class: p.required ? "req" : "" }, p.name));
+      const input = el("input", { id, placeholder: p.description || p.in });
+      inputs[p.name] = [p, input];
+      params.append(input);
+    }
+
+    let body = null;
+    const content = op.requestBody && op.requestBody.content;
+    if (content && content["application/json"]) {
+      body = el("textarea", { spellcheck: "false" });
+      body.value = JSON.stringify(example(content["application/json"].schema, 0), null, 2);
+    }
+
+    const result = el("div");
+    const send = el("button", { class: "send", type: "button" }, "Send");
+    send.addEventListener("click", () => run(path, method, inputs, body, result, send));
+
+    const security = op.security && op.security.length === 0 ? "no sign-in" :
+      op.security && op.security.some(s => s.bearer) ? "bearer token or session" : null;
+    return el("details", { class: "op" + (op.deprecated ? " deprecated" : "") },
+      el("summary", {},
+        el("span", { class: "method " + method }, method),
+        el("span", { class: "path" }, path),
+        el("span", { class: "summary" }, op.summary || ""),
+        op.deprecated ? el("span", { class: "badge" }, "deprecated") : null),
+      el("div", { class: "body" },
+        security ? el("div", { class: "hint" }, "Auth: " + security) : null,
+        Object.keys(inputs).length ? params : null,
+        body, send, result));
+  }
+
+  async function run(path, method, inputs, body, result, button) {
+    let url = path;
+    const query = new URLSearchParams();
+    for (const [name, [p, input]] of Object.entries(inputs)) {
+      const v = input.value.trim();
+      if (p.in === "path") url = url.replace("{" + name + "}", encodeURIComponent(v));
+      else if (v !== "") query.set(name, v);
+    }
+    if ([...query].length) url += "?" + query;
+
+    const init = { method, credentials: "same-origin", headers: {} };
+    if (method !== "GET") init.headers["X-CSRF-Token"] = cookie("talaria_csrf");
+    if (body) {
+      init.headers["Content-Type"] = "application/json";
+      init.body = body.value;
+    }
+
+    button.disabled = true;
+    const started = performance.now();
+    try {
+      const resp = await fetch(url, init);
+      const ms = Math.round(performance.now() - started);
+      const type = resp.headers.get("Content-Type") || "";
+      const head = el("div", {},
+        el("span", { class: "status " + (resp.ok ? "ok" : "err") }, resp.status + " " + resp.statusText),
+        el("span", { class: "hint" }, "  " + ms + " ms · " + (type || "no content") + " · " + url));
+      let out;
+      if (type.includes("json")) {
+        const text = await resp.text();
+        try { out = el("pre", {}, JSON.stringify(JSON.parse(text), null, 2)); } catch { out = el("pre", {}, text); }
+      } else if (type.startsWith("text/") || type.includes("ndjson")) {
+        out = el("pre", {}, await resp.text());
+      } else if (resp.status !== 204 && resp.status !== 304) {
+        const blob = await resp.blob();
+        out = el("p", {}, el("a", { href: URL.createObjectURL(blob), download: "" }, "Download response (" + blob.size + " bytes)"));
+      }
+      result.replaceChildren(head, out || "");
+    } catch (e) {
+      result.replaceChildren(el("pre", { class: "err" }, String(e)));
+    } finally {
+      button.disabled = false;
+    }
+  }
+
+  filter.addEventListener("input", () => spec && render());
+
+  fetch(SPEC, { credentials: "same-origin" }).then(async resp => {
+    if (resp.status === 401) {
+      intro.replaceChildren("Sign in on the ", el("a", { href: "/" }, "dashboard"), " first, then reload this page.");
+      return;
+    }
+    if (!resp.ok) throw new Error(resp.status + " " + resp.statusText);
+    spec = await resp.json();
+    intro.replaceChildren((spec.info && spec.info.description) || "", " ",
+      el("a", { href: SPEC }, "OpenAPI document"));
+    render();
+  }).catch(e => { intro.textContent = "Could not load " + SPEC + ": " + e.message; });
+})();
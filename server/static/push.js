@@ -0,0 +1,58 @@
+// Browser push notifications: registers sw.js and keeps the server's
+// subscription list in sync with the bell button in the header.
+(() => {
+  const btn = document.getElementById("pushToggle");
+  if (!btn || !("serviceWorker" in navigator) || !("PushManager" in window)) return;
+
+  const b64ToBytes = (s) => {
+    const pad = "=".repeat((4 - (s.length % 4)) % 4);
+    const raw = atob((s + pad).replace(/-/g, "+").replace(/_/g, "/"));
+    return Uint8Array.from(raw, (c) => c.charCodeAt(0));
+  };
+
+  const csrfToken = () => {
+    const m = document.cookie.match(/(?:^|; )talaria_csrf=([^;]*)/);
+    return m ? decodeURIComponent(m[1]) : "";
+  };
+
+  const post = (path, sub) =>
+    fetch(path, {
+      method: "POST",
+      headers: { "Content-Type": "application/json", "X-CSRF-Token": csrfToken() },
+      body: JSON.stringify(sub),
+    });
+
+  const render = (sub) => {
+    btn.textContent = sub ? "🔔" : "🔕";
+    btn.title = sub ? "Browser notifications on" : "Browser notifications off";
+  };
+
+  let reg;
+  fetch("/api/push/key")
+    .then((r) => (r.ok ? r.json() : Promise.reject()))
+    .then(async ({ public_key }) => {
+      reg = await navigator.serviceWorker.register("/sw.js");
+      const sub = await reg.pushManager.getSubscription();
+      if (sub) post("/api/push/subscribe", sub); // refresh after a server reset
+      render(sub);
+      btn.hidden = false;
+
+      window.togglePush = async () => {
+        const current = await reg.pushManager.getSubscription();
+        if (current) {
+          await post("/api/push/unsubscribe", current);
+          await current.unsubscribe();
+          render(null);
+          return;
+        }
+        if ((await Notification.requestPermission()) !== "granted") return;
+        const created = await reg.pushManager.subscribe({
+          userVisibleOnly: true,
+          applicationServerKey: b64ToBytes(public_key),
+        });
+        await post("/api/push/subscribe", created);
+        render(created);
+      };
+    })
+    .catch(() => {});
+})();
@@ -0,0 +1,29 @@
+self.addEventListener("push", (event) => {
+  let data = {};
+  try {
+    data = event.data ? event.data.json() : {};
+  } catch (e) {
+    data = { title: "Talaria", body: event.data ? event.data.text() : "" };
+  }
+  const title = data.host ? `${data.title} — ${data.host}` : data.title || "Talaria";
+  event.waitUntil(
+    self.registration.showNotification(title, {
+      body: data.body || "",
+      tag: data.tag,
+      renotify: !!data.tag,
+      timestamp: data.time ? data.time * 1000 : Date.now(),
+    })
+  );
+});
+
+self.addEventListener("notificationclick", (event) => {
+  event.notification.close();
+  event.waitUntil(
+    clients.matchAll({ type: "window", includeUncontrolled: true }).then((list) => {
+      for (const c of list) {
+        if ("focus" in c) return c.focus();
+      }
+      return clients.openWindow("/");
+    })
+  );
+});
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStatsdInterval = 10 * time.Second
+	maxStatsdPacket       = 1432 // fits a 1500-byte MTU after IP/UDP headers
+)
+
+// startStatsdExport emits every tick's metrics over UDP, either as StatsD
+// gauges ("prefix.cpu.usage_percent:12.5|g") or Graphite plaintext
+// ("prefix.cpu.usage_percent 12.5 1760000000"). Carbon only accepts the
+// latter over UDP with ENABLE_UDP_LISTENER set.
+func startStatsdExport() {
+	cfg := GlobalConfig.Exporters.Statsd
+	if !cfg.Enabled || cfg.Address == "" {
+		return
+	}
+	graphite := cfg.Protocol == "graphite"
+	if !graphite && cfg.Protocol != "" && cfg.Protocol != "statsd" {
+		slog.Warn("StatsD exporter disabled: exporters.statsd.protocol is not statsd or graphite", "protocol", cfg.Protocol)
+		return
+	}
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultStatsdInterval
+	}
+	prefix := cfg.Prefix
+	if prefix == "" {
+		host, _ := os.Hostname()
+		prefix = "talaria." + statsdName(strings.Split(host, ".")[0])
+	}
+	prefix = strings.TrimSuffix(prefix, ".")
+
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		slog.Warn("StatsD exporter disabled", "err", err)
+		return
+	}
+
+	go func() {
+		defer conn.Close()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		failing := false
+		for range ticker.C {
+			m := backgroundMetrics()
+			if m == nil {
+				continue
+			}
+			lines := statsdLines(flattenMetrics(m), prefix, m.Timestamp/1000, graphite, cfg.Metrics)
+			var err error
+			for _, p := range statsdPackets(lines) {
+				if _, err = conn.Write(p); err != nil {
+					break
+				}
+			}
+			// UDP only reports errors such as ICMP port unreachable from a
+			// previous write, so log transitions rather than every tick.
+			switch {
+			case err != nil && !failing:
+				slog.Warn("StatsD export failing", "address", cfg.Address, "err", err)
+			case err == nil && failing:
+				slog.Info("StatsD export recovered", "address", cfg.Address)
+			}
+			failing = err != nil
+		}
+	}()
+}
+
+func statsdLines(values map[string]float64, prefix string, unix int64, graphite bool, prefixes []string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if metricSelected(k, prefixes) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		name := prefix + "." + statsdName(k)
+		v := strconv.FormatFloat(values[k], 'f', -1, 64)
+		if graphite {
+			lines = append(lines, fmt.Sprintf("%s %s %d", name, v, unix))
+		} else {
+			lines = append(lines, name+":"+v+"|g")
+		}
+	}
+	return lines
+}
+
+// statsdName keeps dots as the hierarchy separator and replaces anything
+// either protocol treats specially (":", "|", "@", whitespace) with "_".
+func statsdName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// statsdPackets joins lines into newline-separated datagrams of at most
+// maxStatsdPacket bytes.
+func statsdPackets(lines []string) [][]byte {
+	var packets [][]byte
+	var cur []byte
+	for _, l := range lines {
+		if len(cur) > 0 && len(cur)+1+len(l) > maxStatsdPacket {
+			packets = append(packets, cur)
+			cur = nil
+		}
+		if len(cur) > 0 {
+			cur = append(cur, '\n')
+		}
+		cur = append(cur, l...)
+	}
+	if len(cur) > 0 {
+		packets = append(packets, cur)
+	}
+	return packets
+}
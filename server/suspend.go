@@ -0,0 +1,196 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const maxSuspendDuration = 24 * time.Hour
+
+type suspendedProc struct {
+	PID         int    `json:"pid"`
+	SuspendedAt int64  `json:"suspended_at"`
+	ResumeAt    int64  `json:"resume_at"`
+	startStamp  string // ps lstart, guards against PID reuse
+	timer       *time.Timer
+}
+
+var (
+	suspended   = make(map[int]*suspendedProc)
+	suspendedMu sync.Mutex
+)
+
+func processStartStamp(pid int) string {
+	out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "lstart=").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func suspendProcess(pid int, d time.Duration) (*suspendedProc, error) {
+	if d <= 0 || d > maxSuspendDuration {
+		return nil, &processError{http.StatusBadRequest, fmt.Sprintf("Duration must be between 1s and %s", maxSuspendDuration)}
+	}
+	if err := checkProcessOwnership(pid, "suspend"); err != nil {
+		return nil, err
+	}
+
+	stamp := processStartStamp(pid)
+	if err := syscall.Kill(pid, syscall.SIGSTOP); err != nil {
+		return nil, &processError{http.StatusInternalServerError, fmt.Sprintf("Failed to suspend process: %v", err)}
+	}
+
+	now := time.Now()
+	sp := &suspendedProc{
+		PID:         pid,
+		SuspendedAt: now.Unix(),
+		ResumeAt:    now.Add(d).Unix(),
+		startStamp:  stamp,
+	}
+	sp.timer = time.AfterFunc(d, func() { resumeProcess(pid, true) })
+
+	suspendedMu.Lock()
+	if prev, ok := suspended[pid]; ok {
+		prev.timer.Stop()
+	}
+	suspended[pid] = sp
+	suspendedMu.Unlock()
+
+	slog.Info("Process suspended", "pid", pid, "until", time.Unix(sp.ResumeAt, 0).Format("15:04:05"))
+	return sp, nil
+}
+
+// resumeProcess sends SIGCONT to a process suspended through Talaria. A
+// process that is not tracked is still continued when scheduled is false.
+func resumeProcess(pid int, scheduled bool) error {
+	suspendedMu.Lock()
+	sp, ok := suspended[pid]
+	if ok {
+		sp.timer.Stop()
+		delete(suspended, pid)
+	}
+	suspendedMu.Unlock()
+
+	if ok && sp.startStamp != "" && processStartStamp(pid) != sp.startStamp {
+		slog.Warn("Not resuming process: it was replaced while suspended", "pid", pid)
+		return nil
+	}
+	if !ok && scheduled {
+		return nil
+	}
+
+	if err := syscall.Kill(pid, syscall.SIGCONT); err != nil {
+		return err
+	}
+	if scheduled {
+		slog.Info("Process automatically resumed", "pid", pid)
+	}
+	return nil
+}
+
+// forgetSuspended drops the pending auto-resume without signalling, e.g.
+// after the process was continued or killed by other means.
+func forgetSuspended(pid int) {
+	suspendedMu.Lock()
+	if sp, ok := suspended[pid]; ok {
+		sp.timer.Stop()
+		delete(suspended, pid)
+	}
+	suspendedMu.Unlock()
+}
+
+// ResumeSuspendedProcesses continues everything still paused so that
+// stopping Talaria never leaves processes frozen.
+func ResumeSuspendedProcesses() {
+	suspendedMu.Lock()
+	pids := make([]int, 0, len(suspended))
+	for pid := range suspended {
+		pids = append(pids, pid)
+	}
+	suspendedMu.Unlock()
+
+	for _, pid := range pids {
+		resumeProcess(pid, true)
+	}
+}
+
+func listSuspended() []suspendedProc {
+	suspendedMu.Lock()
+	defer suspendedMu.Unlock()
+
+	list := make([]suspendedProc, 0, len(suspended))
+	for _, sp := range suspended {
+		list = append(list, suspendedProc{PID: sp.PID, SuspendedAt: sp.SuspendedAt, ResumeAt: sp.ResumeAt})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ResumeAt < list[j].ResumeAt })
+	return list
+}
+
+func handleSuspend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	pid, err := strconv.Atoi(r.URL.Query().Get("pid"))
+	if err != nil {
+		http.Error(w, "Invalid pid", http.StatusBadRequest)
+		return
+	}
+	secs, err := strconv.Atoi(r.URL.Query().Get("duration"))
+	if err != nil {
+		http.Error(w, "Invalid duration", http.StatusBadRequest)
+		return
+	}
+
+	params := processAuditParams(pid)
+	params["duration"] = strconv.Itoa(secs)
+	sp, err := suspendProcess(pid, time.Duration(secs)*time.Second)
+	auditLog(r, "process.suspend", params, auditResult(err))
+	if err != nil {
+		writeProcessError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sp)
+}
+
+func handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	pid, err := strconv.Atoi(r.URL.Query().Get("pid"))
+	if err != nil {
+		http.Error(w, "Invalid pid", http.StatusBadRequest)
+		return
+	}
+	params := processAuditParams(pid)
+	if err := checkProcessOwnership(pid, "resume"); err != nil {
+		auditLog(r, "process.resume", params, auditResult(err))
+		writeProcessError(w, err)
+		return
+	}
+	if err := resumeProcess(pid, false); err != nil {
+		auditLog(r, "process.resume", params, auditResult(err))
+		http.Error(w, fmt.Sprintf("Failed to resume process: %v", err), http.StatusInternalServerError)
+		return
+	}
+	auditLog(r, "process.resume", params, "ok")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Process %d resumed", pid)
+}
+
+func handleSuspendedList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listSuspended())
+}
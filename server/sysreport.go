@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	systemReportWindow    = 24 * time.Hour
+	systemReportProcesses = 15
+)
+
+// systemReport is the one-off document served by /api/report: everything a
+// support engineer usually asks for, in a single self-contained file.
+type systemReport struct {
+	weeklyReport
+	Hardware  monitor.HardwareInfo
+	Findings  []string
+	Storage   monitor.StorageBreakdown
+	Disks     []monitor.DiskInfo
+	Processes []monitor.ProcessInfo
+}
+
+func buildSystemReport(now time.Time) systemReport {
+	from := now.Add(-systemReportWindow)
+	samples := historyRange(from.UnixMilli(), now.UnixMilli())
+
+	rep := systemReport{Hardware: monitor.GetHardwareInfo()}
+	rep.weeklyReport = weeklyReport{From: from, To: now, Samples: len(samples), Generated: now}
+	rep.Host, _ = os.Hostname()
+	rep.Model, rep.Chip = rep.Hardware.ModelName, rep.Hardware.Chip
+	for _, rm := range reportMetrics {
+		if s, ok := summarize(samples, rm.key); ok {
+			rep.Charts = append(rep.Charts, reportChart{Label: rm.label, Unit: rm.unit, Summary: s, SVG: svgChart(samples, rm.key, from, now)})
+		}
+	}
+	for _, e := range recentEvents(0) {
+		if e.Kind == "alert" && e.Fields["state"] == alertFiring && e.Time >= from.Unix() {
+			rep.Alerts = append(rep.Alerts, e)
+		}
+	}
+
+	m := latestMetrics()
+	if m == nil {
+		return rep
+	}
+	rep.OSVersion, rep.Uptime = m.System.OSVersion, m.System.Uptime
+	rep.Findings = healthFindings(m)
+	rep.Storage, rep.Disks = m.StorageBreak, m.Disks
+
+	procs := append([]monitor.ProcessInfo(nil), m.Processes...)
+	sort.Slice(procs, func(i, j int) bool { return procs[i].CPU > procs[j].CPU })
+	rep.Processes = procs[:min(len(procs), systemReportProcesses)]
+	return rep
+}
+
+// healthFindings lists the things a technician would flag, worst first.
+func healthFindings(m *AllMetrics) []string {
+	h := m.Health
+	var out []string
+	if !h.SIPEnabled {
+		out = append(out, "System Integrity Protection is disabled")
+	}
+	if !h.FileVaultEnabled {
+		out = append(out, "FileVault disk encryption is off")
+	}
+	if !h.FirewallEnabled {
+		out = append(out, "Application firewall is off")
+	}
+	switch {
+	case h.TimeMachineAgeMins < 0:
+		out = append(out, "No Time Machine backup on record")
+	case h.TimeMachineAgeMins > 7*24*60:
+		out = append(out, "Last Time Machine backup was "+h.TimeMachineAgeLabel+" ago")
+	}
+	if h.KernelErrorsLast5m > 0 {
+		out = append(out, fmt.Sprintf("%d kernel errors in the last 5 minutes (trend %s)", h.KernelErrorsLast5m, h.ErrorTrend))
+	}
+	for _, s := range h.LeakSuspects {
+		out = append(out, fmt.Sprintf("%s (PID %d) memory has grown steadily; possible leak", s.Name, s.PID))
+	}
+	if total := m.StorageBreak.TotalGB; total > 0 && m.StorageBreak.FreeGB/total < 0.1 {
+		out = append(out, fmt.Sprintf("Boot volume is nearly full (%.1f GB free)", m.StorageBreak.FreeGB))
+	}
+	if b := m.Battery; b.HasBattery && b.HealthPercent > 0 && b.HealthPercent < defaultBatteryMinHealth {
+		out = append(out, fmt.Sprintf("Battery health is %.0f%% after %d cycles", b.HealthPercent, b.CycleCount))
+	}
+	return out
+}
+
+var systemReportTemplate = template.Must(template.Must(reportTemplate.Clone()).New("system").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Talaria system report — {{.Host}}</title>
+<style>
+body{font-family:-apple-system,Helvetica,Arial,sans-serif;color:#1d1d1f;max-width:760px;margin:24px auto;font-size:14px}
+h3{margin:24px 0 6px}
+table{border-collapse:collapse}
+td,th{padding:2px 12px 2px 0;text-align:left;vertical-align:top}
+th,.dim{color:#666;font-weight:normal}
+.num{text-align:right}
+@media print{h3{break-after:avoid}svg{break-inside:avoid}}
+</style></head>
+<body>
+<h2 style="margin-bottom:4px">{{.Host}}</h2>
+<div class="dim">System report · {{.Generated.Format "2006-01-02 15:04 MST"}}</div>
+
+<h3>System</h3>
+<table>
+{{if .Model}}<tr><th>Model</th><td>{{.Model}}{{if .Hardware.ModelID}} ({{.Hardware.ModelID}}){{end}}</td></tr>{{end}}
+{{if .Chip}}<tr><th>Chip</th><td>{{.Chip}}</td></tr>{{end}}
+{{if .Hardware.MemoryTotal}}<tr><th>Memory</th><td>{{.Hardware.MemoryTotal}}</td></tr>{{end}}
+{{if .Hardware.Serial}}<tr><th>Serial</th><td>{{.Hardware.Serial}}</td></tr>{{end}}
+{{if .OSVersion}}<tr><th>OS</th><td>{{.OSVersion}}</td></tr>{{end}}
+<tr><th>Uptime</th><td>{{.Uptime}}</td></tr>
+</table>
+
+<h3>Health findings</h3>
+{{if .Findings}}<ul>{{range .Findings}}<li>{{.}}</li>{{end}}</ul>{{else}}<p>Nothing to flag.</p>{{end}}
+
+<h3>Storage</h3>
+{{if .Storage.TotalGB}}<p>{{f1 .Storage.UsedGB}} GB used of {{f1 .Storage.TotalGB}} GB ({{f1 .Storage.FreeGB}} GB free{{if .Storage.PurgeableGB}}, {{f1 .Storage.PurgeableGB}} GB purgeable{{end}})</p>
+<table>{{range .Storage.Categories}}<tr><td>{{.Name}}</td><td class="num">{{f1 .Size}} GB</td></tr>{{end}}</table>{{end}}
+{{if .Disks}}<table style="margin-top:8px"><tr><th>Volume</th><th>Type</th><th class="num">Used</th><th class="num">Free</th></tr>
+{{range .Disks}}<tr><td>{{.MountPoint}}</td><td>{{.Filesystem}}</td><td class="num">{{f1 .UsedPct}}%</td><td class="num">{{f1 .FreeGB}} GB</td></tr>{{end}}</table>{{end}}
+
+<h3>Top processes</h3>
+{{if .Processes}}<table><tr><th class="num">PID</th><th>Name</th><th>User</th><th class="num">CPU</th><th class="num">Memory</th></tr>
+{{range .Processes}}<tr><td class="num">{{.PID}}</td><td>{{.Name}}</td><td>{{.User}}</td><td class="num">{{f1 .CPU}}%</td><td class="num">{{f1 .MemMB}} MB</td></tr>{{end}}</table>
+{{else}}<p>No process data.</p>{{end}}
+
+<h3>Last 24 hours</h3>
+{{if not .Samples}}<p>No history was recorded in this period.</p>{{end}}
+{{range .Charts}}
+<div style="margin-top:12px"><b>{{.Label}}</b> <span class="dim">avg {{f1 .Summary.Avg}}{{.Unit}} · min {{f1 .Summary.Min}}{{.Unit}} · max {{f1 .Summary.Max}}{{.Unit}}</span></div>
+{{.SVG}}
+{{end}}
+
+<h3>Alerts</h3>
+{{if .Alerts}}<ul>{{range .Alerts}}<li>{{unix .Time}} — {{.Title}}: {{.Message}}</li>{{end}}</ul>
+{{else}}<p>No alerts fired.</p>{{end}}
+<p class="dim" style="font-size:12px;margin-top:32px">Generated by Talaria</p>
+</body></html>
+`))
+
+// Chromium-based browsers can print HTML to PDF headlessly; macOS has no
+// built-in converter that keeps the layout and SVG charts.
+var pdfBrowsers = []string{
+	"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+	"/Applications/Chromium.app/Contents/MacOS/Chromium",
+	"/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+	"/Applications/Brave Browser.app/Contents/MacOS/Brave Browser",
+	"google-chrome", "chromium", "chromium-browser",
+}
+
+var errNoPDFBrowser = errors.New("PDF output needs Google Chrome, Chromium, Edge or Brave; download the HTML and print it instead")
+
+func htmlToPDF(ctx context.Context, html []byte) ([]byte, error) {
+	browser := ""
+	for _, b := range pdfBrowsers {
+		if p, err := exec.LookPath(b); err == nil {
+			browser = p
+			break
+		}
+	}
+	if browser == "" {
+		return nil, errNoPDFBrowser
+	}
+
+	dir, err := os.MkdirTemp("", "talaria-report")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "report.html"), filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(in, html, 0600); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, browser, "--headless", "--disable-gpu", "--no-pdf-header-footer",
+		"--user-data-dir="+filepath.Join(dir, "profile"), "--print-to-pdf="+out, "file://"+in)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", filepath.Base(browser), err, bytes.TrimSpace(output))
+	}
+	return os.ReadFile(out)
+}
+
+// handleSystemReport serves the full system report as a download.
+// ?format=pdf converts it with a headless browser when one is installed.
+func handleSystemReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	now := time.Now()
+	rep := buildSystemReport(now)
+	var buf bytes.Buffer
+	if err := systemReportTemplate.ExecuteTemplate(&buf, "system", rep); err != nil {
+		http.Error(w, "Failed to render report", http.StatusInternalServerError)
+		return
+	}
+	name := fmt.Sprintf("talaria-%s-%s", rep.Host, now.Format("2006-01-02-1504"))
+
+	switch r.URL.Query().Get("format") {
+	case "", "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.html"`)
+		w.Write(buf.Bytes())
+	case "pdf":
+		pdf, err := htmlToPDF(r.Context(), buf.Bytes())
+		if errors.Is(err, errNoPDFBrowser) {
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+			return
+		}
+		if err != nil {
+			http.Error(w, "PDF conversion failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.pdf"`)
+		w.Write(pdf)
+	default:
+		http.Error(w, "format must be html or pdf", http.StatusBadRequest)
+	}
+}
@@ -2,7 +2,7 @@ package server
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
@@ -16,6 +16,7 @@ import (
 var termUpgrader = websocket.Upgrader{
 	ReadBufferSize:  4096,
 	WriteBufferSize: 4096,
+	CheckOrigin:     sameOrigin,
 }
 
 type termMsg struct {
@@ -28,7 +29,7 @@ type termMsg struct {
 func ServeTerminal(w http.ResponseWriter, r *http.Request) {
 	conn, err := termUpgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("Terminal WS upgrade error: %v", err)
+		slog.Warn("Terminal WebSocket upgrade failed", "err", err)
 		return
 	}
 
@@ -56,16 +57,22 @@ func ServeTerminal(w http.ResponseWriter, r *http.Request) {
 	)
 
 	ptmx, err := pty.Start(cmd)
+	auditLog(r, "terminal.open", map[string]string{"shell": shell}, auditResult(err))
 	if err != nil {
-		log.Printf("PTY start error: %v", err)
+		slog.Error("PTY start failed", "err", err)
 		conn.WriteJSON(termMsg{Type: "exit", Data: "Failed to start shell: " + err.Error()})
 		conn.Close()
 		return
 	}
 
+	opened := time.Now()
 	var closeOnce sync.Once
 	cleanup := func() {
 		closeOnce.Do(func() {
+			auditLog(r, "terminal.close", map[string]string{
+				"shell":    shell,
+				"duration": time.Since(opened).Round(time.Second).String(),
+			}, "ok")
 			ptmx.Close()
 			_ = cmd.Process.Kill()
 			_ = cmd.Wait()
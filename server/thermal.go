@@ -0,0 +1,70 @@
+package server
+
+import (
+	"log/slog"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	thermalSlowdown    = 4
+	minThermalInterval = 5 * time.Second
+)
+
+// adjustForThermal slows the broadcast ticker and pauses the expensive
+// collectors while the machine is Serious/Critical, and restores both once it
+// is back to Nominal. Fair keeps whatever mode we are in so the rate doesn't
+// flap around the threshold. Only called from Run.
+func (h *Hub) adjustForThermal(state string) {
+	switch state {
+	case "Serious", "Critical":
+		if h.throttled || GlobalConfig.ThermalBackoff.Disabled {
+			return
+		}
+		h.throttled = true
+	case "Nominal":
+		if !h.throttled {
+			return
+		}
+		h.throttled = false
+	default:
+		return
+	}
+
+	monitor.SetThrottled(h.throttled)
+	h.ticker.Reset(h.interval())
+
+	if h.throttled {
+		slog.Info("Thermal throttling collection", "state", state, "interval", h.interval())
+		RaiseEvent(Event{
+			Kind:     "system",
+			Severity: SeverityWarning,
+			Title:    "Collection slowed down",
+			Message:  "Thermal state is " + state + "; refresh rate reduced and expensive collectors paused",
+			Fields:   map[string]string{"thermal_state": state},
+		})
+	} else {
+		slog.Info("Thermal state back to Nominal", "interval", h.interval())
+		RaiseEvent(Event{
+			Kind:    "system",
+			Title:   "Collection resumed",
+			Message: "Thermal state is back to Nominal",
+			Fields:  map[string]string{"thermal_state": state},
+		})
+	}
+}
+
+func (h *Hub) interval() time.Duration {
+	if !h.throttled {
+		return h.rate
+	}
+	factor := GlobalConfig.ThermalBackoff.Slowdown
+	if factor <= 1 {
+		factor = thermalSlowdown
+	}
+	d := h.rate * time.Duration(factor)
+	if d < minThermalInterval {
+		d = minThermalInterval
+	}
+	return d
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"talaria/monitor"
+	"time"
+)
+
+const (
+	threatScanInterval = 30 * time.Second
+	threatRealertAfter = time.Hour
+	maxThreatListBytes = 50 << 20
+)
+
+type threatList struct {
+	addrs    map[netip.Addr]string
+	prefixes []threatPrefix
+}
+
+type threatPrefix struct {
+	prefix netip.Prefix
+	source string
+}
+
+var (
+	threats   *threatList
+	threatsMu sync.RWMutex
+
+	threatAlerted = make(map[string]time.Time) // "ip|pid" → last alert
+)
+
+func startThreatIntel() {
+	cfg := GlobalConfig.ThreatIntel
+	if !cfg.Enabled {
+		return
+	}
+
+	refresh := time.Duration(cfg.RefreshHours) * time.Hour
+	if refresh <= 0 {
+		refresh = 24 * time.Hour
+	}
+
+	go func() {
+		reloadThreatLists()
+		reload := time.NewTicker(refresh)
+		scan := time.NewTicker(threatScanInterval)
+		defer reload.Stop()
+		defer scan.Stop()
+
+		scanConnectionsForThreats()
+		for {
+			select {
+			case <-reload.C:
+				reloadThreatLists()
+			case <-scan.C:
+				if !collectionIdle() {
+					scanConnectionsForThreats()
+				}
+			}
+		}
+	}()
+}
+
+func reloadThreatLists() {
+	list := &threatList{addrs: make(map[netip.Addr]string)}
+
+	for _, path := range GlobalConfig.ThreatIntel.Files {
+		f, err := os.Open(path)
+		if err != nil {
+			slog.Warn("Threat list unavailable", "path", path, "err", err)
+			continue
+		}
+		list.parse(f, path)
+		f.Close()
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	for _, url := range GlobalConfig.ThreatIntel.URLs {
+		resp, err := client.Get(url)
+		if err != nil {
+			slog.Warn("Threat list unavailable", "url", url, "err", err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			slog.Warn("Threat list unavailable", "url", url, "status", resp.Status)
+			resp.Body.Close()
+			continue
+		}
+		list.parse(io.LimitReader(resp.Body, maxThreatListBytes), url)
+		resp.Body.Close()
+	}
+
+	threatsMu.Lock()
+	threats = list
+	threatsMu.Unlock()
+	slog.Info("Threat intel loaded", "addresses", len(list.addrs), "networks", len(list.prefixes))
+}
+
+// Accepts one IP or CIDR per line; "#" / ";" comments and trailing columns are ignored.
+func (l *threatList) parse(r io.Reader, source string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		field := strings.Fields(line)[0]
+		field = strings.TrimRight(field, ",;")
+
+		if strings.Contains(field, "/") {
+			if p, err := netip.ParsePrefix(field); err == nil {
+				l.prefixes = append(l.prefixes, threatPrefix{prefix: p.Masked(), source: source})
+			}
+			continue
+		}
+		if a, err := netip.ParseAddr(field); err == nil {
+			l.addrs[a.Unmap()] = source
+		}
+	}
+}
+
+func threatLookup(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	threatsMu.RLock()
+	defer threatsMu.RUnlock()
+	if threats == nil {
+		return ""
+	}
+	if src, ok := threats.addrs[addr]; ok {
+		return src
+	}
+	for _, p := range threats.prefixes {
+		if p.prefix.Contains(addr) {
+			return p.source
+		}
+	}
+	return ""
+}
+
+// annotateThreats returns a copy of d with ThreatMatch filled in; the
+// original is shared with the connection cache.
+func annotateThreats(d monitor.ConnectionDetails) monitor.ConnectionDetails {
+	if !GlobalConfig.ThreatIntel.Enabled {
+		return d
+	}
+	active := make([]monitor.ConnectionInfo, len(d.Active))
+	for i, c := range d.Active {
+		c.ThreatMatch = threatLookup(c.RemoteIP)
+		active[i] = c
+	}
+	d.Active = active
+	return d
+}
+
+func scanConnectionsForThreats() {
+	d := annotateThreats(monitor.GetConnectionDetails())
+	now := time.Now()
+
+	for _, c := range d.Active {
+		if c.ThreatMatch == "" {
+			continue
+		}
+		key := fmt.Sprintf("%s|%d", c.RemoteIP, c.PID)
+		if last, ok := threatAlerted[key]; ok && now.Sub(last) < threatRealertAfter {
+			continue
+		}
+		threatAlerted[key] = now
+
+		RaiseEvent(Event{
+			Kind:     "security",
+			Severity: SeverityCritical,
+			Title:    "Connection to flagged IP",
+			Message:  fmt.Sprintf("%s (PID %d) is connected to %s, listed in %s", c.Process, c.PID, c.Remote, c.ThreatMatch),
+			Fields: map[string]string{
+				"process": c.Process,
+				"pid":     fmt.Sprint(c.PID),
+				"remote":  c.Remote,
+				"source":  c.ThreatMatch,
+			},
+		})
+	}
+
+	for key, last := range threatAlerted {
+		if now.Sub(last) > threatRealertAfter {
+			delete(threatAlerted, key)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// How often the certificate files are checked for a renewal.
+	certCheckInterval = time.Minute
+
+	// 825 days is the longest validity macOS and iOS accept.
+	selfSignedValidity = 825 * 24 * time.Hour
+	// A self-signed certificate this close to expiry is replaced at startup.
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+)
+
+// certReloader serves the configured certificate and picks up a renewed one
+// (certbot, acme.sh) without a restart when either file changes.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func (c *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.cert = &cert
+	c.modTime = c.filesModTime()
+	return nil
+}
+
+func (c *certReloader) filesModTime() time.Time {
+	var latest time.Time
+	for _, f := range []string{c.certFile, c.keyFile} {
+		if fi, err := os.Stat(f); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.checked) >= certCheckInterval {
+		c.checked = time.Now()
+		if c.filesModTime().After(c.modTime) {
+			// A half-written renewal keeps the old certificate in use.
+			if err := c.load(); err != nil {
+				slog.Warn("TLS certificate reload failed", "err", err)
+			} else {
+				slog.Info("TLS certificate reloaded", "file", c.certFile)
+			}
+		}
+	}
+	return c.cert, nil
+}
+
+// TLSEnabled reports whether tls.enabled is on or a certificate source is
+// configured.
+func TLSEnabled() bool {
+	t := GlobalConfig.TLS
+	return t.Enabled || t.CertFile != "" || t.KeyFile != "" || t.ACME.Domain != ""
+}
+
+// TLSConfig is the server's TLS configuration, or nil when TLS is off. The
+// certificate is loaded here so a bad path fails at startup; with ACME it
+// also starts issuance and renewal.
+func TLSConfig() (*tls.Config, error) {
+	cfg := GlobalConfig.TLS
+	if !TLSEnabled() {
+		return nil, nil
+	}
+	certFile, keyFile := cfg.CertFile, cfg.KeyFile
+	if cfg.ACME.Domain != "" && (certFile != "" || keyFile != "") {
+		return nil, errors.New("tls.acme cannot be combined with cert_file and key_file")
+	}
+	if certFile == "" && keyFile == "" {
+		certFile, keyFile = dataPath("tls-cert.pem"), dataPath("tls-key.pem")
+		if err := ensureSelfSignedCert(certFile, keyFile); err != nil {
+			return nil, fmt.Errorf("self-signed certificate: %w", err)
+		}
+	} else if certFile == "" || keyFile == "" {
+		return nil, errors.New("tls needs both cert_file and key_file")
+	}
+	c := &certReloader{certFile: certFile, keyFile: keyFile, checked: time.Now()}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	getCertificate := c.getCertificate
+	if cfg.ACME.Domain != "" {
+		m, err := newACMEManager(c.getCertificate)
+		if err != nil {
+			return nil, err
+		}
+		go m.run()
+		getCertificate = m.getCertificate
+	}
+	tc := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: getCertificate,
+	}
+	if cfg.ClientCA != "" {
+		if err := configureClientAuth(tc); err != nil {
+			return nil, err
+		}
+	}
+	return tc, nil
+}
+
+// ensureSelfSignedCert keeps the generated certificate from earlier runs,
+// so browsers that were told to trust it keep doing so, and makes a new one
+// when there is none or it is about to expire.
+func ensureSelfSignedCert(certFile, keyFile string) error {
+	if pair, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if time.Until(pair.Leaf.NotAfter) > selfSignedRenewBefore {
+			return nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	dnsNames, ips := selfSignedNames()
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: dnsNames[0], Organization: []string{"Talaria"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(der)
+	slog.Info("Generated self-signed TLS certificate", "file", certFile, "names", dnsNames, "ips", ips,
+		"sha256", strings.ToUpper(hex.EncodeToString(sum[:])))
+	return nil
+}
+
+// selfSignedNames lists the names and addresses the dashboard is likely to
+// be opened by: the hostname and its .local Bonjour name, localhost, every
+// interface address, and any host named in server.listen.
+func selfSignedNames() ([]string, []net.IP) {
+	var dnsNames []string
+	var ips []net.IP
+	seen := map[string]bool{}
+	addName := func(n string) {
+		n = strings.ToLower(strings.TrimSuffix(n, "."))
+		if n == "" || seen[n] {
+			return
+		}
+		seen[n] = true
+		if ip := net.ParseIP(n); ip != nil {
+			if !ip.IsUnspecified() {
+				ips = append(ips, ip)
+			}
+			return
+		}
+		dnsNames = append(dnsNames, n)
+	}
+
+	if h, err := os.Hostname(); err == nil {
+		addName(h)
+		if short, _, ok := strings.Cut(h, "."); ok {
+			addName(short)
+		} else {
+			addName(h + ".local")
+		}
+	}
+	addName("localhost")
+	addName(GlobalConfig.TLS.ACME.Domain)
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			if ipn, ok := a.(*net.IPNet); ok && !ipn.IP.IsLinkLocalUnicast() {
+				addName(ipn.IP.String())
+			}
+		}
+	}
+	addName("127.0.0.1")
+	addName("::1")
+	if addrs, err := ListenAddrs(); err == nil {
+		for _, a := range addrs {
+			if host, _, err := net.SplitHostPort(a); err == nil {
+				host, _, _ = strings.Cut(host, "%")
+				addName(host)
+			}
+		}
+	}
+	return dnsNames, ips
+}
+
+func urlScheme() string {
+	if TLSEnabled() {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Topics a dashboard can subscribe to over /ws are the collector names plus
+// "custom". A client that never subscribes receives every section.
+//
+//	{"action": "subscribe", "topics": ["cpu", "memory", "network"]}
+//	{"action": "unsubscribe", "topics": ["network"]}
+//	{"action": "subscribe", "topics": ["all"]}
+//
+// Each command is answered with {"type": "subscriptions", "topics": [...]}.
+// Bookkeeping fields (timestamp, seq, collected_at, ...) are always sent.
+
+// topicSections maps topics whose payload key differs from their name.
+var topicSections = map[string]string{
+	"storage": "storage_breakdown",
+	"diskio":  "disk_io",
+}
+
+func topicSection(topic string) string {
+	if s, ok := topicSections[topic]; ok {
+		return s
+	}
+	return topic
+}
+
+func sectionTopic(section string) string {
+	for t, s := range topicSections {
+		if s == section {
+			return t
+		}
+	}
+	return section
+}
+
+func knownTopic(topic string) bool {
+	if topic == "custom" {
+		return true
+	}
+	for _, c := range collectors {
+		if c.name == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSubscription applies a subscribe or unsubscribe command and reports
+// the resulting topics.
+func (c *Client) handleSubscription(action string, topics []string) {
+	c.reply(map[string]interface{}{"type": "subscriptions", "topics": c.subscribe(action, topics)})
+}
+
+// subscribe updates c's topics and returns them. Topic sets are guarded by
+// the hub's lock because the broadcast loop reads them.
+func (c *Client) subscribe(action string, topics []string) []string {
+	c.hub.mu.Lock()
+	set := c.topics
+	if set == nil {
+		set = make(map[string]bool)
+		if action == "unsubscribe" {
+			for _, col := range collectors {
+				set[col.name] = true
+			}
+			set["custom"] = true
+		}
+	} else {
+		set = make(map[string]bool, len(c.topics))
+		for t := range c.topics {
+			set[t] = true
+		}
+	}
+	all := false
+	for _, t := range topics {
+		t = strings.ToLower(strings.TrimSpace(t))
+		switch {
+		case t == "all" && action == "subscribe":
+			all = true
+		case !knownTopic(t):
+		case action == "subscribe":
+			set[t] = true
+		default:
+			delete(set, t)
+		}
+	}
+	if all {
+		set = nil
+	}
+	key := topicSetKey(set)
+	c.topics, c.topicKey = set, key
+	c.hub.mu.Unlock()
+
+	if set == nil {
+		return []string{"all"}
+	}
+	if len(set) == 0 {
+		return []string{}
+	}
+	return strings.Split(key, ",")
+}
+
+func topicSetKey(set map[string]bool) string {
+	names := make([]string, 0, len(set))
+	for t := range set {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// wantedTopics is the union of the clients' topics, or nil if any of them
+// wants everything. Callers hold the hub's lock.
+func wantedTopics(clients []*Client) map[string]bool {
+	union := make(map[string]bool)
+	for _, c := range clients {
+		if c.topics == nil {
+			return nil
+		}
+		for t := range c.topics {
+			union[t] = true
+		}
+	}
+	return union
+}
+
+// filterSections keeps the bookkeeping fields and the sections in topics.
+func filterSections(sections map[string]json.RawMessage, topics map[string]bool) ([]byte, error) {
+	skip := make(map[string]bool)
+	for _, c := range collectors {
+		if !topics[c.name] {
+			skip[topicSection(c.name)] = true
+		}
+	}
+	if !topics["custom"] {
+		skip["custom"] = true
+	}
+	out := make(map[string]json.RawMessage, len(sections))
+	for k, v := range sections {
+		if !skip[k] {
+			out[k] = v
+		}
+	}
+	return json.Marshal(out)
+}
+
+// topicFilter reads ?include= or ?exclude= (comma-separated topics or payload
+// keys) from a metrics request. It returns nil when neither is given.
+func topicFilter(q url.Values) (map[string]bool, error) {
+	include, exclude := q.Get("include"), q.Get("exclude")
+	if include == "" && exclude == "" {
+		return nil, nil
+	}
+	if include != "" && exclude != "" {
+		return nil, fmt.Errorf("use include or exclude, not both")
+	}
+	names := make(map[string]bool)
+	for _, n := range strings.Split(include+exclude, ",") {
+		n = sectionTopic(strings.ToLower(strings.TrimSpace(n)))
+		if n == "" {
+			continue
+		}
+		if !knownTopic(n) {
+			return nil, fmt.Errorf("unknown section %q", n)
+		}
+		names[n] = true
+	}
+	if include != "" {
+		return names, nil
+	}
+	topics := map[string]bool{"custom": !names["custom"]}
+	for _, c := range collectors {
+		topics[c.name] = !names[c.name]
+	}
+	return topics, nil
+}
+
+// filteredMetrics collects and encodes only the sections in topics, leaving
+// the others out of the payload rather than sending them zeroed.
+func filteredMetrics(topics map[string]bool) []byte {
+	data, err := json.Marshal(collectTopics(0, topics))
+	if err == nil {
+		var sections map[string]json.RawMessage
+		if err = json.Unmarshal(data, &sections); err == nil {
+			data, err = filterSections(sections, topics)
+		}
+	}
+	if err != nil {
+		slog.Error("Failed to encode metrics", "err", err)
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"talaria/monitor"
+	"time"
+)
+
+type uptimeDay struct {
+	Date         string  `json:"date"` // YYYY-MM-DD
+	UpSeconds    int64   `json:"up_seconds"`
+	SleepSeconds int64   `json:"sleep_seconds"`
+	DownSeconds  int64   `json:"down_seconds"`
+	Unknown      int64   `json:"unknown_seconds"` // before the logs begin, or in the future
+	Availability float64 `json:"availability_percent"`
+	Boots        int     `json:"boots"`
+	Sleeps       int     `json:"sleeps"`
+}
+
+// powerState replays events to get the state in effect at each instant:
+// "up" after boot/wake, "sleep" after sleep, "down" after shutdown.
+func powerState(kind string) string {
+	switch kind {
+	case monitor.PowerBoot, monitor.PowerWake:
+		return "up"
+	case monitor.PowerSleep:
+		return "sleep"
+	}
+	return "down"
+}
+
+func buildUptimeCalendar(month time.Time, events []monitor.PowerEvent, now time.Time) []uptimeDay {
+	loc := month.Location()
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 1, 0)
+
+	var days []uptimeDay
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, uptimeDay{Date: d.Format("2006-01-02")})
+	}
+	dayIndex := func(t time.Time) int {
+		return t.In(loc).Day() - 1
+	}
+
+	// add credits [from, to) to state, splitting at midnight.
+	add := func(from, to time.Time, state string) {
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		for from.Before(to) {
+			y, m, d := from.In(loc).Date()
+			midnight := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+			segEnd := to
+			if midnight.Before(segEnd) {
+				segEnd = midnight
+			}
+			secs := int64(segEnd.Sub(from) / time.Second)
+			day := &days[dayIndex(from)]
+			switch state {
+			case "up":
+				day.UpSeconds += secs
+			case "sleep":
+				day.SleepSeconds += secs
+			case "down":
+				day.DownSeconds += secs
+			default:
+				day.Unknown += secs
+			}
+			from = segEnd
+		}
+	}
+
+	state := "unknown"
+	cursor := start
+	for _, e := range events {
+		t := time.Unix(e.Time, 0)
+		if t.After(now) {
+			break
+		}
+		if !t.Before(start) && t.Before(end) {
+			day := &days[dayIndex(t)]
+			switch e.Kind {
+			case monitor.PowerBoot:
+				day.Boots++
+			case monitor.PowerSleep:
+				day.Sleeps++
+			}
+		}
+		if t.After(cursor) {
+			add(cursor, t, state)
+			cursor = t
+		}
+		state = powerState(e.Kind)
+	}
+	if now.After(cursor) {
+		add(cursor, now, state)
+		cursor = now
+	}
+	add(cursor, end, "unknown")
+
+	for i := range days {
+		d := &days[i]
+		// Sleeping counts as available: the host is healthy, just idle.
+		if known := d.UpSeconds + d.SleepSeconds + d.DownSeconds; known > 0 {
+			d.Availability = float64(d.UpSeconds+d.SleepSeconds) / float64(known) * 100
+		}
+	}
+	return days
+}
+
+func handleUptimeCalendar(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	month := now
+	if m := r.URL.Query().Get("month"); m != "" {
+		t, err := time.ParseInLocation("2006-01", m, time.Local)
+		if err != nil {
+			http.Error(w, "Invalid month, expected YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		month = t
+	}
+
+	events := monitor.GetPowerEvents()
+	days := buildUptimeCalendar(month, events, now)
+
+	var up, sleep, down int64
+	boots := 0
+	for _, d := range days {
+		up += d.UpSeconds
+		sleep += d.SleepSeconds
+		down += d.DownSeconds
+		boots += d.Boots
+	}
+	availability := 0.0
+	if known := up + sleep + down; known > 0 {
+		availability = float64(up+sleep) / float64(known) * 100
+	}
+	var coverageStart int64
+	if len(events) > 0 {
+		coverageStart = events[0].Time
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"month":                month.Format("2006-01"),
+		"days":                 days,
+		"availability_percent": availability,
+		"boots":                boots,
+		"coverage_start":       coverageStart,
+	})
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// WebhookConfig is one outgoing webhook under notifications.webhooks. Body is
+// a text/template rendered with webhookData; an empty body posts the event as
+// JSON.
+type WebhookConfig struct {
+	Name    string            `yaml:"name"`
+	Enabled bool              `yaml:"enabled"`
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"` // defaults to POST
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	Startup bool              `yaml:"startup"` // also announce server start
+}
+
+type webhookData struct {
+	Event     Event
+	Host      string
+	Time      string // RFC 3339
+	LocalURL  string // startup only
+	PublicURL string // startup only
+}
+
+type webhookNotifier struct {
+	cfg WebhookConfig
+}
+
+func (n *webhookNotifier) Name() string {
+	if n.cfg.Name != "" {
+		return "webhook " + n.cfg.Name
+	}
+	return "webhook"
+}
+
+func (n *webhookNotifier) Startup(info startupInfo) error {
+	if !n.cfg.Startup {
+		return nil
+	}
+	e := Event{
+		Time:     info.Time.Unix(),
+		Kind:     "system",
+		Severity: SeverityInfo,
+		Title:    "Talaria started",
+		Message:  "Talaria is up and running.",
+	}
+	return n.send(webhookData{
+		Event:     e,
+		Host:      info.Hostname,
+		Time:      info.Time.UTC().Format(time.RFC3339),
+		LocalURL:  info.LocalURL,
+		PublicURL: info.PublicURL,
+	})
+}
+
+func (n *webhookNotifier) Notify(e Event) error {
+	hostname, _ := os.Hostname()
+	return n.send(webhookData{
+		Event: e,
+		Host:  hostname,
+		Time:  time.Unix(e.Time, 0).UTC().Format(time.RFC3339),
+	})
+}
+
+func (n *webhookNotifier) Report(title, text string) error {
+	return n.Notify(Event{
+		Time:     time.Now().Unix(),
+		Kind:     "report",
+		Severity: SeverityInfo,
+		Title:    title,
+		Message:  text,
+	})
+}
+
+func (n *webhookNotifier) send(data webhookData) error {
+	body, err := n.render(data)
+	if err != nil {
+		return err
+	}
+
+	method := strings.ToUpper(n.cfg.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Talaria")
+	for k, v := range n.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: %s", n.cfg.URL, resp.Status)
+	}
+	return nil
+}
+
+// render executes the body template. Besides the webhookData fields it offers
+// {{metric "cpu.usage_percent"}} for the current value of any flattened metric
+// and {{json .Event.Message}} to embed a value as a JSON literal.
+func (n *webhookNotifier) render(data webhookData) ([]byte, error) {
+	if n.cfg.Body == "" {
+		return json.Marshal(map[string]interface{}{"host": data.Host, "event": data.Event})
+	}
+
+	var (
+		metrics     map[string]float64
+		metricsOnce sync.Once
+	)
+	funcs := template.FuncMap{
+		"metric": func(key string) string {
+			metricsOnce.Do(func() { metrics = flattenMetrics(latestMetrics()) })
+			if v, ok := metrics[key]; ok {
+				return strconv.FormatFloat(v, 'f', -1, 64)
+			}
+			return "null"
+		},
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+
+	tmpl, err := template.New(n.Name()).Funcs(funcs).Option("missingkey=zero").Parse(n.cfg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("body template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("body template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,413 @@
+package server
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxPushSubscriptions = 50
+	pushTTL              = 24 * time.Hour
+	vapidTokenLifetime   = 12 * time.Hour
+)
+
+type pushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+	User    string `json:"user,omitempty"`
+	Created int64  `json:"created"`
+}
+
+var (
+	vapidKey     *ecdsa.PrivateKey
+	vapidKeyOnce sync.Once
+	vapidKeyErr  error
+
+	pushSubs   []pushSubscription
+	pushSubsMu sync.Mutex
+	pushLoaded bool
+)
+
+// vapidPrivateKey loads the VAPID key pair from the data dir, generating it
+// on first use. Rotating it invalidates every browser subscription.
+func vapidPrivateKey() (*ecdsa.PrivateKey, error) {
+	vapidKeyOnce.Do(func() {
+		path := dataPath("vapid.pem")
+		if data, err := os.ReadFile(path); err == nil {
+			block, _ := pem.Decode(data)
+			if block == nil {
+				vapidKeyErr = errors.New("vapid.pem: no PEM block")
+				return
+			}
+			key, err := x509.ParseECPrivateKey(block.Bytes)
+			if err != nil {
+				vapidKeyErr = fmt.Errorf("vapid.pem: %w", err)
+				return
+			}
+			vapidKey = key
+			return
+		}
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			vapidKeyErr = err
+			return
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			vapidKeyErr = err
+			return
+		}
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+			vapidKeyErr = err
+			return
+		}
+		slog.Info("Generated VAPID key pair", "path", path)
+		vapidKey = key
+	})
+	return vapidKey, vapidKeyErr
+}
+
+func vapidPublicKey() (string, error) {
+	key, err := vapidPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	pub, err := key.PublicKey.ECDH()
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(pub.Bytes()), nil
+}
+
+// vapidAuthorization builds the RFC 8292 header for the push service that
+// owns endpoint.
+func vapidAuthorization(endpoint string) (string, error) {
+	key, err := vapidPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	subject := GlobalConfig.Notifications.WebPush.Subject
+	if subject == "" {
+		subject = "mailto:talaria@localhost"
+	}
+
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidTokenLifetime).Unix(),
+		"sub": subject,
+	})
+	signing := header + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signing))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	pub, err := vapidPublicKey()
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + signing + "." + enc.EncodeToString(sig) + ", k=" + pub, nil
+}
+
+// encryptPushPayload implements the aes128gcm content encoding from RFC 8291
+// as a single record.
+func encryptPushPayload(sub pushSubscription, plaintext []byte) ([]byte, error) {
+	uaPublic, err := decodeB64(sub.Keys.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("p256dh: %w", err)
+	}
+	authSecret, err := decodeB64(sub.Keys.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm, err := hkdf.Key(sha256.New, shared, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last (and only) record.
+	ciphertext := gcm.Seal(nil, nonce, append(plaintext[:len(plaintext):len(plaintext)], 0x02), nil)
+
+	var body bytes.Buffer
+	body.Write(salt)
+	binary.Write(&body, binary.BigEndian, uint32(4096))
+	body.WriteByte(byte(len(asPublic)))
+	body.Write(asPublic)
+	body.Write(ciphertext)
+	return body.Bytes(), nil
+}
+
+// Browsers hand out keys in either base64url flavour.
+func decodeB64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+var errPushGone = errors.New("subscription expired")
+
+func sendPush(sub pushSubscription, payload []byte, urgency string) error {
+	body, err := encryptPushPayload(sub, payload)
+	if err != nil {
+		return err
+	}
+	auth, err := vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprint(int(pushTTL.Seconds())))
+	req.Header.Set("Urgency", urgency)
+
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return errPushGone
+	case resp.StatusCode/100 != 2:
+		return fmt.Errorf("push service: %s", resp.Status)
+	}
+	return nil
+}
+
+func loadPushSubscriptions() {
+	if pushLoaded {
+		return
+	}
+	pushLoaded = true
+	if data, err := os.ReadFile(dataPath("push_subscriptions.json")); err == nil {
+		json.Unmarshal(data, &pushSubs)
+	}
+}
+
+func savePushSubscriptions() {
+	data, err := json.Marshal(pushSubs)
+	if err == nil {
+		err = os.WriteFile(dataPath("push_subscriptions.json"), data, 0600)
+	}
+	if err != nil {
+		slog.Error("Failed to save push subscriptions", "err", err)
+	}
+}
+
+// startWebPush creates the VAPID key pair up front so the first browser to
+// subscribe doesn't pay for it.
+func startWebPush() {
+	if !GlobalConfig.Notifications.WebPush.Enabled {
+		return
+	}
+	if _, err := vapidPrivateKey(); err != nil {
+		slog.Warn("Web Push disabled", "err", err)
+	}
+}
+
+type webPushNotifier struct{}
+
+func (webPushNotifier) Name() string { return "webpush" }
+
+func (webPushNotifier) Startup(startupInfo) error { return nil }
+
+func (webPushNotifier) Notify(e Event) error {
+	hostname, _ := os.Hostname()
+	payload, _ := json.Marshal(map[string]interface{}{
+		"title": e.Title,
+		"body":  e.Message,
+		"tag":   incidentKey(e),
+		"host":  hostname,
+		"time":  e.Time,
+	})
+	urgency := "normal"
+	if e.Severity == SeverityCritical && e.Fields["state"] != "resolved" {
+		urgency = "high"
+	}
+
+	pushSubsMu.Lock()
+	loadPushSubscriptions()
+	subs := append([]pushSubscription(nil), pushSubs...)
+	pushSubsMu.Unlock()
+
+	var errs []error
+	gone := make(map[string]bool)
+	for _, sub := range subs {
+		err := sendPush(sub, payload, urgency)
+		if errors.Is(err, errPushGone) {
+			gone[sub.Endpoint] = true
+		} else if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(gone) > 0 {
+		pushSubsMu.Lock()
+		kept := pushSubs[:0]
+		for _, sub := range pushSubs {
+			if !gone[sub.Endpoint] {
+				kept = append(kept, sub)
+			}
+		}
+		pushSubs = kept
+		savePushSubscriptions()
+		pushSubsMu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func handlePushKey(w http.ResponseWriter, r *http.Request) {
+	if !GlobalConfig.Notifications.WebPush.Enabled {
+		http.Error(w, "Web Push is disabled", http.StatusNotFound)
+		return
+	}
+	key, err := vapidPublicKey()
+	if err != nil {
+		http.Error(w, "VAPID key unavailable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"public_key": key})
+}
+
+// handlePushSubscription stores (POST /api/push/subscribe) or removes
+// (POST /api/push/unsubscribe) a browser PushSubscription.
+func handlePushSubscription(w http.ResponseWriter, r *http.Request) {
+	if !GlobalConfig.Notifications.WebPush.Enabled {
+		http.Error(w, "Web Push is disabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var sub pushSubscription
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&sub); err != nil {
+		http.Error(w, "Invalid subscription", http.StatusBadRequest)
+		return
+	}
+	if u, err := url.Parse(sub.Endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+		http.Error(w, "Invalid endpoint", http.StatusBadRequest)
+		return
+	}
+
+	subscribe := r.URL.Path == "/api/push/subscribe"
+	if subscribe {
+		if pub, err := decodeB64(sub.Keys.P256dh); err != nil || len(pub) != 65 {
+			http.Error(w, "Invalid p256dh key", http.StatusBadRequest)
+			return
+		}
+		if auth, err := decodeB64(sub.Keys.Auth); err != nil || len(auth) != 16 {
+			http.Error(w, "Invalid auth secret", http.StatusBadRequest)
+			return
+		}
+		if s := getSessionFromRequest(r); s != nil {
+			sub.User = s.user
+		}
+		sub.Created = time.Now().Unix()
+	}
+
+	pushSubsMu.Lock()
+	loadPushSubscriptions()
+	kept := pushSubs[:0]
+	for _, existing := range pushSubs {
+		if existing.Endpoint != sub.Endpoint {
+			kept = append(kept, existing)
+		}
+	}
+	pushSubs = kept
+	if subscribe {
+		if len(pushSubs) >= maxPushSubscriptions {
+			pushSubs = pushSubs[1:]
+		}
+		pushSubs = append(pushSubs, sub)
+	}
+	savePushSubscriptions()
+	pushSubsMu.Unlock()
+
+	action := "push_unsubscribe"
+	if subscribe {
+		action = "push_subscribe"
+	}
+	u, _ := url.Parse(sub.Endpoint)
+	auditLog(r, action, map[string]string{"service": u.Host}, "ok")
+	w.WriteHeader(http.StatusNoContent)
+}
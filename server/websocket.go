@@ -1,8 +1,12 @@
 package server
 
 import (
-	"log"
+	"encoding/json"
+	"log/slog"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -14,6 +18,8 @@ const (
 	pongWait = 60 * time.Second
 
 	pingPeriod = (pongWait * 9) / 10
+
+	latencyPingPeriod = 5 * time.Second
 )
 
 var upgrader = websocket.Upgrader{
@@ -21,25 +27,82 @@ var upgrader = websocket.Upgrader{
 	ReadBufferSize:    1024,
 	WriteBufferSize:   8192, // B6 fix: metrics payload ~5-10KB, avoid buffer reallocation
 	EnableCompression: true, // Enable compression to save bandwidth
+	CheckOrigin:       checkWSOrigin,
+}
+
+// plainUpgrader never negotiates permessage-deflate; deflating every frame
+// costs more CPU than it saves on a LAN.
+var plainUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 8192,
+	CheckOrigin:     checkWSOrigin,
+}
+
+// wantCompression decides whether to offer permessage-deflate. In "auto" mode
+// only clients arriving through a tunnel or from a public address get it; a
+// client can always opt out with ?compress=0.
+func wantCompression(r *http.Request) bool {
+	switch r.URL.Query().Get("compress") {
+	case "0":
+		return false
+	case "1":
+		return GlobalConfig.WebSocket.Compression != "off"
+	}
+
+	switch GlobalConfig.WebSocket.Compression {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+	if r.Header.Get("Cf-Connecting-IP") != "" {
+		return true
+	}
+	addr, err := netip.ParseAddr(getRealIP(r))
+	if err != nil {
+		return true
+	}
+	addr = addr.Unmap()
+	return !(addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast())
 }
 
 func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	u := &plainUpgrader
+	compressed := wantCompression(r)
+	if compressed {
+		u = &upgrader
+		compressed = strings.Contains(strings.ToLower(r.Header.Get("Sec-WebSocket-Extensions")), "permessage-deflate")
+	}
+
+	conn, err := u.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		slog.Warn("WebSocket upgrade failed", "err", err)
 		return
 	}
 
-	client := &Client{hub: hub, conn: conn, send: make(chan *websocket.PreparedMessage, 16)}
+	client := &Client{
+		hub:         hub,
+		conn:        conn,
+		send:        make(chan *websocket.PreparedMessage, 16),
+		replies:     make(chan *websocket.PreparedMessage, 4),
+		done:        make(chan struct{}),
+		id:          generateToken(6),
+		remoteAddr:  getRealIP(r),
+		userAgent:   r.UserAgent(),
+		connectedAt: time.Now(),
+		compressed:  compressed,
+		msgpack:     r.URL.Query().Get("encoding") == "msgpack",
+	}
 	client.hub.register <- client
 
 	go client.writePump()
 	go client.readPump()
+	go client.reply(sparklineSnapshot())
 }
 
 func (c *Client) readPump() {
 	defer func() {
-
+		close(c.done)
 		select {
 		case c.hub.unregister <- c:
 		default:
@@ -49,8 +112,13 @@ func (c *Client) readPump() {
 
 	c.conn.SetReadLimit(512)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error {
+	c.conn.SetPongHandler(func(appData string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		if sent, err := strconv.ParseInt(appData, 10, 64); err == nil {
+			if rtt := time.Now().UnixNano() - sent; rtt > 0 {
+				c.rttNanos.Store(rtt)
+			}
+		}
 		return nil
 	})
 
@@ -58,14 +126,30 @@ func (c *Client) readPump() {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				slog.Debug("WebSocket closed unexpectedly", "err", err)
 			}
 			break
 		}
 
+		var q struct {
+			Action string   `json:"action"`
+			Topics []string `json:"topics"`
+			historyQuery
+		}
+		if json.Unmarshal(message, &q) == nil {
+			switch q.Action {
+			case "query":
+				go c.runQuery(q.historyQuery)
+				continue
+			case "subscribe", "unsubscribe":
+				c.handleSubscription(q.Action, q.Topics)
+				continue
+			}
+		}
+
 		if len(message) > 0 {
 			select {
-			case c.hub.incoming <- message:
+			case c.hub.incoming <- clientMessage{c, message}:
 			default:
 
 			}
@@ -74,7 +158,8 @@ func (c *Client) readPump() {
 }
 
 func (c *Client) writePump() {
-	ticker := time.NewTicker(pingPeriod)
+	// Pings double as latency probes, so they run more often than pongWait requires.
+	ticker := time.NewTicker(latencyPingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
@@ -94,9 +179,16 @@ func (c *Client) writePump() {
 				return
 			}
 
+		case pm := <-c.replies:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WritePreparedMessage(pm); err != nil {
+				return
+			}
+
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			stamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+			if err := c.conn.WriteMessage(websocket.PingMessage, []byte(stamp)); err != nil {
 				return
 			}
 		}
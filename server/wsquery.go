@@ -0,0 +1,268 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxQueryPoints   = 5000
+	queryChunkSize   = 500
+	maxClientQueries = 2 // in flight per connection
+)
+
+// historyQuery is sent by the dashboard as
+// {"action":"query","id":"z1","metric":"cpu","from":...,"to":...,"step":...}
+// with times in unix ms. metric is a flattened key or a prefix ("cpu" matches
+// every "cpu.*" key in history). agg picks how buckets combine: "avg"
+// (default), "min" or "max"; min and max use the rollups' true extremes.
+type historyQuery struct {
+	ID     string `json:"id"`
+	Metric string `json:"metric"`
+	From   int64  `json:"from"`
+	To     int64  `json:"to"`
+	Step   int64  `json:"step"`           // bucket width in ms; 0 returns raw samples
+	Host   string `json:"host,omitempty"` // a federated host; empty for this machine
+	Agg    string `json:"agg,omitempty"`
+}
+
+type queryChunk struct {
+	Type   string       `json:"type"` // always "query"
+	ID     string       `json:"id"`
+	Metric string       `json:"metric,omitempty"`
+	Step   int64        `json:"step,omitempty"`
+	Points [][2]float64 `json:"points,omitempty"` // [t, value]
+	Done   bool         `json:"done,omitempty"`
+	Gaps   []historyGap `json:"gaps,omitempty"` // on the done chunk
+	Error  string       `json:"error,omitempty"`
+}
+
+// runQuery streams the answer to q back to c in chunks, one series at a time,
+// finishing with a chunk that has done set.
+func (c *Client) runQuery(q historyQuery) {
+	if c.queries.Add(1) > maxClientQueries {
+		c.queries.Add(-1)
+		c.reply(queryChunk{Type: "query", ID: q.ID, Done: true, Error: "too many queries in flight"})
+		return
+	}
+	defer c.queries.Add(-1)
+
+	if err := q.normalize(); err != nil {
+		c.reply(queryChunk{Type: "query", ID: q.ID, Done: true, Error: err.Error()})
+		return
+	}
+
+	for _, series := range q.run() {
+		points := series.Points
+		for len(points) > 0 {
+			n := min(len(points), queryChunkSize)
+			if !c.reply(queryChunk{Type: "query", ID: q.ID, Metric: series.Metric, Step: q.Step, Points: points[:n]}) {
+				return
+			}
+			points = points[n:]
+		}
+	}
+	c.reply(queryChunk{Type: "query", ID: q.ID, Done: true, Gaps: q.gaps()})
+}
+
+type querySeries struct {
+	Metric string       `json:"metric"`
+	Points [][2]float64 `json:"points"`
+}
+
+// normalize fills in a missing end time and raises the step so no series
+// exceeds maxQueryPoints.
+func (q *historyQuery) normalize() error {
+	if q.To == 0 {
+		q.To = time.Now().UnixMilli()
+	}
+	if q.Metric == "" || q.From <= 0 || q.From >= q.To {
+		return errors.New("metric, from and to are required")
+	}
+	if q.Step < 0 {
+		q.Step = 0
+	}
+	switch q.Agg {
+	case "":
+		q.Agg = "avg"
+	case "avg", "min", "max":
+	default:
+		return errors.New("agg must be avg, min or max")
+	}
+	if floor := (q.To - q.From) / maxQueryPoints; q.Step < floor {
+		q.Step = floor
+	}
+	return nil
+}
+
+// run returns one series per history key matching q.Metric, sorted by key.
+func (q *historyQuery) run() []querySeries {
+	var samples []historySample
+	if q.Host != "" {
+		samples = fleetRange(q.Host, q.From, q.To)
+	} else {
+		samples = historyRangeStep(q.From, q.To, q.Step)
+	}
+	keys := map[string]bool{}
+	for _, s := range samples {
+		for k := range s.V {
+			if k == q.Metric || strings.HasPrefix(k, q.Metric+".") {
+				keys[k] = true
+			}
+		}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	out := make([]querySeries, 0, len(sorted))
+	for _, key := range sorted {
+		out = append(out, querySeries{Metric: key, Points: bucketSeries(samples, key, q.From, q.Step, q.Agg)})
+	}
+	return out
+}
+
+// gaps returns the sleep and restart gaps in q's range. Federated hosts have
+// none recorded; their history simply has holes.
+func (q *historyQuery) gaps() []historyGap {
+	if q.Host != "" {
+		return []historyGap{}
+	}
+	return historyGapsIn(q.From, q.To)
+}
+
+// handleHistory answers GET /api/history?metric=&from=&to=&step=&agg= with the
+// same downsampled series as the WebSocket query. from and to are unix ms
+// (from defaults to 24h ago, to to now); step is ms or a duration like "5m".
+// host selects a federated host's history instead of this machine's. gaps
+// lists the periods with no samples, so clients can break lines there
+// instead of interpolating across them.
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	qs := r.URL.Query()
+	q := historyQuery{Metric: qs.Get("metric"), Host: qs.Get("host"), Agg: qs.Get("agg")}
+	var err error
+	if v := qs.Get("from"); v != "" {
+		q.From, err = strconv.ParseInt(v, 10, 64)
+	} else {
+		q.From = time.Now().Add(-24 * time.Hour).UnixMilli()
+	}
+	if v := qs.Get("to"); v != "" && err == nil {
+		q.To, err = strconv.ParseInt(v, 10, 64)
+	}
+	if v := qs.Get("step"); v != "" && err == nil {
+		if q.Step, err = strconv.ParseInt(v, 10, 64); err != nil {
+			var d time.Duration
+			d, err = time.ParseDuration(v)
+			q.Step = d.Milliseconds()
+		}
+	}
+	if err != nil {
+		http.Error(w, "Invalid from, to or step", http.StatusBadRequest)
+		return
+	}
+	if err := q.normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"from":   q.From,
+		"to":     q.To,
+		"step":   q.Step,
+		"series": q.run(),
+		"gaps":   q.gaps(),
+	})
+	if err != nil {
+		http.Error(w, "Failed to encode history", http.StatusInternalServerError)
+		return
+	}
+	writeEncoded(w, r, data)
+}
+
+// bucketSeries combines key over step-wide buckets aligned to from, by agg;
+// with a zero step every sample is returned as-is.
+func bucketSeries(samples []historySample, key string, from, step int64, agg string) [][2]float64 {
+	var out [][2]float64
+	var bucket int64 = math.MinInt64
+	var acc float64
+	var n int
+	flush := func() {
+		if n > 0 {
+			if agg == "avg" {
+				acc /= float64(n)
+			}
+			out = append(out, [2]float64{float64(from + bucket*step), acc})
+		}
+		acc, n = 0, 0
+	}
+	for _, s := range samples {
+		v, ok := s.V[key]
+		if !ok {
+			continue
+		}
+		switch agg {
+		case "min":
+			if m, ok := s.Min[key]; ok {
+				v = m
+			}
+		case "max":
+			if m, ok := s.Max[key]; ok {
+				v = m
+			}
+		}
+		if step == 0 {
+			out = append(out, [2]float64{float64(s.T), v})
+			continue
+		}
+		if b := (s.T - from) / step; b != bucket {
+			flush()
+			bucket = b
+		}
+		switch {
+		case n == 0:
+			acc = v
+		case agg == "avg":
+			acc += v
+		case agg == "min":
+			acc = math.Min(acc, v)
+		case agg == "max":
+			acc = math.Max(acc, v)
+		}
+		n++
+	}
+	if step > 0 {
+		flush()
+	}
+	return out
+}
+
+// reply queues a message for this client only. It gives up once the
+// connection is gone.
+func (c *Client) reply(v interface{}) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	pm, err := c.prepare(data)
+	if err != nil {
+		return false
+	}
+	select {
+	case c.replies <- pm:
+		return true
+	case <-c.done:
+		return false
+	}
+}